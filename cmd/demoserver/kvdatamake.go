@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/hanfei1991/microcosm/pb"
 	"github.com/pingcap/tiflow/dm/pkg/log"
@@ -20,6 +21,7 @@ import (
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 )
 
 var (
@@ -148,7 +150,11 @@ func (s *DataRWServer) GenerateData(ctx context.Context, req *pb.GenerateDataReq
 }
 
 func startDataService(ctx context.Context) {
-	grpcServer := grpc.NewServer()
+	// the cvs tasks ping the data service every 10s to detect the dead
+	// connections, which is more frequent than the default policy allows
+	grpcServer := grpc.NewServer(grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+		MinTime: 5 * time.Second,
+	}))
 	var s pb.DataRWServiceServer
 	if mock {
 		s = &dataRWServiceMock{
@@ -375,7 +381,13 @@ func (s *DataRWServer) WriteLines(stream pb.DataRWService_WriteLinesServer) erro
 					}
 				}
 				batch = peddleDB.Batch(2048)
-				batch.Put(res.Key, res.Value)
+				if len(res.Pairs) > 0 {
+					for _, pair := range res.Pairs {
+						batch.Put(pair.Key, pair.Value)
+					}
+				} else {
+					batch.Put(res.Key, res.Value)
+				}
 				err := batch.Commit()
 				if err != nil {
 					log.L().Error("write data failed  ",
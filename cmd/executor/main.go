@@ -7,6 +7,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/hanfei1991/microcosm/executor"
 	"github.com/pingcap/errors"
@@ -14,6 +15,9 @@ import (
 	"go.uber.org/zap"
 )
 
+// shutdownTimeout is the max duration to shutdown the executor gracefully
+const shutdownTimeout = 30 * time.Second
+
 // 1. parse config
 // 2. init logger
 // 3. register singal handler
@@ -41,6 +45,7 @@ func main() {
 	}
 
 	// 3. register signal handler
+	server := executor.NewServer(cfg, nil)
 	ctx, cancel := context.WithCancel(context.Background())
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc,
@@ -53,16 +58,17 @@ func main() {
 		case <-ctx.Done():
 		case sig := <-sc:
 			log.L().Info("got signal to exit", zap.Stringer("signal", sig))
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			err := server.Shutdown(shutdownCtx)
+			shutdownCancel()
+			if err != nil {
+				log.L().Warn("fail to shutdown executor gracefully", zap.Error(err))
+			}
 			cancel()
 		}
 	}()
 
 	// 4. run executor server
-	server := executor.NewServer(cfg, nil)
-	if err != nil {
-		log.L().Error("fail to start executor", zap.Error(err))
-		os.Exit(2)
-	}
 	err = server.Run(ctx)
 	if err != nil && errors.Cause(err) != context.Canceled {
 		log.L().Error("run executor with error", zap.Error(err))
@@ -0,0 +1,67 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiflow/dm/dm/config"
+
+	"github.com/hanfei1991/microcosm/pkg/adapter"
+	"github.com/hanfei1991/microcosm/pkg/meta/metaclient"
+)
+
+// dumpCheckpoint records the progress of the dump of a subtask.
+// Dumpling can't continue a partial dump, so the dump is only skipped when it
+// has finished and the dumped files have been persisted. A restarted worker
+// without a finished checkpoint dumps from the beginning, the saved progress
+// only tells how far the previous dump has gone.
+type dumpCheckpoint struct {
+	Task     string        `json:"task"`
+	SourceID string        `json:"source-id"`
+	Finished bool          `json:"finished"`
+	Progress *dumpProgress `json:"progress,omitempty"`
+}
+
+// dumpProgress is the progress of an unfinished dump.
+type dumpProgress struct {
+	TotalTables     int64   `json:"total-tables"`
+	CompletedTables float64 `json:"completed-tables"`
+	FinishedBytes   float64 `json:"finished-bytes"`
+	FinishedRows    float64 `json:"finished-rows"`
+}
+
+func dumpCheckpointKey(cfg *config.SubTaskConfig) string {
+	return adapter.DMDumpCheckpointKeyAdapter.Encode(cfg.Name, cfg.SourceID)
+}
+
+// loadDumpCheckpoint returns the dump checkpoint of the subtask, or nil if
+// there is no checkpoint.
+func loadDumpCheckpoint(
+	ctx context.Context, kvClient metaclient.KVClient, cfg *config.SubTaskConfig,
+) (*dumpCheckpoint, error) {
+	resp, err := kvClient.Get(ctx, dumpCheckpointKey(cfg))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	cp := &dumpCheckpoint{}
+	if err := json.Unmarshal(resp.Kvs[0].Value, cp); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return cp, nil
+}
+
+func saveDumpCheckpoint(
+	ctx context.Context, kvClient metaclient.KVClient, cfg *config.SubTaskConfig, cp *dumpCheckpoint,
+) error {
+	value, err := json.Marshal(cp)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = kvClient.Put(ctx, dumpCheckpointKey(cfg), string(value))
+	return errors.Trace(err)
+}
@@ -5,8 +5,11 @@ import (
 	"time"
 
 	"github.com/hanfei1991/microcosm/jobmaster/dm"
+	"github.com/hanfei1991/microcosm/jobmaster/dm/metadata"
+	"github.com/hanfei1991/microcosm/jobmaster/dm/runtime"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiflow/dm/dm/config"
+	"github.com/pingcap/tiflow/dm/dm/unit"
 	"github.com/pingcap/tiflow/dm/dumpling"
 	"github.com/pingcap/tiflow/dm/pkg/log"
 	"go.uber.org/zap"
@@ -15,16 +18,24 @@ import (
 
 	"github.com/hanfei1991/microcosm/lib"
 	"github.com/hanfei1991/microcosm/model"
+	dmpkg "github.com/hanfei1991/microcosm/pkg/dm"
 	"github.com/hanfei1991/microcosm/pkg/p2p"
 )
 
 var _ lib.Worker = &dumpWorker{}
 
+// newDumpUnit creates the dm unit of dumpWorker, it is replaced in unit tests.
+var newDumpUnit = func(cfg *config.SubTaskConfig) unit.Unit {
+	return dumpling.NewDumpling(cfg)
+}
+
 type dumpWorker struct {
 	lib.BaseWorker
 
 	cfg        *config.SubTaskConfig
 	unitHolder *unitHolder
+	// lastProgress is the progress saved to the checkpoint last time
+	lastProgress *dumpProgress
 }
 
 func newDumpWorker(cfg lib.WorkerConfig) lib.WorkerImpl {
@@ -37,6 +48,30 @@ func newDumpWorker(cfg lib.WorkerConfig) lib.WorkerImpl {
 func (d *dumpWorker) InitImpl(ctx context.Context) error {
 	log.L().Info("init dump worker")
 
+	if err := preflightSubTaskConfig(d.cfg); err != nil {
+		return err
+	}
+
+	cp, err := loadDumpCheckpoint(ctx, d.MetaKVClient(), d.cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if cp != nil && cp.Finished {
+		// the dumped files have been persisted before restart
+		log.L().Info("dump is finished before, skip dumping",
+			zap.String("task", d.cfg.Name), zap.String("source", d.cfg.SourceID))
+		d.unitHolder = newUnitHolder(lib.WorkerDMDump, d.cfg.SourceID, newDumpUnit(d.cfg))
+		d.unitHolder.markFinished()
+		return nil
+	}
+	if cp != nil && cp.Progress != nil {
+		log.L().Info("dump is not finished before, dump from the beginning",
+			zap.String("task", d.cfg.Name), zap.String("source", d.cfg.SourceID),
+			zap.Float64("completed tables", cp.Progress.CompletedTables),
+			zap.Int64("total tables", cp.Progress.TotalTables),
+			zap.Float64("finished rows", cp.Progress.FinishedRows))
+	}
+
 	rid := dm.NewDMResourceID(d.cfg.Name, d.cfg.SourceID)
 	h, err := d.OpenStorage(ctx, rid)
 	for status.Code(errors.Cause(err)) == codes.Unavailable {
@@ -50,11 +85,47 @@ func (d *dumpWorker) InitImpl(ctx context.Context) error {
 	}
 	d.cfg.ExtStorage = h.BrExternalStorage()
 
-	d.unitHolder = newUnitHolder(lib.WorkerDMDump, d.cfg.SourceID, dumpling.NewDumpling(d.cfg))
+	d.unitHolder = newUnitHolder(lib.WorkerDMDump, d.cfg.SourceID, newDumpUnit(d.cfg))
 	d.unitHolder.storageWriteHandle = h
+	d.unitHolder.onFinished = func(ctx context.Context) error {
+		return saveDumpCheckpoint(ctx, d.MetaKVClient(), d.cfg, &dumpCheckpoint{
+			Task:     d.cfg.Name,
+			SourceID: d.cfg.SourceID,
+			Finished: true,
+		})
+	}
+	d.unitHolder.onRunning = d.saveProgress
 	return errors.Trace(d.unitHolder.init(ctx))
 }
 
+// saveProgress saves the progress of the running dump to the checkpoint if it
+// has changed since the last save.
+func (d *dumpWorker) saveProgress(ctx context.Context, status runtime.TaskStatus) error {
+	s, ok := status.(*runtime.DumpStatus)
+	if !ok {
+		return nil
+	}
+	progress := &dumpProgress{
+		TotalTables:     s.TotalTables,
+		CompletedTables: s.CompletedTables,
+		FinishedBytes:   s.FinishedBytes,
+		FinishedRows:    s.FinishedRows,
+	}
+	if d.lastProgress != nil && *d.lastProgress == *progress {
+		return nil
+	}
+	err := saveDumpCheckpoint(ctx, d.MetaKVClient(), d.cfg, &dumpCheckpoint{
+		Task:     d.cfg.Name,
+		SourceID: d.cfg.SourceID,
+		Progress: progress,
+	})
+	if err != nil {
+		return err
+	}
+	d.lastProgress = progress
+	return nil
+}
+
 func (d *dumpWorker) Tick(ctx context.Context) error {
 	d.unitHolder.lazyProcess()
 	return d.unitHolder.tryUpdateStatus(ctx, d.BaseWorker)
@@ -72,10 +143,37 @@ func (d *dumpWorker) OnMasterFailover(reason lib.MasterFailoverReason) error {
 
 func (d *dumpWorker) OnMasterMessage(topic p2p.Topic, message p2p.MessageValue) error {
 	log.L().Info("dumpWorker.OnMasterMessage", zap.Any("message", message))
+	msg, ok := message.(*dmpkg.OperateTaskMessage)
+	if !ok {
+		return nil
+	}
+	switch msg.Stage {
+	case metadata.StagePaused:
+		return d.Pause(context.Background())
+	case metadata.StageRunning:
+		return d.Resume(context.Background())
+	default:
+		log.L().Warn("unsupported stage to operate dump worker", zap.Int("stage", int(msg.Stage)))
+		return nil
+	}
+}
+
+// Pause pauses the running dump to relieve the load of the source database,
+// the worker is reported as paused until Resume is called.
+func (d *dumpWorker) Pause(ctx context.Context) error {
+	log.L().Info("pause dump worker", zap.String("task", d.cfg.Name), zap.String("source", d.cfg.SourceID))
+	d.unitHolder.pause()
+	return nil
+}
+
+// Resume resumes the dump paused by Pause.
+func (d *dumpWorker) Resume(ctx context.Context) error {
+	log.L().Info("resume dump worker", zap.String("task", d.cfg.Name), zap.String("source", d.cfg.SourceID))
+	d.unitHolder.resume()
 	return nil
 }
 
 func (d *dumpWorker) CloseImpl(ctx context.Context) error {
-	d.unitHolder.close()
+	d.unitHolder.close(ctx)
 	return nil
 }
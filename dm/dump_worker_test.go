@@ -3,18 +3,37 @@ package dm
 import (
 	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/pingcap/errors"
+	brStorage "github.com/pingcap/tidb/br/pkg/storage"
 	"github.com/pingcap/tidb/util/filter"
 	"github.com/pingcap/tiflow/dm/dm/config"
+	"github.com/pingcap/tiflow/dm/dm/pb"
+	"github.com/pingcap/tiflow/dm/dm/unit"
+	"github.com/pingcap/tiflow/dm/pkg/binlog"
 	"github.com/pingcap/tiflow/dm/pkg/log"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
 
+	"github.com/hanfei1991/microcosm/jobmaster/dm/metadata"
+	"github.com/hanfei1991/microcosm/jobmaster/dm/runtime"
 	"github.com/hanfei1991/microcosm/lib"
 	libModel "github.com/hanfei1991/microcosm/lib/model"
 	"github.com/hanfei1991/microcosm/lib/registry"
 	"github.com/hanfei1991/microcosm/pkg/adapter"
 	dcontext "github.com/hanfei1991/microcosm/pkg/context"
+	dmpkg "github.com/hanfei1991/microcosm/pkg/dm"
+	derrors "github.com/hanfei1991/microcosm/pkg/errors"
+	"github.com/hanfei1991/microcosm/pkg/externalresource/broker"
+	resourcemeta "github.com/hanfei1991/microcosm/pkg/externalresource/resourcemeta/model"
+	mockkv "github.com/hanfei1991/microcosm/pkg/meta/kvclient/mock"
 	"github.com/hanfei1991/microcosm/pkg/meta/metaclient"
 )
 
@@ -106,3 +125,497 @@ func TestDumpWorker(t *testing.T) {
 	err = worker.Close(context.Background())
 	require.NoError(t, err)
 }
+
+func TestDumpWorkerInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		modify func(cfg *config.SubTaskConfig)
+		errMsg string
+	}{
+		{
+			modify: func(cfg *config.SubTaskConfig) { cfg.SourceID = "" },
+			errMsg: "source-id is empty",
+		},
+		{
+			modify: func(cfg *config.SubTaskConfig) { cfg.Mode = "dump" },
+			errMsg: `mode "dump" is not one of`,
+		},
+		{
+			modify: func(cfg *config.SubTaskConfig) { cfg.Flavor = "postgres" },
+			errMsg: `flavor "postgres" is not supported`,
+		},
+		{
+			modify: func(cfg *config.SubTaskConfig) { cfg.From.Host = "" },
+			errMsg: "from.host is empty",
+		},
+		{
+			modify: func(cfg *config.SubTaskConfig) { cfg.From.Host = "127.0.0.1:3306" },
+			errMsg: `from.host "127.0.0.1:3306" is not a valid host`,
+		},
+		{
+			modify: func(cfg *config.SubTaskConfig) { cfg.To.Port = 0 },
+			errMsg: "to.port 0 is out of range",
+		},
+		{
+			modify: func(cfg *config.SubTaskConfig) { cfg.To.User = "" },
+			errMsg: "to.user is empty",
+		},
+		{
+			modify: func(cfg *config.SubTaskConfig) {
+				cfg.BAList = &filter.Rules{DoDBs: []string{"~("}}
+			},
+			errMsg: "block-allow-list is invalid",
+		},
+		{
+			modify: func(cfg *config.SubTaskConfig) { cfg.BAList = &filter.Rules{} },
+			errMsg: "block-allow-list has no do-dbs or do-tables rule",
+		},
+		{
+			modify: func(cfg *config.SubTaskConfig) { cfg.BAList = nil },
+			errMsg: "block-allow-list has no do-dbs or do-tables rule",
+		},
+		{
+			// checked by the Adjust of dm
+			modify: func(cfg *config.SubTaskConfig) { cfg.ShardMode = "unknown" },
+			errMsg: "shard mode unknown not supported",
+		},
+	}
+
+	for _, tc := range testCases {
+		cfg := &config.SubTaskConfig{}
+		require.NoError(t, cfg.Decode(string(mockWorkerConfig()), true))
+		require.NoError(t, validateSubTaskConfig(cfg))
+
+		tc.modify(cfg)
+		worker := newDumpWorker(cfg).(*dumpWorker)
+		// the config is checked before the storage is opened, so no base
+		// worker is needed here
+		err := worker.InitImpl(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "ErrBuildJobFailed")
+		require.True(t, derrors.ErrInvalidSubTaskConfig.Equal(errors.Cause(err)))
+		require.Contains(t, err.Error(), tc.errMsg)
+	}
+}
+
+type mockDumpBaseWorker struct {
+	lib.BaseWorker
+
+	kvClient metaclient.KVClient
+	updated  []libModel.WorkerStatus
+	exited   *libModel.WorkerStatus
+}
+
+func (w *mockDumpBaseWorker) MetaKVClient() metaclient.KVClient {
+	return w.kvClient
+}
+
+func (w *mockDumpBaseWorker) OpenStorage(
+	ctx context.Context, resourcePath resourcemeta.ResourceID,
+) (broker.Handle, error) {
+	return &mockStorageHandle{}, nil
+}
+
+func (w *mockDumpBaseWorker) UpdateStatus(ctx context.Context, status libModel.WorkerStatus) error {
+	w.updated = append(w.updated, status)
+	return nil
+}
+
+func (w *mockDumpBaseWorker) Exit(ctx context.Context, status libModel.WorkerStatus, err error) error {
+	w.exited = &status
+	return nil
+}
+
+type mockStorageHandle struct {
+	broker.Handle
+}
+
+func (h *mockStorageHandle) BrExternalStorage() brStorage.ExternalStorage {
+	return nil
+}
+
+func (h *mockStorageHandle) Persist(ctx context.Context) error {
+	return nil
+}
+
+type mockDumpUnit struct {
+	unit.Unit
+
+	processed atomic.Int32
+	finishCh  chan struct{}
+}
+
+func (u *mockDumpUnit) Init(ctx context.Context) error {
+	return nil
+}
+
+func (u *mockDumpUnit) Process(ctx context.Context, pr chan pb.ProcessResult) {
+	u.processed.Inc()
+	select {
+	case <-ctx.Done():
+		pr <- pb.ProcessResult{IsCanceled: true, Errors: []*pb.ProcessError{{Message: "canceled"}}}
+	case <-u.finishCh:
+		pr <- pb.ProcessResult{}
+	}
+}
+
+func (u *mockDumpUnit) Status(_ *binlog.SourceStatus) interface{} {
+	return &pb.DumpStatus{TotalTables: 4, CompletedTables: 3}
+}
+
+func (u *mockDumpUnit) Close() {}
+
+func TestDumpWorkerResumeFromCheckpoint(t *testing.T) {
+	mockUnit := &mockDumpUnit{finishCh: make(chan struct{})}
+	oldNewDumpUnit := newDumpUnit
+	newDumpUnit = func(cfg *config.SubTaskConfig) unit.Unit {
+		return mockUnit
+	}
+	defer func() {
+		newDumpUnit = oldNewDumpUnit
+	}()
+
+	ctx := context.Background()
+	kvClient := mockkv.NewMetaMock()
+	newWorker := func() (*dumpWorker, *mockDumpBaseWorker) {
+		cfg := &config.SubTaskConfig{}
+		require.NoError(t, cfg.Decode(string(mockWorkerConfig()), true))
+		worker := newDumpWorker(cfg).(*dumpWorker)
+		base := &mockDumpBaseWorker{kvClient: kvClient}
+		worker.BaseWorker = base
+		return worker, base
+	}
+
+	// the worker is restarted before the dump finishes, only the progress is
+	// saved
+	worker, base := newWorker()
+	require.NoError(t, worker.InitImpl(ctx))
+	require.NoError(t, worker.Tick(ctx))
+	require.NoError(t, worker.CloseImpl(ctx))
+	require.Nil(t, base.exited)
+	cp, err := loadDumpCheckpoint(ctx, kvClient, worker.cfg)
+	require.NoError(t, err)
+	require.False(t, cp.Finished)
+	require.Equal(t, &dumpProgress{TotalTables: 4, CompletedTables: 3}, cp.Progress)
+
+	// the restarted worker dumps from the beginning and saves the checkpoint
+	worker, base = newWorker()
+	require.NoError(t, worker.InitImpl(ctx))
+	require.NoError(t, worker.Tick(ctx))
+	close(mockUnit.finishCh)
+	require.Eventually(t, func() bool {
+		require.NoError(t, worker.Tick(ctx))
+		return base.exited != nil
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, libModel.WorkerStatusFinished, base.exited.Code)
+	// the progress surfaces through the status reported to the master
+	statusBytes, err := base.exited.Marshal()
+	require.NoError(t, err)
+	var status libModel.WorkerStatus
+	require.NoError(t, status.Unmarshal(statusBytes))
+	require.Equal(t, &libModel.WorkerProgress{
+		Completed: 3,
+		Total:     4,
+		Phase:     progressPhaseDump,
+	}, status.Progress)
+	require.NoError(t, worker.CloseImpl(ctx))
+	require.Equal(t, int32(2), mockUnit.processed.Load())
+	cp, err = loadDumpCheckpoint(ctx, kvClient, worker.cfg)
+	require.NoError(t, err)
+	require.True(t, cp.Finished)
+	require.Nil(t, cp.Progress)
+
+	// the worker restarted after the dump finished resumes from the checkpoint
+	worker, base = newWorker()
+	require.NoError(t, worker.InitImpl(ctx))
+	require.NoError(t, worker.Tick(ctx))
+	require.NotNil(t, base.exited)
+	require.Equal(t, libModel.WorkerStatusFinished, base.exited.Code)
+	require.NoError(t, worker.CloseImpl(ctx))
+	require.Equal(t, int32(2), mockUnit.processed.Load())
+}
+
+// progressDumpUnit is a mockDumpUnit whose status is set by the test
+type progressDumpUnit struct {
+	mockDumpUnit
+
+	mu     sync.Mutex
+	status pb.DumpStatus
+}
+
+func (u *progressDumpUnit) setStatus(status pb.DumpStatus) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.status = status
+}
+
+func (u *progressDumpUnit) Status(_ *binlog.SourceStatus) interface{} {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	status := u.status
+	return &status
+}
+
+func TestDumpWorkerReportProgress(t *testing.T) {
+	mockUnit := &progressDumpUnit{mockDumpUnit: mockDumpUnit{finishCh: make(chan struct{})}}
+	oldNewDumpUnit := newDumpUnit
+	newDumpUnit = func(cfg *config.SubTaskConfig) unit.Unit {
+		return mockUnit
+	}
+	defer func() {
+		newDumpUnit = oldNewDumpUnit
+	}()
+
+	ctx := context.Background()
+	cfg := &config.SubTaskConfig{}
+	require.NoError(t, cfg.Decode(string(mockWorkerConfig()), true))
+	worker := newDumpWorker(cfg).(*dumpWorker)
+	base := &mockDumpBaseWorker{kvClient: mockkv.NewMetaMock()}
+	worker.BaseWorker = base
+	require.NoError(t, worker.InitImpl(ctx))
+	// report the status in each tick
+	worker.unitHolder.statusRateLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	lastDumpStatus := func() *runtime.DumpStatus {
+		require.NotEmpty(t, base.updated)
+		taskStatus, err := runtime.UnmarshalTaskStatus(base.updated[len(base.updated)-1].ExtBytes)
+		require.NoError(t, err)
+		dumpStatus, ok := taskStatus.(*runtime.DumpStatus)
+		require.True(t, ok)
+		return dumpStatus
+	}
+
+	mockUnit.setStatus(pb.DumpStatus{TotalTables: 4, FinishedBytes: 100, FinishedRows: 10, EstimateTotalRows: 40})
+	require.NoError(t, worker.Tick(ctx))
+	status := lastDumpStatus()
+	require.Equal(t, metadata.StageRunning, status.Stage)
+	require.Equal(t, float64(100), status.FinishedBytes)
+	require.Equal(t, float64(10), status.FinishedRows)
+	require.Equal(t, float64(25), status.Percent)
+
+	mockUnit.setStatus(pb.DumpStatus{TotalTables: 4, CompletedTables: 2, FinishedBytes: 300, FinishedRows: 30, EstimateTotalRows: 40})
+	require.NoError(t, worker.Tick(ctx))
+	status = lastDumpStatus()
+	require.Equal(t, float64(300), status.FinishedBytes)
+	require.Equal(t, float64(30), status.FinishedRows)
+	require.Equal(t, float64(75), status.Percent)
+
+	// the progress is saved to the checkpoint as it changes
+	cp, err := loadDumpCheckpoint(ctx, base.kvClient, worker.cfg)
+	require.NoError(t, err)
+	require.False(t, cp.Finished)
+	require.Equal(t, &dumpProgress{
+		TotalTables:     4,
+		CompletedTables: 2,
+		FinishedBytes:   300,
+		FinishedRows:    30,
+	}, cp.Progress)
+
+	close(mockUnit.finishCh)
+	require.Eventually(t, func() bool {
+		require.NoError(t, worker.Tick(ctx))
+		return base.exited != nil
+	}, time.Second, 10*time.Millisecond)
+	taskStatus, err := runtime.UnmarshalTaskStatus(base.exited.ExtBytes)
+	require.NoError(t, err)
+	require.Equal(t, metadata.StageFinished, taskStatus.GetStage())
+	require.Equal(t, float64(100), taskStatus.(*runtime.DumpStatus).Percent)
+	cp, err = loadDumpCheckpoint(ctx, base.kvClient, worker.cfg)
+	require.NoError(t, err)
+	require.True(t, cp.Finished)
+	require.NoError(t, worker.CloseImpl(ctx))
+}
+
+func TestDumpPercent(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, float64(0), dumpPercent(&pb.DumpStatus{}))
+	require.Equal(t, float64(50), dumpPercent(&pb.DumpStatus{TotalTables: 4, CompletedTables: 2}))
+	require.Equal(t, float64(25), dumpPercent(&pb.DumpStatus{TotalTables: 4, CompletedTables: 2, FinishedRows: 10, EstimateTotalRows: 40}))
+	// the estimated total rows may be less than the dumped rows
+	require.Equal(t, float64(100), dumpPercent(&pb.DumpStatus{FinishedRows: 50, EstimateTotalRows: 40}))
+}
+
+// pausableDumpUnit dumps a row every millisecond until its context is
+// canceled, its Resume runs Process again like the dumpling unit.
+type pausableDumpUnit struct {
+	unit.Unit
+
+	total int64
+	rows  atomic.Int64
+}
+
+func (u *pausableDumpUnit) Init(ctx context.Context) error {
+	return nil
+}
+
+func (u *pausableDumpUnit) Process(ctx context.Context, pr chan pb.ProcessResult) {
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	for u.rows.Load() < u.total {
+		select {
+		case <-ctx.Done():
+			pr <- pb.ProcessResult{IsCanceled: true, Errors: []*pb.ProcessError{{Message: "canceled"}}}
+			return
+		case <-ticker.C:
+			u.rows.Inc()
+		}
+	}
+	pr <- pb.ProcessResult{}
+}
+
+func (u *pausableDumpUnit) Pause() {}
+
+func (u *pausableDumpUnit) Resume(ctx context.Context, pr chan pb.ProcessResult) {
+	u.Process(ctx, pr)
+}
+
+func (u *pausableDumpUnit) Status(_ *binlog.SourceStatus) interface{} {
+	return &pb.DumpStatus{FinishedRows: float64(u.rows.Load()), EstimateTotalRows: float64(u.total)}
+}
+
+func (u *pausableDumpUnit) Close() {}
+
+func TestDumpWorkerPauseResume(t *testing.T) {
+	mockUnit := &pausableDumpUnit{total: 200}
+	oldNewDumpUnit := newDumpUnit
+	newDumpUnit = func(cfg *config.SubTaskConfig) unit.Unit {
+		return mockUnit
+	}
+	defer func() {
+		newDumpUnit = oldNewDumpUnit
+	}()
+
+	ctx := context.Background()
+	cfg := &config.SubTaskConfig{}
+	require.NoError(t, cfg.Decode(string(mockWorkerConfig()), true))
+	worker := newDumpWorker(cfg).(*dumpWorker)
+	base := &mockDumpBaseWorker{kvClient: mockkv.NewMetaMock()}
+	worker.BaseWorker = base
+	require.NoError(t, worker.InitImpl(ctx))
+	// report the status in each tick
+	worker.unitHolder.statusRateLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	require.NoError(t, worker.Tick(ctx))
+	require.Eventually(t, func() bool {
+		return mockUnit.rows.Load() >= 10
+	}, time.Second, time.Millisecond)
+
+	// the paused dump makes no progress and is reported as paused once
+	require.NoError(t, worker.OnMasterMessage("", &dmpkg.OperateTaskMessage{Stage: metadata.StagePaused}))
+	require.NoError(t, worker.Tick(ctx))
+	updated := len(base.updated)
+	paused := base.updated[updated-1]
+	require.Equal(t, libModel.WorkerStatusPaused, paused.Code)
+	taskStatus, err := runtime.UnmarshalTaskStatus(paused.ExtBytes)
+	require.NoError(t, err)
+	require.Equal(t, metadata.StagePaused, taskStatus.GetStage())
+	// wait for the canceled run to exit
+	time.Sleep(20 * time.Millisecond)
+	rows := mockUnit.rows.Load()
+	require.Less(t, rows, mockUnit.total)
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, rows, mockUnit.rows.Load())
+	require.NoError(t, worker.Tick(ctx))
+	require.Len(t, base.updated, updated)
+	require.Nil(t, base.exited)
+
+	// the resumed dump continues and completes
+	require.NoError(t, worker.OnMasterMessage("", &dmpkg.OperateTaskMessage{Stage: metadata.StageRunning}))
+	require.NoError(t, worker.Tick(ctx))
+	require.Equal(t, libModel.WorkerStatusNormal, base.updated[len(base.updated)-1].Code)
+	require.Eventually(t, func() bool {
+		require.NoError(t, worker.Tick(ctx))
+		return base.exited != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, libModel.WorkerStatusFinished, base.exited.Code)
+	require.Equal(t, mockUnit.total, mockUnit.rows.Load())
+	require.NoError(t, worker.CloseImpl(ctx))
+}
+
+// fileDumpUnit is a mockDumpUnit which writes rows to a file, it only checks
+// the cancellation between rows.
+type fileDumpUnit struct {
+	mockDumpUnit
+
+	path string
+	rows atomic.Int32
+}
+
+func (u *fileDumpUnit) Process(ctx context.Context, pr chan pb.ProcessResult) {
+	f, err := os.Create(u.path)
+	if err != nil {
+		pr <- pb.ProcessResult{Errors: []*pb.ProcessError{{Message: err.Error()}}}
+		return
+	}
+	defer f.Close()
+	for {
+		if ctx.Err() != nil {
+			pr <- pb.ProcessResult{IsCanceled: true, Errors: []*pb.ProcessError{{Message: "canceled"}}}
+			return
+		}
+		// a row is written in two parts, closing the unit between them
+		// leaves a truncated row
+		_, _ = f.WriteString("1,")
+		time.Sleep(5 * time.Millisecond)
+		_, _ = f.WriteString("abc\n")
+		u.rows.Inc()
+	}
+}
+
+// stuckDumpUnit is a mockDumpUnit which ignores the cancellation
+type stuckDumpUnit struct {
+	mockDumpUnit
+}
+
+func (u *stuckDumpUnit) Process(_ context.Context, pr chan pb.ProcessResult) {
+	<-u.finishCh
+	pr <- pb.ProcessResult{}
+}
+
+func TestDumpWorkerCloseGracefully(t *testing.T) {
+	var mockUnit unit.Unit
+	oldNewDumpUnit := newDumpUnit
+	newDumpUnit = func(cfg *config.SubTaskConfig) unit.Unit {
+		return mockUnit
+	}
+	defer func() {
+		newDumpUnit = oldNewDumpUnit
+	}()
+
+	ctx := context.Background()
+	newWorker := func() *dumpWorker {
+		cfg := &config.SubTaskConfig{}
+		require.NoError(t, cfg.Decode(string(mockWorkerConfig()), true))
+		worker := newDumpWorker(cfg).(*dumpWorker)
+		worker.BaseWorker = &mockDumpBaseWorker{kvClient: mockkv.NewMetaMock()}
+		require.NoError(t, worker.InitImpl(ctx))
+		require.NoError(t, worker.Tick(ctx))
+		return worker
+	}
+
+	// the unit is closed after the row being written is complete
+	fileUnit := &fileDumpUnit{path: filepath.Join(t.TempDir(), "db.tbl.000000000.csv")}
+	mockUnit = fileUnit
+	worker := newWorker()
+	require.Eventually(t, func() bool {
+		return fileUnit.rows.Load() >= 3
+	}, time.Second, time.Millisecond)
+	require.NoError(t, worker.CloseImpl(ctx))
+	content, err := os.ReadFile(fileUnit.path)
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(string(content), "1,abc\n"))
+	require.Equal(t, int(fileUnit.rows.Load()), strings.Count(string(content), "\n"))
+
+	// the wait for a unit which doesn't stop is bounded by the context
+	stuckUnit := &stuckDumpUnit{mockDumpUnit{finishCh: make(chan struct{})}}
+	defer close(stuckUnit.finishCh)
+	mockUnit = stuckUnit
+	worker = newWorker()
+	closeCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, worker.CloseImpl(closeCtx))
+	require.Less(t, time.Since(start), time.Second)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/hanfei1991/microcosm/jobmaster/dm"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiflow/dm/dm/config"
+	"github.com/pingcap/tiflow/dm/dm/unit"
 	"github.com/pingcap/tiflow/dm/loader"
 	"github.com/pingcap/tiflow/dm/pkg/log"
 	"go.uber.org/zap"
@@ -20,6 +21,14 @@ import (
 
 var _ lib.Worker = &loadWorker{}
 
+// newLoadUnit creates the dm unit of loadWorker, it is replaced in unit tests.
+var newLoadUnit = func(cfg *config.SubTaskConfig) unit.Unit {
+	// `workerName` and `etcdClient` of `NewLightning` are not used in dataflow
+	// scenario, we just use readable values here.
+	workerName := "dataflow-worker"
+	return loader.NewLightning(cfg, nil, workerName)
+}
+
 type loadWorker struct {
 	lib.BaseWorker
 
@@ -37,9 +46,14 @@ func newLoadWorker(cfg lib.WorkerConfig) lib.WorkerImpl {
 func (l *loadWorker) InitImpl(ctx context.Context) error {
 	log.L().Info("init load worker")
 
+	if err := preflightSubTaskConfig(l.cfg); err != nil {
+		return err
+	}
+
 	rid := dm.NewDMResourceID(l.cfg.Name, l.cfg.SourceID)
 	h, err := l.OpenStorage(ctx, rid)
-	for status.Code(err) == codes.Unavailable {
+	for status.Code(errors.Cause(err)) == codes.Unavailable {
+		// TODO: use backoff retry later
 		log.L().Info("simple retry", zap.Error(err))
 		time.Sleep(time.Second)
 		h, err = l.OpenStorage(ctx, rid)
@@ -49,10 +63,7 @@ func (l *loadWorker) InitImpl(ctx context.Context) error {
 	}
 	l.cfg.ExtStorage = h.BrExternalStorage()
 
-	// `workerName` and `etcdClient` of `NewLightning` are not used in dataflow
-	// scenario, we just use readable values here.
-	workerName := "dataflow-worker"
-	l.unitHolder = newUnitHolder(lib.WorkerDMLoad, l.cfg.SourceID, loader.NewLightning(l.cfg, nil, workerName))
+	l.unitHolder = newUnitHolder(lib.WorkerDMLoad, l.cfg.SourceID, newLoadUnit(l.cfg))
 	return errors.Trace(l.unitHolder.init(ctx))
 }
 
@@ -77,6 +88,6 @@ func (l *loadWorker) OnMasterMessage(topic p2p.Topic, message p2p.MessageValue)
 }
 
 func (l *loadWorker) CloseImpl(ctx context.Context) error {
-	l.unitHolder.close()
+	l.unitHolder.close(ctx)
 	return nil
 }
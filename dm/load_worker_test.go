@@ -3,7 +3,12 @@ package dm
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/pingcap/tiflow/dm/dm/config"
+	"github.com/pingcap/tiflow/dm/dm/pb"
+	"github.com/pingcap/tiflow/dm/dm/unit"
+	"github.com/pingcap/tiflow/dm/pkg/binlog"
 	"github.com/pingcap/tiflow/dm/pkg/log"
 	"github.com/stretchr/testify/require"
 
@@ -11,6 +16,8 @@ import (
 	libModel "github.com/hanfei1991/microcosm/lib/model"
 	"github.com/hanfei1991/microcosm/lib/registry"
 	dcontext "github.com/hanfei1991/microcosm/pkg/context"
+	derrors "github.com/hanfei1991/microcosm/pkg/errors"
+	mockkv "github.com/hanfei1991/microcosm/pkg/meta/kvclient/mock"
 )
 
 func TestLoadWorker(t *testing.T) {
@@ -46,3 +53,77 @@ func TestLoadWorker(t *testing.T) {
 	err = worker.Close(context.Background())
 	require.NoError(t, err)
 }
+
+func TestLoadWorkerInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.SubTaskConfig{}
+	require.NoError(t, cfg.Decode(string(mockWorkerConfig()), true))
+	cfg.To.User = ""
+	worker := newLoadWorker(cfg).(*loadWorker)
+	// the config is checked before the storage is opened, so no base worker
+	// is needed here
+	err := worker.InitImpl(context.Background())
+	require.True(t, derrors.ErrInvalidSubTaskConfig.Equal(err))
+	require.Contains(t, err.Error(), "to.user is empty")
+}
+
+type mockLoadUnit struct {
+	unit.Unit
+
+	finishCh chan struct{}
+}
+
+func (u *mockLoadUnit) Init(ctx context.Context) error {
+	return nil
+}
+
+func (u *mockLoadUnit) Process(ctx context.Context, pr chan pb.ProcessResult) {
+	select {
+	case <-ctx.Done():
+		pr <- pb.ProcessResult{IsCanceled: true, Errors: []*pb.ProcessError{{Message: "canceled"}}}
+	case <-u.finishCh:
+		pr <- pb.ProcessResult{}
+	}
+}
+
+func (u *mockLoadUnit) Status(_ *binlog.SourceStatus) interface{} {
+	return &pb.LoadStatus{FinishedBytes: 512, TotalBytes: 1024}
+}
+
+func (u *mockLoadUnit) Close() {}
+
+func TestLoadWorkerLifecycle(t *testing.T) {
+	mockUnit := &mockLoadUnit{finishCh: make(chan struct{})}
+	oldNewLoadUnit := newLoadUnit
+	newLoadUnit = func(cfg *config.SubTaskConfig) unit.Unit {
+		return mockUnit
+	}
+	defer func() {
+		newLoadUnit = oldNewLoadUnit
+	}()
+
+	ctx := context.Background()
+	cfg := &config.SubTaskConfig{}
+	require.NoError(t, cfg.Decode(string(mockWorkerConfig()), true))
+	worker := newLoadWorker(cfg).(*loadWorker)
+	base := &mockDumpBaseWorker{kvClient: mockkv.NewMetaMock()}
+	worker.BaseWorker = base
+
+	require.NoError(t, worker.InitImpl(ctx))
+	require.NoError(t, worker.Tick(ctx))
+	require.Nil(t, base.exited)
+
+	close(mockUnit.finishCh)
+	require.Eventually(t, func() bool {
+		require.NoError(t, worker.Tick(ctx))
+		return base.exited != nil
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, libModel.WorkerStatusFinished, base.exited.Code)
+	require.Equal(t, &libModel.WorkerProgress{
+		Completed: 512,
+		Total:     1024,
+		Phase:     progressPhaseLoad,
+	}, base.exited.Progress)
+	require.NoError(t, worker.CloseImpl(ctx))
+}
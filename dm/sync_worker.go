@@ -76,6 +76,6 @@ func (s *syncWorker) OnMasterMessage(topic p2p.Topic, message p2p.MessageValue)
 }
 
 func (s *syncWorker) CloseImpl(ctx context.Context) error {
-	s.unitHolder.close()
+	s.unitHolder.close(ctx)
 	return nil
 }
@@ -2,7 +2,6 @@ package dm
 
 import (
 	"context"
-	"encoding/json"
 	"sync"
 	"time"
 
@@ -18,6 +17,17 @@ import (
 	"github.com/pingcap/tiflow/dm/pkg/backoff"
 	"github.com/pingcap/tiflow/dm/pkg/log"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// progressPhaseDump is the phase of the progress reported by the dump unit
+	progressPhaseDump = "dump"
+	// progressPhaseLoad is the phase of the progress reported by the load unit
+	progressPhaseLoad = "load"
+	// runningStatusInterval is the min interval of the status updates of the
+	// running unit.
+	runningStatusInterval = 3 * time.Second
 )
 
 // unitHolder wrap the dm-worker unit.
@@ -27,6 +37,13 @@ type unitHolder struct {
 
 	autoResume         *worker.AutoResumeInfo
 	storageWriteHandle broker.Handle
+	persisted          bool
+	// onFinished is called after the unit finishes and its storage is
+	// persisted, before the worker exits.
+	onFinished func(ctx context.Context) error
+	// onRunning is called with the status of the running unit each time it
+	// is reported.
+	onRunning func(ctx context.Context, status runtime.TaskStatus) error
 
 	workerType  lib.WorkerType
 	task        string
@@ -35,6 +52,18 @@ type unitHolder struct {
 	lastResult  *pb.ProcessResult // TODO: check if framework can persist result
 	lastStage   worker.ResumeStrategy
 	processOnce sync.Once
+	// statusRateLimiter limits the status updates of the running unit
+	statusRateLimiter *rate.Limiter
+
+	// mu protects the fields below and the run of the unit, because pause
+	// and resume are called by the message handler, concurrently with Tick.
+	mu sync.Mutex
+	// runCancel cancels the context of the current Process or Resume of the
+	// unit, it is nil if the unit has never run.
+	runCancel context.CancelFunc
+	paused    bool
+	// pausedReported is true if the paused status has been reported
+	pausedReported bool
 }
 
 func newUnitHolder(workerType lib.WorkerType, task string, u unit.Unit) *unitHolder {
@@ -60,6 +89,8 @@ func newUnitHolder(workerType lib.WorkerType, task string, u unit.Unit) *unitHol
 		unit:       u,
 		lastStage:  -1, // -1 represents init stage, refactor later.
 		resultCh:   make(chan pb.ProcessResult, 1),
+
+		statusRateLimiter: rate.NewLimiter(rate.Every(runningStatusInterval), 1),
 	}
 }
 
@@ -67,12 +98,78 @@ func (u *unitHolder) init(ctx context.Context) error {
 	return u.unit.Init(ctx)
 }
 
+// markFinished makes the holder report the unit as finished without
+// processing it, it is used when the unit has finished before a restart.
+func (u *unitHolder) markFinished() {
+	u.processOnce.Do(func() {})
+	u.lastResult = &pb.ProcessResult{}
+	u.persisted = true
+}
+
 func (u *unitHolder) lazyProcess() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.paused {
+		return
+	}
 	u.processOnce.Do(func() {
-		go u.unit.Process(u.ctx, u.resultCh)
+		u.startRunNoLock(u.unit.Process)
 	})
 }
 
+// startRunNoLock runs fn, the Process or Resume of the unit, with a new
+// context and result channel, so that the result of a canceled run is never
+// regarded as the result of the new run.
+func (u *unitHolder) startRunNoLock(fn func(ctx context.Context, pr chan pb.ProcessResult)) {
+	var ctx context.Context
+	ctx, u.runCancel = context.WithCancel(u.ctx)
+	u.resultCh = make(chan pb.ProcessResult, 1)
+	go fn(ctx, u.resultCh)
+}
+
+// pause stops the processing of the unit until resume is called, the unit
+// is reported as paused in the next tick. It does nothing if the unit has
+// finished or is paused already.
+func (u *unitHolder) pause() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.paused {
+		return
+	}
+	if u.lastResult != nil && len(u.lastResult.Errors) == 0 {
+		log.L().Info("unit is finished, skip pausing", zap.String("task", u.task))
+		return
+	}
+	u.paused = true
+	u.pausedReported = false
+	// the unit is started by resume if it has not been started yet
+	u.processOnce.Do(func() {})
+	if u.runCancel != nil {
+		u.runCancel()
+	}
+	u.unit.Pause()
+	// the result of the paused run, e.g. a canceled error, is discarded
+	u.lastResult = nil
+}
+
+// resume restarts the processing of the paused unit, it does nothing if the
+// unit is not paused.
+func (u *unitHolder) resume() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.paused {
+		return
+	}
+	u.paused = false
+	if u.runCancel == nil {
+		u.startRunNoLock(u.unit.Process)
+	} else {
+		u.startRunNoLock(u.unit.Resume)
+	}
+	// report the running status in the next tick
+	u.statusRateLimiter = rate.NewLimiter(u.statusRateLimiter.Limit(), 1)
+}
+
 func (u *unitHolder) getResult() (bool, *pb.ProcessResult) {
 	if u.lastResult != nil {
 		return true, u.lastResult
@@ -87,26 +184,51 @@ func (u *unitHolder) getResult() (bool, *pb.ProcessResult) {
 }
 
 func (u *unitHolder) tryUpdateStatus(ctx context.Context, base lib.BaseWorker) error {
-	// TODO: refactor in later pr
-	status := runtime.DefaultTaskStatus{
-		Unit:  u.workerType,
-		Task:  u.task,
-		Stage: metadata.StageRunning,
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.paused {
+		if u.pausedReported {
+			return nil
+		}
+		statusBytes, err := runtime.MarshalTaskStatus(u.taskStatus(metadata.StagePaused))
+		if err != nil {
+			return err
+		}
+		s := libModel.WorkerStatus{
+			Code:     libModel.WorkerStatusPaused,
+			ExtBytes: statusBytes,
+			Progress: u.progress(),
+		}
+		if err := base.UpdateStatus(ctx, s); err == nil {
+			u.pausedReported = true
+		}
+		return nil
 	}
 
 	hasResult, result := u.getResult()
 	if !hasResult {
-		// update status when task first runs.
-		if u.lastStage == 0 {
+		// update status when task first runs, and then periodically to
+		// report the progress of the running unit.
+		if !u.statusRateLimiter.Allow() {
 			return nil
 		}
-		statusBytes, err := json.Marshal(status)
+		taskStatus := u.taskStatus(metadata.StageRunning)
+		statusBytes, err := runtime.MarshalTaskStatus(taskStatus)
 		if err != nil {
 			return err
 		}
+		if u.onRunning != nil {
+			if err := u.onRunning(ctx, taskStatus); err != nil {
+				// the progress is only informative, so we don't block the
+				// status report
+				log.L().Warn("handle running unit failed", zap.Error(err))
+			}
+		}
 		s := libModel.WorkerStatus{
 			Code:     libModel.WorkerStatusNormal,
 			ExtBytes: statusBytes,
+			Progress: u.progress(),
 		}
 		err = base.UpdateStatus(ctx, s)
 		if err == nil {
@@ -117,23 +239,31 @@ func (u *unitHolder) tryUpdateStatus(ctx context.Context, base lib.BaseWorker) e
 
 	// if task is finished
 	if len(result.Errors) == 0 {
-		status.Stage = metadata.StageFinished
-		statusBytes, err := json.Marshal(status)
+		statusBytes, err := runtime.MarshalTaskStatus(u.taskStatus(metadata.StageFinished))
 		if err != nil {
 			return err
 		}
-		if u.storageWriteHandle != nil {
+		if u.storageWriteHandle != nil && !u.persisted {
 			// try to persist storage, if failed, retry next tick
 			err := u.storageWriteHandle.Persist(ctx)
 			if err != nil {
 				log.L().Error("persist storage failed", zap.Error(err))
 				return nil
 			}
+			u.persisted = true
+		}
+		if u.onFinished != nil {
+			if err := u.onFinished(ctx); err != nil {
+				// the unit will run again after restart, which is correct
+				// but slow, so we don't block the exit
+				log.L().Warn("handle finished unit failed", zap.Error(err))
+			}
 		}
 
 		s := libModel.WorkerStatus{
 			Code:     libModel.WorkerStatusFinished,
 			ExtBytes: statusBytes,
+			Progress: u.progress(),
 		}
 		return base.Exit(ctx, s, nil)
 	}
@@ -157,8 +287,7 @@ func (u *unitHolder) tryUpdateStatus(ctx context.Context, base lib.BaseWorker) e
 		u.unit.Pause()
 		u.lastStage = worker.ResumeSkip
 		// wait on next auto resume
-		status.Stage = metadata.StagePaused
-		statusBytes, err := json.Marshal(status)
+		statusBytes, err := runtime.MarshalTaskStatus(u.taskStatus(metadata.StagePaused))
 		if err != nil {
 			return err
 		}
@@ -166,6 +295,7 @@ func (u *unitHolder) tryUpdateStatus(ctx context.Context, base lib.BaseWorker) e
 			Code:         libModel.WorkerStatusError,
 			ErrorMessage: unit.JoinProcessErrors(result.Errors),
 			ExtBytes:     statusBytes,
+			Progress:     u.progress(),
 		}
 		// TODO: UpdateStatus too frequently?
 		// nolint:errcheck
@@ -176,11 +306,10 @@ func (u *unitHolder) tryUpdateStatus(ctx context.Context, base lib.BaseWorker) e
 		u.lastStage = worker.ResumeDispatch
 		// can try auto resume
 		u.lastResult = nil
-		go u.unit.Resume(u.ctx, u.resultCh)
+		u.startRunNoLock(u.unit.Resume)
 		return nil
 	default:
-		status.Stage = metadata.StagePaused
-		statusBytes, err := json.Marshal(status)
+		statusBytes, err := runtime.MarshalTaskStatus(u.taskStatus(metadata.StagePaused))
 		if err != nil {
 			return err
 		}
@@ -188,12 +317,109 @@ func (u *unitHolder) tryUpdateStatus(ctx context.Context, base lib.BaseWorker) e
 			Code:         libModel.WorkerStatusError,
 			ErrorMessage: unit.JoinProcessErrors(result.Errors),
 			ExtBytes:     statusBytes,
+			Progress:     u.progress(),
 		}
 		return base.Exit(ctx, s, nil)
 	}
 }
 
-func (u *unitHolder) close() {
+// progress returns the progress of the unit in the generic form, or nil if
+// the unit doesn't report its progress.
+func (u *unitHolder) progress() *libModel.WorkerProgress {
+	switch s := u.unit.Status(nil).(type) {
+	case *pb.DumpStatus:
+		return &libModel.WorkerProgress{
+			Completed: int64(s.CompletedTables),
+			Total:     s.TotalTables,
+			Phase:     progressPhaseDump,
+		}
+	case *pb.LoadStatus:
+		return &libModel.WorkerProgress{
+			Completed: s.FinishedBytes,
+			Total:     s.TotalBytes,
+			Phase:     progressPhaseLoad,
+		}
+	default:
+		return nil
+	}
+}
+
+// taskStatus returns the status of the unit in the given stage, including the
+// unit specific status, e.g. the dumped rows, if the unit reports it.
+func (u *unitHolder) taskStatus(stage metadata.TaskStage) runtime.TaskStatus {
+	status := runtime.DefaultTaskStatus{
+		Unit:  u.workerType,
+		Task:  u.task,
+		Stage: stage,
+	}
+	switch s := u.unit.Status(nil).(type) {
+	case *pb.DumpStatus:
+		dumpStatus := &runtime.DumpStatus{
+			DefaultTaskStatus: status,
+			TotalTables:       s.TotalTables,
+			CompletedTables:   s.CompletedTables,
+			FinishedBytes:     s.FinishedBytes,
+			FinishedRows:      s.FinishedRows,
+			EstimateTotalRows: s.EstimateTotalRows,
+			Percent:           dumpPercent(s),
+		}
+		// the unit is not initialized if it has finished before a restart
+		if stage == metadata.StageFinished {
+			dumpStatus.Percent = 100
+		}
+		return dumpStatus
+	case *pb.LoadStatus:
+		return &runtime.LoadStatus{
+			DefaultTaskStatus: status,
+			FinishedBytes:     s.FinishedBytes,
+			TotalBytes:        s.TotalBytes,
+			Progress:          s.Progress,
+			MetaBinlog:        s.MetaBinlog,
+			MetaBinlogGTID:    s.MetaBinlogGTID,
+		}
+	default:
+		return &status
+	}
+}
+
+// dumpPercent returns the percentage of the dumped rows, or of the dumped
+// tables if the total rows are not estimated.
+func dumpPercent(s *pb.DumpStatus) float64 {
+	var percent float64
+	switch {
+	case s.EstimateTotalRows > 0:
+		percent = s.FinishedRows / s.EstimateTotalRows * 100
+	case s.TotalTables > 0:
+		percent = s.CompletedTables / float64(s.TotalTables) * 100
+	}
+	// the total rows are estimated, so the dumped rows may exceed them
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+// close cancels the running unit and waits for its Process or Resume to
+// return before closing it, so that the unit stops at a consistent point
+// instead of being closed in the middle of writing. The wait is bounded by
+// ctx, the unit is closed anyway after ctx is done.
+func (u *unitHolder) close(ctx context.Context) {
+	u.mu.Lock()
+	running := u.runCancel != nil && u.lastResult == nil
+	resultCh := u.resultCh
+	u.mu.Unlock()
+
 	u.cancel()
+	if running {
+		select {
+		case r := <-resultCh:
+			u.mu.Lock()
+			u.lastResult = &r
+			u.mu.Unlock()
+		case <-ctx.Done():
+			log.L().Warn("unit does not stop before closing",
+				zap.String("task", u.task), zap.Error(ctx.Err()))
+		}
+	}
 	u.unit.Close()
 }
@@ -0,0 +1,100 @@
+package dm
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pingcap/tidb/util/filter"
+	"github.com/pingcap/tiflow/dm/dm/config"
+
+	derrors "github.com/hanfei1991/microcosm/pkg/errors"
+)
+
+// flavors supported by dm, an empty flavor is detected from the upstream.
+var validFlavors = map[string]struct{}{
+	"":        {},
+	"mysql":   {},
+	"mariadb": {},
+}
+
+// preflightSubTaskConfig checks the SubTaskConfig at the start of InitImpl
+// of a dm worker, the returned error is an ErrBuildJobFailed caused by an
+// ErrInvalidSubTaskConfig.
+func preflightSubTaskConfig(cfg *config.SubTaskConfig) error {
+	if err := validateSubTaskConfig(cfg); err != nil {
+		return derrors.ErrBuildJobFailed.Wrap(err)
+	}
+	return nil
+}
+
+// validateSubTaskConfig checks the SubTaskConfig before it is used to create
+// a dm unit, so that a malformed config fails early with a clear message
+// instead of deep inside the unit.
+func validateSubTaskConfig(cfg *config.SubTaskConfig) error {
+	if cfg == nil {
+		return derrors.ErrInvalidSubTaskConfig.GenWithStackByArgs("config is nil")
+	}
+	if cfg.Name == "" {
+		return derrors.ErrInvalidSubTaskConfig.GenWithStackByArgs("name is empty")
+	}
+	if cfg.SourceID == "" {
+		return derrors.ErrInvalidSubTaskConfig.GenWithStackByArgs("source-id is empty")
+	}
+	switch cfg.Mode {
+	case config.ModeAll, config.ModeFull, config.ModeIncrement:
+	default:
+		return derrors.ErrInvalidSubTaskConfig.GenWithStackByArgs(
+			fmt.Sprintf("mode %q is not one of %q, %q, %q",
+				cfg.Mode, config.ModeAll, config.ModeFull, config.ModeIncrement))
+	}
+	if _, ok := validFlavors[cfg.Flavor]; !ok {
+		return derrors.ErrInvalidSubTaskConfig.GenWithStackByArgs(
+			fmt.Sprintf("flavor %q is not supported", cfg.Flavor))
+	}
+	if err := validateDBConfig("from", &cfg.From); err != nil {
+		return err
+	}
+	if err := validateDBConfig("to", &cfg.To); err != nil {
+		return err
+	}
+	if cfg.BAList == nil || (len(cfg.BAList.DoDBs) == 0 && len(cfg.BAList.DoTables) == 0) {
+		return derrors.ErrInvalidSubTaskConfig.GenWithStackByArgs(
+			"block-allow-list has no do-dbs or do-tables rule")
+	}
+	if _, err := filter.New(cfg.CaseSensitive, cfg.BAList); err != nil {
+		return derrors.ErrInvalidSubTaskConfig.GenWithStackByArgs(
+			fmt.Sprintf("block-allow-list is invalid: %v", err))
+	}
+	// Adjust of dm verifies the rest of the config, it is called on a clone
+	// because it also modifies the config, e.g. adds a suffix to the dump dir.
+	clone, err := cfg.Clone()
+	if err != nil {
+		return derrors.ErrInvalidSubTaskConfig.GenWithStackByArgs(err.Error())
+	}
+	if err := clone.Adjust(false); err != nil {
+		return derrors.ErrInvalidSubTaskConfig.GenWithStackByArgs(err.Error())
+	}
+	return nil
+}
+
+func validateDBConfig(name string, cfg *config.DBConfig) error {
+	if cfg.Host == "" {
+		return derrors.ErrInvalidSubTaskConfig.GenWithStackByArgs(name + ".host is empty")
+	}
+	// the port should be set in the separate port field, so a colon is only
+	// valid in an IPv6 address
+	if strings.ContainsAny(cfg.Host, " /") ||
+		(strings.Contains(cfg.Host, ":") && net.ParseIP(cfg.Host) == nil) {
+		return derrors.ErrInvalidSubTaskConfig.GenWithStackByArgs(
+			fmt.Sprintf("%s.host %q is not a valid host name or IP address", name, cfg.Host))
+	}
+	if cfg.Port <= 0 || cfg.Port > 65535 {
+		return derrors.ErrInvalidSubTaskConfig.GenWithStackByArgs(
+			fmt.Sprintf("%s.port %d is out of range", name, cfg.Port))
+	}
+	if cfg.User == "" {
+		return derrors.ErrInvalidSubTaskConfig.GenWithStackByArgs(name + ".user is empty")
+	}
+	return nil
+}
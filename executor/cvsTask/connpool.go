@@ -0,0 +1,175 @@
+package cvstask
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pingcap/tiflow/dm/pkg/log"
+	"github.com/pingcap/tiflow/pkg/security"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// keepaliveTime and keepaliveTimeout make a connection whose peer is dead
+	// broken in keepaliveTime+keepaliveTimeout if the connection has active
+	// streams. They are not configured per task because a connection is
+	// shared by the tasks.
+	keepaliveTime    = 10 * time.Second
+	keepaliveTimeout = 3 * time.Second
+)
+
+// connTarget is the address and the transport security of a connection, the
+// connections are shared only by the tasks with the same connTarget.
+type connTarget struct {
+	addr string
+	// TLS is enabled if caPath is set, otherwise the connection is insecure
+	caPath   string
+	certPath string
+	keyPath  string
+	// serverName overrides the server name to verify the certificate of the
+	// server, empty means using the host of addr
+	serverName string
+}
+
+type dialFunc func(target connTarget) (*grpc.ClientConn, error)
+
+func defaultDial(target connTarget) (*grpc.ClientConn, error) {
+	creds := grpc.WithInsecure()
+	if target.caPath != "" {
+		credential := &security.Credential{
+			CAPath:   target.caPath,
+			CertPath: target.certPath,
+			KeyPath:  target.keyPath,
+		}
+		tlsCfg, err := credential.ToTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.ServerName = target.serverName
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg))
+	}
+	return grpc.Dial(target.addr, creds, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+		Time:    keepaliveTime,
+		Timeout: keepaliveTimeout,
+	}))
+}
+
+// sharedConn is a grpc connection shared by cvs tasks with the same target,
+// grpc multiplexes streams over a single HTTP/2 connection.
+type sharedConn struct {
+	target   connTarget
+	conn     *grpc.ClientConn
+	refCount int
+}
+
+// connPool is a reference-counted grpc connection pool keyed by connTarget.
+// A connection is closed when the last borrower returns it.
+type connPool struct {
+	sync.Mutex
+
+	dial dialFunc
+	// active holds the connection that is returned to new borrowers
+	active map[connTarget]*sharedConn
+	// conns holds all borrowed connections, including the unhealthy ones that
+	// have been replaced in active but are still used by some borrowers.
+	conns map[*grpc.ClientConn]*sharedConn
+}
+
+var pool = newConnPool(defaultDial)
+
+func newConnPool(dial dialFunc) *connPool {
+	return &connPool{
+		dial:   dial,
+		active: make(map[connTarget]*sharedConn),
+		conns:  make(map[*grpc.ClientConn]*sharedConn),
+	}
+}
+
+func isConnHealthy(conn *grpc.ClientConn) bool {
+	switch conn.GetState() {
+	case connectivity.TransientFailure, connectivity.Shutdown:
+		return false
+	default:
+		return true
+	}
+}
+
+// waitConnReady waits for conn to be ready in timeout. The connection that
+// fails to connect, or is not ready in time, is regarded as unavailable.
+func waitConnReady(ctx context.Context, conn *grpc.ClientConn, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		state := conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Idle:
+			conn.Connect()
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return status.Errorf(codes.Unavailable, "connection to %s is %s", conn.Target(), state)
+		default:
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return status.Errorf(codes.Unavailable, "connection to %s is not ready in %s", conn.Target(), timeout)
+		}
+	}
+}
+
+// borrowConn returns a connection to target, the caller must call returnConn
+// after the connection is not used any more.
+func (c *connPool) borrowConn(target connTarget) (*grpc.ClientConn, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if shared, ok := c.active[target]; ok {
+		if isConnHealthy(shared.conn) {
+			shared.refCount++
+			return shared.conn, nil
+		}
+		// The unhealthy connection will be closed after all of its borrowers
+		// return it, new borrowers will use a re-dialed connection.
+		log.L().Warn("connection is unhealthy, re-dial it",
+			zap.String("addr", target.addr), zap.Stringer("state", shared.conn.GetState()))
+		delete(c.active, target)
+	}
+
+	conn, err := c.dial(target)
+	if err != nil {
+		return nil, err
+	}
+	shared := &sharedConn{target: target, conn: conn, refCount: 1}
+	c.active[target] = shared
+	c.conns[conn] = shared
+	return conn, nil
+}
+
+// returnConn gives back a connection got from borrowConn
+func (c *connPool) returnConn(conn *grpc.ClientConn) {
+	c.Lock()
+	defer c.Unlock()
+
+	shared, ok := c.conns[conn]
+	if !ok {
+		log.L().Warn("return unknown connection", zap.String("target", conn.Target()))
+		return
+	}
+	shared.refCount--
+	if shared.refCount > 0 {
+		return
+	}
+	delete(c.conns, conn)
+	if c.active[shared.target] == shared {
+		delete(c.active, shared.target)
+	}
+	if err := conn.Close(); err != nil {
+		log.L().Warn("close connection failed", zap.String("addr", shared.target.addr), zap.Error(err))
+	}
+}
@@ -0,0 +1,96 @@
+package cvstask
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestConnPoolShareConn(t *testing.T) {
+	t.Parallel()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	server := grpc.NewServer()
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	defer server.Stop()
+	target := connTarget{addr: lis.Addr().String()}
+
+	var (
+		dialMu    sync.Mutex
+		dialCount int
+	)
+	p := newConnPool(func(target connTarget) (*grpc.ClientConn, error) {
+		dialMu.Lock()
+		dialCount++
+		dialMu.Unlock()
+		return defaultDial(target)
+	})
+
+	// multiple tasks borrow connections to the same address concurrently
+	taskNum := 10
+	conns := make([]*grpc.ClientConn, taskNum)
+	errs := make([]error, taskNum)
+	var wg sync.WaitGroup
+	for i := 0; i < taskNum; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conns[i], errs[i] = p.borrowConn(target)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.Nil(t, err)
+	}
+	require.Equal(t, 1, dialCount)
+	for _, conn := range conns {
+		require.Same(t, conns[0], conn)
+	}
+	require.Equal(t, taskNum, p.conns[conns[0]].refCount)
+
+	// the connection is closed after the last task returns it
+	for i := 0; i < taskNum-1; i++ {
+		p.returnConn(conns[i])
+		require.NotEqual(t, connectivity.Shutdown, conns[0].GetState())
+	}
+	p.returnConn(conns[taskNum-1])
+	require.Equal(t, connectivity.Shutdown, conns[0].GetState())
+	require.Len(t, p.active, 0)
+	require.Len(t, p.conns, 0)
+
+	// borrow again will dial a new connection
+	conn, err := p.borrowConn(target)
+	require.Nil(t, err)
+	require.Equal(t, 2, dialCount)
+	p.returnConn(conn)
+}
+
+func TestConnPoolRedialUnhealthyConn(t *testing.T) {
+	t.Parallel()
+
+	p := newConnPool(defaultDial)
+	target := connTarget{addr: "127.0.0.1:1"}
+	conn1, err := p.borrowConn(target)
+	require.Nil(t, err)
+
+	// simulate a broken connection that is still used by a task
+	require.Nil(t, conn1.Close())
+	conn2, err := p.borrowConn(target)
+	require.Nil(t, err)
+	require.NotSame(t, conn1, conn2)
+	require.Len(t, p.conns, 2)
+
+	p.returnConn(conn1)
+	require.Len(t, p.conns, 1)
+	require.Same(t, conn2, p.active[target].conn)
+	p.returnConn(conn2)
+	require.Len(t, p.conns, 0)
+	require.Len(t, p.active, 0)
+}
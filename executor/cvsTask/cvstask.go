@@ -3,28 +3,48 @@ package cvstask
 import (
 	"context"
 	"encoding/json"
-	"math/rand"
 	"sync"
 	"time"
 
 	"github.com/pingcap/tiflow/dm/pkg/log"
+	"github.com/pingcap/tiflow/pkg/security"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/hanfei1991/microcosm/lib"
 	libModel "github.com/hanfei1991/microcosm/lib/model"
 	"github.com/hanfei1991/microcosm/lib/registry"
 	"github.com/hanfei1991/microcosm/model"
 	"github.com/hanfei1991/microcosm/pb"
+	"github.com/hanfei1991/microcosm/pkg/clock"
 	dcontext "github.com/hanfei1991/microcosm/pkg/context"
 	"github.com/hanfei1991/microcosm/pkg/errors"
 	"github.com/hanfei1991/microcosm/pkg/p2p"
+	"github.com/hanfei1991/microcosm/pkg/promutil"
 )
 
 const (
 	bufferSize = 1024
+	// defaultStallTimeout is the default duration that a task makes no
+	// progress before it is regarded as stalled.
+	defaultStallTimeout = 3 * time.Minute
+	// progressPhaseCopy is the phase of the progress reported by the task,
+	// the total of the progress is unknown because the upstream is a stream.
+	progressPhaseCopy = "copy"
+	// defaultConnectMaxAttempts and defaultConnectRetryBaseDelay are the
+	// default retry policy of connecting to the data services.
+	defaultConnectMaxAttempts    = 5
+	defaultConnectRetryBaseDelay = 500 * time.Millisecond
+	defaultDialTimeout           = 5 * time.Second
+	defaultRPCTimeout            = time.Minute
+	// defaultFlushInterval is the default max duration that the records are
+	// held in a batch before they are written to the downstream.
+	defaultFlushInterval = 100 * time.Millisecond
 )
 
 type strPair struct {
@@ -39,6 +59,44 @@ type Config struct {
 	DstHost  string `json:"DstHost"`
 	DstDir   string `json:"DstIdx"`
 	StartLoc string `json:"StartLoc"`
+	// StallTimeout is the duration that the task makes no progress before it
+	// is regarded as stalled, zero means using defaultStallTimeout.
+	StallTimeout time.Duration `json:"StallTimeout"`
+	// Transform is the name of the transform registered by RegisterTransform
+	// applied to the copied records, empty means copying records verbatim.
+	Transform string `json:"Transform"`
+	// ConnectMaxAttempts is the max number of attempts to connect to the
+	// upstream or downstream if it is unavailable, zero means using
+	// defaultConnectMaxAttempts.
+	ConnectMaxAttempts int `json:"ConnectMaxAttempts"`
+	// ConnectRetryBaseDelay is the delay before the first retry of the
+	// connection, it is doubled after each retry. Zero means using
+	// defaultConnectRetryBaseDelay.
+	ConnectRetryBaseDelay time.Duration `json:"ConnectRetryBaseDelay"`
+	// DialTimeout is the timeout of each attempt to connect to the upstream
+	// or downstream, zero means using defaultDialTimeout.
+	DialTimeout time.Duration `json:"DialTimeout"`
+	// RPCTimeout is the timeout of receiving each record from the upstream
+	// and sending each record to the downstream, zero means using
+	// defaultRPCTimeout.
+	RPCTimeout time.Duration `json:"RPCTimeout"`
+	// Security is the TLS credential to connect to the upstream and
+	// downstream, TLS is enabled if its CAPath is set.
+	Security security.Credential `json:"Security"`
+	// ServerName overrides the server name to verify the certificates of the
+	// upstream and downstream, empty means using the hosts of the addresses.
+	ServerName string `json:"ServerName"`
+	// Insecure must be true to connect to the upstream and downstream
+	// without TLS if Security is not set.
+	Insecure bool `json:"Insecure"`
+	// BatchSize is the max number of records written to the downstream in
+	// one WriteLinesRequest, zero or one means writing the records one by one.
+	BatchSize int `json:"BatchSize"`
+	// FlushInterval is the max duration that the records are held in a batch
+	// before they are written to the downstream, zero means using
+	// defaultFlushInterval. It takes effect only if BatchSize is larger than
+	// one.
+	FlushInterval time.Duration `json:"FlushInterval"`
 }
 
 // Status represents business status of cvs task
@@ -48,42 +106,28 @@ type Status struct {
 	Count      int64  `json:"Cnt"`
 }
 
-type connPool struct {
-	sync.Mutex
-
-	pool map[string]connArray
-}
-
-var pool connPool = connPool{pool: make(map[string]connArray)}
-
-func (c *connPool) getConn(addr string) (*grpc.ClientConn, error) {
-	c.Lock()
-	defer c.Unlock()
-	arr, ok := c.pool[addr]
-	if !ok {
-		for i := 0; i < 5; i++ {
-			conn, err := grpc.Dial(addr, grpc.WithInsecure())
-			if err != nil {
-				return nil, err
-			}
-			arr = append(arr, conn)
-		}
-		c.pool[addr] = arr
-	}
-	i := rand.Intn(5)
-	return arr[i], nil
-}
-
-type connArray []*grpc.ClientConn
-
 type cvsTask struct {
 	lib.BaseWorker
 	Config
+	masterID libModel.MasterID
 	counter  *atomic.Int64
 	curLoc   string
 	cancelFn func()
-	buffer   chan strPair
-	isEOF    bool
+	// runDone is closed after the goroutines copying data exit, they exit
+	// when the task is closed or the context passed to InitImpl is canceled.
+	runDone chan struct{}
+	// buffer is written and closed only by Receive, the producer. send, the
+	// consumer, regards a closed buffer as the end of the records.
+	buffer          chan strPair
+	closeBufferOnce sync.Once
+	isEOF           bool
+	// resumed is true if the task is resumed from the status persisted by
+	// its previous run
+	resumed bool
+	// transform is nil if the records are copied verbatim
+	transform TransformFunc
+	// metrics is registered in InitImpl and unregistered in CloseImpl
+	metrics *taskMetrics
 
 	statusCode struct {
 		sync.RWMutex
@@ -95,6 +139,14 @@ type cvsTask struct {
 	}
 
 	statusRateLimiter *rate.Limiter
+
+	clock clock.Clock
+	// progress records the last time the counter advanced, it is only
+	// accessed in Tick.
+	progress struct {
+		count int64
+		time  time.Time
+	}
 }
 
 // RegisterWorker is used to register cvs task worker into global registry
@@ -110,10 +162,33 @@ func newCvsTask(ctx *dcontext.Context, _workerID libModel.WorkerID, masterID lib
 	cfg := conf.(*Config)
 	task := &cvsTask{
 		Config:            *cfg,
+		masterID:          masterID,
 		curLoc:            cfg.StartLoc,
 		buffer:            make(chan strPair, bufferSize),
 		statusRateLimiter: rate.NewLimiter(rate.Every(time.Second), 1),
 		counter:           atomic.NewInt64(0),
+		clock:             clock.New(),
+	}
+	if task.StallTimeout <= 0 {
+		task.StallTimeout = defaultStallTimeout
+	}
+	if task.ConnectMaxAttempts <= 0 {
+		task.ConnectMaxAttempts = defaultConnectMaxAttempts
+	}
+	if task.ConnectRetryBaseDelay <= 0 {
+		task.ConnectRetryBaseDelay = defaultConnectRetryBaseDelay
+	}
+	if task.DialTimeout <= 0 {
+		task.DialTimeout = defaultDialTimeout
+	}
+	if task.RPCTimeout <= 0 {
+		task.RPCTimeout = defaultRPCTimeout
+	}
+	if task.BatchSize <= 0 {
+		task.BatchSize = 1
+	}
+	if task.FlushInterval <= 0 {
+		task.FlushInterval = defaultFlushInterval
 	}
 	return task
 }
@@ -121,55 +196,167 @@ func newCvsTask(ctx *dcontext.Context, _workerID libModel.WorkerID, masterID lib
 // InitImpl implements WorkerImpl.InitImpl
 func (task *cvsTask) InitImpl(ctx context.Context) error {
 	log.L().Info("init the task  ", zap.Any("task id :", task.ID()))
+	if err := task.checkSecurity(); err != nil {
+		return err
+	}
+	if task.Transform != "" {
+		fn, ok := getTransform(task.Transform)
+		if !ok {
+			return errors.ErrCvsTaskUnknownTransform.GenWithStackByArgs(task.Transform)
+		}
+		task.transform = fn
+	}
+	if err := task.resume(ctx); err != nil {
+		return err
+	}
+	task.metrics = newTaskMetrics(task.masterID, task.ID())
 	task.setStatusCode(libModel.WorkerStatusNormal)
+	task.progress.time = task.clock.Now()
 	ctx, task.cancelFn = context.WithCancel(ctx)
+	task.runDone = make(chan struct{})
 	go func() {
-		err := task.Receive(ctx)
+		defer close(task.runDone)
+		task.run(ctx)
+	}()
+
+	return nil
+}
+
+// checkSecurity checks the transport security of the task, so that an invalid
+// credential fails the task before connecting to the data services.
+func (task *cvsTask) checkSecurity() error {
+	if !task.Security.IsTLSEnabled() {
+		if !task.Insecure {
+			return errors.ErrCvsTaskInvalidSecurity.GenWithStackByArgs(
+				"the CA is not set, set Insecure to connect to the data services without TLS")
+		}
+		return nil
+	}
+	if (task.Security.CertPath == "") != (task.Security.KeyPath == "") {
+		return errors.ErrCvsTaskInvalidSecurity.GenWithStackByArgs(
+			"the client certificate and key must be set together")
+	}
+	if _, err := task.Security.ToTLSConfig(); err != nil {
+		return errors.ErrCvsTaskInvalidSecurity.GenWithStackByArgs(err.Error())
+	}
+	return nil
+}
+
+func (task *cvsTask) connTarget(addr string) connTarget {
+	target := connTarget{addr: addr}
+	if task.Security.IsTLSEnabled() {
+		target.caPath = task.Security.CAPath
+		target.certPath = task.Security.CertPath
+		target.keyPath = task.Security.KeyPath
+		target.serverName = task.ServerName
+	}
+	return target
+}
+
+// resume restores the location and count of the task from the status
+// persisted by its previous run, so that a restarted task continues from the
+// last record it has processed instead of StartLoc.
+func (task *cvsTask) resume(ctx context.Context) error {
+	persisted, err := task.BaseWorker.LoadStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if persisted == nil || len(persisted.ExtBytes) == 0 {
+		return nil
+	}
+	var saved Status
+	if err := json.Unmarshal(persisted.ExtBytes, &saved); err != nil {
+		return errors.ErrCvsTaskResumeFailed.Wrap(err)
+	}
+	if saved.CurrentLoc == "" {
+		return nil
+	}
+	log.L().Info("resume the task from the persisted location", zap.String("id", task.ID()),
+		zap.String("location", saved.CurrentLoc), zap.Int64("count", saved.Count))
+	task.curLoc = saved.CurrentLoc
+	task.counter.Store(saved.Count)
+	task.resumed = true
+	return nil
+}
+
+// run copies data from upstream to downstream. The task is marked finished
+// only after the upstream reaches EOF, and all buffered records are sent and
+// acknowledged by the downstream.
+func (task *cvsTask) run(ctx context.Context) {
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		err := task.Receive(gctx)
 		if err != nil {
 			log.L().Error("error happened when reading data from the upstream ", zap.String("id", task.ID()), zap.Any("message", err.Error()))
-			task.setRunError(err)
-			task.setStatusCode(libModel.WorkerStatusError)
 		}
-	}()
-	go func() {
-		err := task.send(ctx)
+		return err
+	})
+	g.Go(func() error {
+		err := task.send(gctx)
 		if err != nil {
 			log.L().Error("error happened when writing data to the downstream ", zap.String("id", task.ID()), zap.Any("message", err.Error()))
-			task.setRunError(err)
-			task.setStatusCode(libModel.WorkerStatusError)
-		} else {
-			task.setStatusCode(libModel.WorkerStatusFinished)
 		}
-	}()
+		return err
+	})
 
-	return nil
+	if err := g.Wait(); err != nil {
+		task.setRunError(err)
+		task.setStatusCode(libModel.WorkerStatusError)
+		return
+	}
+	task.setStatusCode(libModel.WorkerStatusFinished)
 }
 
 // Tick is called on a fixed interval.
 func (task *cvsTask) Tick(ctx context.Context) error {
 	// log.L().Info("cvs task tick", zap.Any(" task id ", string(task.ID())+" -- "+strconv.FormatInt(task.counter, 10)))
-	if task.statusRateLimiter.Allow() {
-		err := task.BaseWorker.UpdateStatus(ctx, task.Status())
-		if errors.ErrWorkerUpdateStatusTryAgain.Equal(err) {
-			log.L().Warn("update status try again later", zap.String("id", task.ID()), zap.String("error", err.Error()))
-			return nil
-		}
-		return err
-	}
+	task.checkStall()
+	// a terminal status is reported by Exit in the same tick, it is never
+	// delayed by the rate limit of the status updates
 	switch task.getStatusCode() {
 	case libModel.WorkerStatusFinished, libModel.WorkerStatusError, libModel.WorkerStatusStopped:
 		return task.BaseWorker.Exit(ctx, task.Status(), task.getRunError())
 	default:
 	}
-	return nil
+	if !task.statusRateLimiter.Allow() {
+		return nil
+	}
+	err := task.BaseWorker.UpdateStatus(ctx, task.Status())
+	if errors.ErrWorkerUpdateStatusTryAgain.Equal(err) {
+		log.L().Warn("update status try again later", zap.String("id", task.ID()), zap.String("error", err.Error()))
+		return nil
+	}
+	return err
+}
+
+// checkStall transitions the task to error if it is running but makes no
+// progress in StallTimeout, so that the master can restart it.
+func (task *cvsTask) checkStall() {
+	if task.getStatusCode() != libModel.WorkerStatusNormal {
+		return
+	}
+	now := task.clock.Now()
+	if count := task.counter.Load(); count != task.progress.count {
+		task.progress.count = count
+		task.progress.time = now
+		return
+	}
+	if stalled := now.Sub(task.progress.time); stalled >= task.StallTimeout {
+		log.L().Warn("cvs task is stalled", zap.String("id", task.ID()),
+			zap.Int64("count", task.progress.count), zap.Duration("stalled", stalled))
+		task.setRunError(errors.ErrCvsTaskStalled.GenWithStackByArgs(stalled))
+		task.setStatusCode(libModel.WorkerStatusError)
+		task.cancelFn()
+	}
 }
 
 // Status returns a short worker status to be periodically sent to the master.
 func (task *cvsTask) Status() libModel.WorkerStatus {
+	count := task.counter.Load()
 	stats := &Status{
 		TaskConfig: task.Config,
 		CurrentLoc: task.curLoc,
-		Count:      task.counter.Load(),
+		Count:      count,
 	}
 	statsBytes, err := json.Marshal(stats)
 	if err != nil {
@@ -178,6 +365,10 @@ func (task *cvsTask) Status() libModel.WorkerStatus {
 	return libModel.WorkerStatus{
 		Code: task.getStatusCode(), ErrorMessage: "",
 		ExtBytes: statsBytes,
+		Progress: &libModel.WorkerProgress{
+			Completed: count,
+			Phase:     progressPhaseCopy,
+		},
 	}
 }
 
@@ -208,27 +399,90 @@ func (task *cvsTask) OnMasterMessage(topic p2p.Topic, message p2p.MessageValue)
 }
 
 // CloseImpl tells the WorkerImpl to quitrunStatusWorker and release resources.
+// It waits for the goroutines copying data to exit, so that they never
+// outlive the task and its grpc connections.
 func (task *cvsTask) CloseImpl(ctx context.Context) error {
 	if task.cancelFn != nil {
 		task.cancelFn()
 	}
+	if task.runDone != nil {
+		<-task.runDone
+	}
+	if task.metrics != nil {
+		promutil.UnregisterWorkerMetrics(task.ID())
+	}
 	return nil
 }
 
+// connect borrows a connection to addr from the pool and opens a stream on
+// it by open. If the data service is unavailable, e.g. being restarted during
+// a rollout, or the connection is not ready in DialTimeout, it retries with an exponential backoff until ConnectMaxAttempts
+// is used up or ctx is done. The caller must return the connection to the
+// pool after the stream is not used any more.
+func (task *cvsTask) connect(ctx context.Context, addr string, open func(conn *grpc.ClientConn) error) (*grpc.ClientConn, error) {
+	delay := task.ConnectRetryBaseDelay
+	for attempt := 1; ; attempt++ {
+		conn, err := pool.borrowConn(task.connTarget(addr))
+		if err == nil {
+			if err = waitConnReady(ctx, conn, task.DialTimeout); err == nil {
+				err = open(conn)
+			}
+			if err == nil {
+				return conn, nil
+			}
+			pool.returnConn(conn)
+		}
+		if attempt >= task.ConnectMaxAttempts || status.Code(err) != codes.Unavailable {
+			return nil, err
+		}
+
+		log.L().Warn("data service is unavailable, retry later", zap.String("id", task.ID()),
+			zap.String("addr", addr), zap.Int("attempt", attempt), zap.Duration("delay", delay), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// callWithTimeout runs call which is an operation of a stream, and cancels
+// the stream by cancelStream if call doesn't return in RPCTimeout, so that a
+// stalled peer makes call fail instead of hanging forever.
+func (task *cvsTask) callWithTimeout(name string, cancelStream context.CancelFunc, call func() error) error {
+	timer := time.AfterFunc(task.RPCTimeout, cancelStream)
+	err := call()
+	if !timer.Stop() {
+		return errors.ErrCvsTaskRPCTimeout.GenWithStackByArgs(name, task.RPCTimeout)
+	}
+	return err
+}
+
 func (task *cvsTask) Receive(ctx context.Context) error {
-	conn, err := pool.getConn(task.SrcHost)
-	if err != nil {
-		log.L().Error("cann't connect with the source address ", zap.String("id", task.ID()), zap.Any("message", task.SrcHost))
+	startLoc := task.curLoc
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	defer cancelStream()
+	var reader pb.DataRWService_ReadLinesClient
+	conn, err := task.connect(ctx, task.SrcHost, func(conn *grpc.ClientConn) (err error) {
+		reader, err = pb.NewDataRWServiceClient(conn).ReadLines(streamCtx,
+			&pb.ReadLinesRequest{FileIdx: int32(task.Idx), LineNo: []byte(startLoc)})
 		return err
-	}
-	client := pb.NewDataRWServiceClient(conn)
-	reader, err := client.ReadLines(ctx, &pb.ReadLinesRequest{FileIdx: int32(task.Idx), LineNo: []byte(task.StartLoc)})
+	})
 	if err != nil {
-		log.L().Error("read data from file failed ", zap.String("id", task.ID()), zap.Error(err))
+		log.L().Error("read data from file failed ", zap.String("id", task.ID()), zap.String("addr", task.SrcHost), zap.Error(err))
 		return err
 	}
+	defer pool.returnConn(conn)
+	// the record at the resumed location has been copied by the previous
+	// run, skip it if the upstream sends it again
+	skipFirst := task.resumed
 	for {
-		reply, err := reader.Recv()
+		var reply *pb.ReadLinesResponse
+		err := task.callWithTimeout("ReadLines.Recv", cancelStream, func() (err error) {
+			reply, err = reader.Recv()
+			return err
+		})
 		if err != nil {
 			log.L().Error("read data failed", zap.String("id", task.ID()), zap.Error(err))
 			if !task.isEOF {
@@ -238,9 +492,16 @@ func (task *cvsTask) Receive(ctx context.Context) error {
 		}
 		if reply.IsEof {
 			log.L().Info("Reach the end of the file ", zap.String("id", task.ID()), zap.Any("fileID", task.Idx))
-			close(task.buffer)
+			task.isEOF = true
+			task.closeBuffer()
 			break
 		}
+		if skipFirst {
+			skipFirst = false
+			if string(reply.Key) == startLoc {
+				continue
+			}
+		}
 		select {
 		case <-ctx.Done():
 			return nil
@@ -251,41 +512,122 @@ func (task *cvsTask) Receive(ctx context.Context) error {
 	return nil
 }
 
+// closeBuffer closes the buffer to tell send that all the records are read,
+// it must not be called unless the upstream reaches EOF, otherwise send
+// would commit the partial records to the downstream.
+func (task *cvsTask) closeBuffer() {
+	task.closeBufferOnce.Do(func() {
+		close(task.buffer)
+	})
+}
+
 func (task *cvsTask) send(ctx context.Context) error {
-	conn, err := pool.getConn(task.DstHost)
-	if err != nil {
-		log.L().Error("can't connect with the destination address ", zap.Any("id", task.ID()), zap.Error(err))
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	defer cancelStream()
+	var writer pb.DataRWService_WriteLinesClient
+	conn, err := task.connect(ctx, task.DstHost, func(conn *grpc.ClientConn) (err error) {
+		writer, err = pb.NewDataRWServiceClient(conn).WriteLines(streamCtx)
 		return err
-	}
-	client := pb.NewDataRWServiceClient(conn)
-	writer, err := client.WriteLines(ctx)
+	})
 	if err != nil {
-		log.L().Error("call write data rpc failed", zap.String("id", task.ID()), zap.Error(err))
+		log.L().Error("call write data rpc failed", zap.String("id", task.ID()), zap.String("addr", task.DstHost), zap.Error(err))
 		task.cancelFn()
 		return err
 	}
+	defer pool.returnConn(conn)
+	limiter := getDownstreamLimiter(task.DstHost)
+	// the records pulled from the buffer are written in batches, and the
+	// progress is updated only after the batch is written.
+	var (
+		pairs   []*pb.KeyValue
+		pending int64
+		lastLoc string
+		flushCh <-chan time.Time
+	)
+	if task.BatchSize > 1 {
+		ticker := time.NewTicker(task.FlushInterval)
+		defer ticker.Stop()
+		flushCh = ticker.C
+	}
+	flush := func() error {
+		if len(pairs) > 0 {
+			req := &pb.WriteLinesRequest{FileIdx: int32(task.Idx), Dir: task.DstDir}
+			if task.BatchSize > 1 {
+				req.Pairs = pairs
+			} else {
+				req.Key, req.Value = pairs[0].Key, pairs[0].Value
+			}
+			err := task.callWithTimeout("WriteLines.Send", cancelStream, func() error {
+				return writer.Send(req)
+			})
+			if err != nil {
+				log.L().Error("call write data rpc failed ", zap.String("id", task.ID()), zap.Error(err))
+				task.cancelFn()
+				return err
+			}
+			pairs = nil
+		}
+		// the skipped records are counted as progress too, and the
+		// location is always the upstream key to resume from
+		if pending > 0 {
+			task.counter.Add(pending)
+			task.metrics.copied.Add(float64(pending))
+			task.curLoc = lastLoc
+			pending = 0
+		}
+		return nil
+	}
 	for {
 		select {
 		case kv, more := <-task.buffer:
 			if !more {
+				if err := flush(); err != nil {
+					return err
+				}
 				log.L().Info("Reach the end of the file ", zap.String("id", task.ID()), zap.Any("cnt", task.counter.Load()), zap.String("last write", task.curLoc))
-				resp, err := writer.CloseAndRecv()
+				var resp *pb.WriteLinesResponse
+				err := task.callWithTimeout("WriteLines.CloseAndRecv", cancelStream, func() (err error) {
+					resp, err = writer.CloseAndRecv()
+					return err
+				})
 				if err != nil {
 					return err
 				}
 				if len(resp.ErrMsg) > 0 {
-					log.L().Warn("close writing meet error", zap.String("id", task.ID()))
+					log.L().Warn("close writing meet error", zap.String("id", task.ID()), zap.String("error", resp.ErrMsg))
+					return errors.ErrCvsTaskWriteFailed.GenWithStackByArgs(resp.ErrMsg)
 				}
 				return nil
 			}
-			err := writer.Send(&pb.WriteLinesRequest{FileIdx: int32(task.Idx), Key: []byte(kv.firstStr), Value: []byte(kv.secondStr), Dir: task.DstDir})
-			if err != nil {
-				log.L().Error("call write data rpc failed ", zap.String("id", task.ID()), zap.Error(err))
-				task.cancelFn()
+			key, val := []byte(kv.firstStr), []byte(kv.secondStr)
+			skip := false
+			if task.transform != nil {
+				key, val, skip, err = task.transform(key, val)
+				if err != nil {
+					log.L().Error("transform record failed", zap.String("id", task.ID()), zap.String("key", kv.firstStr), zap.Error(err))
+					task.cancelFn()
+					return errors.ErrCvsTaskTransformFailed.Wrap(err).GenWithStackByArgs(kv.firstStr)
+				}
+			}
+			if !skip {
+				if limiter != nil {
+					if err := limiter.Wait(ctx); err != nil {
+						return err
+					}
+				}
+				pairs = append(pairs, &pb.KeyValue{Key: key, Value: val})
+			}
+			pending++
+			lastLoc = kv.firstStr
+			if len(pairs) >= task.BatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		case <-flushCh:
+			if err := flush(); err != nil {
 				return err
 			}
-			task.counter.Add(1)
-			task.curLoc = kv.firstStr
 		case <-ctx.Done():
 			return ctx.Err()
 		}
@@ -310,8 +652,12 @@ func (task *cvsTask) getRunError() error {
 	return task.runError.err
 }
 
+// setRunError records the first error of the task, the following errors
+// are usually caused by the first one.
 func (task *cvsTask) setRunError(err error) {
 	task.runError.Lock()
 	defer task.runError.Unlock()
-	task.runError.err = err
+	if task.runError.err == nil {
+		task.runError.err = err
+	}
 }
@@ -0,0 +1,704 @@
+package cvstask
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"go.uber.org/goleak"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hanfei1991/microcosm/lib"
+	libModel "github.com/hanfei1991/microcosm/lib/model"
+	"github.com/hanfei1991/microcosm/pb"
+	"github.com/hanfei1991/microcosm/pkg/clock"
+	dcontext "github.com/hanfei1991/microcosm/pkg/context"
+	"github.com/hanfei1991/microcosm/pkg/errors"
+)
+
+// mockDataRWServer is a DataRWService that generates `lines` records in
+// ReadLines, starting from the requested line number inclusively, and acknowledges each record after `writeDelay` in WriteLines.
+// The records of a batched WriteLinesRequest are received as separate requests, and `writeDelay` is applied once per batch.
+// If `frozen` is true, WriteLines receives nothing until the stream is closed.
+// If `failAtEOF` is true, ReadLines fails instead of sending the EOF marker.
+type mockDataRWServer struct {
+	pb.UnimplementedDataRWServiceServer
+
+	lines      int
+	valueSize  int
+	writeDelay time.Duration
+	frozen     bool
+	failAtEOF  bool
+	acked      atomic.Int64
+	requests   atomic.Int64
+	closed     atomic.Bool
+
+	mu       sync.Mutex
+	received []*pb.WriteLinesRequest
+	readFrom []string
+}
+
+func (s *mockDataRWServer) ReadLines(req *pb.ReadLinesRequest, stream pb.DataRWService_ReadLinesServer) error {
+	s.mu.Lock()
+	s.readFrom = append(s.readFrom, string(req.LineNo))
+	s.mu.Unlock()
+	// an empty or invalid line number means reading from the start
+	start, _ := strconv.Atoi(string(req.LineNo))
+	for i := start; i < s.lines; i++ {
+		err := stream.Send(&pb.ReadLinesResponse{
+			Key: []byte(fmt.Sprintf("%d", i)),
+			Val: []byte(fmt.Sprintf("val-%d", i) + strings.Repeat("v", s.valueSize)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if s.failAtEOF {
+		return fmt.Errorf("mock read failure at EOF")
+	}
+	return stream.Send(&pb.ReadLinesResponse{IsEof: true})
+}
+
+func (s *mockDataRWServer) WriteLines(stream pb.DataRWService_WriteLinesServer) error {
+	if s.frozen {
+		<-stream.Context().Done()
+		return stream.Context().Err()
+	}
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			s.closed.Store(true)
+			return stream.SendAndClose(&pb.WriteLinesResponse{})
+		}
+		if err != nil {
+			return err
+		}
+		time.Sleep(s.writeDelay)
+		s.requests.Add(1)
+		records := []*pb.WriteLinesRequest{req}
+		if len(req.Pairs) > 0 {
+			records = records[:0]
+			for _, pair := range req.Pairs {
+				records = append(records, &pb.WriteLinesRequest{Dir: req.Dir, FileIdx: req.FileIdx, Key: pair.Key, Value: pair.Value})
+			}
+		}
+		s.mu.Lock()
+		s.received = append(s.received, records...)
+		s.mu.Unlock()
+		s.acked.Add(int64(len(records)))
+	}
+}
+
+// rejectingListener closes the first `reject` connections it accepts
+type rejectingListener struct {
+	net.Listener
+	reject   int
+	rejected atomic.Int32
+}
+
+func (l *rejectingListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if int(l.rejected.Load()) >= l.reject {
+			return conn, nil
+		}
+		l.rejected.Inc()
+		_ = conn.Close()
+	}
+}
+
+func newMockDataRWServer(t *testing.T, srv *mockDataRWServer) (addr string, stop func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	return serveMockDataRWServer(srv, lis)
+}
+
+func serveMockDataRWServer(srv *mockDataRWServer, lis net.Listener, opts ...grpc.ServerOption) (addr string, stop func()) {
+	server := grpc.NewServer(opts...)
+	pb.RegisterDataRWServiceServer(server, srv)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	return lis.Addr().String(), server.Stop
+}
+
+// testWorkerSeq makes the IDs of the tasks in the parallel tests unique, so
+// that their metrics don't conflict.
+var testWorkerSeq atomic.Int32
+
+func newCvsTaskForTest(srcHost, dstHost string) *cvsTask {
+	workerID := fmt.Sprintf("worker-%d", testWorkerSeq.Inc())
+	task := newCvsTask(dcontext.Background(), workerID, "master-1", &Config{
+		SrcHost:  srcHost,
+		DstHost:  dstHost,
+		Insecure: true,
+	})
+	task.BaseWorker = lib.MockBaseWorker(workerID, "master-1", task)
+	return task
+}
+
+func TestCvsTaskFinishAfterDownstreamAck(t *testing.T) {
+	t.Parallel()
+
+	srv := &mockDataRWServer{lines: 20, writeDelay: 20 * time.Millisecond}
+	addr, stop := newMockDataRWServer(t, srv)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	task := newCvsTaskForTest(addr, addr)
+	require.Nil(t, task.InitImpl(ctx))
+
+	require.Eventually(t, func() bool {
+		code := task.getStatusCode()
+		if code != libModel.WorkerStatusFinished {
+			require.Equal(t, libModel.WorkerStatusNormal, code)
+			return false
+		}
+		// finished must be reported after all records are acknowledged
+		require.Equal(t, int64(srv.lines), srv.acked.Load())
+		require.True(t, srv.closed.Load())
+		return true
+	}, 5*time.Second, 5*time.Millisecond)
+	require.Equal(t, int64(srv.lines), task.counter.Load())
+	require.Nil(t, task.getRunError())
+
+	// the progress surfaces through the status reported to the master
+	status := task.Status()
+	statusBytes, err := status.Marshal()
+	require.Nil(t, err)
+	var decoded libModel.WorkerStatus
+	require.Nil(t, decoded.Unmarshal(statusBytes))
+	require.Equal(t, &libModel.WorkerProgress{
+		Completed: int64(srv.lines),
+		Phase:     progressPhaseCopy,
+	}, decoded.Progress)
+	require.Nil(t, task.CloseImpl(ctx))
+}
+
+func TestCvsTaskErrorOnDownstreamFailure(t *testing.T) {
+	t.Parallel()
+
+	srv := &mockDataRWServer{lines: 20}
+	addr, stop := newMockDataRWServer(t, srv)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// no server listens on the destination address
+	task := newCvsTaskForTest(addr, "127.0.0.1:1")
+	task.ConnectRetryBaseDelay = 10 * time.Millisecond
+	require.Nil(t, task.InitImpl(ctx))
+
+	require.Eventually(t, func() bool {
+		return task.getStatusCode() == libModel.WorkerStatusError
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Error(t, task.getRunError())
+	require.Nil(t, task.CloseImpl(ctx))
+}
+
+func TestCvsTaskResumeFromPersistedStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// run the task partway, the limiter slows the task down
+	srv := &mockDataRWServer{lines: 20}
+	addr, stop := newMockDataRWServer(t, srv)
+	defer stop()
+	RegisterDownstreamLimiter(addr, rate.NewLimiter(rate.Every(10*time.Millisecond), 1))
+	defer RegisterDownstreamLimiter(addr, nil)
+	task := newCvsTaskForTest(addr, addr)
+	require.Nil(t, task.InitImpl(ctx))
+	require.Eventually(t, func() bool {
+		return task.counter.Load() >= 5
+	}, 5*time.Second, time.Millisecond)
+	require.Nil(t, task.CloseImpl(ctx))
+	saved := task.Status()
+	var savedStatus Status
+	require.Nil(t, json.Unmarshal(saved.ExtBytes, &savedStatus))
+	require.NotEmpty(t, savedStatus.CurrentLoc)
+	require.Less(t, savedStatus.Count, int64(srv.lines))
+
+	// restart the task with the status persisted by the previous run
+	srv2 := &mockDataRWServer{lines: 20}
+	addr2, stop2 := newMockDataRWServer(t, srv2)
+	defer stop2()
+	task2 := newCvsTaskForTest(addr2, addr2)
+	lib.MockBaseWorkerPersistStatus(t, task2.BaseWorker.(*lib.BaseWorkerForTesting).DefaultBaseWorker, saved)
+	require.Nil(t, task2.InitImpl(ctx))
+	require.Eventually(t, func() bool {
+		return task2.getStatusCode() == libModel.WorkerStatusFinished
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Nil(t, task2.CloseImpl(ctx))
+
+	srv2.mu.Lock()
+	defer srv2.mu.Unlock()
+	require.Equal(t, []string{savedStatus.CurrentLoc}, srv2.readFrom)
+	// the record at the saved location is not copied again
+	last, err := strconv.Atoi(savedStatus.CurrentLoc)
+	require.Nil(t, err)
+	require.Len(t, srv2.received, srv.lines-last-1)
+	require.Equal(t, strconv.Itoa(last+1), string(srv2.received[0].Key))
+	require.Equal(t, int64(srv.lines), task2.counter.Load())
+	require.Equal(t, strconv.Itoa(srv.lines-1), task2.curLoc)
+}
+
+func TestCvsTaskErrorAtUpstreamEOF(t *testing.T) {
+	t.Parallel()
+
+	srv := &mockDataRWServer{lines: 20, failAtEOF: true}
+	addr, stop := newMockDataRWServer(t, srv)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	task := newCvsTaskForTest(addr, addr)
+	require.Nil(t, task.InitImpl(ctx))
+
+	require.Eventually(t, func() bool {
+		return task.getStatusCode() == libModel.WorkerStatusError
+	}, 5*time.Second, 10*time.Millisecond)
+	require.ErrorContains(t, task.getRunError(), "mock read failure at EOF")
+	require.False(t, task.isEOF)
+	require.Nil(t, task.CloseImpl(ctx))
+	// the partial records are not committed to the downstream
+	require.False(t, srv.closed.Load())
+
+	// closing the buffer again is harmless, the records not sent remain
+	task.closeBuffer()
+	task.closeBuffer()
+	for range task.buffer {
+	}
+}
+
+func TestCvsTaskRetryConnect(t *testing.T) {
+	t.Parallel()
+
+	srv := &mockDataRWServer{lines: 20}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	rejectingLis := &rejectingListener{Listener: lis, reject: 1}
+	addr, stop := serveMockDataRWServer(srv, rejectingLis)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	task := newCvsTaskForTest(addr, addr)
+	task.ConnectRetryBaseDelay = 10 * time.Millisecond
+	require.Nil(t, task.InitImpl(ctx))
+
+	require.Eventually(t, func() bool {
+		return task.getStatusCode() == libModel.WorkerStatusFinished
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Nil(t, task.getRunError())
+	require.Equal(t, int64(srv.lines), srv.acked.Load())
+	require.Equal(t, int32(1), rejectingLis.rejected.Load())
+	require.Nil(t, task.CloseImpl(ctx))
+}
+
+func TestCvsTaskDialTimeout(t *testing.T) {
+	t.Parallel()
+
+	// the peer accepts the connections but never responds
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer lis.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				_ = conn.Close()
+			}
+		}()
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr := lis.Addr().String()
+	task := newCvsTaskForTest(addr, addr)
+	task.DialTimeout = 200 * time.Millisecond
+	task.ConnectMaxAttempts = 1
+	start := time.Now()
+	require.Nil(t, task.InitImpl(ctx))
+
+	require.Eventually(t, func() bool {
+		return task.getStatusCode() == libModel.WorkerStatusError
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Less(t, time.Since(start), 2*time.Second)
+	require.Equal(t, codes.Unavailable, status.Code(task.getRunError()))
+	require.Nil(t, task.CloseImpl(ctx))
+}
+
+func TestCvsTaskRPCTimeout(t *testing.T) {
+	t.Parallel()
+
+	// the downstream never consumes the records, so sending blocks after the
+	// flow control window is used up
+	srv := &mockDataRWServer{lines: 10000, valueSize: 1024, frozen: true}
+	addr, stop := newMockDataRWServer(t, srv)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	task := newCvsTaskForTest(addr, addr)
+	task.RPCTimeout = 200 * time.Millisecond
+	require.Nil(t, task.InitImpl(ctx))
+
+	require.Eventually(t, func() bool {
+		return task.getStatusCode() == libModel.WorkerStatusError
+	}, 5*time.Second, 10*time.Millisecond)
+	require.True(t, errors.ErrCvsTaskRPCTimeout.Equal(task.getRunError()), task.getRunError())
+	require.Nil(t, task.CloseImpl(ctx))
+}
+
+func TestCvsTaskNoGoroutineLeak(t *testing.T) {
+	// not parallel, goroutines of the other tests would be reported as leaks
+
+	// the downstream never consumes the records, so the goroutines of the
+	// task keep running until they are stopped
+	srv := &mockDataRWServer{lines: 10000, valueSize: 1024, frozen: true}
+	addr, stop := newMockDataRWServer(t, srv)
+	closedTask := newCvsTaskForTest(addr, addr)
+	canceledTask := newCvsTaskForTest(addr, addr)
+	// the goroutines of the server and the mock base workers are ignored
+	ignoreCurrent := goleak.IgnoreCurrent()
+
+	// the task is closed
+	require.Nil(t, closedTask.InitImpl(context.Background()))
+	require.Eventually(t, func() bool {
+		return closedTask.counter.Load() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Nil(t, closedTask.CloseImpl(context.Background()))
+	select {
+	case <-closedTask.runDone:
+	default:
+		require.FailNow(t, "goroutines of the task survive after close")
+	}
+
+	// the context passed to InitImpl is canceled without closing the task
+	ctx, cancel := context.WithCancel(context.Background())
+	require.Nil(t, canceledTask.InitImpl(ctx))
+	require.Eventually(t, func() bool {
+		return canceledTask.counter.Load() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	select {
+	case <-canceledTask.runDone:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "goroutines of the task survive after the context is canceled")
+	}
+
+	stop()
+	goleak.VerifyNone(t, ignoreCurrent)
+}
+
+func TestCvsTaskStallDetection(t *testing.T) {
+	t.Parallel()
+
+	srv := &mockDataRWServer{lines: 10000, valueSize: 1024, frozen: true}
+	addr, stop := newMockDataRWServer(t, srv)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	task := newCvsTaskForTest(addr, addr)
+	task.StallTimeout = 10 * time.Second
+	mockClock := clock.NewMock()
+	task.clock = mockClock
+	require.Nil(t, task.InitImpl(ctx))
+
+	// wait until the downstream applies back pressure and the task makes no
+	// progress any more
+	require.Eventually(t, func() bool {
+		task.checkStall()
+		count := task.counter.Load()
+		time.Sleep(100 * time.Millisecond)
+		return count > 0 && count == task.counter.Load()
+	}, 10*time.Second, 10*time.Millisecond)
+	task.checkStall()
+
+	mockClock.Add(task.StallTimeout - time.Second)
+	task.checkStall()
+	require.Equal(t, libModel.WorkerStatusNormal, task.getStatusCode())
+
+	mockClock.Add(time.Second)
+	task.checkStall()
+	require.Equal(t, libModel.WorkerStatusError, task.getStatusCode())
+	require.Regexp(t, ".*ErrCvsTaskStalled.*", task.getRunError())
+
+	// the errors of canceled goroutines don't override the stalled error
+	time.Sleep(100 * time.Millisecond)
+	require.Regexp(t, ".*ErrCvsTaskStalled.*", task.getRunError())
+	require.Nil(t, task.CloseImpl(ctx))
+}
+
+// tickRecordingWorker records the status updates and exits of the task in
+// Tick instead of sending them to the master
+type tickRecordingWorker struct {
+	lib.BaseWorker
+
+	updated []libModel.WorkerStatus
+	exited  []libModel.WorkerStatus
+}
+
+func (w *tickRecordingWorker) UpdateStatus(_ context.Context, status libModel.WorkerStatus) error {
+	w.updated = append(w.updated, status)
+	return nil
+}
+
+func (w *tickRecordingWorker) Exit(_ context.Context, status libModel.WorkerStatus, _ error) error {
+	w.exited = append(w.exited, status)
+	return errors.ErrWorkerFinish.FastGenByArgs()
+}
+
+func TestCvsTaskExitInTickOfTerminalStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	task := newCvsTaskForTest("", "")
+	worker := &tickRecordingWorker{BaseWorker: task.BaseWorker}
+	task.BaseWorker = worker
+	task.progress.time = task.clock.Now()
+	task.setStatusCode(libModel.WorkerStatusFinished)
+
+	// the finished task exits in the next tick though the rate limiter,
+	// which is not used yet, allows a status update in the same tick
+	err := task.Tick(ctx)
+	require.True(t, errors.ErrWorkerFinish.Equal(err))
+	require.Len(t, worker.exited, 1)
+	require.Equal(t, libModel.WorkerStatusFinished, worker.exited[0].Code)
+	require.Empty(t, worker.updated)
+}
+
+func TestCvsTaskTransform(t *testing.T) {
+	t.Parallel()
+
+	RegisterTransform("test-rewrite-key", func(key, val []byte) ([]byte, []byte, bool, error) {
+		return append([]byte("new-"), key...), val, false, nil
+	})
+	RegisterTransform("test-skip-odd", func(key, val []byte) ([]byte, []byte, bool, error) {
+		idx, err := strconv.Atoi(string(key))
+		if err != nil {
+			return nil, nil, false, err
+		}
+		return key, val, idx%2 == 1, nil
+	})
+
+	testCases := []struct {
+		transform string
+		expected  []*pb.WriteLinesRequest
+	}{
+		{
+			// copy verbatim without transform
+			transform: "",
+			expected: []*pb.WriteLinesRequest{
+				{Key: []byte("0"), Value: []byte("val-0")},
+				{Key: []byte("1"), Value: []byte("val-1")},
+				{Key: []byte("2"), Value: []byte("val-2")},
+				{Key: []byte("3"), Value: []byte("val-3")},
+			},
+		},
+		{
+			transform: "test-rewrite-key",
+			expected: []*pb.WriteLinesRequest{
+				{Key: []byte("new-0"), Value: []byte("val-0")},
+				{Key: []byte("new-1"), Value: []byte("val-1")},
+				{Key: []byte("new-2"), Value: []byte("val-2")},
+				{Key: []byte("new-3"), Value: []byte("val-3")},
+			},
+		},
+		{
+			transform: "test-skip-odd",
+			expected: []*pb.WriteLinesRequest{
+				{Key: []byte("0"), Value: []byte("val-0")},
+				{Key: []byte("2"), Value: []byte("val-2")},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		srv := &mockDataRWServer{lines: 4}
+		addr, stop := newMockDataRWServer(t, srv)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		task := newCvsTaskForTest(addr, addr)
+		task.Transform = tc.transform
+		require.Nil(t, task.InitImpl(ctx))
+		require.Eventually(t, func() bool {
+			return task.getStatusCode() == libModel.WorkerStatusFinished
+		}, 5*time.Second, 5*time.Millisecond)
+		require.Nil(t, task.getRunError())
+		// all the records read are counted, including the skipped ones
+		require.Equal(t, int64(srv.lines), task.counter.Load())
+		require.Equal(t, "3", task.curLoc)
+
+		srv.mu.Lock()
+		require.Len(t, srv.received, len(tc.expected))
+		for i, req := range srv.received {
+			require.Equal(t, tc.expected[i].Key, req.Key)
+			require.Equal(t, tc.expected[i].Value, req.Value)
+		}
+		srv.mu.Unlock()
+
+		require.Nil(t, task.CloseImpl(ctx))
+		cancel()
+		stop()
+	}
+}
+
+func TestCvsTaskTransformError(t *testing.T) {
+	t.Parallel()
+
+	RegisterTransform("test-fail", func(key, val []byte) ([]byte, []byte, bool, error) {
+		return nil, nil, false, fmt.Errorf("bad record %s", key)
+	})
+
+	srv := &mockDataRWServer{lines: 4}
+	addr, stop := newMockDataRWServer(t, srv)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	task := newCvsTaskForTest(addr, addr)
+	task.Transform = "test-fail"
+	require.Nil(t, task.InitImpl(ctx))
+	require.Eventually(t, func() bool {
+		return task.getStatusCode() == libModel.WorkerStatusError
+	}, 5*time.Second, 5*time.Millisecond)
+	require.Regexp(t, ".*ErrCvsTaskTransformFailed.*", task.getRunError())
+	require.Equal(t, int64(0), task.counter.Load())
+	require.Nil(t, task.CloseImpl(ctx))
+
+	// the transform must be registered
+	task = newCvsTaskForTest(addr, addr)
+	task.Transform = "test-not-registered"
+	err := task.InitImpl(ctx)
+	require.True(t, errors.ErrCvsTaskUnknownTransform.Equal(err))
+}
+
+func TestCvsTaskSharedDownstreamLimiter(t *testing.T) {
+	t.Parallel()
+
+	srv := &mockDataRWServer{lines: 30}
+	addr, stop := newMockDataRWServer(t, srv)
+	defer stop()
+
+	const budget = 100
+	RegisterDownstreamLimiter(addr, rate.NewLimiter(budget, 1))
+	defer RegisterDownstreamLimiter(addr, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tasks := []*cvsTask{newCvsTaskForTest(addr, addr), newCvsTaskForTest(addr, addr)}
+	start := time.Now()
+	for _, task := range tasks {
+		require.Nil(t, task.InitImpl(ctx))
+	}
+	require.Eventually(t, func() bool {
+		for _, task := range tasks {
+			if task.getStatusCode() != libModel.WorkerStatusFinished {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 5*time.Millisecond)
+	elapsed := time.Since(start)
+
+	total := 2 * srv.lines
+	require.Equal(t, int64(total), srv.acked.Load())
+	// each task alone is allowed to write at the full budget, so the combined
+	// throughput exceeds the budget if the limiter is not shared
+	minElapsed := time.Duration(total-1) * time.Second / budget
+	require.GreaterOrEqual(t, elapsed, minElapsed)
+	for _, task := range tasks {
+		require.Nil(t, task.getRunError())
+		require.Nil(t, task.CloseImpl(ctx))
+	}
+}
+
+func TestCvsTaskBatchWrite(t *testing.T) {
+	t.Parallel()
+
+	srv := &mockDataRWServer{lines: 20}
+	addr, stop := newMockDataRWServer(t, srv)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	task := newCvsTaskForTest(addr, addr)
+	task.BatchSize = 8
+	// flush only by the batch size and the upstream EOF
+	task.FlushInterval = time.Hour
+	require.Nil(t, task.InitImpl(ctx))
+	require.Eventually(t, func() bool {
+		return task.getStatusCode() == libModel.WorkerStatusFinished
+	}, 5*time.Second, 5*time.Millisecond)
+	require.Nil(t, task.getRunError())
+
+	require.Equal(t, int64(3), srv.requests.Load())
+	require.Equal(t, int64(srv.lines), task.counter.Load())
+	require.Equal(t, strconv.Itoa(srv.lines-1), task.curLoc)
+	srv.mu.Lock()
+	require.Len(t, srv.received, srv.lines)
+	for i, req := range srv.received {
+		require.Equal(t, strconv.Itoa(i), string(req.Key))
+		require.Equal(t, fmt.Sprintf("val-%d", i), string(req.Value))
+	}
+	srv.mu.Unlock()
+	require.Nil(t, task.CloseImpl(ctx))
+}
+
+func TestCvsTaskBatchWriteThroughput(t *testing.T) {
+	t.Parallel()
+
+	const (
+		writeDelay = 5 * time.Millisecond
+		window     = 500 * time.Millisecond
+	)
+	copied := func(batchSize int) int64 {
+		srv := &mockDataRWServer{lines: 100000, writeDelay: writeDelay}
+		addr, stop := newMockDataRWServer(t, srv)
+		defer stop()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		task := newCvsTaskForTest(addr, addr)
+		task.BatchSize = batchSize
+		require.Nil(t, task.InitImpl(ctx))
+		time.Sleep(window)
+		acked := srv.acked.Load()
+		require.Nil(t, task.CloseImpl(ctx))
+		return acked
+	}
+	unbatched := copied(1)
+	batched := copied(64)
+	t.Logf("records copied in %s: unbatched %d, batched %d", window, unbatched, batched)
+	// each request costs writeDelay in the downstream, so batching copies
+	// many more records in the same duration
+	require.Greater(t, unbatched, int64(0))
+	require.Greater(t, batched, 4*unbatched)
+}
@@ -0,0 +1,35 @@
+package cvstask
+
+import (
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+var downstreamLimiters = struct {
+	sync.RWMutex
+	limiters map[string]*rate.Limiter
+}{
+	limiters: make(map[string]*rate.Limiter),
+}
+
+// RegisterDownstreamLimiter makes the cvs tasks whose Config.DstHost is
+// dstHost draw a token from limiter before writing each record, so that the
+// combined write rate of these tasks is bounded by limiter. A nil limiter
+// removes the limit. The tasks look up the limiter when they start writing.
+func RegisterDownstreamLimiter(dstHost string, limiter *rate.Limiter) {
+	downstreamLimiters.Lock()
+	defer downstreamLimiters.Unlock()
+	if limiter == nil {
+		delete(downstreamLimiters.limiters, dstHost)
+		return
+	}
+	downstreamLimiters.limiters[dstHost] = limiter
+}
+
+// getDownstreamLimiter returns nil if the writes to dstHost are not limited
+func getDownstreamLimiter(dstHost string) *rate.Limiter {
+	downstreamLimiters.RLock()
+	defer downstreamLimiters.RUnlock()
+	return downstreamLimiters.limiters[dstHost]
+}
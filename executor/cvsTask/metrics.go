@@ -0,0 +1,32 @@
+package cvstask
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	libModel "github.com/hanfei1991/microcosm/lib/model"
+	"github.com/hanfei1991/microcosm/pb"
+	"github.com/hanfei1991/microcosm/pkg/promutil"
+	"github.com/hanfei1991/microcosm/pkg/tenant"
+)
+
+// taskMetrics are the metrics of a cvs task, they are labeled by the task ID
+// through the const labels of the worker Factory.
+type taskMetrics struct {
+	// copied is the number of records copied in the current run, the skipped
+	// records are counted too.
+	copied prometheus.Counter
+}
+
+func newTaskMetrics(masterID libModel.MasterID, workerID libModel.WorkerID) *taskMetrics {
+	// [TODO] use tenantID if support multi-tenant
+	factory := promutil.NewFactory4Worker(nil, tenant.ProjectInfo{TenantID: tenant.DefaultUserTenantID},
+		pb.JobType_CVSDemo.String(), masterID, workerID)
+	return &taskMetrics{
+		copied: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "cvs",
+			Subsystem: "task",
+			Name:      "copied_records_total",
+			Help:      "Total number of records copied by the cvs task",
+		}),
+	}
+}
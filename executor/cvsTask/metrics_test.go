@@ -0,0 +1,85 @@
+package cvstask
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	libModel "github.com/hanfei1991/microcosm/lib/model"
+	"github.com/hanfei1991/microcosm/pkg/promutil"
+)
+
+// scrapeTaskMetric scrapes the metric of the task from the global registry,
+// ok is false if the metric is not found.
+func scrapeTaskMetric(t *testing.T, name string, workerID libModel.WorkerID) (value float64, ok bool) {
+	rec := httptest.NewRecorder()
+	promutil.HTTPHandlerForMetric().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	label := fmt.Sprintf(`worker_id="%s"`, workerID)
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, name+"{") || !strings.Contains(line, label) {
+			continue
+		}
+		fields := strings.Fields(line)
+		value, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+		require.Nil(t, err)
+		return value, true
+	}
+	require.Nil(t, scanner.Err())
+	return 0, false
+}
+
+func TestCvsTaskMetrics(t *testing.T) {
+	t.Parallel()
+
+	const copiedMetric = "CVSDemo_cvs_task_copied_records_total"
+
+	srv := &mockDataRWServer{lines: 20}
+	addr, stop := newMockDataRWServer(t, srv)
+	defer stop()
+	RegisterDownstreamLimiter(addr, rate.NewLimiter(rate.Every(10*time.Millisecond), 1))
+	defer RegisterDownstreamLimiter(addr, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	task := newCvsTaskForTest(addr, addr)
+	require.Nil(t, task.InitImpl(ctx))
+
+	// the counter advances while the task is copying
+	var last float64
+	for i := 0; i < 2; i++ {
+		require.Eventually(t, func() bool {
+			copied, ok := scrapeTaskMetric(t, copiedMetric, task.ID())
+			if ok && copied > last {
+				last = copied
+				return true
+			}
+			return false
+		}, 5*time.Second, 5*time.Millisecond)
+	}
+	require.Less(t, last, float64(srv.lines))
+
+	require.Eventually(t, func() bool {
+		return task.getStatusCode() == libModel.WorkerStatusFinished
+	}, 5*time.Second, 10*time.Millisecond)
+	copied, ok := scrapeTaskMetric(t, copiedMetric, task.ID())
+	require.True(t, ok)
+	require.Equal(t, float64(srv.lines), copied)
+
+	// the metrics are unregistered after the task is closed
+	require.Nil(t, task.CloseImpl(ctx))
+	_, ok = scrapeTaskMetric(t, copiedMetric, task.ID())
+	require.False(t, ok)
+}
@@ -0,0 +1,180 @@
+package cvstask
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiflow/pkg/security"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	libModel "github.com/hanfei1991/microcosm/lib/model"
+	"github.com/hanfei1991/microcosm/pkg/errors"
+)
+
+const testServerName = "data-service.test"
+
+// testCerts holds the paths of a CA and the server and client certificates
+// signed by it
+type testCerts struct {
+	caPath, serverCertPath, serverKeyPath, clientCertPath, clientKeyPath string
+}
+
+func writePEM(t *testing.T, path, typ string, bytes []byte) {
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: bytes}), 0o600)
+	require.Nil(t, err)
+}
+
+func newTestCerts(t *testing.T) *testCerts {
+	dir := t.TempDir()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.Nil(t, err)
+	certs := &testCerts{caPath: filepath.Join(dir, "ca.pem")}
+	writePEM(t, certs.caPath, "CERTIFICATE", caDER)
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) (certPath, keyPath string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.Nil(t, err)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caTmpl, &key.PublicKey, caKey)
+		require.Nil(t, err)
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.Nil(t, err)
+		certPath, keyPath = filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+		writePEM(t, certPath, "CERTIFICATE", der)
+		writePEM(t, keyPath, "EC PRIVATE KEY", keyDER)
+		return
+	}
+	certs.serverCertPath, certs.serverKeyPath = issue(2, testServerName, x509.ExtKeyUsageServerAuth)
+	certs.clientCertPath, certs.clientKeyPath = issue(3, "cvs-task", x509.ExtKeyUsageClientAuth)
+	return certs
+}
+
+func TestCvsTaskTLS(t *testing.T) {
+	t.Parallel()
+
+	certs := newTestCerts(t)
+	serverCert, err := tls.LoadX509KeyPair(certs.serverCertPath, certs.serverKeyPath)
+	require.Nil(t, err)
+	caPEM, err := os.ReadFile(certs.caPath)
+	require.Nil(t, err)
+	clientCAs := x509.NewCertPool()
+	require.True(t, clientCAs.AppendCertsFromPEM(caPEM))
+	serverCreds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+
+	srv := &mockDataRWServer{lines: 20}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	addr, stop := serveMockDataRWServer(srv, lis, grpc.Creds(serverCreds))
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	task := newCvsTaskForTest(addr, addr)
+	task.Insecure = false
+	task.Security = security.Credential{
+		CAPath:   certs.caPath,
+		CertPath: certs.clientCertPath,
+		KeyPath:  certs.clientKeyPath,
+	}
+	task.ServerName = testServerName
+	require.Nil(t, task.InitImpl(ctx))
+
+	require.Eventually(t, func() bool {
+		return task.getStatusCode() == libModel.WorkerStatusFinished
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Nil(t, task.getRunError())
+	require.Equal(t, int64(srv.lines), srv.acked.Load())
+	require.Nil(t, task.CloseImpl(ctx))
+
+	// the insecure task can't copy from the TLS-enabled server
+	insecureTask := newCvsTaskForTest(addr, addr)
+	insecureTask.ConnectMaxAttempts = 1
+	require.Nil(t, insecureTask.InitImpl(ctx))
+	require.Eventually(t, func() bool {
+		return insecureTask.getStatusCode() == libModel.WorkerStatusError
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Nil(t, insecureTask.CloseImpl(ctx))
+}
+
+func TestCvsTaskCheckSecurity(t *testing.T) {
+	t.Parallel()
+
+	certs := newTestCerts(t)
+	cases := []struct {
+		security security.Credential
+		insecure bool
+		valid    bool
+	}{
+		{insecure: true, valid: true},
+		// neither TLS nor insecure is set
+		{valid: false},
+		{security: security.Credential{CAPath: certs.caPath}, valid: true},
+		{
+			security: security.Credential{
+				CAPath:   certs.caPath,
+				CertPath: certs.clientCertPath,
+				KeyPath:  certs.clientKeyPath,
+			},
+			valid: true,
+		},
+		{security: security.Credential{CAPath: certs.caPath + ".missing"}, valid: false},
+		{security: security.Credential{CAPath: certs.caPath, CertPath: certs.clientCertPath}, valid: false},
+		{
+			security: security.Credential{
+				CAPath:   certs.caPath,
+				CertPath: certs.clientCertPath,
+				KeyPath:  certs.clientKeyPath + ".missing",
+			},
+			valid: false,
+		},
+	}
+	for i, tc := range cases {
+		task := newCvsTaskForTest("127.0.0.1:1", "127.0.0.1:1")
+		task.Security = tc.security
+		task.Insecure = tc.insecure
+		err := task.InitImpl(context.Background())
+		if tc.valid {
+			require.Nil(t, err, "case %d", i)
+			require.Nil(t, task.CloseImpl(context.Background()))
+			continue
+		}
+		require.True(t, errors.ErrCvsTaskInvalidSecurity.Equal(err), "case %d: %v", i, err)
+	}
+}
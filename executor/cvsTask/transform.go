@@ -0,0 +1,31 @@
+package cvstask
+
+import "sync"
+
+// TransformFunc transforms a record read from the upstream before it is
+// written to the downstream. The record is dropped if skip is true, and the
+// task fails if err is not nil.
+type TransformFunc func(key, val []byte) (newKey, newVal []byte, skip bool, err error)
+
+var transforms = struct {
+	sync.RWMutex
+	funcs map[string]TransformFunc
+}{
+	funcs: make(map[string]TransformFunc),
+}
+
+// RegisterTransform registers a transform under name, the cvs tasks whose
+// Config.Transform is name apply it to the records they copy. A transform
+// registered with the same name before is replaced.
+func RegisterTransform(name string, fn TransformFunc) {
+	transforms.Lock()
+	defer transforms.Unlock()
+	transforms.funcs[name] = fn
+}
+
+func getTransform(name string) (TransformFunc, bool) {
+	transforms.RLock()
+	defer transforms.RUnlock()
+	fn, ok := transforms.funcs[name]
+	return fn, ok
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func httpHandler(lis net.Listener) error {
+func newHTTPServer() *http.Server {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
@@ -20,9 +20,12 @@ func httpHandler(lis net.Listener) error {
 	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 	mux.Handle("/metrics", promhttp.Handler())
 
-	httpS := &http.Server{
+	return &http.Server{
 		Handler: mux,
 	}
+}
+
+func httpHandler(httpS *http.Server, lis net.Listener) error {
 	err := httpS.Serve(lis)
 	if err != nil && !common.IsErrNetClosing(err) && err != http.ErrServerClosed {
 		log.L().Error("debug server returned", log.ShortError(err))
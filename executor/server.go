@@ -3,7 +3,9 @@ package executor
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	pcErrors "github.com/pingcap/errors"
@@ -75,6 +77,22 @@ type Server struct {
 	p2pMsgRouter    p2pImpl.MessageRouter
 	discoveryKeeper *serverutils.DiscoveryKeepaliver
 	resourceBroker  broker.Broker
+	httpSrv         *http.Server
+
+	// initialized is closed once Run has set up the components used by
+	// Shutdown, or has returned.
+	initialized     chan struct{}
+	initializedOnce sync.Once
+
+	// createdWorkers records the live workers created in this executor, so
+	// that their resources can be released on shutdown. A worker is removed
+	// when it is closed, unless the runtime is draining. No worker can be
+	// created once draining begins.
+	createdWorkers struct {
+		sync.Mutex
+		m        map[libModel.WorkerID]libModel.MasterID
+		draining bool
+	}
 }
 
 // NewServer creates a new executor server instance
@@ -83,6 +101,7 @@ func NewServer(cfg *Config, ctx *test.Context) *Server {
 		cfg:         cfg,
 		testCtx:     ctx,
 		cliUpdateCh: make(chan cliUpdateInfo),
+		initialized: make(chan struct{}),
 	}
 	return &s
 }
@@ -180,7 +199,57 @@ func (s *Server) makeTask(
 		log.L().Error("Failed to create worker", zap.Error(err))
 		return nil, err
 	}
-	return newWorker, nil
+
+	return s.trackWorker(workerID, masterID, newWorker)
+}
+
+// trackWorker records the worker in createdWorkers until it is closed, it
+// returns an error if the runtime is draining.
+func (s *Server) trackWorker(
+	workerID libModel.WorkerID,
+	masterID libModel.MasterID,
+	runnable worker.Runnable,
+) (worker.Runnable, error) {
+	s.createdWorkers.Lock()
+	defer s.createdWorkers.Unlock()
+	if s.createdWorkers.draining {
+		return nil, errors.ErrRuntimeIsClosed.GenWithStackByArgs()
+	}
+	if s.createdWorkers.m == nil {
+		s.createdWorkers.m = make(map[libModel.WorkerID]libModel.MasterID)
+	}
+	s.createdWorkers.m[workerID] = masterID
+	return &trackedWorker{
+		Runnable: runnable,
+		onClosed: func() {
+			s.createdWorkers.Lock()
+			defer s.createdWorkers.Unlock()
+			// the workers closed by draining are released by Shutdown
+			if !s.createdWorkers.draining {
+				delete(s.createdWorkers.m, workerID)
+			}
+		},
+	}, nil
+}
+
+// trackedWorker calls onClosed after the worker is closed
+type trackedWorker struct {
+	worker.Runnable
+	onClosed func()
+}
+
+// Close implements worker.Closer
+func (w *trackedWorker) Close(ctx context.Context) error {
+	defer w.onClosed()
+	return w.Runnable.Close(ctx)
+}
+
+// Workload implements worker.Workloader
+func (w *trackedWorker) Workload() model.RescUnit {
+	if workloader, ok := w.Runnable.(worker.Workloader); ok {
+		return workloader.Workload()
+	}
+	return 0
 }
 
 // PreDispatchTask implements Executor.PreDispatchTask
@@ -220,6 +289,69 @@ func (s *Server) ConfirmDispatchTask(ctx context.Context, req *pb.ConfirmDispatc
 	return &pb.ConfirmDispatchTaskResponse{}, nil
 }
 
+// Shutdown stops the executor gracefully in order, bounded by the deadline
+// of ctx. The runtime is drained first, then the resources of the workers
+// are released by the broker, and the HTTP server is shut down last, so that
+// the metrics are available until the end. All phases are executed even if
+// a former one fails, and the first error is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	var firstErr error
+	recordErr := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// the components are set up by Run concurrently
+	select {
+	case <-s.initialized:
+	case <-ctx.Done():
+		log.L().Warn("shutdown: executor is not initialized", zap.Error(ctx.Err()))
+		return pcErrors.Trace(ctx.Err())
+	}
+
+	// the workers closed by draining the runtime are kept in createdWorkers,
+	// and no worker can be created during draining, so that all of them are
+	// released afterwards
+	s.createdWorkers.Lock()
+	s.createdWorkers.draining = true
+	s.createdWorkers.Unlock()
+
+	if s.taskRunner != nil {
+		log.L().Info("shutdown: draining runtime", zap.Int64("task-count", s.taskRunner.TaskCount()))
+		s.taskRunner.BeginDrain()
+		err := s.taskRunner.WaitDrained(ctx)
+		if err != nil {
+			log.L().Warn("shutdown: failed to drain runtime", zap.Error(err))
+		}
+		recordErr(err)
+	}
+
+	s.createdWorkers.Lock()
+	workers := s.createdWorkers.m
+	s.createdWorkers.m = nil
+	s.createdWorkers.Unlock()
+
+	if s.resourceBroker != nil {
+		log.L().Info("shutdown: releasing worker resources", zap.Int("worker-count", len(workers)))
+		for workerID, masterID := range workers {
+			s.resourceBroker.OnWorkerClosed(ctx, workerID, masterID)
+		}
+	}
+
+	if s.httpSrv != nil {
+		log.L().Info("shutdown: stopping http server")
+		err := s.httpSrv.Shutdown(ctx)
+		if err != nil {
+			log.L().Warn("shutdown: failed to stop http server", zap.Error(err))
+		}
+		recordErr(err)
+	}
+
+	log.L().Info("shutdown: finished", zap.Error(firstErr))
+	return firstErr
+}
+
 // Stop stops all running goroutines and releases resources in Server
 func (s *Server) Stop() {
 	if s.grpcSrv != nil {
@@ -308,6 +440,7 @@ const (
 // Run drives server logic in independent background goroutines, and use error
 // group to collect errors.
 func (s *Server) Run(ctx context.Context) error {
+	defer s.markInitialized()
 	if test.GetGlobalTestFlag() {
 		return s.startForTest(ctx)
 	}
@@ -315,7 +448,7 @@ func (s *Server) Run(ctx context.Context) error {
 	registerMetrics()
 
 	wg, ctx := errgroup.WithContext(ctx)
-	s.taskRunner = worker.NewTaskRunner(defaultRuntimeIncomingQueueLen, defaultRuntimeInitConcurrency)
+	s.taskRunner = worker.NewTaskRunner(s.cfg.AdvertiseAddr, defaultRuntimeIncomingQueueLen, defaultRuntimeInitConcurrency)
 	s.taskCommitter = worker.NewTaskCommitter(s.taskRunner, defaultTaskPreDispatchRequestTTL)
 	defer func() {
 		s.taskCommitter.Close()
@@ -352,6 +485,7 @@ func (s *Server) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	s.markInitialized()
 
 	err = s.fetchMetaStore(ctx)
 	if err != nil {
@@ -386,6 +520,13 @@ func (s *Server) Run(ctx context.Context) error {
 	return wg.Wait()
 }
 
+// markInitialized notifies Shutdown that the components are set up
+func (s *Server) markInitialized() {
+	s.initializedOnce.Do(func() {
+		close(s.initialized)
+	})
+}
+
 // startTCPService starts grpc server and http server
 func (s *Server) startTCPService(ctx context.Context, wg *errgroup.Group) error {
 	tcpServer, err := tcpserver.NewTCPServer(s.cfg.WorkerAddr, &security.Credential{})
@@ -404,8 +545,9 @@ func (s *Server) startTCPService(ctx context.Context, wg *errgroup.Group) error
 		return s.grpcSrv.Serve(s.tcpServer.GrpcListener())
 	})
 
+	s.httpSrv = newHTTPServer()
 	wg.Go(func() error {
-		return httpHandler(s.tcpServer.HTTP1Listener())
+		return httpHandler(s.httpSrv, s.tcpServer.HTTP1Listener())
 	})
 	return nil
 }
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -19,6 +20,9 @@ import (
 	"github.com/hanfei1991/microcosm/client"
 	"github.com/hanfei1991/microcosm/executor/worker"
 	"github.com/hanfei1991/microcosm/pb"
+	derror "github.com/hanfei1991/microcosm/pkg/errors"
+	"github.com/hanfei1991/microcosm/pkg/externalresource/broker"
+	resModel "github.com/hanfei1991/microcosm/pkg/externalresource/resourcemeta/model"
 	"github.com/hanfei1991/microcosm/pkg/uuid"
 )
 
@@ -98,7 +102,7 @@ func TestCollectMetric(t *testing.T) {
 	addr := fmt.Sprintf("127.0.0.1:%d", port)
 	cfg.WorkerAddr = addr
 	s := NewServer(cfg, nil)
-	s.taskRunner = worker.NewTaskRunner(defaultRuntimeIncomingQueueLen, defaultRuntimeInitConcurrency)
+	s.taskRunner = worker.NewTaskRunner(s.cfg.AdvertiseAddr, defaultRuntimeIncomingQueueLen, defaultRuntimeInitConcurrency)
 
 	s.grpcSrv = grpc.NewServer()
 	registerMetrics()
@@ -189,3 +193,189 @@ func TestSelfRegister(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, executorID, string(s.info.ID))
 }
+
+type shutdownRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *shutdownRecorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *shutdownRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+type slowCloseTask struct {
+	id         string
+	closeDelay time.Duration
+	recorder   *shutdownRecorder
+	pollErr    error
+}
+
+func (t *slowCloseTask) Init(ctx context.Context) error { return nil }
+func (t *slowCloseTask) Poll(ctx context.Context) error { return t.pollErr }
+func (t *slowCloseTask) ID() worker.RunnableID          { return t.id }
+
+func (t *slowCloseTask) Close(ctx context.Context) error {
+	time.Sleep(t.closeDelay)
+	t.recorder.add("task:" + t.id)
+	return nil
+}
+
+type mockShutdownBroker struct {
+	broker.Broker
+	apiURL   string
+	recorder *shutdownRecorder
+}
+
+func (b *mockShutdownBroker) OnWorkerClosed(ctx context.Context, workerID resModel.WorkerID, jobID resModel.JobID) {
+	// the http server must be still available when releasing resources
+	resp, err := http.Get(b.apiURL + "/metrics")
+	if err == nil {
+		resp.Body.Close()
+		b.recorder.add("broker:" + workerID)
+	}
+}
+
+func startServerForShutdown(t *testing.T, closeDelay time.Duration) (*Server, *shutdownRecorder, string, func()) {
+	wg, ctx := errgroup.WithContext(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
+	cfg := NewConfig()
+	port, err := freeport.GetFreePort()
+	require.Nil(t, err)
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	cfg.WorkerAddr = addr
+	s := NewServer(cfg, nil)
+	s.taskRunner = worker.NewTaskRunner(s.cfg.AdvertiseAddr, defaultRuntimeIncomingQueueLen, defaultRuntimeInitConcurrency)
+	wg.Go(func() error {
+		return s.taskRunner.Run(ctx)
+	})
+
+	s.grpcSrv = grpc.NewServer()
+	registerMetrics()
+	err = s.startTCPService(ctx, wg)
+	require.Nil(t, err)
+
+	recorder := &shutdownRecorder{}
+	apiURL := fmt.Sprintf("http://%s", addr)
+	s.resourceBroker = &mockShutdownBroker{apiURL: apiURL, recorder: recorder}
+	for _, id := range []string{"worker-1", "worker-2"} {
+		task, err := s.trackWorker(id, "master-1", &slowCloseTask{id: id, closeDelay: closeDelay, recorder: recorder})
+		require.Nil(t, err)
+		err = s.taskRunner.AddTask(task)
+		require.Nil(t, err)
+	}
+	s.markInitialized()
+	require.Eventually(t, func() bool {
+		return s.taskRunner.TaskCount() == 2
+	}, time.Second, 10*time.Millisecond)
+
+	return s, recorder, apiURL, func() {
+		s.Stop()
+		cancel()
+		_ = wg.Wait()
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	s, recorder, apiURL, stop := startServerForShutdown(t, 100*time.Millisecond)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Shutdown(ctx)
+	}()
+
+	// no worker can be created once the runtime begins draining
+	require.Eventually(t, func() bool {
+		s.createdWorkers.Lock()
+		defer s.createdWorkers.Unlock()
+		return s.createdWorkers.draining
+	}, time.Second, 10*time.Millisecond)
+	_, err := s.trackWorker("worker-3", "master-1", &slowCloseTask{id: "worker-3", recorder: recorder})
+	require.True(t, derror.ErrRuntimeIsClosed.Equal(err))
+	require.Nil(t, <-errCh)
+
+	// runtime is drained before resources are released, and the http
+	// server is shut down at last
+	events := recorder.get()
+	require.Len(t, events, 4)
+	require.ElementsMatch(t, []string{"task:worker-1", "task:worker-2"}, events[:2])
+	require.ElementsMatch(t, []string{"broker:worker-1", "broker:worker-2"}, events[2:])
+	require.Equal(t, int64(0), s.taskRunner.TaskCount())
+	_, err = http.Get(apiURL + "/metrics")
+	require.Error(t, err)
+}
+
+func TestShutdownExitedWorker(t *testing.T) {
+	s, recorder, _, stop := startServerForShutdown(t, 0)
+	defer stop()
+
+	task, err := s.trackWorker("worker-3", "master-1", &slowCloseTask{
+		id:       "worker-3",
+		recorder: recorder,
+		pollErr:  errors.New("worker exited"),
+	})
+	require.Nil(t, err)
+	err = s.taskRunner.AddTask(task)
+	require.Nil(t, err)
+	require.Eventually(t, func() bool {
+		s.createdWorkers.Lock()
+		defer s.createdWorkers.Unlock()
+		_, ok := s.createdWorkers.m["worker-3"]
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = s.Shutdown(ctx)
+	require.Nil(t, err)
+
+	// the resources of the worker exited before shutdown are not released
+	// again
+	events := recorder.get()
+	require.Contains(t, events, "task:worker-3")
+	require.NotContains(t, events, "broker:worker-3")
+	require.Contains(t, events, "broker:worker-1")
+	require.Contains(t, events, "broker:worker-2")
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	s, recorder, apiURL, stop := startServerForShutdown(t, 3*time.Second)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := s.Shutdown(ctx)
+	require.Regexp(t, ".*context deadline exceeded.*", err)
+	require.Less(t, time.Since(start), time.Second)
+
+	// the following phases are executed even if the runtime is not drained
+	require.ElementsMatch(t, []string{"broker:worker-1", "broker:worker-2"}, recorder.get())
+	_, err = http.Get(apiURL + "/metrics")
+	require.Error(t, err)
+}
+
+func TestShutdownBeforeInitialized(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer(NewConfig(), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	// Shutdown waits for Run to set up the components
+	err := s.Shutdown(ctx)
+	require.Regexp(t, ".*context deadline exceeded.*", err)
+
+	s.markInitialized()
+	err = s.Shutdown(context.Background())
+	require.Nil(t, err)
+}
@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/hanfei1991/microcosm/pkg/promutil"
+)
+
+var (
+	metricFactory = promutil.NewFactory4Framework()
+
+	queuedTasksGauge = metricFactory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "executor",
+		Subsystem: "task_runner",
+		Name:      "queued_tasks",
+		Help:      "Number of tasks waiting in the incoming queue of the task runner",
+	}, []string{"executor"})
+
+	processedTasksCounter = metricFactory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "executor",
+		Subsystem: "task_runner",
+		Name:      "processed_tasks_total",
+		Help:      "Total number of tasks taken from the incoming queue of the task runner",
+	}, []string{"executor"})
+)
+
+// taskRunnerMetrics are the metrics of a TaskRunner
+type taskRunnerMetrics struct {
+	queuedTasks    prometheus.Gauge
+	processedTasks prometheus.Counter
+}
+
+func newTaskRunnerMetrics(executor string) *taskRunnerMetrics {
+	return &taskRunnerMetrics{
+		queuedTasks:    queuedTasksGauge.WithLabelValues(executor),
+		processedTasks: processedTasksCounter.WithLabelValues(executor),
+	}
+}
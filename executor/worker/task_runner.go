@@ -39,10 +39,13 @@ type TaskRunner struct {
 
 	cancelMu sync.RWMutex
 	canceled bool
+	// stopped is true after Run returns, new tasks are rejected then.
+	stopped bool
 
 	taskCount atomic.Int64
 
-	clock clock.Clock
+	metrics *taskRunnerMetrics
+	clock   clock.Clock
 }
 
 const (
@@ -72,11 +75,13 @@ func (e *taskEntry) EventLoop(ctx context.Context) error {
 	}
 }
 
-// NewTaskRunner creates a new TaskRunner instance
-func NewTaskRunner(inQueueSize int, initConcurrency int) *TaskRunner {
+// NewTaskRunner creates a new TaskRunner instance, executor is the label of
+// the metrics of the task runner, e.g. the address of the executor.
+func NewTaskRunner(executor string, inQueueSize int, initConcurrency int) *TaskRunner {
 	return &TaskRunner{
 		inQueue:       make(chan *internal.RunnableContainer, inQueueSize),
 		initQuotaSema: semaphore.NewWeighted(int64(initConcurrency)),
+		metrics:       newTaskRunnerMetrics(executor),
 		clock:         clock.New(),
 	}
 }
@@ -84,34 +89,44 @@ func NewTaskRunner(inQueueSize int, initConcurrency int) *TaskRunner {
 // AddTask enqueues a naked task, and AddTask will wrap the task with internal.WrapRunnable.
 // Deprecated. TODO Will be removed once two-phase task dispatching is enabled.
 func (r *TaskRunner) AddTask(task Runnable) error {
-	wrappedTask := internal.WrapRunnable(task, r.clock.Now())
-	select {
-	case r.inQueue <- wrappedTask:
-		return nil
-	default:
-	}
-
-	return derror.ErrRuntimeIncomingQueueFull.GenWithStackByArgs()
+	return r.addWrappedTask(internal.WrapRunnable(task, r.clock.Now()))
 }
 
 // addWrappedTask enqueues a task already wrapped by internal.WrapRunnable.
 // NOTE: internal.RunnableContainer contains the submit-time for the task.
 func (r *TaskRunner) addWrappedTask(task *internal.RunnableContainer) error {
+	r.cancelMu.RLock()
+	defer r.cancelMu.RUnlock()
+	if r.stopped {
+		return derror.ErrRuntimeIsClosed.GenWithStackByArgs()
+	}
+
+	// the gauge is increased before sending, so that it never goes below
+	// zero when Run dequeues the task first
+	r.metrics.queuedTasks.Inc()
 	select {
 	case r.inQueue <- task:
 		return nil
 	default:
+		r.metrics.queuedTasks.Dec()
 	}
 
 	return derror.ErrRuntimeIncomingQueueFull.GenWithStackByArgs()
 }
 
 // Run runs forever until context is canceled or task queue is closed.
-// It receives new added task and call onNewTask with task
+// It receives new added task and call onNewTask with task.
+// Before it returns, all running tasks are closed, and the tasks still in the
+// queue are discarded.
 func (r *TaskRunner) Run(ctx context.Context) error {
+	defer r.discardQueuedTasks()
 	defer r.cancelAll()
 
 	for {
+		// don't launch queued tasks once ctx is canceled
+		if err := ctx.Err(); err != nil {
+			return errors.Trace(err)
+		}
 		select {
 		case <-ctx.Done():
 			return errors.Trace(ctx.Err())
@@ -119,6 +134,8 @@ func (r *TaskRunner) Run(ctx context.Context) error {
 			if task == nil {
 				return derror.ErrRuntimeIsClosed.GenWithStackByArgs()
 			}
+			r.metrics.queuedTasks.Dec()
+			r.metrics.processedTasks.Inc()
 			if err := r.onNewTask(ctx, task); err != nil {
 				log.L().Warn("Failed to launch task",
 					zap.String("id", task.ID()),
@@ -148,7 +165,38 @@ func (r *TaskRunner) Workload() (ret model.RescUnit) {
 }
 
 func (r *TaskRunner) cancelAll() {
+	r.BeginDrain()
+	r.wg.Wait()
+}
+
+// discardQueuedTasks rejects the tasks added later and discards the tasks
+// that are still in the queue, they are never launched.
+func (r *TaskRunner) discardQueuedTasks() {
 	r.cancelMu.Lock()
+	defer r.cancelMu.Unlock()
+	r.stopped = true
+
+	for {
+		select {
+		case task := <-r.inQueue:
+			if task == nil {
+				return
+			}
+			r.metrics.queuedTasks.Dec()
+			task.OnStopped()
+			log.L().Warn("Task is discarded because task runner is stopped",
+				zap.String("id", task.ID()))
+		default:
+			return
+		}
+	}
+}
+
+// BeginDrain stops accepting new tasks and cancels all running tasks.
+// The tasks are closed in background, use WaitDrained to wait for them.
+func (r *TaskRunner) BeginDrain() {
+	r.cancelMu.Lock()
+	defer r.cancelMu.Unlock()
 	if r.canceled {
 		return
 	}
@@ -161,9 +209,23 @@ func (r *TaskRunner) cancelAll() {
 		log.L().Info("Cancelling task", zap.String("id", id))
 		return true
 	})
-	r.cancelMu.Unlock()
+}
 
-	r.wg.Wait()
+// WaitDrained waits until all tasks are closed after BeginDrain is called,
+// it returns an error if ctx is done before that.
+func (r *TaskRunner) WaitDrained(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return errors.Trace(ctx.Err())
+	case <-done:
+		return nil
+	}
 }
 
 func (r *TaskRunner) onNewTask(ctx context.Context, task *internal.RunnableContainer) (ret error) {
@@ -7,8 +7,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hanfei1991/microcosm/executor/worker/internal"
 	"github.com/hanfei1991/microcosm/pkg/clock"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -23,7 +25,7 @@ func TestTaskRunnerBasics(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	tr := NewTaskRunner(workerNum+1, 1)
+	tr := NewTaskRunner(t.Name(), workerNum+1, 1)
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
@@ -64,7 +66,7 @@ func TestTaskRunnerInitBlocked(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	tr := NewTaskRunner(10, 10)
+	tr := NewTaskRunner(t.Name(), 10, 10)
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
@@ -108,7 +110,7 @@ func TestTaskRunnerSubmitTime(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	tr := NewTaskRunner(10, 10)
+	tr := NewTaskRunner(t.Name(), 10, 10)
 
 	mockClock := clock.NewMock()
 	tr.clock = mockClock
@@ -140,3 +142,168 @@ func TestTaskRunnerSubmitTime(t *testing.T) {
 	cancel()
 	wg.Wait()
 }
+
+func TestTaskRunnerDrain(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tr := NewTaskRunner(t.Name(), 10, 10)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = tr.Run(ctx)
+	}()
+
+	for i := 0; i < 5; i++ {
+		err := tr.AddTask(newDummyWorker(fmt.Sprintf("worker-%d", i)))
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool {
+		return tr.TaskCount() == 5
+	}, 1*time.Second, 10*time.Millisecond)
+
+	tr.BeginDrain()
+	err := tr.WaitDrained(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), tr.TaskCount())
+
+	// no new task is launched after draining
+	err = tr.AddTask(newDummyWorker("worker-new"))
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, int64(0), tr.TaskCount())
+
+	// draining twice is fine
+	tr.BeginDrain()
+	require.NoError(t, tr.WaitDrained(ctx))
+
+	cancel()
+	wg.Wait()
+}
+
+func TestTaskRunnerWaitDrainedTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tr := NewTaskRunner(t.Name(), 10, 10)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = tr.Run(ctx)
+	}()
+
+	worker := newDummyWorker("worker-blocked")
+	worker.BlockInit()
+	require.NoError(t, tr.AddTask(worker))
+	require.Eventually(t, func() bool {
+		return tr.TaskCount() == 1
+	}, 1*time.Second, 10*time.Millisecond)
+
+	// the task blocked in Init can't be drained
+	tr.BeginDrain()
+	waitCtx, waitCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer waitCancel()
+	err := tr.WaitDrained(waitCtx)
+	require.Regexp(t, ".*context deadline exceeded.*", err)
+
+	worker.UnblockInit()
+	require.NoError(t, tr.WaitDrained(ctx))
+
+	cancel()
+	wg.Wait()
+}
+
+func TestTaskRunnerDiscardQueuedTasks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// the init quota is occupied by the blocked task, so the other tasks are
+	// stuck in the queue
+	tr := NewTaskRunner(t.Name(), 10, 1)
+	blocked := newDummyWorker("worker-blocked")
+	blocked.BlockInit()
+	require.NoError(t, tr.AddTask(blocked))
+	var queued []*internal.RunnableContainer
+	for i := 0; i < 5; i++ {
+		task := internal.WrapRunnable(newDummyWorker(fmt.Sprintf("worker-%d", i)), time.Now())
+		require.NoError(t, tr.addWrappedTask(task))
+		queued = append(queued, task)
+	}
+
+	runCtx, runCancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- tr.Run(runCtx)
+	}()
+	require.Eventually(t, func() bool {
+		return tr.TaskCount() == 1
+	}, 1*time.Second, 10*time.Millisecond)
+
+	runCancel()
+	blocked.UnblockInit()
+	select {
+	case err := <-done:
+		require.Regexp(t, ".*context canceled.*", err)
+	case <-ctx.Done():
+		require.FailNow(t, "task runner doesn't stop")
+	}
+	require.Equal(t, int64(0), tr.TaskCount())
+
+	// at most one task is taken from the queue and fails to be launched, the
+	// others are discarded
+	discarded := 0
+	for _, task := range queued {
+		if task.Status() == internal.TaskClosing {
+			discarded++
+		}
+	}
+	require.GreaterOrEqual(t, discarded, len(queued)-1)
+	require.Len(t, tr.inQueue, 0)
+
+	// the stopped task runner rejects new tasks
+	err := tr.AddTask(newDummyWorker("worker-new"))
+	require.Regexp(t, ".*ErrRuntimeIsClosed.*", err)
+}
+
+func TestTaskRunnerMetrics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tr := NewTaskRunner(t.Name(), 10, 10)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, tr.AddTask(newDummyWorker(fmt.Sprintf("worker-%d", i))))
+	}
+	require.Equal(t, float64(3), testutil.ToFloat64(tr.metrics.queuedTasks))
+	require.Equal(t, float64(0), testutil.ToFloat64(tr.metrics.processedTasks))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = tr.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(tr.metrics.queuedTasks) == 0
+	}, 1*time.Second, 10*time.Millisecond)
+	require.Equal(t, float64(3), testutil.ToFloat64(tr.metrics.processedTasks))
+
+	require.NoError(t, tr.AddTask(newDummyWorker("worker-3")))
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(tr.metrics.processedTasks) == 4
+	}, 1*time.Second, 10*time.Millisecond)
+	require.Equal(t, float64(0), testutil.ToFloat64(tr.metrics.queuedTasks))
+
+	cancel()
+	wg.Wait()
+}
+
+func TestTaskRunnerMetricsQueueFull(t *testing.T) {
+	tr := NewTaskRunner(t.Name(), 1, 1)
+	require.NoError(t, tr.AddTask(newDummyWorker("worker-1")))
+	err := tr.AddTask(newDummyWorker("worker-2"))
+	require.Regexp(t, ".*ErrRuntimeIncomingQueueFull.*", err)
+	// the rejected task is not counted as queued
+	require.Equal(t, float64(1), testutil.ToFloat64(tr.metrics.queuedTasks))
+}
@@ -9,6 +9,7 @@ import (
 	"unsafe"
 
 	"github.com/pingcap/tiflow/dm/pkg/log"
+	"github.com/pingcap/tiflow/pkg/security"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
@@ -32,6 +33,17 @@ type Config struct {
 	DstHost string `toml:"dstHost" json:"dstHost"`
 	DstDir  string `toml:"dstDir" json:"dstDir"`
 	FileNum int    `toml:"fileNum" json:"fileNum"`
+	// Transform is the name of the transform applied by the cvs tasks
+	Transform string `toml:"transform" json:"transform"`
+	// Security, ServerName and Insecure are the transport security of the
+	// connections to the data services, refer to cvsTask.Config.
+	Security   security.Credential `toml:"security" json:"security"`
+	ServerName string              `toml:"serverName" json:"serverName"`
+	Insecure   bool                `toml:"insecure" json:"insecure"`
+	// BatchSize and FlushInterval control how the cvs tasks batch the
+	// records written to the destination, refer to cvsTask.Config.
+	BatchSize     int           `toml:"batchSize" json:"batchSize"`
+	FlushInterval time.Duration `toml:"flushInterval" json:"flushInterval"`
 }
 
 // SyncFileInfo records sync file progress
@@ -278,11 +290,19 @@ func (jm *JobMaster) OnWorkerOnline(worker lib.WorkerHandle) error {
 
 func getTaskConfig(jobStatus *Status, id int) *cvsTask.Config {
 	return &cvsTask.Config{
-		SrcHost:  jobStatus.SrcHost,
-		DstHost:  jobStatus.DstHost,
-		DstDir:   jobStatus.DstDir,
-		StartLoc: jobStatus.FileInfos[id].Location,
-		Idx:      id,
+		SrcHost:   jobStatus.SrcHost,
+		DstHost:   jobStatus.DstHost,
+		DstDir:    jobStatus.DstDir,
+		StartLoc:  jobStatus.FileInfos[id].Location,
+		Idx:       id,
+		Transform: jobStatus.Transform,
+
+		Security:   jobStatus.Security,
+		ServerName: jobStatus.ServerName,
+		Insecure:   jobStatus.Insecure,
+
+		BatchSize:     jobStatus.BatchSize,
+		FlushInterval: jobStatus.FlushInterval,
 	}
 }
 
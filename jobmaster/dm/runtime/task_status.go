@@ -48,6 +48,9 @@ type DumpStatus struct {
 	FinishedBytes     float64
 	FinishedRows      float64
 	EstimateTotalRows float64
+	// Percent is the percentage of the dump progress, it is derived from the
+	// fields above.
+	Percent float64
 }
 
 // LoadStatus records necessary information of a load unit
@@ -27,6 +27,8 @@ type BaseJobMaster interface {
 	MetaKVClient() metaclient.KVClient
 	GetWorkers() map[libModel.WorkerID]WorkerHandle
 	CreateWorker(workerType WorkerType, config WorkerConfig, cost model.RescUnit, resources ...resourcemeta.ResourceID) (libModel.WorkerID, error)
+	SetMaxCreateWorkerConcurrency(limit int64)
+	CreatingWorkerCount() int64
 	JobMasterID() libModel.MasterID
 	UpdateJobStatus(ctx context.Context, status libModel.WorkerStatus) error
 	CurrentEpoch() libModel.Epoch
@@ -180,6 +182,16 @@ func (d *DefaultBaseJobMaster) CreateWorker(workerType WorkerType, config Worker
 	return d.master.CreateWorker(workerType, config, cost, resources...)
 }
 
+// SetMaxCreateWorkerConcurrency delegates the SetMaxCreateWorkerConcurrency of inner master
+func (d *DefaultBaseJobMaster) SetMaxCreateWorkerConcurrency(limit int64) {
+	d.master.SetMaxCreateWorkerConcurrency(limit)
+}
+
+// CreatingWorkerCount delegates the CreatingWorkerCount of inner master
+func (d *DefaultBaseJobMaster) CreatingWorkerCount() int64 {
+	return d.master.CreatingWorkerCount()
+}
+
 // UpdateStatus delegates the UpdateStatus of inner worker
 func (d *DefaultBaseJobMaster) UpdateStatus(ctx context.Context, status libModel.WorkerStatus) error {
 	ctx = d.errCenter.WithCancelOnFirstError(ctx)
@@ -83,7 +83,9 @@ type MasterImpl interface {
 const (
 	createWorkerWaitQuotaTimeout = 5 * time.Second
 	createWorkerTimeout          = 10 * time.Second
-	maxCreateWorkerConcurrency   = 100
+	// defaultMaxCreateWorkerConcurrency is the default limit of workers
+	// being created concurrently by one master
+	defaultMaxCreateWorkerConcurrency = 100
 )
 
 // BaseMaster defines the master interface, it embeds the Master interface and
@@ -107,6 +109,16 @@ type BaseMaster interface {
 		cost model.RescUnit,
 		resources ...resourcemeta.ResourceID,
 	) (libModel.WorkerID, error)
+
+	// SetMaxCreateWorkerConcurrency sets the limit of workers being created
+	// concurrently, excess creations in CreateWorker wait until earlier ones
+	// are dispatched. It should be called before any worker is created, a
+	// non-positive limit resets it to the default value.
+	SetMaxCreateWorkerConcurrency(limit int64)
+
+	// CreatingWorkerCount returns the number of workers being created, which
+	// never exceeds the limit of concurrent worker creation.
+	CreatingWorkerCount() int64
 }
 
 // DefaultBaseMaster implements BaseMaster interface
@@ -220,7 +232,7 @@ func NewBaseMaster(
 		nodeID:        nodeID,
 		advertiseAddr: advertiseAddr,
 
-		createWorkerQuota: quota.NewConcurrencyQuota(maxCreateWorkerConcurrency),
+		createWorkerQuota: quota.NewConcurrencyQuota(defaultMaxCreateWorkerConcurrency),
 		// [TODO] use tenantID if support muliti-tenant
 		userMetaKVClient: kvclient.NewPrefixKVClient(params.UserRawKVClient, tenant.DefaultUserTenantID),
 		deps:             ctx.Deps(),
@@ -442,11 +454,12 @@ func (m *DefaultBaseMaster) refreshMetadata(ctx context.Context) (isInit bool, e
 	}
 
 	// We should update the master data to reflect our current information
+	prevEpoch := masterMeta.Epoch
 	masterMeta.Epoch = epoch
 	masterMeta.Addr = m.advertiseAddr
 	masterMeta.NodeID = m.nodeID
 
-	if err := metaClient.Update(ctx, masterMeta); err != nil {
+	if err := metaClient.Update(ctx, masterMeta, prevEpoch); err != nil {
 		return false, 0, errors.Trace(err)
 	}
 
@@ -467,7 +480,8 @@ func (m *DefaultBaseMaster) markStatusCodeInMetadata(
 	}
 
 	masterMeta.StatusCode = code
-	return metaClient.Update(ctx, masterMeta)
+	// the update fails if the master has been taken over by a newer epoch
+	return metaClient.Update(ctx, masterMeta, m.currentEpoch.Load())
 }
 
 // prepareWorkerConfig extracts information from WorkerConfig into detail fields.
@@ -522,18 +536,20 @@ func (m *DefaultBaseMaster) CreateWorker(
 	ctx := m.errCenter.WithCancelOnFirstError(context.Background())
 	quotaCtx, cancel := context.WithTimeout(ctx, createWorkerWaitQuotaTimeout)
 	defer cancel()
-	if err := m.createWorkerQuota.Consume(quotaCtx); err != nil {
+	createWorkerQuota := m.createWorkerQuota
+	if err := createWorkerQuota.Consume(quotaCtx); err != nil {
 		return "", derror.Wrap(derror.ErrMasterConcurrencyExceeded, err)
 	}
 
 	configBytes, workerID, err := m.prepareWorkerConfig(workerType, config)
 	if err != nil {
+		createWorkerQuota.Release()
 		return "", err
 	}
 
 	go func() {
 		defer func() {
-			m.createWorkerQuota.Release()
+			createWorkerQuota.Release()
 		}()
 
 		requestCtx, cancel := context.WithTimeout(ctx, createWorkerTimeout)
@@ -590,6 +606,19 @@ func (m *DefaultBaseMaster) CreateWorker(
 	return workerID, nil
 }
 
+// SetMaxCreateWorkerConcurrency implements BaseMaster.SetMaxCreateWorkerConcurrency
+func (m *DefaultBaseMaster) SetMaxCreateWorkerConcurrency(limit int64) {
+	if limit <= 0 {
+		limit = defaultMaxCreateWorkerConcurrency
+	}
+	m.createWorkerQuota = quota.NewConcurrencyQuota(limit)
+}
+
+// CreatingWorkerCount implements BaseMaster.CreatingWorkerCount
+func (m *DefaultBaseMaster) CreatingWorkerCount() int64 {
+	return m.createWorkerQuota.Used()
+}
+
 // IsMasterReady implements BaseMaster.IsMasterReady
 func (m *DefaultBaseMaster) IsMasterReady() bool {
 	return m.workerManager.IsInitialized()
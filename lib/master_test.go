@@ -8,10 +8,12 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 
 	"github.com/hanfei1991/microcosm/lib/metadata"
 	libModel "github.com/hanfei1991/microcosm/lib/model"
 	"github.com/hanfei1991/microcosm/lib/statusutil"
+	"github.com/hanfei1991/microcosm/pb"
 	derror "github.com/hanfei1991/microcosm/pkg/errors"
 	resourcemeta "github.com/hanfei1991/microcosm/pkg/externalresource/resourcemeta/model"
 	pkgOrm "github.com/hanfei1991/microcosm/pkg/orm"
@@ -292,3 +294,60 @@ func TestPrepareWorkerConfig(t *testing.T) {
 		require.Equal(t, tc.workerID, workerID)
 	}
 }
+
+func TestMasterCreateWorkerConcurrencyLimit(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	master := NewMockMasterImpl("", masterName)
+	master.timeoutConfig.MasterHeartbeatCheckLoopInterval = time.Millisecond * 10
+	prepareMeta(ctx, t, master.GetFrameMetaClient())
+
+	master.On("InitImpl", mock.Anything).Return(nil)
+	err := master.Init(ctx)
+	require.NoError(t, err)
+
+	const (
+		limit       = 2
+		workerCount = 10
+	)
+	master.SetMaxCreateWorkerConcurrency(limit)
+
+	var maxCreating atomic.Int64
+	master.serverMasterClient.On(
+		"ScheduleTask",
+		mock.Anything,
+		mock.Anything,
+		mock.Anything).Return(
+		&pb.ScheduleTaskResponse{}, derror.ErrClusterResourceNotEnough.FastGenByArgs()).
+		Run(func(args mock.Arguments) {
+			creating := master.CreatingWorkerCount()
+			for {
+				old := maxCreating.Load()
+				if creating <= old || maxCreating.CAS(old, creating) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		})
+	master.On("OnWorkerDispatched", mock.Anything, mock.Anything).Return(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := master.CreateWorker(workerTypePlaceholder, &dummyConfig{param: 1}, 100)
+			require.NoError(t, err)
+			require.LessOrEqual(t, master.CreatingWorkerCount(), int64(limit))
+		}()
+	}
+	wg.Wait()
+
+	require.Eventually(t, func() bool {
+		return master.CreatingWorkerCount() == 0
+	}, time.Second*5, time.Millisecond*10)
+	require.Equal(t, int64(limit), maxCreating.Load())
+}
@@ -56,9 +56,15 @@ func (c *MasterMetadataClient) Store(ctx context.Context, data *libModel.MasterM
 	return errors.Trace(c.metaClient.UpsertJob(ctx, data))
 }
 
-// Update update the data
-func (c *MasterMetadataClient) Update(ctx context.Context, data *libModel.MasterMetaKVData) error {
-	return errors.Trace(c.metaClient.UpdateJob(ctx, data))
+// Update update the data if its stored epoch is still expectedEpoch. The data
+// created by Load for a master without metadata is not stored, so it is skipped.
+func (c *MasterMetadataClient) Update(
+	ctx context.Context, data *libModel.MasterMetaKVData, expectedEpoch libModel.Epoch,
+) error {
+	if data.SeqID == 0 {
+		return nil
+	}
+	return errors.Trace(c.metaClient.UpdateJob(ctx, data, expectedEpoch))
 }
 
 // Delete deletes the metadata of this master
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	libModel "github.com/hanfei1991/microcosm/lib/model"
+	derror "github.com/hanfei1991/microcosm/pkg/errors"
 	pkgOrm "github.com/hanfei1991/microcosm/pkg/orm"
 )
 
@@ -105,6 +106,16 @@ func TestOperateMasterMetadata(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, addr2, loadMeta().Addr)
 
+	// update master meta with the expected epoch, a stale epoch is refused
+	cli := NewMasterMetadataClient(meta.ID, metaClient)
+	stored := loadMeta()
+	stored.Epoch = 1
+	err = cli.Update(ctx, stored, 0)
+	require.NoError(t, err)
+	require.Equal(t, libModel.Epoch(1), loadMeta().Epoch)
+	err = cli.Update(ctx, stored, 0)
+	require.True(t, derror.ErrMetaConcurrentModification.Equal(err))
+
 	err = DeleteMasterMeta(ctx, metaClient, meta.ID)
 	require.NoError(t, err)
 	// meta is not found in metastore, load meta will return a new master meta
@@ -4,6 +4,7 @@ package lib
 // can finish its unit tests.
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -90,3 +91,16 @@ func MockBaseWorkerWaitUpdateStatus(
 		return ok
 	}, time.Second, 100*time.Millisecond)
 }
+
+// MockBaseWorkerPersistStatus persists the status of the worker to the mock
+// metastore, as if it is persisted by the previous run of the worker.
+func MockBaseWorkerPersistStatus(
+	t *testing.T,
+	worker *DefaultBaseWorker,
+	status libModel.WorkerStatus,
+) {
+	status.JobID = worker.masterID
+	status.ID = worker.id
+	err := worker.frameMetaClient.UpsertWorker(context.Background(), &status)
+	require.NoError(t, err)
+}
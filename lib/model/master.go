@@ -83,3 +83,39 @@ const (
 	MasterStatusFinished
 	MasterStatusStopped
 )
+
+// Each job master status is either active or terminal, the metastore queries
+// filtering jobs by state must use the following sets.
+var (
+	// ActiveMasterStatuses are the statuses of the jobs which are running or
+	// going to run
+	ActiveMasterStatuses = []MasterStatusCode{
+		MasterStatusUninit,
+		MasterStatusInit,
+	}
+	// TerminalMasterStatuses are the statuses that a job never leaves once reached
+	TerminalMasterStatuses = []MasterStatusCode{
+		MasterStatusFinished,
+		MasterStatusStopped,
+	}
+)
+
+// IsActive returns whether the status is one of ActiveMasterStatuses
+func (c MasterStatusCode) IsActive() bool {
+	for _, code := range ActiveMasterStatuses {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal returns whether the status is one of TerminalMasterStatuses
+func (c MasterStatusCode) IsTerminal() bool {
+	for _, code := range TerminalMasterStatuses {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,10 @@
 package model
 
 import (
+	"database/sql/driver"
 	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/pingcap/errors"
 
@@ -23,6 +26,9 @@ const (
 	WorkerStatusError
 	WorkerStatusFinished
 	WorkerStatusStopped
+	// WorkerStatusPaused means the worker is paused by its master, and it
+	// continues after being resumed.
+	WorkerStatusPaused
 )
 
 // WorkerUpdateColumns is used in gorm update.
@@ -37,6 +43,61 @@ var WorkerUpdateColumns = []string{
 	"status",
 	"errmsg",
 	"ext_bytes",
+	"progress",
+}
+
+// WorkerProgress is the progress of a worker. Unlike ExtBytes, it is in the
+// same form for all worker types, so it can be understood by the master
+// without knowing the business logic.
+type WorkerProgress struct {
+	// Completed is the amount of finished work in the current phase
+	Completed int64 `json:"completed"`
+	// Total is the total amount of work in the current phase, 0 means the
+	// total is unknown
+	Total int64 `json:"total"`
+	// Phase is the name of the current phase, it is defined by the worker
+	Phase string `json:"phase,omitempty"`
+}
+
+// Ratio returns the ratio of the completed work, which is in [0, 1]. It returns
+// false if the total is unknown.
+func (p *WorkerProgress) Ratio() (float64, bool) {
+	if p.Total <= 0 {
+		return 0, false
+	}
+	if p.Completed >= p.Total {
+		return 1, true
+	}
+	if p.Completed <= 0 {
+		return 0, true
+	}
+	return float64(p.Completed) / float64(p.Total), true
+}
+
+// Scan implements sql.Scanner
+func (p *WorkerProgress) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*p = WorkerProgress{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported worker progress type %T", value)
+	}
+	if len(data) == 0 {
+		*p = WorkerProgress{}
+		return nil
+	}
+	return json.Unmarshal(data, p)
+}
+
+// Value implements driver.Valuer
+func (p WorkerProgress) Value() (driver.Value, error) {
+	return json.Marshal(p)
 }
 
 // WorkerStatus records worker information, including master id, worker id,
@@ -56,6 +117,16 @@ type WorkerStatus struct {
 	// business logic only.
 	// Business logic can parse the raw bytes and decode into business Go object
 	ExtBytes []byte `json:"ext-bytes" gorm:"column:ext_bytes;type:blob"`
+
+	// Progress is the optional progress of the worker, it is populated by the
+	// business logic of any worker type.
+	Progress *WorkerProgress `json:"progress,omitempty" gorm:"column:progress;type:blob"`
+
+	// Owner and LeaseExpiry record the claim of the worker, the worker is
+	// owned by Owner until LeaseExpiry. They are only changed by the claim
+	// operations of the metastore, not by upserting or updating the worker.
+	Owner       string     `json:"owner,omitempty" gorm:"column:owner;type:varchar(64) not null default ''"`
+	LeaseExpiry *time.Time `json:"lease-expiry,omitempty" gorm:"column:lease_expiry"`
 }
 
 // HasSignificantChange indicates whether `s` has significant changes worth persisting.
@@ -97,5 +168,6 @@ func (s *WorkerStatus) Map() map[string]interface{} {
 		"status":     s.Code,
 		"errmsg":     s.ErrorMessage,
 		"ext_bytes":  s.ExtBytes,
+		"progress":   s.Progress,
 	}
 }
@@ -18,6 +18,7 @@ func TestTerminateState(t *testing.T) {
 		{WorkerStatusError, true},
 		{WorkerStatusFinished, true},
 		{WorkerStatusStopped, true},
+		{WorkerStatusPaused, false},
 	}
 	s := &WorkerStatus{}
 	for _, tc := range testCases {
@@ -65,3 +66,54 @@ func TestHasSignificantChange(t *testing.T) {
 		require.Equal(t, tc.changed, changed)
 	}
 }
+
+func TestWorkerProgress(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		progress WorkerProgress
+		ratio    float64
+		known    bool
+	}{
+		{progress: WorkerProgress{Completed: 10}, ratio: 0, known: false},
+		{progress: WorkerProgress{Completed: 0, Total: 4}, ratio: 0, known: true},
+		{progress: WorkerProgress{Completed: 1, Total: 4}, ratio: 0.25, known: true},
+		{progress: WorkerProgress{Completed: 5, Total: 4}, ratio: 1, known: true},
+	}
+	for _, tc := range testCases {
+		ratio, known := tc.progress.Ratio()
+		require.Equal(t, tc.known, known)
+		require.Equal(t, tc.ratio, ratio)
+	}
+
+	// round trip through the JSON encoding of WorkerStatus
+	status := &WorkerStatus{
+		Code:     WorkerStatusNormal,
+		ExtBytes: []byte("ext"),
+		Progress: &WorkerProgress{Completed: 1, Total: 4, Phase: "dump"},
+	}
+	bytes, err := status.Marshal()
+	require.NoError(t, err)
+	decoded := &WorkerStatus{}
+	require.NoError(t, decoded.Unmarshal(bytes))
+	require.Equal(t, status.Progress, decoded.Progress)
+	require.Equal(t, status.ExtBytes, decoded.ExtBytes)
+
+	// the progress is optional
+	bytes, err = (&WorkerStatus{Code: WorkerStatusNormal}).Marshal()
+	require.NoError(t, err)
+	require.NotContains(t, string(bytes), "progress")
+	decoded = &WorkerStatus{}
+	require.NoError(t, decoded.Unmarshal(bytes))
+	require.Nil(t, decoded.Progress)
+
+	// round trip through the database column
+	value, err := status.Progress.Value()
+	require.NoError(t, err)
+	var scanned WorkerProgress
+	require.NoError(t, scanned.Scan(value))
+	require.Equal(t, *status.Progress, scanned)
+	require.NoError(t, scanned.Scan(nil))
+	require.Equal(t, WorkerProgress{}, scanned)
+	require.Error(t, scanned.Scan(1))
+}
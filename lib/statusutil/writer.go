@@ -64,7 +64,10 @@ func (w *Writer) UpdateStatus(ctx context.Context, newStatus *libModel.WorkerSta
 		}
 	}
 
-	w.lastStatus = newStatus
+	// newStatus is copied because the caller may modify it in place and
+	// pass it again, which would hide the changes from the next call.
+	lastStatus := *newStatus
+	w.lastStatus = &lastStatus
 
 	// TODO replace the timeout with a variable.
 	return w.sendStatusMessageWithRetry(ctx, 15*time.Second, newStatus)
@@ -97,6 +97,29 @@ func TestWriterUpdate(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestWriterUpdateInPlace(t *testing.T) {
+	suite := newWriterTestSuite(t, "master-1", "executor-1", 1, "worker-1")
+	ctx := context.Background()
+
+	st := &libModel.WorkerStatus{
+		JobID: "master-1",
+		ID:    "worker-1",
+		Code:  libModel.WorkerStatusInit,
+	}
+	err := suite.cli.UpsertWorker(ctx, st)
+	require.NoError(t, err)
+	err = suite.writer.UpdateStatus(ctx, st)
+	require.NoError(t, err)
+
+	// the changes of the status modified in place are persisted too
+	st.Code = libModel.WorkerStatusFinished
+	err = suite.writer.UpdateStatus(ctx, st)
+	require.NoError(t, err)
+	status, err := suite.cli.GetWorkerByID(ctx, st.JobID, st.ID)
+	require.NoError(t, err)
+	require.Equal(t, libModel.WorkerStatusFinished, status.Code)
+}
+
 func TestWriterSendRetry(t *testing.T) {
 	suite := newWriterTestSuite(t, "master-1", "executor-1", 1, "worker-1")
 	ctx := context.Background()
@@ -74,6 +74,11 @@ type BaseWorker interface {
 
 	MetaKVClient() metaclient.KVClient
 	UpdateStatus(ctx context.Context, status libModel.WorkerStatus) error
+	// LoadStatus returns the status of the worker persisted in the metastore,
+	// or nil if it has never been persisted. Called in InitImpl, it returns
+	// the status persisted by the previous run of the worker with the same id,
+	// which can be used to resume the worker.
+	LoadStatus(ctx context.Context) (*libModel.WorkerStatus, error)
 	SendMessage(ctx context.Context, topic p2p.Topic, message interface{}) (bool, error)
 	OpenStorage(ctx context.Context, resourcePath resourcemeta.ResourceID) (broker.Handle, error)
 	// Exit should be called when worker (in user logic) wants to exit.
@@ -365,6 +370,7 @@ func (w *DefaultBaseWorker) UpdateStatus(ctx context.Context, status libModel.Wo
 	w.workerStatus.Code = status.Code
 	w.workerStatus.ErrorMessage = status.ErrorMessage
 	w.workerStatus.ExtBytes = status.ExtBytes
+	w.workerStatus.Progress = status.Progress
 	err := w.statusSender.UpdateStatus(ctx, w.workerStatus)
 	if err != nil {
 		return errors.Trace(err)
@@ -372,6 +378,18 @@ func (w *DefaultBaseWorker) UpdateStatus(ctx context.Context, status libModel.Wo
 	return nil
 }
 
+// LoadStatus implements BaseWorker.LoadStatus
+func (w *DefaultBaseWorker) LoadStatus(ctx context.Context) (*libModel.WorkerStatus, error) {
+	status, err := w.frameMetaClient.GetWorkerByID(ctx, w.masterID, w.id)
+	if err != nil {
+		if pkgOrm.IsNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, errors.Trace(err)
+	}
+	return status, nil
+}
+
 // SendMessage implements BaseWorker.SendMessage
 func (w *DefaultBaseWorker) SendMessage(
 	ctx context.Context,
@@ -397,6 +415,7 @@ func (w *DefaultBaseWorker) Exit(ctx context.Context, status libModel.WorkerStat
 	w.workerStatus.Code = status.Code
 	w.workerStatus.ErrorMessage = status.ErrorMessage
 	w.workerStatus.ExtBytes = status.ExtBytes
+	w.workerStatus.Progress = status.Progress
 	if err1 := w.statusSender.UpdateStatus(ctx, w.workerStatus); err1 != nil {
 		return err1
 	}
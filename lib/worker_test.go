@@ -24,13 +24,14 @@ var (
 
 func putMasterMeta(ctx context.Context, t *testing.T, metaclient pkgOrm.Client, metaData *libModel.MasterMetaKVData) {
 	// FIXME: current backend mock db is not support unique index
-	if _, err := metaclient.GetJobByID(ctx, metaData.ID); err != nil {
+	stored, err := metaclient.GetJobByID(ctx, metaData.ID)
+	if err != nil {
 		err := metaclient.UpsertJob(ctx, metaData)
 		require.NoError(t, err)
 		return
 	}
 
-	err := metaclient.UpdateJob(ctx, metaData)
+	err = metaclient.UpdateJob(ctx, metaData, stored.Epoch)
 	require.NoError(t, err)
 }
 
@@ -81,7 +82,11 @@ func TestWorkerInitAndClose(t *testing.T) {
 		return hbMsg.FromWorkerID == workerID1 && hbMsg.Epoch == 1
 	}, "unexpected heartbeat %v", hbMsg)
 
-	err = worker.UpdateStatus(ctx, libModel.WorkerStatus{Code: libModel.WorkerStatusNormal})
+	progress := &libModel.WorkerProgress{Completed: 10, Total: 100, Phase: "test"}
+	err = worker.UpdateStatus(ctx, libModel.WorkerStatus{
+		Code:     libModel.WorkerStatusNormal,
+		Progress: progress,
+	})
 	require.NoError(t, err)
 
 	var statusMsg *statusutil.WorkerStatusMessage
@@ -97,7 +102,7 @@ func TestWorkerInitAndClose(t *testing.T) {
 	checkWorkerStatusMsg(t, &statusutil.WorkerStatusMessage{
 		Worker:      workerID1,
 		MasterEpoch: 1,
-		Status:      &libModel.WorkerStatus{Code: libModel.WorkerStatusNormal},
+		Status:      &libModel.WorkerStatus{Code: libModel.WorkerStatusNormal, Progress: progress},
 	}, statusMsg)
 
 	worker.On("CloseImpl").Return(nil)
@@ -251,7 +256,12 @@ func TestWorkerStatus(t *testing.T) {
 	worker.On("Tick", mock.Anything).Return(nil)
 	worker.On("CloseImpl", mock.Anything).Return(nil)
 
-	err := worker.Init(ctx)
+	// the status has never been persisted
+	loaded, err := worker.LoadStatus(ctx)
+	require.NoError(t, err)
+	require.Nil(t, loaded)
+
+	err = worker.Init(ctx)
 	require.NoError(t, err)
 
 	rawStatus, ok := worker.messageSender.TryPop(masterNodeName, statusutil.WorkerStatusTopic(masterName))
@@ -283,6 +293,11 @@ func TestWorkerStatus(t *testing.T) {
 		},
 	}, msg)
 
+	loaded, err = worker.LoadStatus(ctx)
+	require.NoError(t, err)
+	require.Equal(t, libModel.WorkerStatusNormal, loaded.Code)
+	require.Equal(t, fastMarshalDummyStatus(t, 6), loaded.ExtBytes)
+
 	err = worker.Close(ctx)
 	require.NoError(t, err)
 }
@@ -501,4 +516,5 @@ func checkWorkerStatusMsg(t *testing.T, expect, msg *statusutil.WorkerStatusMess
 	require.Equal(t, expect.Status.Code, expect.Status.Code)
 	require.Equal(t, expect.Status.ErrorMessage, expect.Status.ErrorMessage)
 	require.Equal(t, expect.Status.ExtBytes, expect.Status.ExtBytes)
+	require.Equal(t, expect.Status.Progress, msg.Status.Progress)
 }
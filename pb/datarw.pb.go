@@ -504,6 +504,9 @@ type WriteLinesRequest struct {
 	FileIdx int32  `protobuf:"varint,2,opt,name=file_idx,json=fileIdx,proto3" json:"file_idx,omitempty"`
 	Key     []byte `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
 	Value   []byte `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	// pairs carries a batch of records, it is used instead of key and value
+	// if it is not empty.
+	Pairs []*KeyValue `protobuf:"bytes,5,rep,name=pairs,proto3" json:"pairs,omitempty"`
 }
 
 func (m *WriteLinesRequest) Reset()         { *m = WriteLinesRequest{} }
@@ -567,6 +570,13 @@ func (m *WriteLinesRequest) GetValue() []byte {
 	return nil
 }
 
+func (m *WriteLinesRequest) GetPairs() []*KeyValue {
+	if m != nil {
+		return m.Pairs
+	}
+	return nil
+}
+
 type WriteLinesResponse struct {
 	ErrMsg string `protobuf:"bytes,1,opt,name=err_msg,json=errMsg,proto3" json:"err_msg,omitempty"`
 }
@@ -611,6 +621,58 @@ func (m *WriteLinesResponse) GetErrMsg() string {
 	return ""
 }
 
+type KeyValue struct {
+	Key   []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *KeyValue) Reset()         { *m = KeyValue{} }
+func (m *KeyValue) String() string { return proto.CompactTextString(m) }
+func (*KeyValue) ProtoMessage()    {}
+func (*KeyValue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_03dd23a8ba2c07e2, []int{12}
+}
+func (m *KeyValue) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *KeyValue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_KeyValue.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *KeyValue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyValue.Merge(m, src)
+}
+func (m *KeyValue) XXX_Size() int {
+	return m.Size()
+}
+func (m *KeyValue) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyValue.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyValue proto.InternalMessageInfo
+
+func (m *KeyValue) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *KeyValue) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*GenerateDataRequest)(nil), "pb.GenerateDataRequest")
 	proto.RegisterType((*GenerateDataResponse)(nil), "pb.GenerateDataResponse")
@@ -624,46 +686,49 @@ func init() {
 	proto.RegisterType((*ReadLinesResponse)(nil), "pb.ReadLinesResponse")
 	proto.RegisterType((*WriteLinesRequest)(nil), "pb.WriteLinesRequest")
 	proto.RegisterType((*WriteLinesResponse)(nil), "pb.WriteLinesResponse")
+	proto.RegisterType((*KeyValue)(nil), "pb.KeyValue")
 }
 
 func init() { proto.RegisterFile("datarw.proto", fileDescriptor_03dd23a8ba2c07e2) }
 
 var fileDescriptor_03dd23a8ba2c07e2 = []byte{
-	// 532 bytes of a gzipped FileDescriptorProto
+	// 567 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x54, 0xcd, 0x6e, 0xd3, 0x40,
-	0x10, 0x8e, 0x13, 0xf2, 0x37, 0x32, 0xad, 0xb3, 0x35, 0xad, 0xb1, 0x84, 0x55, 0x2d, 0x07, 0x7a,
-	0x69, 0x40, 0x05, 0x6e, 0x48, 0x08, 0x08, 0xa0, 0x48, 0x6d, 0x90, 0xcc, 0xa1, 0xc7, 0xca, 0x89,
-	0xa7, 0xed, 0xaa, 0x8e, 0x9d, 0xae, 0xed, 0x10, 0xde, 0x82, 0xc7, 0xe2, 0xd8, 0x23, 0x47, 0x94,
-	0xbc, 0x01, 0x4f, 0x80, 0x76, 0xbd, 0x71, 0x6c, 0xa7, 0x52, 0x6f, 0x9e, 0xd9, 0x6f, 0xe6, 0x9b,
-	0x6f, 0xf6, 0x5b, 0x83, 0xee, 0x7b, 0x89, 0xc7, 0x7f, 0xf4, 0x67, 0x3c, 0x4a, 0x22, 0x52, 0x9f,
-	0x8d, 0xe9, 0x37, 0xd8, 0xfb, 0x8a, 0x21, 0x72, 0x2f, 0xc1, 0x81, 0x97, 0x78, 0x2e, 0xde, 0xa6,
-	0x18, 0x27, 0xe4, 0x29, 0x74, 0x2e, 0x59, 0x80, 0x17, 0x61, 0x3a, 0xb5, 0xb4, 0x43, 0xed, 0xa8,
-	0xe9, 0xb6, 0x45, 0x3c, 0x4a, 0xa7, 0xe4, 0x19, 0x00, 0xc7, 0x49, 0xc4, 0x7d, 0x79, 0x58, 0x97,
-	0x87, 0xdd, 0x2c, 0x33, 0x4a, 0xa7, 0xf4, 0x25, 0x98, 0xe5, 0x86, 0xf1, 0x2c, 0x0a, 0x63, 0x24,
-	0x07, 0xd0, 0x46, 0xce, 0x2f, 0xa6, 0xf1, 0x95, 0x6c, 0xd8, 0x75, 0x5b, 0xc8, 0xf9, 0x59, 0x7c,
-	0x45, 0x9f, 0xc3, 0xee, 0xa7, 0x6b, 0x9c, 0xdc, 0x0c, 0x18, 0x5f, 0xb3, 0x1b, 0xd0, 0xf0, 0x19,
-	0x57, 0x38, 0xf1, 0x49, 0xcf, 0xc0, 0xd8, 0x80, 0x1e, 0xe8, 0x48, 0x0e, 0x41, 0x17, 0x07, 0x52,
-	0x00, 0xf3, 0x17, 0x6a, 0x46, 0x40, 0xce, 0xbf, 0xb0, 0x00, 0x87, 0xfe, 0x82, 0x1a, 0xb0, 0x33,
-	0x8c, 0x5d, 0xf4, 0xfc, 0x9f, 0x8a, 0x92, 0xbe, 0x80, 0xdd, 0x3c, 0xa3, 0xfa, 0x9b, 0xd0, 0xe4,
-	0x22, 0x21, 0xbb, 0x77, 0xdc, 0x2c, 0xa0, 0x3b, 0xa0, 0x9f, 0xb2, 0x38, 0x11, 0x9d, 0x62, 0x17,
-	0x6f, 0xe9, 0x31, 0xf4, 0x0a, 0xb1, 0x2a, 0xb5, 0x60, 0xbd, 0xae, 0xca, 0xf6, 0xe8, 0x00, 0x0c,
-	0xc1, 0x72, 0xca, 0x42, 0x59, 0x2e, 0xe5, 0x2a, 0xf4, 0xd0, 0x5f, 0x14, 0xd1, 0x43, 0x7f, 0x41,
-	0xf6, 0xa1, 0x15, 0xb0, 0x10, 0x47, 0x91, 0xd4, 0xa0, 0xbb, 0x2a, 0xa2, 0x08, 0xbd, 0x42, 0x17,
-	0x45, 0x6a, 0x40, 0xe3, 0x06, 0xb3, 0x69, 0x75, 0x57, 0x7c, 0x8a, 0xcc, 0xdc, 0x0b, 0x54, 0xad,
-	0xf8, 0x14, 0x9a, 0x58, 0xfc, 0x39, 0xba, 0xb4, 0x1a, 0x99, 0x26, 0x19, 0x08, 0x9a, 0x6c, 0x75,
-	0xd6, 0xa3, 0xd2, 0xd5, 0x5c, 0x43, 0xef, 0x9c, 0xb3, 0x04, 0x4b, 0xd3, 0x6e, 0x5d, 0x4e, 0x6e,
-	0x96, 0xcd, 0xae, 0x73, 0x01, 0x6a, 0xa6, 0xc6, 0x66, 0x26, 0x13, 0x9a, 0x73, 0x2f, 0x48, 0x51,
-	0x52, 0xe9, 0x6e, 0x16, 0xd0, 0x63, 0x20, 0x45, 0xa6, 0x07, 0x6e, 0xf8, 0xe4, 0x5f, 0x1d, 0x1e,
-	0x4b, 0x77, 0x9d, 0x7f, 0x47, 0x3e, 0x67, 0x13, 0x24, 0xef, 0xa0, 0x9b, 0x6f, 0x84, 0x98, 0xfd,
-	0xd9, 0xb8, 0x5f, 0x5d, 0xb3, 0xfd, 0xa4, 0x92, 0xcd, 0x48, 0x68, 0xed, 0x95, 0x46, 0xde, 0x03,
-	0x6c, 0xe8, 0x89, 0x04, 0x6e, 0x09, 0xb7, 0xf7, 0xab, 0xe9, 0x75, 0x83, 0x23, 0x8d, 0x7c, 0x00,
-	0xbd, 0xe8, 0x7a, 0x72, 0x20, 0xb0, 0xf7, 0x3c, 0x2c, 0xdb, 0xda, 0x3e, 0x50, 0x62, 0xdf, 0x40,
-	0x37, 0x37, 0x12, 0x31, 0x04, 0xac, 0xe8, 0xb3, 0x6c, 0xfa, 0x6d, 0xa7, 0x9d, 0x40, 0x5b, 0xf9,
-	0x96, 0x10, 0x81, 0x28, 0xdb, 0xda, 0xde, 0x2b, 0xe5, 0x54, 0xcd, 0x5b, 0xe8, 0xac, 0x1f, 0x13,
-	0x91, 0x80, 0xca, 0xfb, 0xb3, 0xcd, 0x72, 0x32, 0x2b, 0xfb, 0x68, 0xfd, 0x5e, 0x3a, 0xda, 0xdd,
-	0xd2, 0xd1, 0xfe, 0x2e, 0x1d, 0xed, 0xd7, 0xca, 0xa9, 0xdd, 0xad, 0x9c, 0xda, 0x9f, 0x95, 0x53,
-	0x1b, 0xb7, 0xe4, 0xff, 0xe4, 0xf5, 0xff, 0x00, 0x00, 0x00, 0xff, 0xff, 0x42, 0x11, 0xc6, 0x92,
-	0x5f, 0x04, 0x00, 0x00,
+	0x10, 0x8e, 0x13, 0xf2, 0x37, 0x98, 0xd6, 0xd9, 0xa6, 0xad, 0xb1, 0x84, 0x15, 0x2d, 0x07, 0x72,
+	0x69, 0x40, 0x01, 0x6e, 0x48, 0x08, 0x08, 0xa0, 0x88, 0x36, 0x48, 0x46, 0xa2, 0xc7, 0xca, 0x89,
+	0xa7, 0x65, 0xd5, 0x24, 0x4e, 0xd7, 0x4e, 0x48, 0xdf, 0x81, 0x03, 0x8f, 0xc5, 0xb1, 0x47, 0x8e,
+	0x28, 0x79, 0x03, 0x9e, 0xa0, 0xda, 0xf5, 0xda, 0xb1, 0x9d, 0x4a, 0xbd, 0x79, 0x66, 0xbe, 0xf9,
+	0xe6, 0x9b, 0xd9, 0x19, 0x83, 0xee, 0xb9, 0xa1, 0xcb, 0x7f, 0x76, 0x66, 0xdc, 0x0f, 0x7d, 0x52,
+	0x9c, 0x0d, 0xe9, 0x57, 0xd8, 0xfb, 0x8c, 0x53, 0xe4, 0x6e, 0x88, 0x3d, 0x37, 0x74, 0x1d, 0xbc,
+	0x9a, 0x63, 0x10, 0x92, 0xc7, 0x50, 0x3b, 0x67, 0x63, 0x3c, 0x9b, 0xce, 0x27, 0xa6, 0xd6, 0xd2,
+	0xda, 0x65, 0xa7, 0x2a, 0xec, 0xc1, 0x7c, 0x42, 0x9e, 0x00, 0x70, 0x1c, 0xf9, 0xdc, 0x93, 0xc1,
+	0xa2, 0x0c, 0xd6, 0x23, 0xcf, 0x60, 0x3e, 0xa1, 0xcf, 0xa1, 0x99, 0x25, 0x0c, 0x66, 0xfe, 0x34,
+	0x40, 0x72, 0x08, 0x55, 0xe4, 0xfc, 0x6c, 0x12, 0x5c, 0x48, 0xc2, 0xba, 0x53, 0x41, 0xce, 0x4f,
+	0x82, 0x0b, 0xfa, 0x14, 0x76, 0x3f, 0xfc, 0xc0, 0xd1, 0x65, 0x8f, 0xf1, 0xb8, 0xba, 0x01, 0x25,
+	0x8f, 0x71, 0x85, 0x13, 0x9f, 0xf4, 0x04, 0x8c, 0x0d, 0xe8, 0x1e, 0x46, 0xd2, 0x02, 0x5d, 0x04,
+	0x64, 0x03, 0xcc, 0x5b, 0x2a, 0x8d, 0x80, 0x9c, 0x7f, 0x62, 0x63, 0xec, 0x7b, 0x4b, 0x6a, 0xc0,
+	0x4e, 0x3f, 0x70, 0xd0, 0xf5, 0xae, 0x55, 0x49, 0xfa, 0x0c, 0x76, 0x13, 0x8f, 0xe2, 0x6f, 0x42,
+	0x99, 0x0b, 0x87, 0x64, 0xaf, 0x39, 0x91, 0x41, 0x77, 0x40, 0x3f, 0x66, 0x41, 0x28, 0x98, 0x02,
+	0x07, 0xaf, 0xe8, 0x11, 0x34, 0x52, 0xb6, 0x4a, 0x35, 0x21, 0x1e, 0x57, 0x6e, 0x7a, 0xb4, 0x07,
+	0x86, 0xa8, 0x72, 0xcc, 0xa6, 0x18, 0xa8, 0xda, 0x31, 0xba, 0xef, 0x2d, 0xd3, 0xe8, 0xbe, 0xb7,
+	0x24, 0x07, 0x50, 0x19, 0xb3, 0x29, 0x0e, 0x7c, 0xd9, 0x83, 0xee, 0x28, 0x8b, 0x22, 0x34, 0x52,
+	0x2c, 0xaa, 0xa8, 0x01, 0xa5, 0x4b, 0x8c, 0xd4, 0xea, 0x8e, 0xf8, 0x14, 0x9e, 0x85, 0x3b, 0x56,
+	0xb9, 0xe2, 0x53, 0xf4, 0xc4, 0x82, 0x8f, 0xfe, 0xb9, 0x59, 0x8a, 0x7a, 0x92, 0x86, 0x28, 0x13,
+	0x8d, 0xce, 0x7c, 0x90, 0x79, 0x9a, 0x5f, 0x1a, 0x34, 0x4e, 0x39, 0x0b, 0x31, 0x23, 0x77, 0xeb,
+	0x75, 0x92, 0x6d, 0xd9, 0x0c, 0x3b, 0xe9, 0x40, 0x89, 0x2a, 0x6d, 0x44, 0x35, 0xa1, 0xbc, 0x70,
+	0xc7, 0x73, 0x94, 0xb5, 0x74, 0x27, 0x32, 0x08, 0x85, 0xf2, 0xcc, 0x65, 0x3c, 0x30, 0xcb, 0xad,
+	0x52, 0xfb, 0x61, 0x57, 0xef, 0xcc, 0x86, 0x9d, 0x2f, 0x78, 0xfd, 0x5d, 0x04, 0x9d, 0x28, 0x44,
+	0x8f, 0x80, 0xa4, 0xd5, 0xdc, 0xb7, 0x58, 0x5d, 0xa8, 0xc5, 0x0c, 0x77, 0xcc, 0x26, 0x91, 0x51,
+	0x4c, 0xc9, 0xe8, 0xfe, 0x2f, 0xc2, 0x23, 0xb9, 0xb6, 0xa7, 0xdf, 0x90, 0x2f, 0xd8, 0x08, 0xc9,
+	0x1b, 0xa8, 0x27, 0xa3, 0x26, 0x4d, 0x21, 0x2b, 0xff, 0x7e, 0xd6, 0x7e, 0xce, 0x1b, 0x09, 0xa3,
+	0x85, 0x17, 0x1a, 0x79, 0x0b, 0xb0, 0x91, 0x4c, 0x24, 0x70, 0x6b, 0xa0, 0xd6, 0x41, 0xde, 0x1d,
+	0x13, 0xb4, 0x35, 0xf2, 0x0e, 0xf4, 0xf4, 0x39, 0x91, 0x43, 0x81, 0xbd, 0xe3, 0x62, 0x2d, 0x73,
+	0x3b, 0xa0, 0x06, 0xf4, 0x0a, 0xea, 0xc9, 0x86, 0x12, 0x43, 0xc0, 0xd2, 0x0b, 0x6c, 0xed, 0xe7,
+	0x3c, 0x2a, 0xab, 0x0b, 0x55, 0x75, 0x10, 0x84, 0x08, 0x44, 0xf6, 0x5e, 0xac, 0xbd, 0x8c, 0x4f,
+	0xe5, 0xbc, 0x86, 0x5a, 0x7c, 0xa5, 0x44, 0x02, 0x72, 0x87, 0x6d, 0x35, 0xb3, 0xce, 0x28, 0xed,
+	0xbd, 0xf9, 0x67, 0x65, 0x6b, 0x37, 0x2b, 0x5b, 0xfb, 0xb7, 0xb2, 0xb5, 0xdf, 0x6b, 0xbb, 0x70,
+	0xb3, 0xb6, 0x0b, 0x7f, 0xd7, 0x76, 0x61, 0x58, 0x91, 0x3f, 0xaa, 0x97, 0xb7, 0x03, 0x00, 0x41,
+	0xe2, 0x5e, 0xc0, 0xb8, 0x04, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1332,6 +1397,20 @@ func (m *WriteLinesRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Pairs) > 0 {
+		for iNdEx := len(m.Pairs) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Pairs[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintDatarw(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x2a
+		}
+	}
 	if len(m.Value) > 0 {
 		i -= len(m.Value)
 		copy(dAtA[i:], m.Value)
@@ -1391,6 +1470,43 @@ func (m *WriteLinesResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *KeyValue) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *KeyValue) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *KeyValue) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Value) > 0 {
+		i -= len(m.Value)
+		copy(dAtA[i:], m.Value)
+		i = encodeVarintDatarw(dAtA, i, uint64(len(m.Value)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Key) > 0 {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintDatarw(dAtA, i, uint64(len(m.Key)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
 func encodeVarintDatarw(dAtA []byte, offset int, v uint64) int {
 	offset -= sovDatarw(v)
 	base := offset
@@ -1562,6 +1678,12 @@ func (m *WriteLinesRequest) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovDatarw(uint64(l))
 	}
+	if len(m.Pairs) > 0 {
+		for _, e := range m.Pairs {
+			l = e.Size()
+			n += 1 + l + sovDatarw(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -1578,6 +1700,23 @@ func (m *WriteLinesResponse) Size() (n int) {
 	return n
 }
 
+func (m *KeyValue) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovDatarw(uint64(l))
+	}
+	l = len(m.Value)
+	if l > 0 {
+		n += 1 + l + sovDatarw(uint64(l))
+	}
+	return n
+}
+
 func sovDatarw(x uint64) (n int) {
 	return (math_bits.Len64(x|1) + 6) / 7
 }
@@ -2597,6 +2736,40 @@ func (m *WriteLinesRequest) Unmarshal(dAtA []byte) error {
 				m.Value = []byte{}
 			}
 			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Pairs", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDatarw
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthDatarw
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthDatarw
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Pairs = append(m.Pairs, &KeyValue{})
+			if err := m.Pairs[len(m.Pairs)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipDatarw(dAtA[iNdEx:])
@@ -2700,6 +2873,124 @@ func (m *WriteLinesResponse) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *KeyValue) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowDatarw
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: KeyValue: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: KeyValue: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDatarw
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthDatarw
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthDatarw
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
+			if m.Key == nil {
+				m.Key = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDatarw
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthDatarw
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthDatarw
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
+			if m.Value == nil {
+				m.Value = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipDatarw(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthDatarw
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func skipDatarw(dAtA []byte) (n int, err error) {
 	l := len(dAtA)
 	iNdEx := 0
@@ -24,6 +24,9 @@ var (
 
 	// TODO: discuss the key prefix
 	DMJobKeyAdapter KeyAdapter = keyHexEncoderDecoder("/data-flow/dm/job/")
+	// DMDumpCheckpointKeyAdapter is the key of the dump checkpoint of a
+	// subtask, encoded from task name and source id
+	DMDumpCheckpointKeyAdapter KeyAdapter = keyHexEncoderDecoder("/data-flow/dm/checkpoint/dump/")
 )
 
 // KeyAdapter is used to construct etcd like key
@@ -60,6 +60,8 @@ var (
 	ErrInvalidMasterMessage           = errors.Normalize("invalid master message: %s", errors.RFCCodeText("DFLOW:ErrInvalidMasterMessage"))
 	ErrSendingMessageToTombstone      = errors.Normalize("trying to send message to a tombstone worker handle: %s", errors.RFCCodeText("DFLOW:ErrSendingMessageToTombstone"))
 	ErrMasterNotInitialized           = errors.Normalize("master is not initialized", errors.RFCCodeText("DFLOW:ErrMasterNotInitialized"))
+	ErrJobDispatchExhausted           = errors.Normalize("job %s failed to be dispatched after %d attempts, last error: %s", errors.RFCCodeText("DFLOW:ErrJobDispatchExhausted"))
+	ErrJobWaitAckTimeout              = errors.Normalize("job %s is not acked by its job master in %s", errors.RFCCodeText("DFLOW:ErrJobWaitAckTimeout"))
 
 	ErrWorkerTypeNotFound         = errors.Normalize("worker type is not found: type %d", errors.RFCCodeText("DFLOW:ErrWorkerTypeNotFound"))
 	ErrWorkerNotFound             = errors.Normalize("worker is not found: worker ID %s", errors.RFCCodeText("DFLOW:ErrWorkerNotFound"))
@@ -109,19 +111,23 @@ var (
 	ErrPlannerDAGDepthExceeded = errors.Normalize("dag depth exceeded: %d", errors.RFCCodeText("DFLOW:ErrPlannerDAGDepthExceeded"))
 
 	// meta related errors
-	ErrMetaNewClientFail      = errors.Normalize("create meta client fail", errors.RFCCodeText("DFLOW:ErrMetaNewClientFail"))
-	ErrMetaOpFail             = errors.Normalize("meta operation fail", errors.RFCCodeText("DFLOW:ErrMetaOpFail"))
-	ErrMetaOptionInvalid      = errors.Normalize("meta option invalid", errors.RFCCodeText("DFLOW:ErrMetaOptionInvalid"))
-	ErrMetaOptionConflict     = errors.Normalize("WithRange/WithPrefix/WithFromKey, more than one option are used", errors.RFCCodeText("DFLOW:ErrMetaOptionConflict"))
-	ErrMetaEmptyKey           = errors.Normalize("meta empty key", errors.RFCCodeText("DFLOW:ErrMetaEmptyKey"))
-	ErrMetaRevisionUnmatch    = errors.Normalize("meta revision unmatch", errors.RFCCodeText("DFLOW:ErrMetaRevisionUnmatch"))
-	ErrMetaNestedTxn          = errors.Normalize("meta unsupported nested txn", errors.RFCCodeText("DFLOW:ErrMetaNestedTxn"))
-	ErrMetaCommittedTxn       = errors.Normalize("meta already committed txn", errors.RFCCodeText("DFLOW:ErrMetaCommittedTxn"))
-	ErrMetaStoreIDDuplicate   = errors.Normalize("metastore id duplicated", errors.RFCCodeText("DFLOW:ErrMetaStoreIDDuplicate"))
-	ErrMetaStoreUnfounded     = errors.Normalize("metastore unfounded:%s", errors.RFCCodeText("DFLOW:ErrMetaStoreUnfounded"))
-	ErrMetaEntryNotFound      = errors.Normalize("meta entry not found", errors.RFCCodeText("DFLOW:ErrMetaEntryNotFound"))
-	ErrMetaParamsInvalid      = errors.Normalize("meta params invalid:%s", errors.RFCCodeText("DFLOW:ErrMetaParamsInvalid"))
-	ErrMetaEntryAlreadyExists = errors.Normalize("meta entry already exists", errors.RFCCodeText("DFLOW:ErrMetaEntryAlreadyExists"))
+	ErrMetaNewClientFail          = errors.Normalize("create meta client fail", errors.RFCCodeText("DFLOW:ErrMetaNewClientFail"))
+	ErrMetaOpFail                 = errors.Normalize("meta operation fail", errors.RFCCodeText("DFLOW:ErrMetaOpFail"))
+	ErrMetaOptionInvalid          = errors.Normalize("meta option invalid", errors.RFCCodeText("DFLOW:ErrMetaOptionInvalid"))
+	ErrMetaOptionConflict         = errors.Normalize("WithRange/WithPrefix/WithFromKey, more than one option are used", errors.RFCCodeText("DFLOW:ErrMetaOptionConflict"))
+	ErrMetaEmptyKey               = errors.Normalize("meta empty key", errors.RFCCodeText("DFLOW:ErrMetaEmptyKey"))
+	ErrMetaRevisionUnmatch        = errors.Normalize("meta revision unmatch", errors.RFCCodeText("DFLOW:ErrMetaRevisionUnmatch"))
+	ErrMetaNestedTxn              = errors.Normalize("meta unsupported nested txn", errors.RFCCodeText("DFLOW:ErrMetaNestedTxn"))
+	ErrMetaCommittedTxn           = errors.Normalize("meta already committed txn", errors.RFCCodeText("DFLOW:ErrMetaCommittedTxn"))
+	ErrMetaStoreIDDuplicate       = errors.Normalize("metastore id duplicated", errors.RFCCodeText("DFLOW:ErrMetaStoreIDDuplicate"))
+	ErrMetaStoreUnfounded         = errors.Normalize("metastore unfounded:%s", errors.RFCCodeText("DFLOW:ErrMetaStoreUnfounded"))
+	ErrMetaEntryNotFound          = errors.Normalize("meta entry not found", errors.RFCCodeText("DFLOW:ErrMetaEntryNotFound"))
+	ErrMetaParamsInvalid          = errors.Normalize("meta params invalid:%s", errors.RFCCodeText("DFLOW:ErrMetaParamsInvalid"))
+	ErrMetaEntryAlreadyExists     = errors.Normalize("meta entry already exists", errors.RFCCodeText("DFLOW:ErrMetaEntryAlreadyExists"))
+	ErrMetaValueDecodeFail        = errors.Normalize("meta value decode fail: %s", errors.RFCCodeText("DFLOW:ErrMetaValueDecodeFail"))
+	ErrClientNotInitialized       = errors.Normalize("meta client is not initialized, call Initialize first", errors.RFCCodeText("DFLOW:ErrClientNotInitialized"))
+	ErrMetaSchemaIncompatible     = errors.Normalize("metastore schema version %d is incompatible with the schema version %d of this binary", errors.RFCCodeText("DFLOW:ErrMetaSchemaIncompatible"))
+	ErrMetaConcurrentModification = errors.Normalize("meta entry %s is modified concurrently, expected epoch %d", errors.RFCCodeText("DFLOW:ErrMetaConcurrentModification"))
 
 	// DataSet errors
 	ErrDatasetEntryNotFound = errors.Normalize("dataset entry not found. Key: %s", errors.RFCCodeText("DFLOW:ErrDatasetEntryNotFound"))
@@ -136,5 +142,18 @@ var (
 	ErrCreateLocalFileDirectoryFailed = errors.Normalize("creating local file resource directory failed", errors.RFCCodeText("DFLOW:ErrCreateLocalFileDirectoryFailed"))
 	ErrCleaningLocalTempFiles         = errors.Normalize("errors is encountered when cleaning local temp files", errors.RFCCodeText("DFLOW:ErrCleaningLocalTempFiles"))
 	ErrRemovingLocalResource          = errors.Normalize("removing a local resource file directory has failed", errors.RFCCodeText("DFLOW:ErrRemovingLocalResource"))
+	ErrPersistingLocalResource        = errors.Normalize("moving a local resource file directory into place has failed", errors.RFCCodeText("DFLOW:ErrPersistingLocalResource"))
 	ErrFailToCreateExternalStorage    = errors.Normalize("failed to create external storage", errors.RFCCodeText("DFLOW:ErrFailToCreateExternalStorage"))
+
+	// cvs task related errors
+	ErrCvsTaskWriteFailed      = errors.Normalize("cvs task failed to write data to downstream: %s", errors.RFCCodeText("DFLOW:ErrCvsTaskWriteFailed"))
+	ErrCvsTaskStalled          = errors.Normalize("cvs task made no progress in %s", errors.RFCCodeText("DFLOW:ErrCvsTaskStalled"))
+	ErrCvsTaskUnknownTransform = errors.Normalize("cvs task transform %s is not registered", errors.RFCCodeText("DFLOW:ErrCvsTaskUnknownTransform"))
+	ErrCvsTaskTransformFailed  = errors.Normalize("cvs task failed to transform record %s", errors.RFCCodeText("DFLOW:ErrCvsTaskTransformFailed"))
+	ErrCvsTaskRPCTimeout       = errors.Normalize("cvs task rpc %s is not finished in %s", errors.RFCCodeText("DFLOW:ErrCvsTaskRPCTimeout"))
+	ErrCvsTaskInvalidSecurity  = errors.Normalize("cvs task security config is invalid: %s", errors.RFCCodeText("DFLOW:ErrCvsTaskInvalidSecurity"))
+	ErrCvsTaskResumeFailed     = errors.Normalize("cvs task failed to resume from the persisted status", errors.RFCCodeText("DFLOW:ErrCvsTaskResumeFailed"))
+
+	// DM related errors
+	ErrInvalidSubTaskConfig = errors.Normalize("invalid subtask config: %s", errors.RFCCodeText("DFLOW:ErrInvalidSubTaskConfig"))
 )
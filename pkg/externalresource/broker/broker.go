@@ -137,6 +137,7 @@ func (b *DefaultBroker) newHandleForLocalFile(
 	var (
 		res             *resModel.LocalFileResourceDescriptor
 		creatorWorkerID libModel.WorkerID
+		filePath        string
 	)
 
 	if !exists {
@@ -148,18 +149,21 @@ func (b *DefaultBroker) newHandleForLocalFile(
 		defer func() {
 			if retErr != nil {
 				//nolint:errcheck
-				_ = b.fileManager.RemoveResource(workerID, resName)
+				_ = b.fileManager.DiscardResource(workerID, resName)
 			}
 		}()
+		// A new resource is written to its temporary path,
+		// and it is moved into place when the handle is persisted.
+		filePath = res.TemporaryPath()
 	} else {
 		creatorWorkerID = record.Worker
 		res, err = b.fileManager.GetPersistedResource(record.Worker, resName)
 		if err != nil {
 			return nil, err
 		}
+		filePath = res.AbsolutePath()
 	}
 
-	filePath := res.AbsolutePath()
 	log.L().Info("Using local storage with path", zap.String("path", filePath))
 
 	ls, err := newBrStorageForLocalFile(filePath)
@@ -177,6 +181,7 @@ func (b *DefaultBroker) newHandleForLocalFile(
 		workerID:    creatorWorkerID,
 		executorID:  b.executorID,
 		fileManager: b.fileManager,
+		desc:        res,
 	}, nil
 }
 
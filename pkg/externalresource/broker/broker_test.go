@@ -48,6 +48,13 @@ func TestBrokerOpenNewStorage(t *testing.T) {
 	err = f.Close(context.Background())
 	require.NoError(t, err)
 
+	// The data is not visible before the resource is persisted.
+	fileName := filepath.Join(dir, "worker-1", "test-1", "1.txt")
+	require.NoFileExists(t, fileName)
+	_, err = brk.fileManager.GetPersistedResource("worker-1", "test-1")
+	require.Error(t, err)
+	require.Regexp(t, ".*ErrResourceDoesNotExist.*", err)
+
 	innerClient.On("CreateResource", mock.Anything, &pb.CreateResourceRequest{
 		ResourceId:      "/local/test-1",
 		CreatorExecutor: "executor-1",
@@ -60,8 +67,40 @@ func TestBrokerOpenNewStorage(t *testing.T) {
 
 	innerClient.AssertExpectations(t)
 
-	fileName := filepath.Join(dir, "worker-1", "test-1", "1.txt")
 	require.FileExists(t, fileName)
+
+	// The handle can still be used after persisting.
+	f, err = hdl.BrExternalStorage().Create(context.Background(), "2.txt")
+	require.NoError(t, err)
+	err = f.Close(context.Background())
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(dir, "worker-1", "test-1", "2.txt"))
+}
+
+func TestBrokerDiscardStorage(t *testing.T) {
+	brk, client, dir := newBroker(t)
+
+	innerClient := client.GetLeaderClient().(*manager.MockClient)
+	innerClient.On("QueryResource", mock.Anything, &pb.QueryResourceRequest{ResourceId: "/local/test-3"}, mock.Anything).
+		Return((*pb.QueryResourceResponse)(nil), status.Error(codes.NotFound, "resource manager error"))
+	hdl, err := brk.OpenStorage(context.Background(), "worker-1", "job-1", "/local/test-3")
+	require.NoError(t, err)
+
+	f, err := hdl.BrExternalStorage().Create(context.Background(), "1.txt")
+	require.NoError(t, err)
+	err = f.Close(context.Background())
+	require.NoError(t, err)
+
+	err = hdl.Discard(context.Background())
+	require.NoError(t, err)
+
+	// No meta record is created for a discarded resource.
+	innerClient.AssertNotCalled(t, "CreateResource", mock.Anything, mock.Anything, mock.Anything)
+	require.NoDirExists(t, filepath.Join(dir, "worker-1", "test-3"))
+	require.NoDirExists(t, filepath.Join(dir, "worker-1", ".tmp", "test-3"))
+	_, err = brk.fileManager.GetPersistedResource("worker-1", "test-3")
+	require.Error(t, err)
+	require.Regexp(t, ".*ErrResourceDoesNotExist.*", err)
 }
 
 func TestBrokerOpenExistingStorage(t *testing.T) {
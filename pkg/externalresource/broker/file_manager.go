@@ -34,8 +34,9 @@ func NewLocalFileManager(config storagecfg.LocalFileConfig) *LocalFileManager {
 	}
 }
 
-// CreateResource makes a local directory for the given resource name,
-// and returns a LocalFileResourceDescriptor.
+// CreateResource makes a temporary local directory for the given resource name,
+// and returns a LocalFileResourceDescriptor. The data should be written to
+// the TemporaryPath of the descriptor until the resource is persisted.
 // The resource is NOT marked as persisted by this method.
 // Only use it when we are sure it is a NEW resource.
 func (m *LocalFileManager) CreateResource(
@@ -47,7 +48,7 @@ func (m *LocalFileManager) CreateResource(
 		Creator:      creator,
 		ResourceName: resName,
 	}
-	if err := os.MkdirAll(res.AbsolutePath(), 0o700); err != nil {
+	if err := os.MkdirAll(res.TemporaryPath(), 0o700); err != nil {
 		return nil, derrors.ErrCreateLocalFileDirectoryFailed.Wrap(err)
 	}
 	// TODO check for quota when we implement quota.
@@ -87,7 +88,8 @@ func (m *LocalFileManager) GetPersistedResource(
 }
 
 // RemoveTemporaryFiles cleans up all temporary files (i.e., unpersisted file resources),
-// created by `creator`.
+// created by `creator`. Note that the temporary directory itself is never
+// marked as persisted, so it is removed as a whole.
 func (m *LocalFileManager) RemoveTemporaryFiles(creator libModel.WorkerID) error {
 	log.L().Info("Start cleaning temporary files",
 		zap.String("worker-id", creator))
@@ -168,14 +170,32 @@ func (m *LocalFileManager) RemoveResource(creator libModel.WorkerID, resName res
 	return nil
 }
 
-// SetPersisted marks a file resource as persisted.
+// SetPersisted moves the temporary directory of a file resource, if any,
+// into place and marks the resource as persisted. The rename is atomic,
+// so the resource is visible either with all its data or not at all.
 // NOTE it is only marked as persisted in memory, because
 // we assume that if the executor process crashes, the
 // file resources are lost.
 func (m *LocalFileManager) SetPersisted(
 	creator libModel.WorkerID,
 	resName resModel.ResourceName,
-) {
+) error {
+	res := &resModel.LocalFileResourceDescriptor{
+		BasePath:     m.config.BaseDir,
+		Creator:      creator,
+		ResourceName: resName,
+	}
+	if _, err := os.Stat(res.TemporaryPath()); err == nil {
+		if err := os.MkdirAll(filepath.Dir(res.AbsolutePath()), 0o700); err != nil {
+			return derrors.ErrCreateLocalFileDirectoryFailed.Wrap(err)
+		}
+		if err := os.Rename(res.TemporaryPath(), res.AbsolutePath()); err != nil {
+			return derrors.ErrPersistingLocalResource.Wrap(err)
+		}
+	} else if !os.IsNotExist(err) {
+		return derrors.ErrReadLocalFileDirectoryFailed.Wrap(err)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -186,7 +206,28 @@ func (m *LocalFileManager) SetPersisted(
 	}
 
 	persistedResourceSet[resName] = struct{}{}
-	return
+	return nil
+}
+
+// DiscardResource removes the temporary directory of a file resource
+// that has not been persisted. Persisted data is left untouched.
+func (m *LocalFileManager) DiscardResource(
+	creator libModel.WorkerID,
+	resName resModel.ResourceName,
+) error {
+	res := &resModel.LocalFileResourceDescriptor{
+		BasePath:     m.config.BaseDir,
+		Creator:      creator,
+		ResourceName: resName,
+	}
+	if err := os.RemoveAll(res.TemporaryPath()); err != nil {
+		return derrors.ErrCleaningLocalTempFiles.Wrap(err)
+	}
+
+	log.L().Info("temporary resource is discarded",
+		zap.String("creator", creator),
+		zap.String("resource-name", resName))
+	return nil
 }
 
 // isPersisted returns whether a resource has been persisted.
@@ -34,15 +34,19 @@ func TestFileManagerBasics(t *testing.T) {
 		ResourceName: "resource-1",
 	}, res1)
 
-	storage, err := newBrStorageForLocalFile(res1.AbsolutePath())
+	storage, err := newBrStorageForLocalFile(res1.TemporaryPath())
 	require.NoError(t, err)
 	fwriter, err := storage.Create(context.Background(), "1.txt")
 	require.NoError(t, err)
 	err = fwriter.Close(context.Background())
 	require.NoError(t, err)
-	require.FileExists(t, res1.AbsolutePath()+"/1.txt")
+	require.FileExists(t, res1.TemporaryPath()+"/1.txt")
+	require.NoDirExists(t, res1.AbsolutePath())
 
-	fm.SetPersisted("worker-1", "resource-1")
+	err = fm.SetPersisted("worker-1", "resource-1")
+	require.NoError(t, err)
+	require.FileExists(t, res1.AbsolutePath()+"/1.txt")
+	require.NoDirExists(t, res1.TemporaryPath())
 
 	// Creates resource-2
 	res2, err := fm.CreateResource("worker-1", "resource-2")
@@ -53,19 +57,19 @@ func TestFileManagerBasics(t *testing.T) {
 		ResourceName: "resource-2",
 	}, res2)
 
-	storage, err = newBrStorageForLocalFile(res2.AbsolutePath())
+	storage, err = newBrStorageForLocalFile(res2.TemporaryPath())
 	require.NoError(t, err)
 	fwriter, err = storage.Create(context.Background(), "1.txt")
 	require.NoError(t, err)
 	err = fwriter.Close(context.Background())
 	require.NoError(t, err)
-	require.FileExists(t, res2.AbsolutePath()+"/1.txt")
+	require.FileExists(t, res2.TemporaryPath()+"/1.txt")
 
 	// Clean up temporary files
 	err = fm.RemoveTemporaryFiles("worker-1")
 	require.NoError(t, err)
 
-	require.NoDirExists(t, res2.AbsolutePath())
+	require.NoDirExists(t, res2.TemporaryPath())
 	require.DirExists(t, res1.AbsolutePath())
 
 	// Clean up persisted resource
@@ -94,16 +98,17 @@ func TestFileManagerManyWorkers(t *testing.T) {
 			fmt.Sprintf("resource-%d-1", i))
 		require.NoError(t, err)
 
-		storage, err := newBrStorageForLocalFile(res.AbsolutePath())
+		storage, err := newBrStorageForLocalFile(res.TemporaryPath())
 		require.NoError(t, err)
 		fwriter, err := storage.Create(context.Background(), "1.txt")
 		require.NoError(t, err)
 		err = fwriter.Close(context.Background())
 		require.NoError(t, err)
-		require.FileExists(t, res.AbsolutePath()+"/1.txt")
+		require.FileExists(t, res.TemporaryPath()+"/1.txt")
 
-		fm.SetPersisted(fmt.Sprintf("worker-%d", i),
+		err = fm.SetPersisted(fmt.Sprintf("worker-%d", i),
 			fmt.Sprintf("resource-%d-1", i))
+		require.NoError(t, err)
 
 		// Then create a temporary resource
 		res, err = fm.CreateResource(
@@ -111,13 +116,13 @@ func TestFileManagerManyWorkers(t *testing.T) {
 			fmt.Sprintf("resource-%d-2", i))
 		require.NoError(t, err)
 
-		storage, err = newBrStorageForLocalFile(res.AbsolutePath())
+		storage, err = newBrStorageForLocalFile(res.TemporaryPath())
 		require.NoError(t, err)
 		fwriter, err = storage.Create(context.Background(), "1.txt")
 		require.NoError(t, err)
 		err = fwriter.Close(context.Background())
 		require.NoError(t, err)
-		require.FileExists(t, res.AbsolutePath()+"/1.txt")
+		require.FileExists(t, res.TemporaryPath()+"/1.txt")
 	}
 
 	// Garbage collects about half the workers' temporary files.
@@ -134,9 +139,9 @@ func TestFileManagerManyWorkers(t *testing.T) {
 
 		resourceID2 := fmt.Sprintf("resource-%d-2", i)
 		if i < numWorkers/2 {
-			require.NoDirExists(t, filepath.Join(dir, workerID, resourceID2))
+			require.NoDirExists(t, filepath.Join(dir, workerID, ".tmp", resourceID2))
 		} else {
-			require.DirExists(t, filepath.Join(dir, workerID, resourceID2))
+			require.DirExists(t, filepath.Join(dir, workerID, ".tmp", resourceID2))
 		}
 	}
 }
@@ -170,7 +175,8 @@ func TestCreateAndGetResource(t *testing.T) {
 	require.Error(t, err)
 	require.Regexp(t, ".*ErrResourceDoesNotExist.*", err)
 
-	fm.SetPersisted("worker-1", "resource-1")
+	err = fm.SetPersisted("worker-1", "resource-1")
+	require.NoError(t, err)
 	_, err = fm.GetPersistedResource("worker-1", "resource-1")
 	require.NoError(t, err)
 
@@ -181,3 +187,33 @@ func TestCreateAndGetResource(t *testing.T) {
 	require.Error(t, err)
 	require.Regexp(t, ".*ErrResourceDoesNotExist.*", err)
 }
+
+func TestDiscardResource(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fm := NewLocalFileManager(storagecfg.LocalFileConfig{BaseDir: dir})
+
+	res, err := fm.CreateResource("worker-1", "resource-1")
+	require.NoError(t, err)
+	require.DirExists(t, res.TemporaryPath())
+
+	err = fm.DiscardResource("worker-1", "resource-1")
+	require.NoError(t, err)
+	require.NoDirExists(t, res.TemporaryPath())
+	require.NoDirExists(t, res.AbsolutePath())
+
+	// Discarding a resource with no temporary data is a no-op.
+	err = fm.DiscardResource("worker-1", "resource-1")
+	require.NoError(t, err)
+
+	// Persisted data is not affected by discarding.
+	_, err = fm.CreateResource("worker-1", "resource-2")
+	require.NoError(t, err)
+	err = fm.SetPersisted("worker-1", "resource-2")
+	require.NoError(t, err)
+	err = fm.DiscardResource("worker-1", "resource-2")
+	require.NoError(t, err)
+	_, err = fm.GetPersistedResource("worker-1", "resource-2")
+	require.NoError(t, err)
+}
@@ -54,5 +54,10 @@ type FileManager interface {
 	SetPersisted(
 		creator libModel.WorkerID,
 		resName resModel.ResourceName,
-	)
+	) error
+
+	DiscardResource(
+		creator libModel.WorkerID,
+		resName resModel.ResourceName,
+	) error
 }
@@ -30,6 +30,7 @@ type LocalBroker struct {
 
 	mu            sync.Mutex
 	persistedList []resourcemeta.ResourceID
+	creators      map[resourcemeta.ResourceID]resourcemeta.WorkerID
 }
 
 // NewBrokerForTesting creates a LocalBroker instance for testing only
@@ -43,6 +44,7 @@ func NewBrokerForTesting(executorID resourcemeta.ExecutorID) *LocalBroker {
 	return &LocalBroker{
 		DefaultBroker: NewBroker(cfg, executorID, client),
 		client:        client.GetLeaderClient().(*manager.MockClient),
+		creators:      make(map[resourcemeta.ResourceID]resourcemeta.WorkerID),
 	}
 }
 
@@ -56,10 +58,18 @@ func (b *LocalBroker) OpenStorage(
 	b.clientMu.Lock()
 	defer b.clientMu.Unlock()
 
-	st := status.New(codes.NotFound, "resource manager error")
-
-	b.client.On("QueryResource", mock.Anything, &pb.QueryResourceRequest{ResourceId: resourcePath}, mock.Anything).
-		Return((*pb.QueryResourceResponse)(nil), st.Err())
+	if creator, ok := b.getCreator(resourcePath); ok {
+		b.client.On("QueryResource", mock.Anything, &pb.QueryResourceRequest{ResourceId: resourcePath}, mock.Anything).
+			Return(&pb.QueryResourceResponse{
+				CreatorExecutor: string(b.executorID),
+				JobId:           jobID,
+				CreatorWorkerId: creator,
+			}, nil)
+	} else {
+		st := status.New(codes.NotFound, "resource manager error")
+		b.client.On("QueryResource", mock.Anything, &pb.QueryResourceRequest{ResourceId: resourcePath}, mock.Anything).
+			Return((*pb.QueryResourceResponse)(nil), st.Err())
+	}
 	defer func() {
 		b.client.ExpectedCalls = nil
 	}()
@@ -68,7 +78,7 @@ func (b *LocalBroker) OpenStorage(
 		return nil, err
 	}
 
-	return &brExternalStorageHandleForTesting{parent: b, Handle: h}, nil
+	return &brExternalStorageHandleForTesting{parent: b, Handle: h, workerID: workerID}, nil
 }
 
 // AssertPersisted checks resource is in persisted list
@@ -79,11 +89,22 @@ func (b *LocalBroker) AssertPersisted(t *testing.T, id resourcemeta.ResourceID)
 	require.Contains(t, b.persistedList, id)
 }
 
-func (b *LocalBroker) appendPersistRecord(id resourcemeta.ResourceID) {
+func (b *LocalBroker) appendPersistRecord(id resourcemeta.ResourceID, creator resourcemeta.WorkerID) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	b.persistedList = append(b.persistedList, id)
+	if _, ok := b.creators[id]; !ok {
+		b.creators[id] = creator
+	}
+}
+
+func (b *LocalBroker) getCreator(id resourcemeta.ResourceID) (resourcemeta.WorkerID, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	creator, ok := b.creators[id]
+	return creator, ok
 }
 
 // AssertFileExists checks lock file exists
@@ -100,10 +121,23 @@ func (b *LocalBroker) AssertFileExists(
 
 type brExternalStorageHandleForTesting struct {
 	Handle
-	parent *LocalBroker
+	parent   *LocalBroker
+	workerID resourcemeta.WorkerID
 }
 
 func (h *brExternalStorageHandleForTesting) Persist(ctx context.Context) error {
-	h.parent.appendPersistRecord(h.ID())
+	h.parent.clientMu.Lock()
+	defer h.parent.clientMu.Unlock()
+
+	h.parent.client.On("CreateResource", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.CreateResourceResponse{}, nil)
+	defer func() {
+		h.parent.client.ExpectedCalls = nil
+	}()
+	if err := h.Handle.Persist(ctx); err != nil {
+		return err
+	}
+
+	h.parent.appendPersistRecord(h.ID(), h.workerID)
 	return nil
 }
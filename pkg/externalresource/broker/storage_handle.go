@@ -14,8 +14,15 @@ import (
 // Handle defines an interface for interact with framework
 type Handle interface {
 	ID() resModel.ResourceID
+	// BrExternalStorage returns the storage to access the resource.
+	// For a new resource, the data written is not visible to others
+	// until Persist is called. The storage returned before Persist
+	// should not be used after Persist.
 	BrExternalStorage() brStorage.ExternalStorage
+	// Persist atomically moves the data into place and then records
+	// the resource as committed.
 	Persist(ctx context.Context) error
+	// Discard cleans up the data that has not been persisted.
 	Discard(ctx context.Context) error
 }
 
@@ -32,6 +39,7 @@ type BrExternalStorageHandle struct {
 	inner       brStorage.ExternalStorage
 	client      *rpcutil.FailoverRPCClients[pb.ResourceManagerClient]
 	fileManager FileManager
+	desc        *resModel.LocalFileResourceDescriptor
 }
 
 // ID implements Handle.ID
@@ -64,11 +72,21 @@ func (h *BrExternalStorageHandle) Persist(ctx context.Context) error {
 		// garbage collection eventually.
 		return errors.Trace(err)
 	}
-	h.fileManager.SetPersisted(h.workerID, h.name)
+	if err := h.fileManager.SetPersisted(h.workerID, h.name); err != nil {
+		return err
+	}
+
+	// The data has been moved out of the temporary path,
+	// so the storage needs to point to the new location.
+	inner, err := newBrStorageForLocalFile(h.desc.AbsolutePath())
+	if err != nil {
+		return err
+	}
+	h.inner = inner
 	return nil
 }
 
 // Discard implements Handle.Discard
 func (h *BrExternalStorageHandle) Discard(ctx context.Context) error {
-	return nil
+	return h.fileManager.DiscardResource(h.workerID, h.name)
 }
@@ -6,6 +6,10 @@ import (
 	libModel "github.com/hanfei1991/microcosm/lib/model"
 )
 
+// localTempDirName is the directory under a creator's directory
+// where unpersisted resources are written to.
+const localTempDirName = ".tmp"
+
 // LocalFileResourceDescriptor contains necessary data
 // to access a local file resource.
 type LocalFileResourceDescriptor struct {
@@ -19,3 +23,9 @@ type LocalFileResourceDescriptor struct {
 func (d *LocalFileResourceDescriptor) AbsolutePath() string {
 	return filepath.Join(d.BasePath, d.Creator, d.ResourceName)
 }
+
+// TemporaryPath returns the absolute path where the given resource
+// is written to before it is persisted.
+func (d *LocalFileResourceDescriptor) TemporaryPath() string {
+	return filepath.Join(d.BasePath, d.Creator, localTempDirName, d.ResourceName)
+}
@@ -33,6 +33,7 @@ var ResourceUpdateColumns = []string{
 	"worker_id",
 	"executor_id",
 	"deleted",
+	"content_type",
 }
 
 // ResourceMeta is the records stored in the metastore.
@@ -44,6 +45,10 @@ type ResourceMeta struct {
 	Worker    WorkerID         `json:"worker" gorm:"column:worker_id;type:varchar(64) not null"`
 	Executor  ExecutorID       `json:"executor" gorm:"column:executor_id;type:varchar(64) not null;index:idx_rei,priority:1"`
 	Deleted   bool             `json:"deleted" gorm:"column:deleted;type:BOOLEAN"`
+	// ContentType describes the kind of data held by the resource, such as
+	// csv, parquet or a binary dump, so that consumers can find compatible
+	// inputs without opening the resource. It is empty if unknown.
+	ContentType string `json:"content-type" gorm:"column:content_type;type:varchar(64) not null default '';index:idx_rct"`
 }
 
 // GetID implements dataset.DataEntry
@@ -63,12 +68,13 @@ func (m *ResourceMeta) ToQueryResourceResponse() *pb.QueryResourceResponse {
 // Map is used in gorm update
 func (m *ResourceMeta) Map() map[string]interface{} {
 	return map[string]interface{}{
-		"project_id":  m.ProjectID,
-		"id":          m.ID,
-		"job_id":      m.Job,
-		"worker_id":   m.Worker,
-		"executor_id": m.Executor,
-		"deleted":     m.Deleted,
+		"project_id":   m.ProjectID,
+		"id":           m.ID,
+		"job_id":       m.Job,
+		"worker_id":    m.Worker,
+		"executor_id":  m.Executor,
+		"deleted":      m.Deleted,
+		"content_type": m.ContentType,
 	}
 }
 
@@ -34,10 +34,17 @@ const (
 type etcdImpl struct {
 	cli     *clientv3.Client
 	closeMu sync.Mutex
+	// codec is used to encode values on put and decode values on get
+	codec metaclient.ValueCodec
 }
 
 // NewEtcdImpl creates a new etcdImpl instance
 func NewEtcdImpl(config *metaclient.StoreConfigParams) (*etcdImpl, error) {
+	codec, err := metaclient.ParseValueCodec(config.ValueCodec)
+	if err != nil {
+		return nil, err
+	}
+
 	cli, err := clientv3.New(clientv3.Config{
 		Endpoints: config.Endpoints,
 		// [TODO] TLS
@@ -50,7 +57,8 @@ func NewEtcdImpl(config *metaclient.StoreConfigParams) (*etcdImpl, error) {
 	}
 
 	c := &etcdImpl{
-		cli: cli,
+		cli:   cli,
+		codec: codec,
 	}
 	return c, nil
 }
@@ -65,6 +73,10 @@ func (c *etcdImpl) getEtcdOptions(op metaclient.Op) []clientv3.OpOption {
 	case op.IsOptsWithRange():
 		etcdOps = append(etcdOps, clientv3.WithRange(string(op.RangeBytes())))
 	}
+	// etcd panics on the delete ops with limit
+	if op.IsGet() && op.Limit() > 0 {
+		etcdOps = append(etcdOps, clientv3.WithLimit(op.Limit()))
+	}
 
 	return etcdOps
 }
@@ -75,7 +87,8 @@ func (c *etcdImpl) getEtcdOp(op metaclient.Op) clientv3.Op {
 	case op.IsGet():
 		return clientv3.OpGet(string(op.KeyBytes()), opts...)
 	case op.IsPut():
-		return clientv3.OpPut(string(op.KeyBytes()), string(op.ValueBytes()), opts...)
+		val := metaclient.EncodeValue(c.codec, op.ValueBytes())
+		return clientv3.OpPut(string(op.KeyBytes()), string(val), opts...)
 	case op.IsDelete():
 		return clientv3.OpDelete(string(op.KeyBytes()), opts...)
 	case op.IsTxn():
@@ -90,6 +103,25 @@ func (c *etcdImpl) getEtcdOp(op metaclient.Op) clientv3.Op {
 	panic("unknown op type")
 }
 
+// getEtcdCmp converts cmp to the etcd comparison, the values are compared
+// after encoded by the codec of the client, same as they are stored
+func (c *etcdImpl) getEtcdCmp(cmp metaclient.Cmp) clientv3.Cmp {
+	key := string(cmp.KeyBytes())
+	switch {
+	case cmp.IsKeyExists():
+		return clientv3.Compare(clientv3.Version(key), ">", 0)
+	case cmp.IsKeyNotExists():
+		return clientv3.Compare(clientv3.Version(key), "=", 0)
+	case cmp.IsValueEqual():
+		val := metaclient.EncodeValue(c.codec, cmp.ValueBytes())
+		return clientv3.Compare(clientv3.Value(key), "=", string(val))
+	case cmp.IsModRevisionEqual():
+		return clientv3.Compare(clientv3.ModRevision(key), "=", cmp.Revision())
+	}
+
+	panic("unknown cmp type")
+}
+
 func (c *etcdImpl) Put(ctx context.Context, key, val string) (*metaclient.PutResponse, metaclient.Error) {
 	op := metaclient.OpPut(key, val)
 	etcdResp, err := c.cli.Do(ctx, c.getEtcdOp(op))
@@ -114,8 +146,14 @@ func (c *etcdImpl) Get(ctx context.Context, key string, opts ...metaclient.OpOpt
 		return nil, etcdErrorFromOpFail(err)
 	}
 
-	getRsp := etcdResp.Get()
-	return makeGetResp(getRsp), nil
+	getRsp := makeGetResp(etcdResp.Get())
+	if err := c.decodeGetResp(getRsp); err != nil {
+		return nil, err
+	}
+	if err := c.fillLeaseTTL(ctx, getRsp); err != nil {
+		return nil, err
+	}
+	return getRsp, nil
 }
 
 func (c *etcdImpl) Delete(ctx context.Context, key string, opts ...metaclient.OpOption) (*metaclient.DeleteResponse, metaclient.Error) {
@@ -151,6 +189,12 @@ func (c *etcdImpl) Do(ctx context.Context, op metaclient.Op) (metaclient.OpRespo
 	case op.IsGet():
 		rsp := etcdResp.Get()
 		getRsp := makeGetResp(rsp)
+		if err := c.decodeGetResp(getRsp); err != nil {
+			return metaclient.OpResponse{}, err
+		}
+		if err := c.fillLeaseTTL(ctx, getRsp); err != nil {
+			return metaclient.OpResponse{}, err
+		}
 		return getRsp.OpResponse(), nil
 	case op.IsPut():
 		rsp := etcdResp.Put()
@@ -163,6 +207,9 @@ func (c *etcdImpl) Do(ctx context.Context, op metaclient.Op) (metaclient.OpRespo
 	case op.IsTxn():
 		rsp := etcdResp.Txn()
 		txnRsp := makeTxnResp(rsp)
+		if err := c.decodeTxnResp(txnRsp); err != nil {
+			return metaclient.OpResponse{}, err
+		}
 		return txnRsp.OpResponse(), nil
 	default:
 	}
@@ -172,12 +219,53 @@ func (c *etcdImpl) Do(ctx context.Context, op metaclient.Op) (metaclient.OpRespo
 	}
 }
 
+// decodeGetResp decodes the values written with any codec, it is done even if
+// the codec of the client is CodecNone, because the values may be written by
+// other clients with a codec.
+func (c *etcdImpl) decodeGetResp(rsp *metaclient.GetResponse) *etcdError {
+	if err := metaclient.DecodeGetResponse(rsp); err != nil {
+		return &etcdError{displayed: err}
+	}
+	return nil
+}
+
+// fillLeaseTTL looks up the remaining TTL of the leases attached to the keys
+// in rsp, each lease is looked up only once
+func (c *etcdImpl) fillLeaseTTL(ctx context.Context, rsp *metaclient.GetResponse) *etcdError {
+	ttls := make(map[int64]int64)
+	for _, kv := range rsp.Kvs {
+		if kv.Lease == 0 {
+			continue
+		}
+		ttl, ok := ttls[kv.Lease]
+		if !ok {
+			leaseRsp, err := c.cli.TimeToLive(ctx, clientv3.LeaseID(kv.Lease))
+			if err != nil {
+				return etcdErrorFromOpFail(err)
+			}
+			ttl = leaseRsp.TTL
+			ttls[kv.Lease] = ttl
+		}
+		kv.TTL = ttl
+	}
+	return nil
+}
+
+func (c *etcdImpl) decodeTxnResp(rsp *metaclient.TxnResponse) *etcdError {
+	if err := metaclient.DecodeTxnResponse(rsp); err != nil {
+		return &etcdError{displayed: err}
+	}
+	return nil
+}
+
 type etcdTxn struct {
 	clientv3.Txn
 
-	mu  sync.Mutex
-	kv  *etcdImpl
-	ops []clientv3.Op
+	mu      sync.Mutex
+	kv      *etcdImpl
+	cmps    []clientv3.Cmp
+	ops     []clientv3.Op
+	elseOps []clientv3.Op
 	// cache error to make chain operation work
 	Err       *etcdError
 	committed bool
@@ -216,29 +304,61 @@ func (t *etcdTxn) Do(ops ...metaclient.Op) metaclient.Txn {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	if t.Err != nil {
+	t.ops = t.appendOps(t.ops, ops)
+	return t
+}
+
+func (t *etcdTxn) Else(ops ...metaclient.Op) metaclient.Txn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.elseOps = t.appendOps(t.elseOps, ops)
+	return t
+}
+
+func (t *etcdTxn) If(cmps ...metaclient.Cmp) metaclient.Txn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.checkUsable() {
 		return t
 	}
+	for _, cmp := range cmps {
+		t.cmps = append(t.cmps, t.kv.getEtcdCmp(cmp))
+	}
+	return t
+}
+
+// checkUsable returns false and records the error if the txn can't cache
+// more comparisons or ops, it must be called with t.mu held
+func (t *etcdTxn) checkUsable() bool {
+	if t.Err != nil {
+		return false
+	}
 	if t.committed {
 		t.Err = &etcdError{
 			displayed: cerrors.ErrMetaCommittedTxn.GenWithStackByArgs(),
 		}
-		return t
+		return false
 	}
+	return true
+}
 
-	etcdOps := make([]clientv3.Op, 0, len(ops))
+// appendOps appends ops to etcdOps, it must be called with t.mu held
+func (t *etcdTxn) appendOps(etcdOps []clientv3.Op, ops []metaclient.Op) []clientv3.Op {
+	if !t.checkUsable() {
+		return etcdOps
+	}
 	for _, op := range ops {
 		if op.IsTxn() {
 			t.Err = &etcdError{
 				displayed: cerrors.ErrMetaNestedTxn.GenWithStackByArgs(),
 			}
-			return t
+			return etcdOps
 		}
 		etcdOps = append(etcdOps, t.kv.getEtcdOp(op))
 	}
-
-	t.ops = append(t.ops, etcdOps...)
-	return t
+	return etcdOps
 }
 
 func (t *etcdTxn) Commit() (*metaclient.TxnResponse, metaclient.Error) {
@@ -257,11 +377,14 @@ func (t *etcdTxn) Commit() (*metaclient.TxnResponse, metaclient.Error) {
 	t.committed = true
 	t.mu.Unlock()
 
-	t.Txn.Then(t.ops...)
-	etcdResp, err := t.Txn.Commit()
+	etcdResp, err := t.Txn.If(t.cmps...).Then(t.ops...).Else(t.elseOps...).Commit()
 	if err != nil {
 		return nil, etcdErrorFromOpFail(err)
 	}
 
-	return makeTxnResp(etcdResp), nil
+	txnRsp := makeTxnResp(etcdResp)
+	if err := t.kv.decodeTxnResp(txnRsp); err != nil {
+		return nil, err
+	}
+	return txnRsp, nil
 }
@@ -1,7 +1,9 @@
 package etcdkv
 
 import (
+	"bytes"
 	"context"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -10,6 +12,7 @@ import (
 	"github.com/hanfei1991/microcosm/pkg/meta/metaclient"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/server/v3/embed"
 )
 
@@ -299,6 +302,16 @@ func (suite *SuiteTestEtcd) TestKeyRangeOption() {
 				},
 			},
 		},
+		{
+			t: tNone,
+			q: query{
+				key:  "hello",
+				opts: []metaclient.OpOption{metaclient.WithPrefix(), metaclient.WithLimit(1)},
+				expected: []kv{
+					{"hello1", "world1"},
+				},
+			},
+		},
 		{
 			t: tNone,
 			q: query{
@@ -502,6 +515,199 @@ func (suite *SuiteTestEtcd) TestGenEpoch() {
 	testGenerator(t, cli)
 }
 
+func (suite *SuiteTestEtcd) TestLeaseTTL() {
+	conf := &metaclient.StoreConfigParams{
+		Endpoints: []string{suite.endpoints},
+	}
+	t := suite.T()
+	cli, err := NewEtcdImpl(conf)
+	require.Nil(t, err)
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	defer clearKeySpace(ctx, cli)
+
+	const ttl = 10
+	lease, err := cli.cli.Grant(ctx, ttl)
+	require.Nil(t, err)
+	_, err = cli.cli.Put(ctx, "lease-key1", "value1", clientv3.WithLease(lease.ID))
+	require.Nil(t, err)
+	_, err = cli.cli.Put(ctx, "lease-key2", "value2", clientv3.WithLease(lease.ID))
+	require.Nil(t, err)
+	_, cerr := cli.Put(ctx, "lease-key3", "value3")
+	require.Nil(t, cerr)
+
+	rsp, cerr := cli.Get(ctx, "lease-key", metaclient.WithPrefix())
+	require.Nil(t, cerr)
+	require.Len(t, rsp.Kvs, 3)
+	for _, kv := range rsp.Kvs[:2] {
+		require.Equal(t, int64(lease.ID), kv.Lease)
+		require.Greater(t, kv.TTL, int64(0))
+		require.LessOrEqual(t, kv.TTL, int64(ttl))
+	}
+	require.Equal(t, int64(0), rsp.Kvs[2].Lease)
+	require.Equal(t, int64(0), rsp.Kvs[2].TTL)
+
+	opRsp, cerr := cli.Do(ctx, metaclient.OpGet("lease-key1"))
+	require.Nil(t, cerr)
+	kvs := opRsp.Get().Kvs
+	require.Len(t, kvs, 1)
+	require.Greater(t, kvs[0].TTL, int64(0))
+	require.LessOrEqual(t, kvs[0].TTL, int64(ttl))
+}
+
+func (suite *SuiteTestEtcd) TestTxnCompareAndSwap() {
+	conf := &metaclient.StoreConfigParams{
+		Endpoints: []string{suite.endpoints},
+	}
+	t := suite.T()
+	cli, err := NewEtcdImpl(conf)
+	require.Nil(t, err)
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	defer clearKeySpace(ctx, cli)
+
+	txnRsp, cerr := cli.Txn(ctx).If(metaclient.CmpKeyNotExists("cas-key")).
+		Do(metaclient.OpPut("cas-key", "value1")).
+		Else(metaclient.OpGet("cas-key")).Commit()
+	require.Nil(t, cerr)
+	require.True(t, txnRsp.Succeeded)
+	require.NotNil(t, txnRsp.Responses[0].GetResponsePut())
+
+	txnRsp, cerr = cli.Txn(ctx).If(metaclient.CmpKeyNotExists("cas-key")).
+		Do(metaclient.OpPut("cas-key", "value2")).
+		Else(metaclient.OpGet("cas-key")).Commit()
+	require.Nil(t, cerr)
+	require.False(t, txnRsp.Succeeded)
+	getRsp := txnRsp.Responses[0].GetResponseGet()
+	require.Len(t, getRsp.Kvs, 1)
+	require.Equal(t, "value1", string(getRsp.Kvs[0].Value))
+	modRev := getRsp.Kvs[0].ModRevision
+	require.Greater(t, modRev, int64(0))
+
+	txnRsp, cerr = cli.Txn(ctx).If(metaclient.CmpValueEqual("cas-key", "value1")).
+		Do(metaclient.OpPut("cas-key", "value2")).Commit()
+	require.Nil(t, cerr)
+	require.True(t, txnRsp.Succeeded)
+	txnRsp, cerr = cli.Txn(ctx).If(metaclient.CmpValueEqual("cas-key", "value1")).
+		Do(metaclient.OpPut("cas-key", "value3")).Commit()
+	require.Nil(t, cerr)
+	require.False(t, txnRsp.Succeeded)
+
+	txnRsp, cerr = cli.Txn(ctx).If(metaclient.CmpModRevisionEqual("cas-key", modRev)).
+		Do(metaclient.OpPut("cas-key", "value3")).Commit()
+	require.Nil(t, cerr)
+	require.False(t, txnRsp.Succeeded)
+	rsp, cerr := cli.Get(ctx, "cas-key")
+	require.Nil(t, cerr)
+	require.Equal(t, "value2", string(rsp.Kvs[0].Value))
+	txnRsp, cerr = cli.Txn(ctx).If(
+		metaclient.CmpKeyExists("cas-key"),
+		metaclient.CmpModRevisionEqual("cas-key", rsp.Kvs[0].ModRevision),
+	).Do(metaclient.OpDelete("cas-key")).Commit()
+	require.Nil(t, cerr)
+	require.True(t, txnRsp.Succeeded)
+	rsp, cerr = cli.Get(ctx, "cas-key")
+	require.Nil(t, cerr)
+	require.Len(t, rsp.Kvs, 0)
+}
+
+func (suite *SuiteTestEtcd) TestValueCodec() {
+	t := suite.T()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	newClient := func(codec string) *etcdImpl {
+		cli, err := NewEtcdImpl(&metaclient.StoreConfigParams{
+			Endpoints:  []string{suite.endpoints},
+			ValueCodec: codec,
+		})
+		require.Nil(t, err)
+		return cli
+	}
+	rawCli := newClient("")
+	defer rawCli.Close()
+	defer clearKeySpace(ctx, rawCli)
+
+	large := strings.Repeat("large value;", 32*1024)
+	codecs := []metaclient.ValueCodec{metaclient.CodecIdentity, metaclient.CodecGzip, metaclient.CodecSnappy}
+	clis := make([]*etcdImpl, 0, len(codecs))
+	for _, codec := range codecs {
+		cli := newClient(codec.String())
+		defer cli.Close()
+		clis = append(clis, cli)
+
+		key := "codec-" + codec.String()
+		_, err := cli.Put(ctx, key, large)
+		require.Nil(t, err)
+		rsp, err := cli.Get(ctx, key)
+		require.Nil(t, err)
+		require.Len(t, rsp.Kvs, 1)
+		require.Equal(t, large, string(rsp.Kvs[0].Value))
+
+		// the stored value is prefixed with the codec header
+		etcdRsp, etcdErr := rawCli.cli.Get(ctx, key)
+		require.Nil(t, etcdErr)
+		require.Len(t, etcdRsp.Kvs, 1)
+		header := metaclient.EncodeValue(metaclient.CodecIdentity, nil)
+		header[len(header)-1] = byte(codec)
+		require.True(t, bytes.HasPrefix(etcdRsp.Kvs[0].Value, header))
+		if codec != metaclient.CodecIdentity {
+			require.Less(t, len(etcdRsp.Kvs[0].Value), len(large)/10)
+		}
+		// the client without codec can also decode the value
+		rsp, err = rawCli.Get(ctx, key)
+		require.Nil(t, err)
+		require.Len(t, rsp.Kvs, 1)
+		require.Equal(t, large, string(rsp.Kvs[0].Value))
+
+		txnKey := "codec-txn-" + codec.String()
+		txnRsp, err := cli.Txn(ctx).Do(metaclient.OpPut(txnKey, large), metaclient.OpGet(txnKey)).Commit()
+		require.Nil(t, err)
+		require.Equal(t, large, string(txnRsp.Responses[1].GetResponseGet().Kvs[0].Value))
+	}
+
+	// values written with any codec can be decoded by clients with other codecs
+	for _, cli := range clis {
+		rsp, err := cli.Get(ctx, "codec-", metaclient.WithPrefix())
+		require.Nil(t, err)
+		require.Len(t, rsp.Kvs, 2*len(codecs))
+		for _, kv := range rsp.Kvs {
+			require.Equal(t, large, string(kv.Value))
+		}
+		opRsp, err := cli.Do(ctx, metaclient.OpGet("codec-", metaclient.WithPrefix()))
+		require.Nil(t, err)
+		require.Len(t, opRsp.Get().Kvs, 2*len(codecs))
+	}
+
+	// values stored without codec header, e.g. before the codec is enabled,
+	// are read as they are
+	raws := map[string]string{
+		"codec-raw-empty": "",
+		"codec-raw-json":  `{"id":"job-1"}`,
+		"codec-raw-byte":  string([]byte{byte(metaclient.CodecGzip), 'a'}),
+	}
+	for key, val := range raws {
+		_, err := rawCli.Put(ctx, key, val)
+		require.Nil(t, err)
+	}
+	for _, cli := range clis {
+		for key, val := range raws {
+			rsp, err := cli.Get(ctx, key)
+			require.Nil(t, err)
+			require.Len(t, rsp.Kvs, 1)
+			require.Equal(t, val, string(rsp.Kvs[0].Value))
+		}
+	}
+
+	_, cerr := NewEtcdImpl(&metaclient.StoreConfigParams{
+		Endpoints:  []string{suite.endpoints},
+		ValueCodec: "zstd",
+	})
+	require.Regexp(t, ".*ErrMetaParamsInvalid.*", cerr)
+}
+
 func testGenerator(t *testing.T, kvcli metaclient.KVClient) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -536,3 +742,24 @@ func testGenerator(t *testing.T, kvcli metaclient.KVClient) {
 func TestEtcdSuite(t *testing.T) {
 	suite.Run(t, new(SuiteTestEtcd))
 }
+
+func TestGetEtcdOpKeyRange(t *testing.T) {
+	t.Parallel()
+
+	cli := &etcdImpl{}
+	testCases := []struct {
+		op       metaclient.Op
+		rangeEnd string
+	}{
+		{op: metaclient.OpGet("hello"), rangeEnd: ""},
+		{op: metaclient.OpGet("hello", metaclient.WithPrefix()), rangeEnd: "hellp"},
+		{op: metaclient.OpGet("hello", metaclient.WithRange("world")), rangeEnd: "world"},
+		{op: metaclient.OpGet("hello", metaclient.WithFromKey()), rangeEnd: "\x00"},
+		{op: metaclient.OpDelete("hello", metaclient.WithPrefix()), rangeEnd: "hellp"},
+	}
+	for _, tc := range testCases {
+		etcdOp := cli.getEtcdOp(tc.op)
+		require.Equal(t, []byte("hello"), etcdOp.KeyBytes())
+		require.Equal(t, tc.rangeEnd, string(etcdOp.RangeBytes()))
+	}
+}
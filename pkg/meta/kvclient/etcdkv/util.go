@@ -25,9 +25,15 @@ func makePutResp(etcdResp *clientv3.PutResponse) *metaclient.PutResponse {
 func makeGetResp(etcdResp *clientv3.GetResponse) *metaclient.GetResponse {
 	kvs := make([]*metaclient.KeyValue, 0, len(etcdResp.Kvs))
 	for _, kv := range etcdResp.Kvs {
+		// version 0 means the key is deleted
+		if kv.Version == 0 {
+			continue
+		}
 		kvs = append(kvs, &metaclient.KeyValue{
-			Key:   kv.Key,
-			Value: kv.Value,
+			Key:         kv.Key,
+			Value:       kv.Value,
+			ModRevision: kv.ModRevision,
+			Lease:       kv.Lease,
 		})
 	}
 	resp := &metaclient.GetResponse{
@@ -84,6 +90,7 @@ func makeTxnResp(etcdResp *clientv3.TxnResponse) *metaclient.TxnResponse {
 		Header: &metaclient.ResponseHeader{
 			ClusterID: strconv.FormatUint(etcdResp.Header.ClusterId, 10),
 		},
+		Succeeded: etcdResp.Succeeded,
 		Responses: rsps,
 	}
 }
@@ -0,0 +1,37 @@
+package etcdkv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/hanfei1991/microcosm/pkg/meta/metaclient"
+)
+
+func TestMakeGetResp(t *testing.T) {
+	t.Parallel()
+
+	etcdResp := &clientv3.GetResponse{
+		Header: &etcdserverpb.ResponseHeader{ClusterId: 1},
+		Kvs: []*mvccpb.KeyValue{
+			{Key: []byte("key1"), Value: []byte("value1"), Version: 1},
+			{Key: []byte("key2"), Value: []byte("value2"), Version: 0},
+			{Key: []byte("key3"), Value: []byte("value3"), Version: 2, Lease: 100},
+		},
+	}
+	resp := makeGetResp(etcdResp)
+	require.Equal(t, "1", resp.Header.ClusterID)
+	require.Equal(t, []*metaclient.KeyValue{
+		{Key: []byte("key1"), Value: []byte("value1")},
+		{Key: []byte("key3"), Value: []byte("value3"), Lease: 100},
+	}, resp.Kvs)
+	for _, kv := range resp.Kvs {
+		require.NotNil(t, kv)
+	}
+
+	resp = makeGetResp(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{}})
+	require.Len(t, resp.Kvs, 0)
+}
@@ -3,7 +3,7 @@ package mock
 import (
 	"context"
 	"fmt"
-	"strings"
+	"sort"
 	"sync"
 
 	cerrors "github.com/hanfei1991/microcosm/pkg/errors"
@@ -11,9 +11,11 @@ import (
 )
 
 type mockTxn struct {
-	c   context.Context
-	m   *MetaMock
-	ops []metaclient.Op
+	c       context.Context
+	m       *MetaMock
+	cmps    []metaclient.Cmp
+	ops     []metaclient.Op
+	elseOps []metaclient.Op
 }
 
 func (t *mockTxn) Do(ops ...metaclient.Op) metaclient.Txn {
@@ -21,6 +23,16 @@ func (t *mockTxn) Do(ops ...metaclient.Op) metaclient.Txn {
 	return t
 }
 
+func (t *mockTxn) If(cmps ...metaclient.Cmp) metaclient.Txn {
+	t.cmps = append(t.cmps, cmps...)
+	return t
+}
+
+func (t *mockTxn) Else(ops ...metaclient.Op) metaclient.Txn {
+	t.elseOps = append(t.elseOps, ops...)
+	return t
+}
+
 func (t *mockTxn) Commit() (*metaclient.TxnResponse, metaclient.Error) {
 	txnRsp := &metaclient.TxnResponse{
 		Header: &metaclient.ResponseHeader{
@@ -33,7 +45,24 @@ func (t *mockTxn) Commit() (*metaclient.TxnResponse, metaclient.Error) {
 	t.m.Lock()
 	defer t.m.Unlock()
 
-	for _, op := range t.ops {
+	// check the context before applying any op, so a canceled txn
+	// leaves the store untouched
+	if err := t.c.Err(); err != nil {
+		return nil, &mockError{caused: err}
+	}
+
+	txnRsp.Succeeded = true
+	for _, cmp := range t.cmps {
+		if !t.m.compareNoLock(cmp) {
+			txnRsp.Succeeded = false
+			break
+		}
+	}
+	ops := t.ops
+	if !txnRsp.Succeeded {
+		ops = t.elseOps
+	}
+	for _, op := range ops {
 		rsp, err := t.m.doNoLock(t.c, op)
 		if err != nil {
 			return nil, err
@@ -69,17 +98,30 @@ func (t *mockTxn) Commit() (*metaclient.TxnResponse, metaclient.Error) {
 
 // MetaMock uses a simple in memory kv storage to implement metaclient.Client
 // and metaclient.KV interface. MetaMock is used in unit test.
-// not support Option yet
 type MetaMock struct {
 	sync.Mutex
-	store    map[string]string
-	revision int64
+	store map[string]string
+	// modRevisions records the revision of the last modification of each
+	// key in store
+	modRevisions map[string]int64
+	revision     int64
+	codec        metaclient.ValueCodec
+	watchers     map[*mockWatcher]struct{}
 }
 
 // NewMetaMock creates a new MetaMock instance
 func NewMetaMock() *MetaMock {
+	return NewMetaMockWithCodec(metaclient.CodecNone)
+}
+
+// NewMetaMockWithCodec creates a new MetaMock instance which encodes values
+// with the given codec, same as the etcd client does.
+func NewMetaMockWithCodec(codec metaclient.ValueCodec) *MetaMock {
 	return &MetaMock{
-		store: make(map[string]string),
+		store:        make(map[string]string),
+		modRevisions: make(map[string]int64),
+		codec:        codec,
+		watchers:     make(map[*mockWatcher]struct{}),
 	}
 }
 
@@ -88,12 +130,25 @@ func (m *MetaMock) Delete(ctx context.Context, key string, opts ...metaclient.Op
 	m.Lock()
 	defer m.Unlock()
 
-	return m.deleteNoLock(ctx, key, opts...)
+	return m.deleteNoLock(ctx, metaclient.OpDelete(key, opts...))
 }
 
-func (m *MetaMock) deleteNoLock(ctx context.Context, key string, opts ...metaclient.OpOption) (*metaclient.DeleteResponse, metaclient.Error) {
-	delete(m.store, key)
+func (m *MetaMock) deleteNoLock(ctx context.Context, op metaclient.Op) (*metaclient.DeleteResponse, metaclient.Error) {
+	if err := op.CheckValidOp(); err != nil {
+		return nil, &mockError{caused: cerrors.ErrMetaOptionInvalid.Wrap(err)}
+	}
 	m.revision++
+	for _, key := range m.matchedKeysNoLock(op) {
+		delete(m.store, key)
+		delete(m.modRevisions, key)
+		m.notifyNoLock(metaclient.Event{
+			Type: metaclient.EventTypeDelete,
+			Kv: &metaclient.KeyValue{
+				Key:         []byte(key),
+				ModRevision: m.revision,
+			},
+		})
+	}
 	return &metaclient.DeleteResponse{
 		Header: &metaclient.ResponseHeader{
 			ClusterID: "mock_cluster",
@@ -110,8 +165,17 @@ func (m *MetaMock) Put(ctx context.Context, key, value string) (*metaclient.PutR
 }
 
 func (m *MetaMock) putNoLock(ctx context.Context, key, value string) (*metaclient.PutResponse, metaclient.Error) {
-	m.store[key] = value
+	m.store[key] = string(metaclient.EncodeValue(m.codec, []byte(value)))
 	m.revision++
+	m.modRevisions[key] = m.revision
+	m.notifyNoLock(metaclient.Event{
+		Type: metaclient.EventTypePut,
+		Kv: &metaclient.KeyValue{
+			Key:         []byte(key),
+			Value:       []byte(value),
+			ModRevision: m.revision,
+		},
+	})
 	return &metaclient.PutResponse{
 		Header: &metaclient.ResponseHeader{
 			ClusterID: "mock_cluster",
@@ -124,27 +188,84 @@ func (m *MetaMock) Get(ctx context.Context, key string, opts ...metaclient.OpOpt
 	m.Lock()
 	defer m.Unlock()
 
-	return m.getNoLock(ctx, key, opts...)
+	return m.getNoLock(ctx, metaclient.OpGet(key, opts...))
 }
 
-func (m *MetaMock) getNoLock(ctx context.Context, key string, opts ...metaclient.OpOption) (*metaclient.GetResponse, metaclient.Error) {
+func (m *MetaMock) getNoLock(ctx context.Context, op metaclient.Op) (*metaclient.GetResponse, metaclient.Error) {
+	if err := op.CheckValidOp(); err != nil {
+		return nil, &mockError{caused: cerrors.ErrMetaOptionInvalid.Wrap(err)}
+	}
 	ret := &metaclient.GetResponse{
 		Header: &metaclient.ResponseHeader{
 			ClusterID: "mock_cluster",
 		},
 	}
-	for k, v := range m.store {
-		if !strings.HasPrefix(k, key) {
-			continue
-		}
+	keys := m.matchedKeysNoLock(op)
+	if op.Limit() > 0 && int64(len(keys)) > op.Limit() {
+		keys = keys[:op.Limit()]
+	}
+	for _, k := range keys {
 		ret.Kvs = append(ret.Kvs, &metaclient.KeyValue{
-			Key:   []byte(k),
-			Value: []byte(v),
+			Key:         []byte(k),
+			Value:       []byte(m.store[k]),
+			ModRevision: m.modRevisions[k],
 		})
 	}
+	// values may be written with any codec, so they are always decoded
+	if err := metaclient.DecodeGetResponse(ret); err != nil {
+		return nil, &mockError{caused: err}
+	}
 	return ret, nil
 }
 
+// matchedKeysNoLock returns the keys in store in the range of op in
+// ascending order
+func (m *MetaMock) matchedKeysNoLock(op metaclient.Op) []string {
+	var keys []string
+	for k := range m.store {
+		if inRange(op, k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// inRange returns whether k is in the range of op, same as etcd, an op
+// without range end matches the exact key only and the range end "\x00"
+// means all the keys >= the key of op.
+func inRange(op metaclient.Op, k string) bool {
+	key, end := string(op.KeyBytes()), string(op.RangeBytes())
+	switch {
+	case end == "":
+		return k == key
+	case k < key:
+		return false
+	case end != "\x00" && k >= end:
+		return false
+	}
+	return true
+}
+
+// compareNoLock evaluates cmp against the store, the values are compared
+// after encoded by the codec, same as the etcd client does
+func (m *MetaMock) compareNoLock(cmp metaclient.Cmp) bool {
+	key := string(cmp.KeyBytes())
+	val, exists := m.store[key]
+	switch {
+	case cmp.IsKeyExists():
+		return exists
+	case cmp.IsKeyNotExists():
+		return !exists
+	case cmp.IsValueEqual():
+		return exists && val == string(metaclient.EncodeValue(m.codec, cmp.ValueBytes()))
+	case cmp.IsModRevisionEqual():
+		return m.modRevisions[key] == cmp.Revision()
+	default:
+	}
+	return false
+}
+
 // Do implements extension.KVClientEx.Do
 func (m *MetaMock) Do(ctx context.Context, op metaclient.Op) (metaclient.OpResponse, metaclient.Error) {
 	m.Lock()
@@ -156,13 +277,13 @@ func (m *MetaMock) Do(ctx context.Context, op metaclient.Op) (metaclient.OpRespo
 func (m *MetaMock) doNoLock(ctx context.Context, op metaclient.Op) (metaclient.OpResponse, metaclient.Error) {
 	switch {
 	case op.IsGet():
-		rsp, err := m.getNoLock(ctx, string(op.KeyBytes()))
+		rsp, err := m.getNoLock(ctx, op)
 		if err != nil {
 			return metaclient.OpResponse{}, err
 		}
 		return rsp.OpResponse(), nil
 	case op.IsDelete():
-		rsp, err := m.deleteNoLock(ctx, string(op.KeyBytes()))
+		rsp, err := m.deleteNoLock(ctx, op)
 		if err != nil {
 			return metaclient.OpResponse{}, err
 		}
@@ -214,3 +335,7 @@ func (e *mockError) IsRetryable() bool {
 func (e *mockError) Error() string {
 	return e.caused.Error()
 }
+
+func (e *mockError) Unwrap() error {
+	return e.caused
+}
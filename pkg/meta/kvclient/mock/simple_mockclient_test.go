@@ -2,10 +2,12 @@ package mock
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	cerrors "github.com/hanfei1991/microcosm/pkg/errors"
 	"github.com/hanfei1991/microcosm/pkg/meta/metaclient"
 	"github.com/stretchr/testify/require"
 )
@@ -158,6 +160,175 @@ func TestMockBasicKV(t *testing.T) {
 	cli.Close()
 }
 
+func TestMockKeyRangeOption(t *testing.T) {
+	t.Parallel()
+
+	cli := NewMetaMock()
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	input := prepare{
+		kvs: []kv{
+			{"hello1", "world1"},
+			{"hello2", "world2"},
+			{"interesting", "world"},
+			{"dataflow", "engine"},
+			{"TiDB", "component"},
+		},
+	}
+	actions := []action{
+		{
+			t: tNone,
+			q: query{
+				key:      "hello",
+				opts:     []metaclient.OpOption{},
+				expected: []kv{},
+			},
+		},
+		{
+			t: tNone,
+			q: query{
+				key:  "hello1",
+				opts: []metaclient.OpOption{},
+				expected: []kv{
+					{"hello1", "world1"},
+				},
+			},
+		},
+		{
+			t: tNone,
+			q: query{
+				key:  "hello",
+				opts: []metaclient.OpOption{metaclient.WithRange("s")},
+				expected: []kv{
+					{"hello1", "world1"},
+					{"hello2", "world2"},
+					{"interesting", "world"},
+				},
+			},
+		},
+		{
+			t: tNone,
+			q: query{
+				key:      "hello2",
+				opts:     []metaclient.OpOption{metaclient.WithRange("Z")},
+				expected: []kv{},
+			},
+		},
+		{
+			t: tNone,
+			q: query{
+				key:  "hello",
+				opts: []metaclient.OpOption{metaclient.WithPrefix()},
+				expected: []kv{
+					{"hello1", "world1"},
+					{"hello2", "world2"},
+				},
+			},
+		},
+		{
+			t: tNone,
+			q: query{
+				key:  "hello",
+				opts: []metaclient.OpOption{metaclient.WithPrefix(), metaclient.WithLimit(1)},
+				expected: []kv{
+					{"hello1", "world1"},
+				},
+			},
+		},
+		{
+			t: tNone,
+			q: query{
+				key:  "Hello",
+				opts: []metaclient.OpOption{metaclient.WithFromKey()},
+				expected: []kv{
+					{"TiDB", "component"},
+					{"dataflow", "engine"},
+					{"hello1", "world1"},
+					{"hello2", "world2"},
+					{"interesting", "world"},
+				},
+			},
+		},
+		{
+			t: tNone,
+			q: query{
+				key:  "Hello",
+				opts: []metaclient.OpOption{metaclient.WithFromKey(), metaclient.WithLimit(2)},
+				expected: []kv{
+					{"TiDB", "component"},
+					{"dataflow", "engine"},
+				},
+			},
+		},
+		{
+			t: tNone,
+			q: query{
+				key:  "hello",
+				opts: []metaclient.OpOption{metaclient.WithPrefix(), metaclient.WithFromKey()},
+				err:  cerrors.ErrMetaOptionInvalid,
+			},
+		},
+		{
+			t:    tDel,
+			do:   kv{"hello", ""},
+			opts: []metaclient.OpOption{},
+			q: query{
+				key:  "hello",
+				opts: []metaclient.OpOption{metaclient.WithPrefix()},
+				expected: []kv{
+					{"hello1", "world1"},
+					{"hello2", "world2"},
+				},
+			},
+		},
+		{
+			t:    tDel,
+			do:   kv{"hello", ""},
+			opts: []metaclient.OpOption{metaclient.WithPrefix()},
+			q: query{
+				key:  "",
+				opts: []metaclient.OpOption{metaclient.WithFromKey()},
+				expected: []kv{
+					{"TiDB", "component"},
+					{"dataflow", "engine"},
+					{"interesting", "world"},
+				},
+			},
+		},
+		{
+			t:    tDel,
+			do:   kv{"AZ", ""},
+			opts: []metaclient.OpOption{metaclient.WithRange("Titan")},
+			q: query{
+				key:  "",
+				opts: []metaclient.OpOption{metaclient.WithFromKey()},
+				expected: []kv{
+					{"dataflow", "engine"},
+					{"interesting", "world"},
+				},
+			},
+		},
+		{
+			t:    tDel,
+			do:   kv{"egg", ""},
+			opts: []metaclient.OpOption{metaclient.WithFromKey()},
+			q: query{
+				key:  "",
+				opts: []metaclient.OpOption{metaclient.WithFromKey()},
+				expected: []kv{
+					{"dataflow", "engine"},
+				},
+			},
+		},
+	}
+
+	// test get key range(WithRange/WithPrefix/WithFromKey) and limit
+	prepareData(ctx, t, cli, input)
+	testAction(ctx, t, cli, actions)
+}
+
 func testGenerator(t *testing.T, kvcli metaclient.KVClient) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -195,6 +366,39 @@ func TestGenEpoch(t *testing.T) {
 	testGenerator(t, cli)
 }
 
+func TestMockDo(t *testing.T) {
+	t.Parallel()
+
+	cli := NewMetaMock()
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	opRsp, err := cli.Do(ctx, metaclient.OpPut("key1", "value1"))
+	require.Nil(t, err)
+	require.NotNil(t, opRsp.Put())
+	putRsp, err := cli.Put(ctx, "key2", "value2")
+	require.Nil(t, err)
+	require.Equal(t, putRsp, opRsp.Put())
+
+	opRsp, err = cli.Do(ctx, metaclient.OpGet("key", metaclient.WithPrefix()))
+	require.Nil(t, err)
+	getRsp, err := cli.Get(ctx, "key", metaclient.WithPrefix())
+	require.Nil(t, err)
+	require.Len(t, getRsp.Kvs, 2)
+	require.Equal(t, getRsp, opRsp.Get())
+
+	opRsp, err = cli.Do(ctx, metaclient.OpDelete("key1"))
+	require.Nil(t, err)
+	require.NotNil(t, opRsp.Del())
+	getRsp, err = cli.Get(ctx, "key1")
+	require.Nil(t, err)
+	require.Len(t, getRsp.Kvs, 0)
+
+	_, err = cli.Do(ctx, metaclient.EmptyOp)
+	require.Error(t, err)
+}
+
 func TestMockTxn(t *testing.T) {
 	t.Parallel()
 
@@ -240,3 +444,194 @@ func TestMockTxn(t *testing.T) {
 	require.Nil(t, rsp)
 	require.Error(t, err)
 }
+
+func TestMockTxnCanceled(t *testing.T) {
+	t.Parallel()
+
+	cli := NewMetaMock()
+	defer cli.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	txn := cli.Txn(ctx)
+	txn.Do(metaclient.OpPut("key1", "value1"))
+	txn.Do(metaclient.OpPut("key2", "value2"))
+	rsp, err := txn.Commit()
+	require.Nil(t, rsp)
+	require.Error(t, err)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// nothing should be written
+	for _, key := range []string{"key1", "key2"} {
+		getRsp, err := cli.Get(context.Background(), key)
+		require.Nil(t, err)
+		require.Len(t, getRsp.Kvs, 0)
+	}
+}
+
+func TestMockTxnCompareAndSwap(t *testing.T) {
+	t.Parallel()
+
+	cli := NewMetaMock()
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// the key doesn't exist, create it
+	txnRsp, err := cli.Txn(ctx).If(metaclient.CmpKeyNotExists("key1")).
+		Do(metaclient.OpPut("key1", "value1")).
+		Else(metaclient.OpGet("key1")).Commit()
+	require.Nil(t, err)
+	require.True(t, txnRsp.Succeeded)
+	require.NotNil(t, txnRsp.Responses[0].GetResponsePut())
+
+	// the key exists now, the else branch is taken
+	txnRsp, err = cli.Txn(ctx).If(metaclient.CmpKeyNotExists("key1")).
+		Do(metaclient.OpPut("key1", "value2")).
+		Else(metaclient.OpGet("key1")).Commit()
+	require.Nil(t, err)
+	require.False(t, txnRsp.Succeeded)
+	getRsp := txnRsp.Responses[0].GetResponseGet()
+	require.Len(t, getRsp.Kvs, 1)
+	require.Equal(t, "value1", string(getRsp.Kvs[0].Value))
+	modRev := getRsp.Kvs[0].ModRevision
+	require.Greater(t, modRev, int64(0))
+
+	// swap the value only if it is unchanged
+	txnRsp, err = cli.Txn(ctx).If(metaclient.CmpValueEqual("key1", "value1")).
+		Do(metaclient.OpPut("key1", "value2")).Commit()
+	require.Nil(t, err)
+	require.True(t, txnRsp.Succeeded)
+	txnRsp, err = cli.Txn(ctx).If(metaclient.CmpValueEqual("key1", "value1")).
+		Do(metaclient.OpPut("key1", "value3")).Commit()
+	require.Nil(t, err)
+	require.False(t, txnRsp.Succeeded)
+	require.Len(t, txnRsp.Responses, 0)
+
+	// the mod revision is changed by the last swap
+	txnRsp, err = cli.Txn(ctx).If(metaclient.CmpModRevisionEqual("key1", modRev)).
+		Do(metaclient.OpPut("key1", "value3")).Commit()
+	require.Nil(t, err)
+	require.False(t, txnRsp.Succeeded)
+	rsp, err := cli.Get(ctx, "key1")
+	require.Nil(t, err)
+	require.Equal(t, "value2", string(rsp.Kvs[0].Value))
+	txnRsp, err = cli.Txn(ctx).If(
+		metaclient.CmpKeyExists("key1"),
+		metaclient.CmpModRevisionEqual("key1", rsp.Kvs[0].ModRevision),
+	).Do(metaclient.OpDelete("key1")).Commit()
+	require.Nil(t, err)
+	require.True(t, txnRsp.Succeeded)
+	rsp, err = cli.Get(ctx, "key1")
+	require.Nil(t, err)
+	require.Len(t, rsp.Kvs, 0)
+}
+
+func TestMockModRevision(t *testing.T) {
+	t.Parallel()
+
+	cli := NewMetaMock()
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	getModRevision := func() int64 {
+		rsp, err := cli.Get(ctx, "key1")
+		require.Nil(t, err)
+		require.Len(t, rsp.Kvs, 1)
+		return rsp.Kvs[0].ModRevision
+	}
+
+	_, err := cli.Put(ctx, "key1", "value1")
+	require.Nil(t, err)
+	rev1 := getModRevision()
+	require.Greater(t, rev1, int64(0))
+	// reads don't change the revision
+	require.Equal(t, rev1, getModRevision())
+	opRsp, err := cli.Do(ctx, metaclient.OpGet("key1"))
+	require.Nil(t, err)
+	require.Equal(t, rev1, opRsp.Get().Kvs[0].ModRevision)
+	txnRsp, err := cli.Txn(ctx).Do(metaclient.OpGet("key1")).Commit()
+	require.Nil(t, err)
+	require.Equal(t, rev1, txnRsp.Responses[0].GetResponseGet().Kvs[0].ModRevision)
+
+	_, err = cli.Put(ctx, "key1", "value2")
+	require.Nil(t, err)
+	rev2 := getModRevision()
+	require.Equal(t, rev1+1, rev2)
+	require.Equal(t, rev2, getModRevision())
+
+	// modifying other keys doesn't change the revision of key1
+	_, err = cli.Put(ctx, "key2", "value2")
+	require.Nil(t, err)
+	_, err = cli.Delete(ctx, "key2")
+	require.Nil(t, err)
+	require.Equal(t, rev2, getModRevision())
+}
+
+func TestMockValueCodec(t *testing.T) {
+	t.Parallel()
+
+	cli := NewMetaMockWithCodec(metaclient.CodecNone)
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	large := strings.Repeat("large value;", 32*1024)
+	codecs := []metaclient.ValueCodec{metaclient.CodecIdentity, metaclient.CodecGzip, metaclient.CodecSnappy}
+	for _, codec := range codecs {
+		cli.codec = codec
+		key := "codec-" + codec.String()
+		_, err := cli.Put(ctx, key, large)
+		require.Nil(t, err)
+		rsp, err := cli.Get(ctx, key)
+		require.Nil(t, err)
+		require.Len(t, rsp.Kvs, 1)
+		require.Equal(t, large, string(rsp.Kvs[0].Value))
+
+		stored := cli.store[key]
+		header := metaclient.EncodeValue(metaclient.CodecIdentity, nil)
+		header[len(header)-1] = byte(codec)
+		require.True(t, strings.HasPrefix(stored, string(header)))
+		if codec != metaclient.CodecIdentity {
+			require.Less(t, len(stored), len(large)/10)
+		}
+
+		txnKey := "codec-txn-" + codec.String()
+		txnRsp, err := cli.Txn(ctx).Do(metaclient.OpPut(txnKey, large), metaclient.OpGet(txnKey)).Commit()
+		require.Nil(t, err)
+		require.Equal(t, large, string(txnRsp.Responses[1].GetResponseGet().Kvs[0].Value))
+	}
+
+	// values written with any codec can be decoded with other codecs
+	for _, codec := range codecs {
+		cli.codec = codec
+		rsp, err := cli.Get(ctx, "codec-", metaclient.WithPrefix())
+		require.Nil(t, err)
+		require.Len(t, rsp.Kvs, 2*len(codecs))
+		for _, kv := range rsp.Kvs {
+			require.Equal(t, large, string(kv.Value))
+		}
+	}
+
+	// the client without codec also decodes the values
+	cli.codec = metaclient.CodecNone
+	rsp, err := cli.Get(ctx, "codec-", metaclient.WithPrefix())
+	require.Nil(t, err)
+	require.Len(t, rsp.Kvs, 2*len(codecs))
+	for _, kv := range rsp.Kvs {
+		require.Equal(t, large, string(kv.Value))
+	}
+
+	// values stored without codec header are read as they are
+	for _, raw := range []string{"", `{"id":"job-1"}`, string([]byte{byte(metaclient.CodecGzip), 'a'})} {
+		cli.store["raw"] = raw
+		for _, codec := range codecs {
+			cli.codec = codec
+			rsp, err := cli.Get(ctx, "raw")
+			require.Nil(t, err)
+			require.Len(t, rsp.Kvs, 1)
+			require.Equal(t, raw, string(rsp.Kvs[0].Value))
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package mock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hanfei1991/microcosm/pkg/meta/metaclient"
+)
+
+// mockWatcher buffers the events of a watch without limit, so the writers
+// of MetaMock are never blocked by a slow watcher
+type mockWatcher struct {
+	op metaclient.Op
+
+	mu      sync.Mutex
+	pending []metaclient.Event
+	notify  chan struct{}
+}
+
+func (w *mockWatcher) push(ev metaclient.Event) {
+	w.mu.Lock()
+	w.pending = append(w.pending, ev)
+	w.mu.Unlock()
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (w *mockWatcher) run(ctx context.Context, ch chan<- metaclient.Event) {
+	defer close(ch)
+	for {
+		w.mu.Lock()
+		events := w.pending
+		w.pending = nil
+		w.mu.Unlock()
+
+		for _, ev := range events {
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-w.notify:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Watch watches the changes of key, the same options as Get are supported to
+// watch a range of keys. The events are sent to the returned channel in the
+// order of the changes, and the channel is closed when ctx is done.
+func (m *MetaMock) Watch(ctx context.Context, key string, opts ...metaclient.OpOption) <-chan metaclient.Event {
+	w := &mockWatcher{
+		op:     metaclient.OpGet(key, opts...),
+		notify: make(chan struct{}, 1),
+	}
+	m.Lock()
+	m.watchers[w] = struct{}{}
+	m.Unlock()
+
+	ch := make(chan metaclient.Event)
+	go func() {
+		w.run(ctx, ch)
+
+		m.Lock()
+		delete(m.watchers, w)
+		m.Unlock()
+	}()
+	return ch
+}
+
+func (m *MetaMock) notifyNoLock(ev metaclient.Event) {
+	for w := range m.watchers {
+		if inRange(w.op, string(ev.Kv.Key)) {
+			w.push(ev)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package mock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hanfei1991/microcosm/pkg/meta/metaclient"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockWatch(t *testing.T) {
+	t.Parallel()
+
+	cli := NewMetaMockWithCodec(metaclient.CodecSnappy)
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	recv := func(ch <-chan metaclient.Event) metaclient.Event {
+		select {
+		case ev, ok := <-ch:
+			require.True(t, ok)
+			return ev
+		case <-time.After(time.Second):
+			require.FailNow(t, "no event received")
+		}
+		return metaclient.Event{}
+	}
+
+	watchCtx, watchCancel := context.WithCancel(ctx)
+	prefixCh := cli.Watch(watchCtx, "watch/", metaclient.WithPrefix())
+	exactCh := cli.Watch(watchCtx, "watch/key2")
+
+	_, err := cli.Put(ctx, "watch/key1", "value1")
+	require.Nil(t, err)
+	_, err = cli.Put(ctx, "other", "value")
+	require.Nil(t, err)
+	_, err = cli.Delete(ctx, "watch/key1")
+	require.Nil(t, err)
+	_, err = cli.Txn(ctx).Do(metaclient.OpPut("watch/key2", "value2")).Commit()
+	require.Nil(t, err)
+
+	ev := recv(prefixCh)
+	require.Equal(t, metaclient.EventTypePut, ev.Type)
+	require.Equal(t, "watch/key1", string(ev.Kv.Key))
+	require.Equal(t, "value1", string(ev.Kv.Value))
+	putRev := ev.Kv.ModRevision
+	ev = recv(prefixCh)
+	require.Equal(t, metaclient.EventTypeDelete, ev.Type)
+	require.Equal(t, "watch/key1", string(ev.Kv.Key))
+	require.Greater(t, ev.Kv.ModRevision, putRev)
+	ev = recv(prefixCh)
+	require.Equal(t, metaclient.EventTypePut, ev.Type)
+	require.Equal(t, "watch/key2", string(ev.Kv.Key))
+
+	ev = recv(exactCh)
+	require.Equal(t, metaclient.EventTypePut, ev.Type)
+	require.Equal(t, "watch/key2", string(ev.Kv.Key))
+	require.Equal(t, "value2", string(ev.Kv.Value))
+
+	// the channels are closed after the context is canceled
+	watchCancel()
+	for _, ch := range []<-chan metaclient.Event{prefixCh, exactCh} {
+		select {
+		case _, ok := <-ch:
+			require.False(t, ok)
+		case <-time.After(time.Second):
+			require.FailNow(t, "watch channel is not closed")
+		}
+	}
+	require.Eventually(t, func() bool {
+		cli.Lock()
+		defer cli.Unlock()
+		return len(cli.watchers) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,68 @@
+package metaclient
+
+type cmpType int
+
+const (
+	// A default Cmp has cmpType 0, which is invalid.
+	cmpKeyExists cmpType = iota + 1
+	cmpKeyNotExists
+	cmpValueEqual
+	cmpModRevisionEqual
+)
+
+// Cmp is a comparison on a key, which is the condition of a transaction
+type Cmp struct {
+	T   cmpType
+	key []byte
+
+	// for value comparison
+	val []byte
+	// for mod revision comparison
+	revision int64
+}
+
+// CmpKeyExists compares true if the key exists
+func CmpKeyExists(key string) Cmp {
+	return Cmp{T: cmpKeyExists, key: []byte(key)}
+}
+
+// CmpKeyNotExists compares true if the key doesn't exist
+func CmpKeyNotExists(key string) Cmp {
+	return Cmp{T: cmpKeyNotExists, key: []byte(key)}
+}
+
+// CmpValueEqual compares true if the value of the key is val, a nonexistent
+// key compares false.
+func CmpValueEqual(key, val string) Cmp {
+	return Cmp{T: cmpValueEqual, key: []byte(key), val: []byte(val)}
+}
+
+// CmpModRevisionEqual compares true if the key is last modified at revision,
+// which is KeyValue.ModRevision got before. A nonexistent key has mod revision 0.
+func CmpModRevisionEqual(key string, revision int64) Cmp {
+	return Cmp{T: cmpModRevisionEqual, key: []byte(key), revision: revision}
+}
+
+// IsKeyExists returns true if the comparison is created by CmpKeyExists
+func (cmp Cmp) IsKeyExists() bool { return cmp.T == cmpKeyExists }
+
+// IsKeyNotExists returns true if the comparison is created by CmpKeyNotExists
+func (cmp Cmp) IsKeyNotExists() bool { return cmp.T == cmpKeyNotExists }
+
+// IsValueEqual returns true if the comparison is created by CmpValueEqual
+func (cmp Cmp) IsValueEqual() bool { return cmp.T == cmpValueEqual }
+
+// IsModRevisionEqual returns true if the comparison is created by CmpModRevisionEqual
+func (cmp Cmp) IsModRevisionEqual() bool { return cmp.T == cmpModRevisionEqual }
+
+// KeyBytes returns the key compared
+func (cmp Cmp) KeyBytes() []byte { return cmp.key }
+
+// WithKeyBytes sets the key compared
+func (cmp *Cmp) WithKeyBytes(key []byte) { cmp.key = key }
+
+// ValueBytes returns the value compared with by CmpValueEqual
+func (cmp Cmp) ValueBytes() []byte { return cmp.val }
+
+// Revision returns the revision compared with by CmpModRevisionEqual
+func (cmp Cmp) Revision() int64 { return cmp.revision }
@@ -0,0 +1,155 @@
+package metaclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+
+	cerrors "github.com/hanfei1991/microcosm/pkg/errors"
+)
+
+// ValueCodec is the codec used to encode the values stored in metastore.
+// An encoded value is prefixed with a header, which is the valueMagic followed
+// by a byte indicating its codec, so values written with different codecs can
+// be decoded by the same client. Values without the header are the ones
+// stored as they are, e.g. before the codec is enabled, and are decoded as is.
+type ValueCodec byte
+
+// Defines the supported value codecs. CodecNone disables the codec layer and
+// values are stored as they are, without a header.
+const (
+	CodecNone ValueCodec = iota
+	CodecIdentity
+	CodecGzip
+	CodecSnappy
+)
+
+// valueMagic starts the header of an encoded value. The leading NUL byte can't
+// start a JSON or text value, so it never collides with a raw value.
+var valueMagic = []byte{0x00, 'm', 'c', 'v'}
+
+// valueHeaderLen is the length of valueMagic plus the codec byte
+var valueHeaderLen = len(valueMagic) + 1
+
+var codecNames = map[ValueCodec]string{
+	CodecNone:     "",
+	CodecIdentity: "identity",
+	CodecGzip:     "gzip",
+	CodecSnappy:   "snappy",
+}
+
+// String implements fmt.Stringer
+func (c ValueCodec) String() string {
+	return codecNames[c]
+}
+
+// ParseValueCodec parses the codec name used in StoreConfigParams
+func ParseValueCodec(name string) (ValueCodec, error) {
+	for codec, n := range codecNames {
+		if n == name {
+			return codec, nil
+		}
+	}
+	return CodecNone, cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("unknown value codec " + name)
+}
+
+// EncodeValue encodes the value with codec and prepends the codec header,
+// the value is returned unchanged if codec is CodecNone.
+func EncodeValue(codec ValueCodec, val []byte) []byte {
+	switch codec {
+	case CodecGzip:
+		var buf bytes.Buffer
+		// writing to a bytes.Buffer never fails
+		buf.Write(valueMagic)
+		buf.WriteByte(byte(codec))
+		w := gzip.NewWriter(&buf)
+		_, _ = w.Write(val)
+		_ = w.Close()
+		return buf.Bytes()
+	case CodecSnappy:
+		encoded := make([]byte, valueHeaderLen+snappy.MaxEncodedLen(len(val)))
+		copy(encoded, valueMagic)
+		encoded[len(valueMagic)] = byte(codec)
+		return encoded[:valueHeaderLen+len(snappy.Encode(encoded[valueHeaderLen:], val))]
+	case CodecIdentity:
+		encoded := make([]byte, 0, valueHeaderLen+len(val))
+		encoded = append(encoded, valueMagic...)
+		encoded = append(encoded, byte(codec))
+		return append(encoded, val...)
+	default:
+		return val
+	}
+}
+
+// DecodeValue decodes a value encoded by EncodeValue with any codec, the value
+// without codec header is returned unchanged.
+func DecodeValue(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, valueMagic) {
+		return data, nil
+	}
+	if len(data) < valueHeaderLen {
+		return nil, cerrors.ErrMetaValueDecodeFail.GenWithStackByArgs("missing codec header")
+	}
+	codec, payload := ValueCodec(data[len(valueMagic)]), data[valueHeaderLen:]
+	switch codec {
+	case CodecIdentity:
+		return payload, nil
+	case CodecGzip:
+		r, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, cerrors.ErrMetaValueDecodeFail.Wrap(err)
+		}
+		defer r.Close()
+		val, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, cerrors.ErrMetaValueDecodeFail.Wrap(err)
+		}
+		return val, nil
+	case CodecSnappy:
+		val, err := snappy.Decode(nil, payload)
+		if err != nil {
+			return nil, cerrors.ErrMetaValueDecodeFail.Wrap(err)
+		}
+		return val, nil
+	default:
+		return nil, cerrors.ErrMetaValueDecodeFail.GenWithStackByArgs("unknown codec header")
+	}
+}
+
+// DecodeGetResponse decodes the values in GetResponse in place
+func DecodeGetResponse(resp *GetResponse) error {
+	for _, kv := range resp.Kvs {
+		val, err := DecodeValue(kv.Value)
+		if err != nil {
+			return err
+		}
+		kv.Value = val
+	}
+	return nil
+}
+
+// DecodeTxnResponse decodes the values of all get responses in TxnResponse in place
+func DecodeTxnResponse(resp *TxnResponse) error {
+	for _, r := range resp.Responses {
+		switch tv := r.Response.(type) {
+		case *ResponseOpResponseGet:
+			if tv.ResponseGet == nil {
+				continue
+			}
+			if err := DecodeGetResponse(tv.ResponseGet); err != nil {
+				return err
+			}
+		case *ResponseOpResponseTxn:
+			if tv.ResponseTxn == nil {
+				continue
+			}
+			if err := DecodeTxnResponse(tv.ResponseTxn); err != nil {
+				return err
+			}
+		default:
+		}
+	}
+	return nil
+}
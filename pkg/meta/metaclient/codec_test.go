@@ -0,0 +1,113 @@
+package metaclient
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueCodecRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	large := []byte(strings.Repeat("microcosm job meta;", 64*1024))
+	codecs := []ValueCodec{CodecIdentity, CodecGzip, CodecSnappy}
+	for _, codec := range codecs {
+		encoded := EncodeValue(codec, large)
+		require.Equal(t, valueMagic, encoded[:len(valueMagic)])
+		require.Equal(t, byte(codec), encoded[len(valueMagic)])
+		if codec != CodecIdentity {
+			require.Less(t, len(encoded), len(large)/10, codec.String())
+		}
+		decoded, err := DecodeValue(encoded)
+		require.Nil(t, err)
+		require.Equal(t, large, decoded)
+
+		// empty value is also encoded with a header
+		decoded, err = DecodeValue(EncodeValue(codec, nil))
+		require.Nil(t, err)
+		require.Len(t, decoded, 0)
+	}
+
+	require.Equal(t, large, EncodeValue(CodecNone, large))
+}
+
+func TestValueCodecDecodeError(t *testing.T) {
+	t.Parallel()
+
+	header := func(codec ValueCodec) []byte {
+		return append(append([]byte{}, valueMagic...), byte(codec))
+	}
+	_, err := DecodeValue(valueMagic)
+	require.Regexp(t, ".*ErrMetaValueDecodeFail.*", err)
+	_, err = DecodeValue(append(header(0xff), 'a'))
+	require.Regexp(t, ".*ErrMetaValueDecodeFail.*", err)
+	_, err = DecodeValue(append(header(CodecGzip), 'a'))
+	require.Regexp(t, ".*ErrMetaValueDecodeFail.*", err)
+	_, err = DecodeValue(append(header(CodecSnappy), 0xff))
+	require.Regexp(t, ".*ErrMetaValueDecodeFail.*", err)
+}
+
+func TestValueCodecDecodeRaw(t *testing.T) {
+	t.Parallel()
+
+	// values stored before the codec is enabled are returned as they are
+	raws := [][]byte{
+		nil,
+		{},
+		[]byte(`{"id":"job-1","epoch":1}`),
+		[]byte("plain text"),
+		{byte(CodecIdentity), 'a'},
+		{byte(CodecGzip), 'a'},
+		{byte(CodecSnappy), 0xff},
+		{0x00, 'm'},
+	}
+	for _, raw := range raws {
+		decoded, err := DecodeValue(raw)
+		require.Nil(t, err)
+		require.Equal(t, raw, decoded)
+	}
+
+	resp := &GetResponse{Kvs: []*KeyValue{
+		{Key: []byte("k1"), Value: []byte("v1")},
+		{Key: []byte("k2"), Value: EncodeValue(CodecSnappy, []byte("v2"))},
+	}}
+	require.Nil(t, DecodeGetResponse(resp))
+	require.Equal(t, []byte("v1"), resp.Kvs[0].Value)
+	require.Equal(t, []byte("v2"), resp.Kvs[1].Value)
+}
+
+func TestParseValueCodec(t *testing.T) {
+	t.Parallel()
+
+	for codec, name := range codecNames {
+		parsed, err := ParseValueCodec(name)
+		require.Nil(t, err)
+		require.Equal(t, codec, parsed)
+	}
+	_, err := ParseValueCodec("zstd")
+	require.Regexp(t, ".*ErrMetaParamsInvalid.*", err)
+}
+
+func TestDecodeTxnResponse(t *testing.T) {
+	t.Parallel()
+
+	resp := &TxnResponse{
+		Responses: []ResponseOp{
+			{Response: &ResponseOpResponsePut{ResponsePut: &PutResponse{}}},
+			{Response: &ResponseOpResponseGet{ResponseGet: &GetResponse{
+				Kvs: []*KeyValue{{Key: []byte("k1"), Value: EncodeValue(CodecGzip, []byte("v1"))}},
+			}}},
+			{Response: &ResponseOpResponseTxn{ResponseTxn: &TxnResponse{
+				Responses: []ResponseOp{
+					{Response: &ResponseOpResponseGet{ResponseGet: &GetResponse{
+						Kvs: []*KeyValue{{Key: []byte("k2"), Value: EncodeValue(CodecSnappy, []byte("v2"))}},
+					}}},
+				},
+			}}},
+		},
+	}
+	require.Nil(t, DecodeTxnResponse(resp))
+	require.Equal(t, []byte("v1"), resp.Responses[1].GetResponseGet().Kvs[0].Value)
+	require.Equal(t, []byte("v2"), resp.Responses[2].GetResponseTxn().Responses[0].GetResponseGet().Kvs[0].Value)
+}
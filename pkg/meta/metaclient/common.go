@@ -45,6 +45,8 @@ type DeleteResponse struct {
 // TxnResponse .
 type TxnResponse struct {
 	Header *ResponseHeader
+	// Succeeded is true if all the comparisons of the txn are true
+	Succeeded bool
 	// Responses is a list of responses corresponding to the results from applying
 	// success if succeeded is true or failure if succeeded is false.
 	Responses []ResponseOp
@@ -178,9 +180,46 @@ type KeyValue struct {
 	Key []byte
 	// Value is the value held by the key, in bytes.
 	Value []byte
+	// ModRevision is the revision of the last modification of the key, it
+	// can be used by CmpModRevisionEqual to compare-and-swap the key.
+	ModRevision int64
+	// Lease is the id of the lease attached to the key, 0 means no lease.
+	Lease int64
+	// TTL is the remaining time-to-live of the lease in seconds, it is -1
+	// if the lease has expired. It is only filled by the get operations out
+	// of transactions, and is 0 if the key has no lease.
+	TTL int64
 }
 
 // String only for debug
 func (kv *KeyValue) String() string {
 	return fmt.Sprintf("key:%s, value:%s;", string(kv.Key), string(kv.Value))
 }
+
+// EventType is the type of the change of a key
+type EventType int
+
+// event types
+const (
+	EventTypePut EventType = iota
+	EventTypeDelete
+)
+
+// String implements fmt.Stringer
+func (t EventType) String() string {
+	switch t {
+	case EventTypePut:
+		return "PUT"
+	case EventTypeDelete:
+		return "DELETE"
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Event is a change of a key got by watching the key
+type Event struct {
+	Type EventType
+	// Kv holds the key and its new value after the change. For a delete
+	// event, only Key and ModRevision(the revision of the deletion) are set.
+	Kv *KeyValue
+}
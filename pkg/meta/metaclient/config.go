@@ -26,6 +26,9 @@ type StoreConfigParams struct {
 	// TODO: replace the slice when we migrate to db
 	Endpoints []string       `toml:"endpoints" json:"endpoints"`
 	Auth      AuthConfParams `toml:"auth" json:"auth"`
+	// ValueCodec is the codec used to compress values, one of "identity",
+	// "gzip" and "snappy". Empty means values are stored as they are.
+	ValueCodec string `toml:"value-codec" json:"value-codec"`
 }
 
 // SetEndpoints sets endpoints to StoreConfigParams
@@ -11,6 +11,14 @@ type Txn interface {
 	// Using snapshot isolation
 	Do(ops ...Op) Txn
 
+	// If caches the comparisons in the Txn, the Ops passed to Do are applied
+	// if all the comparisons are true, otherwise the Ops passed to Else are
+	// applied. A Txn without comparisons always applies the Ops passed to Do.
+	If(cmps ...Cmp) Txn
+
+	// Else caches the Ops applied if any comparison passed to If is false
+	Else(ops ...Op) Txn
+
 	// Commit tries to commit the transaction.
 	// Any Op fail will cause entire txn rollback and return error
 	Commit() (*TxnResponse, Error)
@@ -42,6 +42,9 @@ type Op struct {
 	// for put
 	val []byte
 
+	// for get
+	limit int64
+
 	// txn
 	ops []Op
 
@@ -94,6 +97,9 @@ func (op *Op) WithRangeBytes(end []byte) { op.end = end }
 // ValueBytes returns the byte slice holding the Op's value, if any.
 func (op Op) ValueBytes() []byte { return op.val }
 
+// Limit returns the max number of the keys returned by the get Op, 0 means no limit.
+func (op Op) Limit() int64 { return op.limit }
+
 // NewOp creates a new op instance
 func NewOp() *Op {
 	return &Op{key: []byte("")}
@@ -241,3 +247,12 @@ func WithFromKey() OpOption {
 		op.isOptsWithFromKey = true
 	}
 }
+
+// WithLimit limits the number of keys returned by 'Get' request to limit,
+// the keys are returned in ascending order. 0 means no limit.
+// It has no effect on the other requests.
+func WithLimit(limit int64) OpOption {
+	return func(op *Op) {
+		op.limit = limit
+	}
+}
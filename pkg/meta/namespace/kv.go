@@ -120,6 +120,21 @@ func (txn *txnPrefix) Do(ops ...metaclient.Op) metaclient.Txn {
 	return txn
 }
 
+func (txn *txnPrefix) If(cmps ...metaclient.Cmp) metaclient.Txn {
+	pfxCmps := make([]metaclient.Cmp, 0, len(cmps))
+	for _, cmp := range cmps {
+		cmp.WithKeyBytes([]byte(txn.kv.pfx + string(cmp.KeyBytes())))
+		pfxCmps = append(pfxCmps, cmp)
+	}
+	txn.Txn = txn.Txn.If(pfxCmps...)
+	return txn
+}
+
+func (txn *txnPrefix) Else(ops ...metaclient.Op) metaclient.Txn {
+	txn.Txn = txn.Txn.Else(txn.kv.prefixOps(ops)...)
+	return txn
+}
+
 func (txn *txnPrefix) Commit() (*metaclient.TxnResponse, metaclient.Error) {
 	resp, err := txn.Txn.Commit()
 	if err != nil {
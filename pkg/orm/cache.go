@@ -0,0 +1,80 @@
+package orm
+
+import (
+	"container/list"
+	"sync"
+)
+
+const defaultJobConfigCacheSize = 1024
+
+type jobConfigEntry struct {
+	jobID  string
+	config []byte
+}
+
+// jobConfigCache is a LRU cache of job config blobs keyed by jobID.
+// Each invalidation bumps the generation, so that a config loaded before
+// an invalidation won't be put into the cache after the invalidation.
+// Writers should invalidate after the write is done, otherwise a concurrent
+// reader may cache the config loaded between the invalidation and the write.
+type jobConfigCache struct {
+	mu         sync.Mutex
+	capacity   int
+	generation uint64
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+func newJobConfigCache(capacity int) *jobConfigCache {
+	return &jobConfigCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached config of jobID and the current generation
+func (c *jobConfigCache) get(jobID string) ([]byte, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[jobID]
+	if !ok {
+		return nil, c.generation, false
+	}
+	c.ll.MoveToFront(elem)
+	return elem.Value.(*jobConfigEntry).config, c.generation, true
+}
+
+// put caches the config if no invalidation happens after `generation`
+func (c *jobConfigCache) put(jobID string, config []byte, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if elem, ok := c.items[jobID]; ok {
+		c.ll.MoveToFront(elem)
+		elem.Value.(*jobConfigEntry).config = config
+		return
+	}
+	c.items[jobID] = c.ll.PushFront(&jobConfigEntry{jobID: jobID, config: config})
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*jobConfigEntry).jobID)
+	}
+}
+
+// invalidate removes the cached config of jobID
+func (c *jobConfigCache) invalidate(jobID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if elem, ok := c.items[jobID]; ok {
+		c.ll.Remove(elem)
+		delete(c.items, jobID)
+	}
+}
@@ -0,0 +1,44 @@
+package orm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobConfigCache(t *testing.T) {
+	t.Parallel()
+
+	cache := newJobConfigCache(2)
+	_, gen, ok := cache.get("j1")
+	require.False(t, ok)
+	cache.put("j1", []byte("c1"), gen)
+	config, _, ok := cache.get("j1")
+	require.True(t, ok)
+	require.Equal(t, []byte("c1"), config)
+
+	// config loaded before invalidation is not cached
+	_, gen, ok = cache.get("j2")
+	require.False(t, ok)
+	cache.invalidate("j2")
+	cache.put("j2", []byte("stale"), gen)
+	_, _, ok = cache.get("j2")
+	require.False(t, ok)
+
+	// evict the least recently used entry
+	_, gen, _ = cache.get("j1")
+	cache.put("j2", []byte("c2"), gen)
+	_, _, ok = cache.get("j1")
+	require.True(t, ok)
+	cache.put("j3", []byte("c3"), gen)
+	_, _, ok = cache.get("j2")
+	require.False(t, ok)
+	_, _, ok = cache.get("j1")
+	require.True(t, ok)
+	_, _, ok = cache.get("j3")
+	require.True(t, ok)
+
+	cache.invalidate("j1")
+	_, _, ok = cache.get("j1")
+	require.False(t, ok)
+}
@@ -3,13 +3,19 @@ package orm
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"regexp"
 	"time"
 
 	dmysql "github.com/go-sql-driver/mysql"
 	"github.com/pingcap/log"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
@@ -18,6 +24,7 @@ import (
 	resourcemeta "github.com/hanfei1991/microcosm/pkg/externalresource/resourcemeta/model"
 	"github.com/hanfei1991/microcosm/pkg/meta/metaclient"
 	"github.com/hanfei1991/microcosm/pkg/orm/model"
+	"github.com/hanfei1991/microcosm/pkg/p2p"
 	"github.com/hanfei1991/microcosm/pkg/tenant"
 )
 
@@ -27,11 +34,18 @@ var globalModels = []interface{}{
 	&libModel.MasterMetaKVData{},
 	&libModel.WorkerStatus{},
 	&resourcemeta.ResourceMeta{},
-	&model.LogicEpoch{},
+	&model.Counter{},
+	&model.JobLabel{},
+	&model.SchemaVersion{},
 }
 
-// TODO: retry and idempotent??
-// TODO: context control??
+// defaultProjectOperationBatchSize is the count of project operations loaded
+// in one batch by IterateProjectOperations
+const defaultProjectOperationBatchSize = 1024
+
+// defaultInClauseChunkSize is the max count of values in one IN clause, a
+// longer list of values is split into multiple queries
+const defaultInClauseChunkSize = 512
 
 // TimeRange defines a time range with [start, end] time
 type TimeRange struct {
@@ -39,6 +53,33 @@ type TimeRange struct {
 	end   time.Time
 }
 
+// JobUpdate defines the columns of a job that can be updated partially.
+// Only the non-nil fields will be written to metastore.
+type JobUpdate struct {
+	Status *libModel.MasterStatusCode
+	Addr   *string
+	NodeID *p2p.NodeID
+	Epoch  *libModel.Epoch
+}
+
+// columns returns the column-value map of the non-nil fields
+func (u JobUpdate) columns() map[string]interface{} {
+	values := make(map[string]interface{}, 4)
+	if u.Status != nil {
+		values["status"] = *u.Status
+	}
+	if u.Addr != nil {
+		values["address"] = *u.Addr
+	}
+	if u.NodeID != nil {
+		values["node_id"] = *u.NodeID
+	}
+	if u.Epoch != nil {
+		values["epoch"] = *u.Epoch
+	}
+	return values
+}
+
 // Client defines an interface that has the ability to manage every kind of
 // logic abstraction in metastore, including project, project op, job, worker
 // and resource
@@ -54,38 +95,110 @@ type Client interface {
 	WorkerClient
 	// resource meta
 	ResourceClient
+	// named counter
+	CounterClient
 
 	// Initialize will create all tables for backend operation
 	Initialize(ctx context.Context) error
+
+	// Ping checks whether the connection to the metastore is alive
+	Ping(ctx context.Context) error
+
+	// PoolStats returns the statistics of the connection pool to the
+	// metastore, such as the in-use and idle connections and the waits for
+	// a free connection
+	PoolStats() sql.DBStats
+
+	// GetEpoch returns the current logic epoch, which is the epoch returned
+	// by the latest GenEpoch, or the initial epoch if GenEpoch has never been
+	// called. Unlike GenEpoch, it does NOT advance the epoch, so it can be
+	// used to inspect the epoch without consuming one.
+	GetEpoch(ctx context.Context) (libModel.Epoch, error)
+
+	// Transaction runs fn in a transaction, the operations of the Client
+	// passed to fn are all executed in the transaction. The transaction is
+	// committed if fn returns nil, otherwise it is rolled back and the error
+	// of fn is returned. The Client passed to fn must not be used after fn
+	// returns, nor be used concurrently.
+	Transaction(ctx context.Context, fn func(tx Client) error) error
+}
+
+// CounterClient defines interface that manages named counters in metastore
+type CounterClient interface {
+	// GenNamedCounter increases the counter `name` by 1 and returns the new
+	// value, counters with different names advance independently
+	GenNamedCounter(ctx context.Context, name string) (int64, error)
 }
 
 // ProjectClient defines interface that manages project in metastore
 type ProjectClient interface {
 	CreateProject(ctx context.Context, project *model.ProjectInfo) error
-	DeleteProject(ctx context.Context, projectID string) error
+	// DeleteProject deletes the project, the returned Result tells whether
+	// the project existed
+	DeleteProject(ctx context.Context, projectID string) (Result, error)
 	QueryProjects(ctx context.Context) ([]*model.ProjectInfo, error)
+	// QueryProjectsWithPagination is like QueryJobsWithPagination, for projects
+	QueryProjectsWithPagination(ctx context.Context, offset, limit int) ([]*model.ProjectInfo, int64, error)
+	QueryProjectsWithStats(ctx context.Context) ([]*model.ProjectStats, error)
 	GetProjectByID(ctx context.Context, projectID string) (*model.ProjectInfo, error)
 }
 
 // ProjectOperationClient defines interface that manages project operation in metastore
 // TODO: support pagination and cursor here
-// support `order by time desc limit N`
 type ProjectOperationClient interface {
 	CreateProjectOperation(ctx context.Context, op *model.ProjectOperation) error
 	QueryProjectOperations(ctx context.Context, projectID string) ([]*model.ProjectOperation, error)
+	QueryRecentProjectOperations(ctx context.Context, projectID string, n int) ([]*model.ProjectOperation, error)
 	QueryProjectOperationsByTimeRange(ctx context.Context, projectID string, tr TimeRange) ([]*model.ProjectOperation, error)
+	// IterateProjectOperations calls fn with each operation of projectID in
+	// chronological order, the operations are loaded in batches. An error
+	// returned by fn stops the iteration and is returned as it is.
+	IterateProjectOperations(ctx context.Context, projectID string, fn func(*model.ProjectOperation) error) error
 }
 
 // JobClient defines interface that manages job in metastore
 type JobClient interface {
 	UpsertJob(ctx context.Context, job *libModel.MasterMetaKVData) error
-	UpdateJob(ctx context.Context, job *libModel.MasterMetaKVData) error
+	UpdateJob(ctx context.Context, job *libModel.MasterMetaKVData, expectedEpoch libModel.Epoch) error
+	UpdateJobFields(ctx context.Context, jobID string, opts JobUpdate) error
 	DeleteJob(ctx context.Context, jobID string) (Result, error)
 
 	GetJobByID(ctx context.Context, jobID string) (*libModel.MasterMetaKVData, error)
+	JobExists(ctx context.Context, jobID string) (bool, error)
+	GetJobConfig(ctx context.Context, jobID string, v interface{}) error
 	QueryJobs(ctx context.Context) ([]*libModel.MasterMetaKVData, error)
+	// QueryJobsWithPagination returns at most limit jobs starting from offset
+	// in the order of creation, and the total count of jobs. A non-positive
+	// limit means no limit.
+	QueryJobsWithPagination(ctx context.Context, offset, limit int) ([]*libModel.MasterMetaKVData, int64, error)
 	QueryJobsByProjectID(ctx context.Context, projectID string) ([]*libModel.MasterMetaKVData, error)
+	// QueryLatestJobByProjectID returns the most recently updated job of
+	// projectID, or ErrMetaEntryNotFound if the project has no job
+	QueryLatestJobByProjectID(ctx context.Context, projectID string) (*libModel.MasterMetaKVData, error)
+	// QueryJobsUpdatedAfter returns the jobs updated after t in the order of
+	// updated_at, it is used to sync the changes of jobs incrementally.
+	QueryJobsUpdatedAfter(ctx context.Context, t time.Time) ([]*libModel.MasterMetaKVData, error)
+	// QueryJobsInBatches calls fn with at most batchSize jobs each time until
+	// all jobs are visited, the slice passed to fn is reused between calls.
+	QueryJobsInBatches(ctx context.Context, batchSize int, fn func([]*libModel.MasterMetaKVData) error) error
 	QueryJobsByStatus(ctx context.Context, jobID string, status int) ([]*libModel.MasterMetaKVData, error)
+	// QueryJobsByStatusOnly returns all the jobs with `status`
+	QueryJobsByStatusOnly(ctx context.Context, status int) ([]*libModel.MasterMetaKVData, error)
+	// CountJobs returns the count of all jobs without loading them
+	CountJobs(ctx context.Context) (int64, error)
+	CountJobsByStatus(ctx context.Context) (map[int]int64, error)
+	CountProjectJobsByStatus(ctx context.Context, projectID string) (map[int]int64, error)
+	QueryJobTerminalStates(ctx context.Context) (map[string]bool, error)
+	// ListActiveProjectIDs returns the distinct ids of the projects which have
+	// jobs in one of libModel.ActiveMasterStatuses
+	ListActiveProjectIDs(ctx context.Context) ([]string, error)
+	// MoveJobToProject moves the job with its workers and resources to
+	// newProjectID, and records a move operation in newProjectID atomically
+	MoveJobToProject(ctx context.Context, jobID, newProjectID string) error
+
+	SetJobLabels(ctx context.Context, jobID string, labels map[string]string) error
+	GetJobLabels(ctx context.Context, jobID string) (map[string]string, error)
+	QueryJobsByLabel(ctx context.Context, key, value string) ([]*libModel.MasterMetaKVData, error)
 }
 
 // WorkerClient defines interface that manages worker in metastore
@@ -93,9 +206,33 @@ type WorkerClient interface {
 	UpsertWorker(ctx context.Context, worker *libModel.WorkerStatus) error
 	UpdateWorker(ctx context.Context, worker *libModel.WorkerStatus) error
 	DeleteWorker(ctx context.Context, masterID string, workerID string) (Result, error)
+	// DeleteWorkersByMasterID deletes all the workers of masterID in one
+	// statement, and returns the count of deleted workers.
+	DeleteWorkersByMasterID(ctx context.Context, masterID string) (int64, error)
 	GetWorkerByID(ctx context.Context, masterID string, workerID string) (*libModel.WorkerStatus, error)
+	WorkerExists(ctx context.Context, masterID string, workerID string) (bool, error)
 	QueryWorkersByMasterID(ctx context.Context, masterID string) ([]*libModel.WorkerStatus, error)
+	// CountWorkersByMasterID returns the count of workers of masterID without loading them
+	CountWorkersByMasterID(ctx context.Context, masterID string) (int64, error)
 	QueryWorkersByStatus(ctx context.Context, masterID string, status int) ([]*libModel.WorkerStatus, error)
+	// QueryWorkersInBatches is like QueryJobsInBatches, for the workers of masterID
+	QueryWorkersInBatches(ctx context.Context, masterID string, batchSize int, fn func([]*libModel.WorkerStatus) error) error
+	// WatchWorkers returns the current workers of the master, and a channel
+	// delivering the subsequent worker statuses written through this client.
+	// The channel is closed when ctx is done, the client is closed, or the
+	// receiver falls too far behind, the caller can watch again to resync.
+	WatchWorkers(ctx context.Context, masterID string) ([]*libModel.WorkerStatus, <-chan *libModel.WorkerStatus, error)
+	// ClaimWorker makes ownerID the owner of the worker for ttl if the
+	// worker is not owned by others or the lease of the owner has expired,
+	// and returns whether the claim succeeded. Claiming a worker owned by
+	// ownerID itself succeeds and extends the lease.
+	ClaimWorker(ctx context.Context, masterID, workerID, ownerID string, ttl time.Duration) (bool, error)
+	// RenewWorkerClaim extends the lease of ownerID by ttl from now, and
+	// returns false if the worker is not owned by ownerID any more
+	RenewWorkerClaim(ctx context.Context, masterID, workerID, ownerID string, ttl time.Duration) (bool, error)
+	// ReleaseWorkerClaim gives up the claim of ownerID, and returns false if
+	// the worker is not owned by ownerID
+	ReleaseWorkerClaim(ctx context.Context, masterID, workerID, ownerID string) (bool, error)
 }
 
 // ResourceClient defines interface that manages resource in metastore
@@ -105,41 +242,98 @@ type ResourceClient interface {
 	UpdateResource(ctx context.Context, resource *resourcemeta.ResourceMeta) error
 	DeleteResource(ctx context.Context, resourceID string) (Result, error)
 	GetResourceByID(ctx context.Context, resourceID string) (*resourcemeta.ResourceMeta, error)
+	ResourceExists(ctx context.Context, resourceID string) (bool, error)
 	QueryResources(ctx context.Context) ([]*resourcemeta.ResourceMeta, error)
 	QueryResourcesByJobID(ctx context.Context, jobID string) ([]*resourcemeta.ResourceMeta, error)
+	// QueryResourcesByJobIDs returns all the resources of the jobs in jobIDs
+	QueryResourcesByJobIDs(ctx context.Context, jobIDs []string) ([]*resourcemeta.ResourceMeta, error)
+	// CountResourcesByJobID returns the count of resources of jobID without loading them
+	CountResourcesByJobID(ctx context.Context, jobID string) (int64, error)
 	QueryResourcesByExecutorID(ctx context.Context, executorID string) ([]*resourcemeta.ResourceMeta, error)
+	// QueryResourcesByDeleted returns the soft-deleted resources if deleted
+	// is true, otherwise the live ones
+	QueryResourcesByDeleted(ctx context.Context, deleted bool) ([]*resourcemeta.ResourceMeta, error)
+	// QueryResourcesByContentType returns all the resources holding the
+	// data of contentType
+	QueryResourcesByContentType(ctx context.Context, contentType string) ([]*resourcemeta.ResourceMeta, error)
+	QueryOrphanedResources(ctx context.Context) ([]*resourcemeta.ResourceMeta, error)
+	// QueryResourcesInBatches is like QueryJobsInBatches, for all resources
+	QueryResourcesInBatches(ctx context.Context, batchSize int, fn func([]*resourcemeta.ResourceMeta) error) error
 }
 
 // NewClient return the client to operate framework metastore
 func NewClient(mc metaclient.StoreConfigParams, conf DBConfig) (Client, error) {
-	err := createDatabaseForProject(mc, tenant.FrameTenantID, conf)
-	if err != nil {
+	if err := conf.validate(); err != nil {
 		return nil, err
 	}
 
-	dsn := generateDSNByParams(mc, tenant.FrameTenantID, conf, true)
-	sqlDB, err := newSQLDB("mysql", dsn, conf)
-	if err != nil {
-		return nil, err
+	var sqlDB *sql.DB
+	if conf.Backend == BackendSQLite {
+		dsn, err := generateSQLiteDSN(mc, tenant.FrameTenantID)
+		if err != nil {
+			return nil, err
+		}
+		sqlDB, err = newSQLDB(sqlite.DriverName, dsn, conf)
+		if err != nil {
+			return nil, err
+		}
+		// writes to a SQLite database are serialized, so one connection is
+		// enough and avoids 'database is locked' errors
+		sqlDB.SetMaxOpenConns(1)
+	} else {
+		err := createDatabaseForProject(mc, tenant.FrameTenantID, conf)
+		if err != nil {
+			return nil, err
+		}
+
+		dsn := generateDSNByParams(mc, tenant.FrameTenantID, conf, true)
+		sqlDB, err = newSQLDB("mysql", dsn, conf)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	cli, err := newClient(sqlDB)
+	cli, err := newClient(sqlDB, conf)
 	if err != nil {
 		sqlDB.Close()
+		return nil, err
+	}
+
+	// refuse to operate on the metastore if the schema is incompatible
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := cli.checkSchemaVersion(ctx); err != nil {
+		cli.Close()
+		return nil, err
 	}
 
-	return cli, err
+	return cli, nil
+}
+
+// projectIDPattern limits the projectID to characters which are safe to be
+// used as a schema name without quoting
+var projectIDPattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+// validateProjectID checks that projectID can be used in DDL and as DBName
+func validateProjectID(projectID tenant.ProjectID) error {
+	if !projectIDPattern.MatchString(projectID) {
+		return newMetaError(cerrors.ErrMetaOpFail, fmt.Errorf("invalid project id %q", projectID))
+	}
+	return nil
 }
 
-// TODO: check the projectID
 func createDatabaseForProject(mc metaclient.StoreConfigParams, projectID tenant.ProjectID, conf DBConfig) error {
+	if err := validateProjectID(projectID); err != nil {
+		return err
+	}
+
 	dsn := generateDSNByParams(mc, projectID, conf, false)
 	log.L().Info("mysql connection", zap.String("dsn", dsn))
 
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		log.L().Error("open dsn fail", zap.String("dsn", dsn), zap.Error(err))
-		return cerrors.ErrMetaOpFail.Wrap(err)
+		return newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 	defer db.Close()
 
@@ -148,7 +342,7 @@ func createDatabaseForProject(mc metaclient.StoreConfigParams, projectID tenant.
 	query := fmt.Sprintf("CREATE DATABASE if not exists %s", projectID)
 	_, err = db.ExecContext(ctx, query)
 	if err != nil {
-		return cerrors.ErrMetaOpFail.Wrap(err)
+		return newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return nil
@@ -176,19 +370,35 @@ func generateDSNByParams(mc metaclient.StoreConfigParams, projectID tenant.Proje
 	dsnCfg.Params["writeTimeout"] = conf.WriteTimeout
 	dsnCfg.Params["timeout"] = conf.DialTimeout
 	dsnCfg.Params["parseTime"] = "true"
-	// TODO: check for timezone
-	dsnCfg.Params["loc"] = "Local"
+	dsnCfg.Params["loc"] = conf.Timezone
+	if conf.Timezone == "" {
+		dsnCfg.Params["loc"] = defaultTimezone
+	}
 
 	// dsn format: [username[:password]@][protocol[(address)]]/
 	return dsnCfg.FormatDSN()
 }
 
+// generateSQLiteDSN uses the file named by projectID in the directory of
+// the first endpoint as the database, to achieve isolation
+func generateSQLiteDSN(mc metaclient.StoreConfigParams, projectID tenant.ProjectID) (string, error) {
+	if err := validateProjectID(projectID); err != nil {
+		return "", err
+	}
+	if len(mc.Endpoints) == 0 {
+		return "", cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("no directory for the sqlite backend")
+	}
+
+	return fmt.Sprintf("file:%s?_busy_timeout=%d",
+		filepath.Join(mc.Endpoints[0], projectID+".db"), defaultSQLiteBusyTimeout.Milliseconds()), nil
+}
+
 // newSqlDB return sql.DB for specified driver and dsn
 func newSQLDB(driver string, dsn string, conf DBConfig) (*sql.DB, error) {
 	db, err := sql.Open(driver, dsn)
 	if err != nil {
 		log.L().Error("open dsn fail", zap.String("dsn", dsn), zap.Any("config", conf), zap.Error(err))
-		return nil, cerrors.ErrMetaOpFail.Wrap(err)
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	db.SetConnMaxIdleTime(conf.ConnMaxIdleTime)
@@ -198,13 +408,19 @@ func newSQLDB(driver string, dsn string, conf DBConfig) (*sql.DB, error) {
 	return db, nil
 }
 
-func newClient(sqlDB *sql.DB) (*metaOpsClient, error) {
-	db, err := gorm.Open(mysql.New(mysql.Config{
-		Conn:                      sqlDB,
-		SkipInitializeWithVersion: false,
-	}), &gorm.Config{
+func newClient(sqlDB *sql.DB, conf DBConfig) (*metaOpsClient, error) {
+	var dialector gorm.Dialector
+	if conf.Backend == BackendSQLite {
+		dialector = sqlite.Dialector{Conn: sqlDB}
+	} else {
+		dialector = mysql.New(mysql.Config{
+			Conn:                      sqlDB,
+			SkipInitializeWithVersion: false,
+		})
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
 		SkipDefaultTransaction: true,
-		// TODO: logger
+		Logger:                 newGormLogger(log.L(), conf),
 	})
 	if err != nil {
 		log.L().Error("create gorm client fail", zap.Error(err))
@@ -212,7 +428,10 @@ func newClient(sqlDB *sql.DB) (*metaOpsClient, error) {
 	}
 
 	return &metaOpsClient{
-		db: db,
+		db:             db,
+		configCache:    newJobConfigCache(defaultJobConfigCacheSize),
+		workerWatchers: newWorkerWatchers(),
+		retryPolicy:    newRetryPolicy(conf),
 	}, nil
 }
 
@@ -220,36 +439,220 @@ func newClient(sqlDB *sql.DB) (*metaOpsClient, error) {
 type metaOpsClient struct {
 	// gorm claim to be thread safe
 	db *gorm.DB
+	// configCache caches the config of jobs, it is invalidated by job
+	// modifications through this client
+	configCache *jobConfigCache
+	// workerWatchers dispatches worker statuses written through this client
+	// to the watchers
+	workerWatchers *workerWatchers
+	// retryPolicy decides how the operations are retried on transient errors
+	retryPolicy retryPolicy
+	// initialized is set once the seed rows are known to exist, either
+	// created by Initialize of this client or found in the backend
+	initialized atomic.Bool
+	// txn is only set for the client bound to a transaction, the side effects
+	// on configCache and workerWatchers are deferred until it commits
+	txn *txnEffects
+}
+
+// txnEffects records the side effects of the operations in a transaction
+type txnEffects struct {
+	modifiedJobs []string
+	workers      []*libModel.WorkerStatus
 }
 
 func (c *metaOpsClient) Close() error {
+	if c.txn != nil {
+		// the connections are owned by the parent client
+		return nil
+	}
+	c.workerWatchers.close()
 	impl, err := c.db.DB()
 	if err != nil {
 		return err
 	}
 	if impl != nil {
-		return cerrors.ErrMetaOpFail.Wrap(impl.Close())
+		return newMetaError(cerrors.ErrMetaOpFail, impl.Close())
 	}
 
 	return nil
 }
 
+// Ping implements Client.Ping
+func (c *metaOpsClient) Ping(ctx context.Context) error {
+	impl, err := c.db.DB()
+	if err != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return newMetaError(cerrors.ErrMetaOpFail, impl.PingContext(ctx))
+}
+
+// PoolStats implements Client.PoolStats
+func (c *metaOpsClient) PoolStats() sql.DBStats {
+	impl, err := c.db.DB()
+	if err != nil {
+		// the client bound to a transaction has no connection pool
+		return sql.DBStats{}
+	}
+
+	return impl.Stats()
+}
+
 ////////////////////////// Initialize
 // Initialize will create all related tables in SQL backend
 // TODO: What happen if we upgrade the definition of model when rolling update?
 // TODO: need test: change column definition/add column/drop column?
 func (c *metaOpsClient) Initialize(ctx context.Context) error {
-	if err := c.db.AutoMigrate(globalModels...); err != nil {
-		return cerrors.ErrMetaOpFail.Wrap(err)
+	// the tables of an incompatible schema must not be migrated
+	if err := c.checkSchemaVersion(ctx); err != nil {
+		return err
+	}
+
+	if err := c.db.WithContext(ctx).AutoMigrate(globalModels...); err != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+	if err := c.createIndexes(ctx); err != nil {
+		return err
+	}
+
+	// check the epoch record in counters
+	if err := model.InitializeEpoch(ctx, c.db); err != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	if err := c.stampSchemaVersion(ctx); err != nil {
+		return err
 	}
+	c.initialized.Store(true)
 
-	// check first record in logic_epochs
-	return model.InitializeEpoch(ctx, c.db)
+	return nil
+}
+
+// checkInitialized returns ErrClientNotInitialized if the seed rows created
+// by Initialize don't exist. Initialize may be called by another client of
+// the same backend, so the backend is checked until the rows are found.
+func (c *metaOpsClient) checkInitialized(ctx context.Context) error {
+	if c.initialized.Load() {
+		return nil
+	}
+
+	ok, err := c.exists(ctx, c.db.WithContext(ctx).Model(&model.Counter{}).
+		Where("name = ?", model.LogicEpochCounter))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return cerrors.ErrClientNotInitialized.GenWithStackByArgs()
+	}
+	c.initialized.Store(true)
+
+	return nil
+}
+
+////////////////////////// Transaction
+// Transaction runs fn with a client bound to a new transaction
+func (c *metaOpsClient) Transaction(ctx context.Context, fn func(tx Client) error) error {
+	var (
+		txCli *metaOpsClient
+		fnErr error
+	)
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txCli = &metaOpsClient{
+			db: tx,
+			// the configs read in the transaction may be rolled back, so
+			// they are not put into the cache of the parent client
+			configCache:    newJobConfigCache(defaultJobConfigCacheSize),
+			workerWatchers: c.workerWatchers,
+			// a failed statement can't be retried alone in a transaction
+			retryPolicy: retryPolicy{},
+			txn:         &txnEffects{},
+		}
+		txCli.initialized.Store(c.initialized.Load())
+		fnErr = fn(txCli)
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	for _, jobID := range txCli.txn.modifiedJobs {
+		c.configCache.invalidate(jobID)
+	}
+	for _, worker := range txCli.txn.workers {
+		c.workerWatchers.notify(worker)
+	}
+	return nil
+}
+
+// invalidateJobConfig drops the cached config of the job modified by this client
+func (c *metaOpsClient) invalidateJobConfig(jobID string) {
+	c.configCache.invalidate(jobID)
+	if c.txn != nil {
+		c.txn.modifiedJobs = append(c.txn.modifiedJobs, jobID)
+	}
+}
+
+// notifyWorker dispatches the worker status written by this client to the
+// watchers, it is deferred until commit in a transaction
+func (c *metaOpsClient) notifyWorker(worker *libModel.WorkerStatus) {
+	if c.txn != nil {
+		c.txn.workers = append(c.txn.workers, worker)
+		return
+	}
+	c.workerWatchers.notify(worker)
 }
 
 /////////////////////////////// Logic Epoch
 func (c *metaOpsClient) GenEpoch(ctx context.Context) (libModel.Epoch, error) {
-	return model.GenEpoch(ctx, c.db)
+	if err := c.checkInitialized(ctx); err != nil {
+		return 0, err
+	}
+
+	startTime := time.Now()
+	epoch, err := model.GenEpoch(ctx, c.db)
+	genEpochDuration.Observe(time.Since(startTime).Seconds())
+	if err != nil {
+		return 0, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+	epochGeneratedCounter.Inc()
+
+	return epoch, nil
+}
+
+// GetEpoch reads the current logic epoch without increasing it
+func (c *metaOpsClient) GetEpoch(ctx context.Context) (libModel.Epoch, error) {
+	var epoch libModel.Epoch
+	if err := c.retry(ctx, func() error {
+		var err error
+		epoch, err = model.GetEpoch(ctx, c.db)
+		return err
+	}); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, cerrors.ErrMetaEntryNotFound.Wrap(err)
+		}
+		return 0, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return epoch, nil
+}
+
+/////////////////////////////// Named Counter
+// GenNamedCounter increases the counter `name` by 1 and returns the new value
+func (c *metaOpsClient) GenNamedCounter(ctx context.Context, name string) (int64, error) {
+	if name == "" {
+		return 0, cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("input counter name is empty")
+	}
+
+	value, err := model.GenNamedCounter(ctx, c.db, name)
+	if err != nil {
+		return 0, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return value, nil
 }
 
 ///////////////////////// Project Operation
@@ -258,41 +661,140 @@ func (c *metaOpsClient) CreateProject(ctx context.Context, project *model.Projec
 	if project == nil {
 		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("input project info is nil")
 	}
-	if result := c.db.Create(project); result.Error != nil {
-		return cerrors.ErrMetaOpFail.Wrap(result.Error)
+	if result := c.db.WithContext(ctx).Create(project); result.Error != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, result.Error)
 	}
 
 	return nil
 }
 
 // DeleteProject delete the model.ProjectInfo
-func (c *metaOpsClient) DeleteProject(ctx context.Context, projectID string) error {
-	if result := c.db.Where("id=?", projectID).Delete(&model.ProjectInfo{}); result.Error != nil {
-		return cerrors.ErrMetaOpFail.Wrap(result.Error)
+func (c *metaOpsClient) DeleteProject(ctx context.Context, projectID string) (Result, error) {
+	result := c.db.WithContext(ctx).Where("id=?", projectID).Delete(&model.ProjectInfo{})
+	if result.Error != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, result.Error)
 	}
 
-	return nil
+	return &ormResult{rowsAffected: result.RowsAffected}, nil
 }
 
 // QueryProject query all projects
 func (c *metaOpsClient) QueryProjects(ctx context.Context) ([]*model.ProjectInfo, error) {
-	var projects []*model.ProjectInfo
-	if result := c.db.Find(&projects); result.Error != nil {
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+	projects, _, err := c.QueryProjectsWithPagination(ctx, 0, 0)
+	return projects, err
+}
+
+// QueryProjectsWithPagination query a page of projects and the total count of projects
+func (c *metaOpsClient) QueryProjectsWithPagination(ctx context.Context, offset, limit int) ([]*model.ProjectInfo, int64, error) {
+	return findPage[model.ProjectInfo](ctx, c, offset, limit)
+}
+
+// sqliteTimeLayouts are the layouts of time strings returned by sqlite
+var sqliteTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// nullTime is a nullable time. Besides time.Time, it can be scanned from a
+// string, because sqlite loses the column type of aggregations like MAX.
+type nullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+// Scan implements sql.Scanner
+func (t *nullTime) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		t.Time, t.Valid = time.Time{}, false
+		return nil
+	case time.Time:
+		t.Time, t.Valid = v, true
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	default:
+		return fmt.Errorf("unsupported time type %T", value)
+	}
+}
+
+// Value implements driver.Valuer
+func (t nullTime) Value() (driver.Value, error) {
+	if !t.Valid {
+		return nil, nil
 	}
+	return t.Time, nil
+}
+
+func (t *nullTime) parse(s string) error {
+	for _, layout := range sqliteTimeLayouts {
+		if tm, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			t.Time, t.Valid = tm, true
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid time %s", s)
+}
 
-	return projects, nil
+// projectStatsRow is used to scan the result of QueryProjectsWithStats
+type projectStatsRow struct {
+	model.ProjectInfo
+	JobCount   int64
+	LastOpTime nullTime
+}
+
+// QueryProjectsWithStats query all projects with their job count and the
+// time of the most recent operation in one query
+func (c *metaOpsClient) QueryProjectsWithStats(ctx context.Context) ([]*model.ProjectStats, error) {
+	// expected SQL: SELECT project_infos.*,
+	// (SELECT count(*) FROM master_meta_kv_data WHERE master_meta_kv_data.project_id = project_infos.id
+	// AND master_meta_kv_data.deleted IS NULL) AS job_count,
+	// (SELECT MAX(created_at) FROM project_operations WHERE project_operations.project_id = project_infos.id)
+	// AS last_op_time FROM project_infos
+	jobCount := c.db.WithContext(ctx).Model(&libModel.MasterMetaKVData{}).Select("count(*)").
+		Where("master_meta_kv_data.project_id = project_infos.id")
+	lastOpTime := c.db.WithContext(ctx).Model(&model.ProjectOperation{}).Select("MAX(created_at)").
+		Where("project_operations.project_id = project_infos.id")
+
+	var rows []*projectStatsRow
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Model(&model.ProjectInfo{}).
+			Select("project_infos.*, (?) AS job_count, (?) AS last_op_time", jobCount, lastOpTime).
+			Scan(&rows).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	stats := make([]*model.ProjectStats, 0, len(rows))
+	for _, row := range rows {
+		st := &model.ProjectStats{
+			ProjectInfo: row.ProjectInfo,
+			JobCount:    row.JobCount,
+		}
+		if row.LastOpTime.Valid {
+			lastOpTime := row.LastOpTime.Time
+			st.LastOpTime = &lastOpTime
+		}
+		stats = append(stats, st)
+	}
+	return stats, nil
 }
 
 // GetProjectByID query project by projectID
 func (c *metaOpsClient) GetProjectByID(ctx context.Context, projectID string) (*model.ProjectInfo, error) {
 	var project model.ProjectInfo
-	if result := c.db.Where("id = ?", projectID).First(&project); result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return nil, cerrors.ErrMetaEntryNotFound.Wrap(result.Error)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("id = ?", projectID).First(&project).Error
+	}); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, cerrors.ErrMetaEntryNotFound.Wrap(err)
 		}
 
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return &project, nil
@@ -304,8 +806,8 @@ func (c *metaOpsClient) CreateProjectOperation(ctx context.Context, op *model.Pr
 		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("input project operation is nil")
 	}
 
-	if result := c.db.Create(op); result.Error != nil {
-		return cerrors.ErrMetaOpFail.Wrap(result.Error)
+	if result := c.db.WithContext(ctx).Create(op); result.Error != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, result.Error)
 	}
 
 	return nil
@@ -314,8 +816,30 @@ func (c *metaOpsClient) CreateProjectOperation(ctx context.Context, op *model.Pr
 // QueryProjectOperations query all operations of the projectID
 func (c *metaOpsClient) QueryProjectOperations(ctx context.Context, projectID string) ([]*model.ProjectOperation, error) {
 	var projectOps []*model.ProjectOperation
-	if result := c.db.Where("project_id = ?", projectID).Find(&projectOps); result.Error != nil {
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("project_id = ?", projectID).Find(&projectOps).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return projectOps, nil
+}
+
+// QueryRecentProjectOperations query the latest n operations of the projectID,
+// ordered by created time desc
+func (c *metaOpsClient) QueryRecentProjectOperations(ctx context.Context,
+	projectID string, n int,
+) ([]*model.ProjectOperation, error) {
+	if n <= 0 {
+		return nil, cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("input limit should be positive")
+	}
+
+	var projectOps []*model.ProjectOperation
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("project_id = ?", projectID).Order("created_at DESC").Order("seq_id DESC").
+			Limit(n).Find(&projectOps).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return projectOps, nil
@@ -326,14 +850,56 @@ func (c *metaOpsClient) QueryProjectOperationsByTimeRange(ctx context.Context,
 	projectID string, tr TimeRange,
 ) ([]*model.ProjectOperation, error) {
 	var projectOps []*model.ProjectOperation
-	if result := c.db.Where("project_id = ? AND created_at >= ? AND created_at <= ?", projectID, tr.start,
-		tr.end).Find(&projectOps); result.Error != nil {
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("project_id = ? AND created_at >= ? AND created_at <= ?", projectID, tr.start,
+			tr.end).Find(&projectOps).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return projectOps, nil
 }
 
+// IterateProjectOperations visits all operations of the projectID ordered by
+// created time, and seq_id for the operations created at the same time
+func (c *metaOpsClient) IterateProjectOperations(ctx context.Context,
+	projectID string, fn func(*model.ProjectOperation) error,
+) error {
+	return c.iterateProjectOperations(ctx, projectID, defaultProjectOperationBatchSize, fn)
+}
+
+func (c *metaOpsClient) iterateProjectOperations(ctx context.Context,
+	projectID string, batchSize int, fn func(*model.ProjectOperation) error,
+) error {
+	var last *model.ProjectOperation
+	for {
+		var projectOps []*model.ProjectOperation
+		if err := c.retry(ctx, func() error {
+			// expected SQL: SELECT * FROM `project_operations` WHERE project_id = xxx
+			// AND (created_at > xxx OR (created_at = xxx AND seq_id > xxx))
+			// ORDER BY created_at,seq_id LIMIT batchSize
+			tx := c.db.WithContext(ctx).Where("project_id = ?", projectID)
+			if last != nil {
+				tx = tx.Where("created_at > ? OR (created_at = ? AND seq_id > ?)",
+					last.CreatedAt, last.CreatedAt, last.SeqID)
+			}
+			return tx.Order("created_at").Order("seq_id").Limit(batchSize).Find(&projectOps).Error
+		}); err != nil {
+			return newMetaError(cerrors.ErrMetaOpFail, err)
+		}
+
+		for _, op := range projectOps {
+			if err := fn(op); err != nil {
+				return err
+			}
+		}
+		if len(projectOps) < batchSize {
+			return nil
+		}
+		last = projectOps[len(projectOps)-1]
+	}
+}
+
 /////////////////////////////// Job Operation
 // UpsertJob upsert the jobInfo
 func (c *metaOpsClient) UpsertJob(ctx context.Context, job *libModel.MasterMetaKVData) error {
@@ -341,25 +907,80 @@ func (c *metaOpsClient) UpsertJob(ctx context.Context, job *libModel.MasterMetaK
 		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("input master meta is nil")
 	}
 
-	if err := c.db.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "id"}},
-		DoUpdates: clause.AssignmentColumns(libModel.MasterUpdateColumns),
-	}).Create(job).Error; err != nil {
-		return cerrors.ErrMetaOpFail.Wrap(err)
+	defer c.invalidateJobConfig(job.ID)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns(libModel.MasterUpdateColumns),
+		}).Create(job).Error
+	}); err != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return nil
 }
 
-// UpdateJob update the jobInfo
-func (c *metaOpsClient) UpdateJob(ctx context.Context, job *libModel.MasterMetaKVData) error {
+// UpdateJob update the jobInfo if the epoch of the stored job is still
+// expectedEpoch, otherwise ErrMetaConcurrentModification is returned, which
+// prevents a stale master from overwriting the job after failover.
+// ErrMetaEntryNotFound is returned if the job doesn't exist.
+func (c *metaOpsClient) UpdateJob(
+	ctx context.Context, job *libModel.MasterMetaKVData, expectedEpoch libModel.Epoch,
+) error {
 	if job == nil {
 		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("input master meta is nil")
 	}
 	// we don't use `Save` here to avoid user dealing with the basic model
-	// expected SQL: UPDATE xxx SET xxx='xxx', updated_at='2013-11-17 21:34:10' WHERE id=xxx;
-	if err := c.db.Model(&libModel.MasterMetaKVData{}).Where("id = ?", job.ID).Updates(job.Map()).Error; err != nil {
-		return cerrors.ErrMetaOpFail.Wrap(err)
+	// expected SQL: UPDATE xxx SET xxx='xxx', updated_at='2013-11-17 21:34:10' WHERE id=xxx AND epoch=xxx;
+	defer c.invalidateJobConfig(job.ID)
+	var conflict bool
+	err := c.retry(ctx, func() error {
+		conflict = false
+		return c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&libModel.MasterMetaKVData{}).
+				Where("id = ? AND epoch = ?", job.ID, expectedEpoch).Updates(job.Map())
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				return nil
+			}
+			// no row is updated, the job is either missing or taken over by
+			// another master with a different epoch
+			var current libModel.MasterMetaKVData
+			if err := tx.Select("epoch").Where("id = ?", job.ID).First(&current).Error; err != nil {
+				return err
+			}
+			conflict = current.Epoch != expectedEpoch
+			return nil
+		})
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return cerrors.ErrMetaEntryNotFound.Wrap(err)
+		}
+
+		return newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+	if conflict {
+		return cerrors.ErrMetaConcurrentModification.GenWithStackByArgs(job.ID, expectedEpoch)
+	}
+
+	return nil
+}
+
+// UpdateJobFields update the specified columns of the jobInfo, columns not
+// set in `opts` keep unchanged
+func (c *metaOpsClient) UpdateJobFields(ctx context.Context, jobID string, opts JobUpdate) error {
+	values := opts.columns()
+	if len(values) == 0 {
+		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("no job field to update")
+	}
+	// expected SQL: UPDATE xxx SET status=xxx, updated_at='2013-11-17 21:34:10' WHERE id=xxx;
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Model(&libModel.MasterMetaKVData{}).Where("id = ?", jobID).Updates(values).Error
+	}); err != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return nil
@@ -367,9 +988,10 @@ func (c *metaOpsClient) UpdateJob(ctx context.Context, job *libModel.MasterMetaK
 
 // DeleteJob delete the specified jobInfo
 func (c *metaOpsClient) DeleteJob(ctx context.Context, jobID string) (Result, error) {
-	result := c.db.Where("id = ?", jobID).Delete(&libModel.MasterMetaKVData{})
+	defer c.invalidateJobConfig(jobID)
+	result := c.db.WithContext(ctx).Where("id = ?", jobID).Delete(&libModel.MasterMetaKVData{})
 	if result.Error != nil {
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+		return nil, newMetaError(cerrors.ErrMetaOpFail, result.Error)
 	}
 
 	return &ormResult{rowsAffected: result.RowsAffected}, nil
@@ -378,44 +1000,325 @@ func (c *metaOpsClient) DeleteJob(ctx context.Context, jobID string) (Result, er
 // GetJobByID query job by `jobID`
 func (c *metaOpsClient) GetJobByID(ctx context.Context, jobID string) (*libModel.MasterMetaKVData, error) {
 	var job libModel.MasterMetaKVData
-	if result := c.db.Where("id = ?", jobID).First(&job); result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return nil, cerrors.ErrMetaEntryNotFound.Wrap(result.Error)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("id = ?", jobID).First(&job).Error
+	}); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, cerrors.ErrMetaEntryNotFound.Wrap(err)
 		}
 
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return &job, nil
 }
 
+// JobExists checks whether job `jobID` exists without fetching the row
+func (c *metaOpsClient) JobExists(ctx context.Context, jobID string) (bool, error) {
+	return c.exists(ctx, c.db.WithContext(ctx).Model(&libModel.MasterMetaKVData{}).Where("id = ?", jobID))
+}
+
+// GetJobConfig decodes the config of job `jobID` into v. The config blob is
+// cached until the job is modified through this client. The cached blob is
+// decoded for each call, so that callers never share the decoded objects.
+func (c *metaOpsClient) GetJobConfig(ctx context.Context, jobID string, v interface{}) error {
+	config, generation, ok := c.configCache.get(jobID)
+	if !ok {
+		var job libModel.MasterMetaKVData
+		if err := c.retry(ctx, func() error {
+			return c.db.WithContext(ctx).Select("config").Where("id = ?", jobID).First(&job).Error
+		}); err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return cerrors.ErrMetaEntryNotFound.Wrap(err)
+			}
+
+			return newMetaError(cerrors.ErrMetaOpFail, err)
+		}
+		config = job.Config
+		c.configCache.put(jobID, config, generation)
+	}
+
+	if err := json.Unmarshal(config, v); err != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+	return nil
+}
+
 // QueryJobsByProjectID query all jobs of projectID
 func (c *metaOpsClient) QueryJobs(ctx context.Context) ([]*libModel.MasterMetaKVData, error) {
+	jobs, _, err := c.QueryJobsWithPagination(ctx, 0, 0)
+	return jobs, err
+}
+
+// QueryJobsWithPagination query a page of jobs and the total count of jobs
+func (c *metaOpsClient) QueryJobsWithPagination(ctx context.Context, offset, limit int) ([]*libModel.MasterMetaKVData, int64, error) {
+	return findPage[libModel.MasterMetaKVData](ctx, c, offset, limit)
+}
+
+// QueryJobsByProjectID query all jobs of projectID
+func (c *metaOpsClient) QueryJobsByProjectID(ctx context.Context, projectID string) ([]*libModel.MasterMetaKVData, error) {
 	var jobs []*libModel.MasterMetaKVData
-	if result := c.db.Find(&jobs); result.Error != nil {
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("project_id = ?", projectID).Find(&jobs).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return jobs, nil
 }
 
-// QueryJobsByProjectID query all jobs of projectID
-func (c *metaOpsClient) QueryJobsByProjectID(ctx context.Context, projectID string) ([]*libModel.MasterMetaKVData, error) {
+// QueryLatestJobByProjectID query the job of projectID with the max updated_at
+func (c *metaOpsClient) QueryLatestJobByProjectID(ctx context.Context, projectID string) (*libModel.MasterMetaKVData, error) {
+	var job libModel.MasterMetaKVData
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("project_id = ?", projectID).
+			Order("updated_at DESC, seq_id DESC").Take(&job).Error
+	}); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, cerrors.ErrMetaEntryNotFound.Wrap(err)
+		}
+
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return &job, nil
+}
+
+// QueryJobsUpdatedAfter query the jobs with updated_at after t
+func (c *metaOpsClient) QueryJobsUpdatedAfter(ctx context.Context, t time.Time) ([]*libModel.MasterMetaKVData, error) {
 	var jobs []*libModel.MasterMetaKVData
-	if result := c.db.Where("project_id = ?", projectID).Find(&jobs); result.Error != nil {
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("updated_at > ?", t).
+			Order("updated_at, seq_id").Find(&jobs).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return jobs, nil
 }
 
-// QueryJobsByStatus query all jobs with `status` of the projectID
+// QueryJobsInBatches visits all jobs in batches of batchSize
+func (c *metaOpsClient) QueryJobsInBatches(ctx context.Context,
+	batchSize int, fn func([]*libModel.MasterMetaKVData) error,
+) error {
+	return findInBatches(c.db.WithContext(ctx), batchSize, fn)
+}
+
+// QueryJobsByStatus query the job `jobID` if it is in `status`
 func (c *metaOpsClient) QueryJobsByStatus(ctx context.Context,
 	jobID string, status int,
 ) ([]*libModel.MasterMetaKVData, error) {
 	var jobs []*libModel.MasterMetaKVData
-	if result := c.db.Where("id = ? AND status = ?", jobID, status).Find(&jobs); result.Error != nil {
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("id = ? AND status = ?", jobID, status).Find(&jobs).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return jobs, nil
+}
+
+// QueryJobsByStatusOnly query all jobs with `status`
+func (c *metaOpsClient) QueryJobsByStatusOnly(ctx context.Context, status int) ([]*libModel.MasterMetaKVData, error) {
+	var jobs []*libModel.MasterMetaKVData
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("status = ?", status).Find(&jobs).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return jobs, nil
+}
+
+// statusCount is used to scan the result of `GROUP BY status`
+type statusCount struct {
+	Status int
+	Count  int64
+}
+
+// CountJobsByStatus count the jobs grouped by status
+func (c *metaOpsClient) CountJobsByStatus(ctx context.Context) (map[int]int64, error) {
+	return c.countJobsByStatus(ctx, c.db.WithContext(ctx).Model(&libModel.MasterMetaKVData{}))
+}
+
+// CountJobs count all jobs
+func (c *metaOpsClient) CountJobs(ctx context.Context) (int64, error) {
+	// expected SQL: SELECT count(*) FROM `master_meta_kv_data`
+	return c.count(ctx, c.db.WithContext(ctx).Model(&libModel.MasterMetaKVData{}))
+}
+
+// CountProjectJobsByStatus count the jobs of projectID grouped by status
+func (c *metaOpsClient) CountProjectJobsByStatus(ctx context.Context, projectID string) (map[int]int64, error) {
+	return c.countJobsByStatus(ctx, c.db.WithContext(ctx).Model(&libModel.MasterMetaKVData{}).Where("project_id = ?", projectID))
+}
+
+func (c *metaOpsClient) countJobsByStatus(ctx context.Context, tx *gorm.DB) (map[int]int64, error) {
+	// expected SQL: SELECT status, count(*) AS count FROM xxx WHERE xxx GROUP BY status
+	var rows []statusCount
+	if err := c.retry(ctx, func() error {
+		return tx.Select("status, count(*) AS count").Group("status").Scan(&rows).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// jobTerminalState is used to scan the result of QueryJobTerminalStates
+type jobTerminalState struct {
+	ID       string
+	Terminal bool
+}
+
+// QueryJobTerminalStates returns whether each job is terminal, keyed by job id.
+// Only the id and status columns are read, so it is cheaper than QueryJobs
+// when the caller doesn't need the full job rows.
+func (c *metaOpsClient) QueryJobTerminalStates(ctx context.Context) (map[string]bool, error) {
+	// expected SQL: SELECT id, status IN (xxx) AS terminal FROM xxx WHERE deleted IS NULL
+	var rows []jobTerminalState
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Model(&libModel.MasterMetaKVData{}).
+			Select("id, status IN ? AS terminal", libModel.TerminalMasterStatuses).
+			Scan(&rows).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	states := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		states[row.ID] = row.Terminal
+	}
+	return states, nil
+}
+
+// ListActiveProjectIDs implements JobClient.ListActiveProjectIDs
+func (c *metaOpsClient) ListActiveProjectIDs(ctx context.Context) ([]string, error) {
+	// expected SQL: SELECT DISTINCT project_id FROM xxx WHERE status IN (xxx) AND deleted IS NULL
+	var projectIDs []string
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Model(&libModel.MasterMetaKVData{}).
+			Where("status IN ?", libModel.ActiveMasterStatuses).
+			Distinct().Pluck("project_id", &projectIDs).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return projectIDs, nil
+}
+
+// MoveJobToProject implements JobClient.MoveJobToProject
+func (c *metaOpsClient) MoveJobToProject(ctx context.Context, jobID, newProjectID string) error {
+	if jobID == "" || newProjectID == "" {
+		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("input job id or project id is empty")
+	}
+
+	// all the statements are rolled back together, so the whole transaction
+	// can be retried
+	err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&libModel.MasterMetaKVData{}).Where("id = ?", jobID).
+				Update("project_id", newProjectID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+			if err := tx.Model(&libModel.WorkerStatus{}).Where("job_id = ?", jobID).
+				Update("project_id", newProjectID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&resourcemeta.ResourceMeta{}).Where("job_id = ?", jobID).
+				Update("project_id", newProjectID).Error; err != nil {
+				return err
+			}
+			return tx.Create(&model.ProjectOperation{
+				ProjectID: newProjectID,
+				Operation: model.ProjectOperationMove,
+				JobID:     jobID,
+			}).Error
+		})
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return cerrors.ErrMetaEntryNotFound.Wrap(err)
+		}
+
+		return newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return nil
+}
+
+// SetJobLabels replaces all labels of the job with `labels`
+func (c *metaOpsClient) SetJobLabels(ctx context.Context, jobID string, labels map[string]string) error {
+	if jobID == "" {
+		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("input job id is empty")
+	}
+
+	jobLabels := make([]*model.JobLabel, 0, len(labels))
+	for key, value := range labels {
+		if key == "" {
+			return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("input label key is empty")
+		}
+		jobLabels = append(jobLabels, &model.JobLabel{
+			JobID: jobID,
+			Key:   key,
+			Value: value,
+		})
+	}
+
+	// replacing all labels is idempotent, so the whole transaction can be retried
+	err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("job_id = ?", jobID).Delete(&model.JobLabel{}).Error; err != nil {
+				return err
+			}
+			if len(jobLabels) == 0 {
+				return nil
+			}
+			return tx.Create(&jobLabels).Error
+		})
+	})
+	if err != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return nil
+}
+
+// GetJobLabels returns all labels of the job
+func (c *metaOpsClient) GetJobLabels(ctx context.Context, jobID string) (map[string]string, error) {
+	var jobLabels []*model.JobLabel
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("job_id = ?", jobID).Find(&jobLabels).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	labels := make(map[string]string, len(jobLabels))
+	for _, label := range jobLabels {
+		labels[label.Key] = label.Value
+	}
+	return labels, nil
+}
+
+// QueryJobsByLabel query all jobs labeled with `key`=`value`
+func (c *metaOpsClient) QueryJobsByLabel(ctx context.Context, key, value string) ([]*libModel.MasterMetaKVData, error) {
+	// expected SQL: SELECT master_meta_kv_data.* FROM master_meta_kv_data JOIN job_labels
+	// ON job_labels.job_id = master_meta_kv_data.id WHERE job_labels.label_key = ? AND job_labels.label_value = ?
+	var jobs []*libModel.MasterMetaKVData
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Select("master_meta_kv_data.*").
+			Joins("JOIN job_labels ON job_labels.job_id = master_meta_kv_data.id").
+			Where("job_labels.label_key = ? AND job_labels.label_value = ?", key, value).
+			Find(&jobs).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return jobs, nil
@@ -428,12 +1331,15 @@ func (c *metaOpsClient) UpsertWorker(ctx context.Context, worker *libModel.Worke
 		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("input worker meta is nil")
 	}
 
-	if err := c.db.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "id"}, {Name: "job_id"}},
-		DoUpdates: clause.AssignmentColumns(libModel.WorkerUpdateColumns),
-	}).Create(worker).Error; err != nil {
-		return cerrors.ErrMetaOpFail.Wrap(err)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}, {Name: "job_id"}},
+			DoUpdates: clause.AssignmentColumns(libModel.WorkerUpdateColumns),
+		}).Create(worker).Error
+	}); err != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, err)
 	}
+	c.notifyWorker(worker)
 
 	return nil
 }
@@ -443,59 +1349,181 @@ func (c *metaOpsClient) UpdateWorker(ctx context.Context, worker *libModel.Worke
 		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("input worker meta is nil")
 	}
 	// we don't use `Save` here to avoid user dealing with the basic model
-	if err := c.db.Model(&libModel.WorkerStatus{}).Where("job_id = ? AND id = ?", worker.JobID, worker.ID).Updates(worker.Map()).Error; err != nil {
-		return cerrors.ErrMetaOpFail.Wrap(err)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Model(&libModel.WorkerStatus{}).Where("job_id = ? AND id = ?", worker.JobID, worker.ID).Updates(worker.Map()).Error
+	}); err != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+	c.notifyWorker(worker)
+
+	return nil
+}
+
+// ClaimWorker implements WorkerClient.ClaimWorker
+// The lease is compared with the clock of the caller, so the clocks of the
+// claimers should be synchronized.
+func (c *metaOpsClient) ClaimWorker(ctx context.Context, masterID, workerID, ownerID string, ttl time.Duration) (bool, error) {
+	if err := checkClaimParams(ownerID, ttl); err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	return c.updateWorkerClaim(ctx, c.db.WithContext(ctx).Model(&libModel.WorkerStatus{}).
+		Where("job_id = ? AND id = ?", masterID, workerID).
+		Where("owner = '' OR owner = ? OR lease_expiry < ?", ownerID, now),
+		map[string]interface{}{
+			"owner":        ownerID,
+			"lease_expiry": now.Add(ttl),
+		})
+}
+
+// RenewWorkerClaim implements WorkerClient.RenewWorkerClaim
+func (c *metaOpsClient) RenewWorkerClaim(ctx context.Context, masterID, workerID, ownerID string, ttl time.Duration) (bool, error) {
+	if err := checkClaimParams(ownerID, ttl); err != nil {
+		return false, err
 	}
 
+	return c.updateWorkerClaim(ctx, c.db.WithContext(ctx).Model(&libModel.WorkerStatus{}).
+		Where("job_id = ? AND id = ? AND owner = ?", masterID, workerID, ownerID),
+		map[string]interface{}{
+			"lease_expiry": time.Now().Add(ttl),
+		})
+}
+
+// ReleaseWorkerClaim implements WorkerClient.ReleaseWorkerClaim
+func (c *metaOpsClient) ReleaseWorkerClaim(ctx context.Context, masterID, workerID, ownerID string) (bool, error) {
+	if ownerID == "" {
+		return false, cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("empty owner id")
+	}
+
+	return c.updateWorkerClaim(ctx, c.db.WithContext(ctx).Model(&libModel.WorkerStatus{}).
+		Where("job_id = ? AND id = ? AND owner = ?", masterID, workerID, ownerID),
+		map[string]interface{}{
+			"owner":        "",
+			"lease_expiry": nil,
+		})
+}
+
+// checkClaimParams checks the owner and the lease ttl of a claim
+func checkClaimParams(ownerID string, ttl time.Duration) error {
+	if ownerID == "" {
+		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("empty owner id")
+	}
+	if ttl <= 0 {
+		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("non-positive lease ttl")
+	}
 	return nil
 }
 
+// updateWorkerClaim updates the claim columns of the worker selected by tx,
+// and returns whether the worker is updated
+func (c *metaOpsClient) updateWorkerClaim(ctx context.Context, tx *gorm.DB, values map[string]interface{}) (bool, error) {
+	var rowsAffected int64
+	if err := c.retry(ctx, func() error {
+		result := tx.Updates(values)
+		rowsAffected = result.RowsAffected
+		return result.Error
+	}); err != nil {
+		return false, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
 // DeleteWorker delete the specified workInfo
 func (c *metaOpsClient) DeleteWorker(ctx context.Context, masterID string, workerID string) (Result, error) {
-	result := c.db.Where("job_id = ? AND id = ?", masterID, workerID).Delete(&libModel.WorkerStatus{})
+	result := c.db.WithContext(ctx).Where("job_id = ? AND id = ?", masterID, workerID).Delete(&libModel.WorkerStatus{})
 	if result.Error != nil {
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+		return nil, newMetaError(cerrors.ErrMetaOpFail, result.Error)
 	}
 
 	return &ormResult{rowsAffected: result.RowsAffected}, nil
 }
 
+// DeleteWorkersByMasterID delete all the workers of the masterID
+func (c *metaOpsClient) DeleteWorkersByMasterID(ctx context.Context, masterID string) (int64, error) {
+	result := c.db.WithContext(ctx).Where("job_id = ?", masterID).Delete(&libModel.WorkerStatus{})
+	if result.Error != nil {
+		return 0, newMetaError(cerrors.ErrMetaOpFail, result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
 // GetWorkerByID query worker info by workerID
 func (c *metaOpsClient) GetWorkerByID(ctx context.Context, masterID string, workerID string) (*libModel.WorkerStatus, error) {
 	var worker libModel.WorkerStatus
-	if result := c.db.Where("job_id = ? AND id = ?", masterID,
-		workerID).First(&worker); result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return nil, cerrors.ErrMetaEntryNotFound.Wrap(result.Error)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("job_id = ? AND id = ?", masterID,
+			workerID).First(&worker).Error
+	}); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, cerrors.ErrMetaEntryNotFound.Wrap(err)
 		}
 
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return &worker, nil
 }
 
+// WorkerExists checks whether worker `workerID` of master `masterID` exists
+func (c *metaOpsClient) WorkerExists(ctx context.Context, masterID string, workerID string) (bool, error) {
+	return c.exists(ctx, c.db.WithContext(ctx).Model(&libModel.WorkerStatus{}).Where("job_id = ? AND id = ?", masterID, workerID))
+}
+
 // QueryWorkersByMasterID query all workers of masterID
 func (c *metaOpsClient) QueryWorkersByMasterID(ctx context.Context, masterID string) ([]*libModel.WorkerStatus, error) {
 	var workers []*libModel.WorkerStatus
-	if result := c.db.Where("job_id = ?", masterID).Find(&workers); result.Error != nil {
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("job_id = ?", masterID).Find(&workers).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return workers, nil
 }
 
+// CountWorkersByMasterID count all workers of masterID
+func (c *metaOpsClient) CountWorkersByMasterID(ctx context.Context, masterID string) (int64, error) {
+	// expected SQL: SELECT count(*) FROM `worker_statuses` WHERE job_id = masterID
+	return c.count(ctx, c.db.WithContext(ctx).Model(&libModel.WorkerStatus{}).Where("job_id = ?", masterID))
+}
+
 // QueryWorkersByStatus query all workers with specified status of masterID
 func (c *metaOpsClient) QueryWorkersByStatus(ctx context.Context, masterID string, status int) ([]*libModel.WorkerStatus, error) {
 	var workers []*libModel.WorkerStatus
-	if result := c.db.Where("job_id = ? AND status = ?", masterID,
-		status).Find(&workers); result.Error != nil {
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("job_id = ? AND status = ?", masterID,
+			status).Find(&workers).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return workers, nil
 }
 
+// QueryWorkersInBatches visits the workers of masterID in batches of batchSize
+func (c *metaOpsClient) QueryWorkersInBatches(ctx context.Context,
+	masterID string, batchSize int, fn func([]*libModel.WorkerStatus) error,
+) error {
+	return findInBatches(c.db.WithContext(ctx).Where("job_id = ?", masterID), batchSize, fn)
+}
+
+// WatchWorkers returns the workers of masterID and the subsequent updates
+func (c *metaOpsClient) WatchWorkers(ctx context.Context, masterID string) ([]*libModel.WorkerStatus, <-chan *libModel.WorkerStatus, error) {
+	// register the watcher before the snapshot, so no update is lost between
+	// them. An update may be delivered again if it is in the snapshot already.
+	watcher := c.workerWatchers.add(ctx, masterID)
+	workers, err := c.QueryWorkersByMasterID(ctx, masterID)
+	if err != nil {
+		c.workerWatchers.remove(masterID, watcher)
+		return nil, nil, err
+	}
+
+	return workers, watcher.ch, nil
+}
+
 /////////////////////////////// Resource Operation
 // UpsertResource upsert the ResourceMeta
 func (c *metaOpsClient) UpsertResource(ctx context.Context, resource *resourcemeta.ResourceMeta) error {
@@ -503,11 +1531,13 @@ func (c *metaOpsClient) UpsertResource(ctx context.Context, resource *resourceme
 		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("input resource meta is nil")
 	}
 
-	if err := c.db.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "id"}},
-		DoUpdates: clause.AssignmentColumns(resourcemeta.ResourceUpdateColumns),
-	}).Create(resource).Error; err != nil {
-		return cerrors.ErrMetaOpFail.Wrap(err)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns(resourcemeta.ResourceUpdateColumns),
+		}).Create(resource).Error
+	}); err != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return nil
@@ -518,7 +1548,7 @@ func (c *metaOpsClient) CreateResource(ctx context.Context, resource *resourceme
 		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("input resource meta is nil")
 	}
 
-	err := c.db.Transaction(func(tx *gorm.DB) error {
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		var count int64
 		err := tx.Model(&resourcemeta.ResourceMeta{}).
 			Where("id = ?", resource.ID).
@@ -532,12 +1562,12 @@ func (c *metaOpsClient) CreateResource(ctx context.Context, resource *resourceme
 		}
 
 		if err := tx.Create(resource).Error; err != nil {
-			return cerrors.ErrMetaOpFail.Wrap(err)
+			return newMetaError(cerrors.ErrMetaOpFail, err)
 		}
 		return nil
 	})
 	if err != nil {
-		return cerrors.ErrMetaOpFail.Wrap(err)
+		return newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 	return nil
 }
@@ -548,8 +1578,10 @@ func (c *metaOpsClient) UpdateResource(ctx context.Context, resource *resourceme
 		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("input resource meta is nil")
 	}
 	// we don't use `Save` here to avoid user dealing with the basic model
-	if err := c.db.Model(&resourcemeta.ResourceMeta{}).Where("id = ?", resource.ID).Updates(resource.Map()).Error; err != nil {
-		return cerrors.ErrMetaOpFail.Wrap(err)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Model(&resourcemeta.ResourceMeta{}).Where("id = ?", resource.ID).Updates(resource.Map()).Error
+	}); err != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return nil
@@ -557,9 +1589,9 @@ func (c *metaOpsClient) UpdateResource(ctx context.Context, resource *resourceme
 
 // DeleteResource delete the specified model.libModel.resourcemeta.ResourceMeta
 func (c *metaOpsClient) DeleteResource(ctx context.Context, resourceID string) (Result, error) {
-	result := c.db.Where("id = ?", resourceID).Delete(&resourcemeta.ResourceMeta{})
+	result := c.db.WithContext(ctx).Where("id = ?", resourceID).Delete(&resourcemeta.ResourceMeta{})
 	if result.Error != nil {
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+		return nil, newMetaError(cerrors.ErrMetaOpFail, result.Error)
 	}
 
 	return &ormResult{rowsAffected: result.RowsAffected}, nil
@@ -568,21 +1600,30 @@ func (c *metaOpsClient) DeleteResource(ctx context.Context, resourceID string) (
 // GetResourceByID query resource of the resource_id
 func (c *metaOpsClient) GetResourceByID(ctx context.Context, resourceID string) (*resourcemeta.ResourceMeta, error) {
 	var resource resourcemeta.ResourceMeta
-	if result := c.db.Where("id = ?", resourceID).First(&resource); result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return nil, cerrors.ErrMetaEntryNotFound.Wrap(result.Error)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("id = ?", resourceID).First(&resource).Error
+	}); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, cerrors.ErrMetaEntryNotFound.Wrap(err)
 		}
 
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return &resource, nil
 }
 
+// ResourceExists checks whether resource `resourceID` exists
+func (c *metaOpsClient) ResourceExists(ctx context.Context, resourceID string) (bool, error) {
+	return c.exists(ctx, c.db.WithContext(ctx).Model(&resourcemeta.ResourceMeta{}).Where("id = ?", resourceID))
+}
+
 func (c *metaOpsClient) QueryResources(ctx context.Context) ([]*resourcemeta.ResourceMeta, error) {
 	var resources []*resourcemeta.ResourceMeta
-	if result := c.db.Find(&resources); result.Error != nil {
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Find(&resources).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return resources, nil
@@ -591,23 +1632,111 @@ func (c *metaOpsClient) QueryResources(ctx context.Context) ([]*resourcemeta.Res
 // QueryResourcesByJobID query all resources of the jobID
 func (c *metaOpsClient) QueryResourcesByJobID(ctx context.Context, jobID string) ([]*resourcemeta.ResourceMeta, error) {
 	var resources []*resourcemeta.ResourceMeta
-	if result := c.db.Where("job_id = ?", jobID).Find(&resources); result.Error != nil {
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("job_id = ?", jobID).Find(&resources).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return resources, nil
 }
 
+// QueryResourcesByJobIDs query all resources of the jobIDs
+func (c *metaOpsClient) QueryResourcesByJobIDs(ctx context.Context, jobIDs []string) ([]*resourcemeta.ResourceMeta, error) {
+	return c.queryResourcesByJobIDs(ctx, jobIDs, defaultInClauseChunkSize)
+}
+
+func (c *metaOpsClient) queryResourcesByJobIDs(
+	ctx context.Context, jobIDs []string, chunkSize int,
+) ([]*resourcemeta.ResourceMeta, error) {
+	var resources []*resourcemeta.ResourceMeta
+	for start := 0; start < len(jobIDs); start += chunkSize {
+		end := start + chunkSize
+		if end > len(jobIDs) {
+			end = len(jobIDs)
+		}
+
+		var chunk []*resourcemeta.ResourceMeta
+		if err := c.retry(ctx, func() error {
+			return c.db.WithContext(ctx).Where("job_id IN ?", jobIDs[start:end]).Find(&chunk).Error
+		}); err != nil {
+			return nil, newMetaError(cerrors.ErrMetaOpFail, err)
+		}
+		resources = append(resources, chunk...)
+	}
+
+	return resources, nil
+}
+
+// CountResourcesByJobID count all resources of jobID
+func (c *metaOpsClient) CountResourcesByJobID(ctx context.Context, jobID string) (int64, error) {
+	// expected SQL: SELECT count(*) FROM `resource_meta` WHERE job_id = jobID
+	return c.count(ctx, c.db.WithContext(ctx).Model(&resourcemeta.ResourceMeta{}).Where("job_id = ?", jobID))
+}
+
 // QueryResourcesByExecutorID query all resources of the executor_id
 func (c *metaOpsClient) QueryResourcesByExecutorID(ctx context.Context, executorID string) ([]*resourcemeta.ResourceMeta, error) {
 	var resources []*resourcemeta.ResourceMeta
-	if result := c.db.Where("executor_id = ?", executorID).Find(&resources); result.Error != nil {
-		return nil, cerrors.ErrMetaOpFail.Wrap(result.Error)
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("executor_id = ?", executorID).Find(&resources).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
 	}
 
 	return resources, nil
 }
 
+// QueryResourcesByDeleted query all resources with the deleted flag
+func (c *metaOpsClient) QueryResourcesByDeleted(ctx context.Context, deleted bool) ([]*resourcemeta.ResourceMeta, error) {
+	var resources []*resourcemeta.ResourceMeta
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("deleted = ?", deleted).Find(&resources).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return resources, nil
+}
+
+// QueryResourcesByContentType query all resources of the content type
+func (c *metaOpsClient) QueryResourcesByContentType(ctx context.Context, contentType string) ([]*resourcemeta.ResourceMeta, error) {
+	var resources []*resourcemeta.ResourceMeta
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Where("content_type = ?", contentType).Find(&resources).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return resources, nil
+}
+
+// QueryOrphanedResources query all resources whose job doesn't exist or
+// has been deleted
+func (c *metaOpsClient) QueryOrphanedResources(ctx context.Context) ([]*resourcemeta.ResourceMeta, error) {
+	// expected SQL: SELECT resource_meta.* FROM resource_meta LEFT JOIN master_meta_kv_data
+	// ON master_meta_kv_data.id = resource_meta.job_id AND master_meta_kv_data.deleted IS NULL
+	// WHERE master_meta_kv_data.id IS NULL
+	var resources []*resourcemeta.ResourceMeta
+	if err := c.retry(ctx, func() error {
+		return c.db.WithContext(ctx).Select("resource_meta.*").
+			Joins("LEFT JOIN master_meta_kv_data ON master_meta_kv_data.id = resource_meta.job_id " +
+				"AND master_meta_kv_data.deleted IS NULL").
+			Where("master_meta_kv_data.id IS NULL").
+			Find(&resources).Error
+	}); err != nil {
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return resources, nil
+}
+
+// QueryResourcesInBatches visits all resources in batches of batchSize
+func (c *metaOpsClient) QueryResourcesInBatches(ctx context.Context,
+	batchSize int, fn func([]*resourcemeta.ResourceMeta) error,
+) error {
+	return findInBatches(c.db.WithContext(ctx), batchSize, fn)
+}
+
 // Result defines a query result interface
 type Result interface {
 	RowsAffected() int64
@@ -620,3 +1749,94 @@ type ormResult struct {
 func (r ormResult) RowsAffected() int64 {
 	return r.rowsAffected
 }
+
+// exists checks whether any row matches the query, it returns false rather
+// than a not found error if no row matches
+func (c *metaOpsClient) exists(ctx context.Context, tx *gorm.DB) (bool, error) {
+	// expected SQL: SELECT 1 FROM xxx WHERE xxx LIMIT 1
+	var (
+		one   int
+		found bool
+	)
+	if err := c.retry(ctx, func() error {
+		result := tx.Select("1").Limit(1).Scan(&one)
+		found = result.RowsAffected > 0
+		return result.Error
+	}); err != nil {
+		return false, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return found, nil
+}
+
+// findInBatches loads the rows matching the query batchSize rows at a time in
+// the order of primary key, and calls fn for each batch. An error returned by
+// fn stops the iteration and is returned as it is.
+func findInBatches[T any](tx *gorm.DB, batchSize int, fn func([]*T) error) error {
+	if batchSize <= 0 {
+		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("batch size should be positive")
+	}
+
+	// expected SQL: SELECT * FROM xxx WHERE xxx AND seq_id > xxx ORDER BY seq_id LIMIT batchSize
+	var (
+		rows  []*T
+		fnErr error
+	)
+	result := tx.FindInBatches(&rows, batchSize, func(_ *gorm.DB, _ int) error {
+		fnErr = fn(rows)
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if result.Error != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, result.Error)
+	}
+
+	return nil
+}
+
+// count returns the count of rows matching the query
+func (c *metaOpsClient) count(ctx context.Context, tx *gorm.DB) (int64, error) {
+	var cnt int64
+	if err := c.retry(ctx, func() error {
+		return tx.Count(&cnt).Error
+	}); err != nil {
+		return 0, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return cnt, nil
+}
+
+// findPage loads at most limit rows of T starting from offset in the order of
+// primary key, and counts all the rows of T. A non-positive limit means no
+// limit, and the count query is skipped if all the rows are loaded.
+func findPage[T any](ctx context.Context, c *metaOpsClient, offset, limit int) ([]*T, int64, error) {
+	if offset < 0 {
+		return nil, 0, cerrors.ErrMetaParamsInvalid.GenWithStackByArgs("offset should not be negative")
+	}
+
+	var (
+		rows  []*T
+		total int64
+	)
+	if err := c.retry(ctx, func() error {
+		// expected SQL: SELECT * FROM xxx ORDER BY seq_id LIMIT limit OFFSET offset
+		tx := c.db.WithContext(ctx).Order("seq_id").Offset(offset)
+		if limit > 0 {
+			tx = tx.Limit(limit)
+		}
+		if err := tx.Find(&rows).Error; err != nil {
+			return err
+		}
+		if offset == 0 && limit <= 0 {
+			total = int64(len(rows))
+			return nil
+		}
+		return c.db.WithContext(ctx).Model(new(T)).Count(&total).Error
+	}); err != nil {
+		return nil, 0, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return rows, total, nil
+}
@@ -7,6 +7,8 @@ import (
 	"errors"
 	"reflect"
 	"regexp"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -61,11 +63,150 @@ func TestNewMetaOpsClient(t *testing.T) {
 	defer sqlDB.Close()
 	defer mock.ExpectClose()
 	require.Nil(t, err)
-	cli, err = newClient(sqlDB)
+	cli, err = newClient(sqlDB, NewDefaultDBConfig())
 	require.Nil(t, err)
 	require.NotNil(t, cli)
 }
 
+func TestPoolStats(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	sqlDB.SetMaxOpenConns(2)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+
+	stats := cli.PoolStats()
+	require.Equal(t, 2, stats.MaxOpenConnections)
+	require.Equal(t, 0, stats.InUse)
+
+	// three concurrent queries with a pool of two connections
+	mock.MatchExpectationsInOrder(false)
+	expectedSQL := "SELECT * FROM `project_infos` WHERE id = ? ORDER BY `project_infos`.`seq_id` LIMIT 1"
+	for i := 0; i < 3; i++ {
+		mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WillDelayFor(100 * time.Millisecond).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("p111", "tenant1"))
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cli.GetProjectByID(context.Background(), "p111")
+			require.NoError(t, err)
+		}()
+	}
+	require.Eventually(t, func() bool {
+		stats := cli.PoolStats()
+		return stats.InUse == 2 && stats.WaitCount > 0
+	}, time.Second, time.Millisecond)
+	wg.Wait()
+
+	stats = cli.PoolStats()
+	require.Equal(t, 0, stats.InUse)
+	require.Equal(t, int64(1), stats.WaitCount)
+	require.Greater(t, stats.WaitDuration, time.Duration(0))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBConfigTimezone(t *testing.T) {
+	t.Parallel()
+
+	var store metaclient.StoreConfigParams
+	store.SetEndpoints("127.0.0.1:3306")
+
+	conf := NewDefaultDBConfig()
+	dsnCfg, err := mysql.ParseDSN(generateDSNByParams(store, "test", conf, true))
+	require.NoError(t, err)
+	require.Equal(t, time.Local, dsnCfg.Loc)
+
+	conf.Timezone = "UTC"
+	require.NoError(t, conf.validate())
+	dsnCfg, err = mysql.ParseDSN(generateDSNByParams(store, "test", conf, true))
+	require.NoError(t, err)
+	require.Equal(t, time.UTC, dsnCfg.Loc)
+
+	conf.Timezone = "Asia/Shanghai"
+	require.NoError(t, conf.validate())
+	dsnCfg, err = mysql.ParseDSN(generateDSNByParams(store, "test", conf, true))
+	require.NoError(t, err)
+	require.Equal(t, "Asia/Shanghai", dsnCfg.Loc.String())
+
+	conf.Timezone = "Mars/Olympus"
+	err = conf.validate()
+	require.True(t, cerrors.ErrMetaParamsInvalid.Equal(err))
+	cli, err := NewClient(store, conf)
+	require.Nil(t, cli)
+	require.True(t, cerrors.ErrMetaParamsInvalid.Equal(err))
+
+	// the created time of the operation in UTC is stored and read unchanged
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	conf.Timezone = "UTC"
+	metaCli, err := newClient(sqlDB, conf)
+	require.Nil(t, err)
+
+	createdAt := time.Date(2022, 4, 25, 2, 24, 38, 362000000, time.UTC)
+	mock.ExpectExec("INSERT INTO `project_operations`").
+		WithArgs("p111", "Submit", "j222", createdAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT [*] FROM `project_operations` WHERE project_id").WithArgs("p111").WillReturnRows(
+		sqlmock.NewRows([]string{"seq_id", "project_id", "operation", "job_id", "created_at"}).AddRow(
+			1, "p111", "Submit", "j222", createdAt))
+
+	ctx := context.Background()
+	err = metaCli.CreateProjectOperation(ctx, &model.ProjectOperation{
+		ProjectID: "p111",
+		Operation: "Submit",
+		JobID:     "j222",
+		CreatedAt: createdAt,
+	})
+	require.NoError(t, err)
+	ops, err := metaCli.QueryProjectOperations(ctx, "p111")
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	require.Equal(t, createdAt, ops[0].CreatedAt)
+	require.Equal(t, time.UTC, ops[0].CreatedAt.Location())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestValidateProjectID(t *testing.T) {
+	t.Parallel()
+
+	for _, projectID := range []string{"dfe_root", "project1", "A", strings.Repeat("a", 64)} {
+		require.NoError(t, validateProjectID(projectID))
+	}
+
+	for _, projectID := range []string{
+		"",
+		strings.Repeat("a", 65),
+		"test`; DROP DATABASE dfe_root; --",
+		"`test`",
+		"test db",
+		"test-db",
+		"test.db",
+		"测试",
+	} {
+		err := validateProjectID(projectID)
+		require.Error(t, err)
+		var metaErr *MetaError
+		require.True(t, errors.As(err, &metaErr))
+		require.Equal(t, perrors.RFCErrorCode("DFLOW:ErrMetaOpFail"), metaErr.RFCCode())
+
+		// the invalid projectID is rejected before connecting to the backend
+		var store metaclient.StoreConfigParams
+		store.SetEndpoints("127.0.0.1:1")
+		err = createDatabaseForProject(store, projectID, NewDefaultDBConfig())
+		require.True(t, errors.As(err, &metaErr))
+		require.Contains(t, err.Error(), "invalid project id")
+	}
+}
+
 // nolint: deadcode
 func testInitialize(t *testing.T) {
 	t.Parallel()
@@ -74,7 +215,7 @@ func testInitialize(t *testing.T) {
 	defer sqlDB.Close()
 	defer mock.ExpectClose()
 	require.Nil(t, err)
-	cli, err := newClient(sqlDB)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
 	require.Nil(t, err)
 	require.NotNil(t, cli)
 
@@ -123,7 +264,7 @@ func TestProject(t *testing.T) {
 	defer sqlDB.Close()
 	defer mock.ExpectClose()
 	require.Nil(t, err)
-	cli, err := newClient(sqlDB)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
 	require.Nil(t, err)
 	require.NotNil(t, cli)
 
@@ -183,6 +324,9 @@ func TestProject(t *testing.T) {
 			inputs: []interface{}{
 				"p111",
 			},
+			output: &ormResult{
+				rowsAffected: 1,
+			},
 			mockExpectResFn: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("DELETE FROM `project_infos` WHERE id").WithArgs("p111").WillReturnResult(sqlmock.NewResult(0, 1))
 			},
@@ -273,7 +417,7 @@ func TestProjectOperation(t *testing.T) {
 	defer sqlDB.Close()
 	defer mock.ExpectClose()
 	require.Nil(t, err)
-	cli, err := newClient(sqlDB)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
 	require.Nil(t, err)
 	require.NotNil(t, cli)
 
@@ -320,6 +464,58 @@ func TestProjectOperation(t *testing.T) {
 				mock.ExpectQuery("SELECT [*] FROM `project_operations` WHERE project_id").WithArgs("p111").WillReturnError(errors.New("QueryProjectOperations error"))
 			},
 		},
+		{
+			// SELECT * FROM `project_operations` WHERE project_id = '111' ORDER BY created_at DESC,seq_id DESC LIMIT 2
+			fn: "QueryRecentProjectOperations",
+			inputs: []interface{}{
+				"p111",
+				2,
+			},
+			output: []*model.ProjectOperation{
+				{
+					SeqID:     2,
+					ProjectID: "p111",
+					Operation: "Drop",
+					JobID:     "j222",
+					CreatedAt: tm1,
+				},
+				{
+					SeqID:     1,
+					ProjectID: "p111",
+					Operation: "Submit",
+					JobID:     "j222",
+					CreatedAt: tm,
+				},
+			},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT [*] FROM `project_operations` WHERE project_id = [?] ORDER BY created_at DESC,seq_id DESC LIMIT 2").
+					WithArgs("p111").WillReturnRows(
+					sqlmock.NewRows([]string{"seq_id", "project_id", "operation", "job_id", "created_at"}).AddRow(
+						2, "p111", "Drop", "j222", tm1).AddRow(
+						1, "p111", "Submit", "j222", tm))
+			},
+		},
+		{
+			fn: "QueryRecentProjectOperations",
+			inputs: []interface{}{
+				"p111",
+				2,
+			},
+			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT [*] FROM `project_operations` WHERE project_id").WithArgs("p111").WillReturnError(
+					errors.New("QueryRecentProjectOperations error"))
+			},
+		},
+		{
+			fn: "QueryRecentProjectOperations",
+			inputs: []interface{}{
+				"p111",
+				0,
+			},
+			err:             cerrors.ErrMetaParamsInvalid.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {},
+		},
 		{
 			// SELECT * FROM `project_operations` WHERE project_id = '111' AND created_at >= '2022-04-13 23:51:42.46' AND created_at <= '2022-04-13 23:51:42.46'
 			fn: "QueryProjectOperationsByTimeRange",
@@ -382,13 +578,14 @@ func TestJob(t *testing.T) {
 	defer sqlDB.Close()
 	defer mock.ExpectClose()
 	require.Nil(t, err)
-	cli, err := newClient(sqlDB)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
 	require.Nil(t, err)
 	require.NotNil(t, cli)
 
 	tm := time.Now()
 	createdAt := tm.Add(time.Duration(1))
 	updatedAt := tm.Add(time.Duration(1))
+	jobStatus := libModel.MasterStatusFinished
 
 	testCases := []tCase{
 		{
@@ -450,10 +647,87 @@ func TestJob(t *testing.T) {
 					Addr:       "127.0.0.1",
 					Config:     []byte{0x11, 0x22},
 				},
+				libModel.Epoch(1),
 			},
 			mockExpectResFn: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("UPDATE `master_meta_kv_data` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE `master_meta_kv_data` SET .* WHERE \\(id = \\? AND epoch = \\?\\)").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			// the epoch of the job has been changed by another master
+			fn: "UpdateJob",
+			inputs: []interface{}{
+				&libModel.MasterMetaKVData{
+					ProjectID: "p111",
+					ID:        "j111",
+					Epoch:     1,
+				},
+				libModel.Epoch(1),
+			},
+			err: cerrors.ErrMetaConcurrentModification.GenWithStackByArgs("j111", 1),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE `master_meta_kv_data` SET").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT `epoch` FROM `master_meta_kv_data` WHERE id = ?").WithArgs("j111").
+					WillReturnRows(sqlmock.NewRows([]string{"epoch"}).AddRow(2))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			// the job doesn't exist
+			fn: "UpdateJob",
+			inputs: []interface{}{
+				&libModel.MasterMetaKVData{
+					ProjectID: "p111",
+					ID:        "j113",
+					Epoch:     1,
+				},
+				libModel.Epoch(1),
+			},
+			err: cerrors.ErrMetaEntryNotFound.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE `master_meta_kv_data` SET").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT `epoch` FROM `master_meta_kv_data` WHERE id = ?").WithArgs("j113").
+					WillReturnRows(sqlmock.NewRows([]string{"epoch"}))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			// UPDATE `master_meta_kv_data` SET `status`=?,`updated_at`=? WHERE id = ? AND `master_meta_kv_data`.`deleted` IS NULL
+			fn: "UpdateJobFields",
+			inputs: []interface{}{
+				"j111",
+				JobUpdate{Status: &jobStatus},
+			},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				expectedSQL := "UPDATE `master_meta_kv_data` SET `status`=?,`updated_at`=? WHERE id = ? AND `master_meta_kv_data`.`deleted` IS NULL"
+				mock.ExpectExec(regexp.QuoteMeta(expectedSQL)).WithArgs(
+					jobStatus, anyTime{}, "j111").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			fn: "UpdateJobFields",
+			inputs: []interface{}{
+				"j111",
+				JobUpdate{Status: &jobStatus},
+			},
+			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE `master_meta_kv_data` SET").WillReturnError(errors.New("UpdateJobFields error"))
+			},
+		},
+		{
+			fn: "UpdateJobFields",
+			inputs: []interface{}{
+				"j111",
+				JobUpdate{},
 			},
+			err:             cerrors.ErrMetaParamsInvalid.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {},
 		},
 		{
 			// SELECT * FROM `master_meta_kv_data` WHERE project_id = '111-222-333' AND job_id = '111' ORDER BY `master_meta_kv_data`.`id` LIMIT 1
@@ -587,144 +861,485 @@ func TestJob(t *testing.T) {
 					errors.New("QueryJobsByStatus error"))
 			},
 		},
-	}
-
-	for _, tc := range testCases {
-		testInner(t, mock, cli, tc)
-	}
-}
-
-func TestWorker(t *testing.T) {
-	t.Parallel()
-
-	sqlDB, mock, err := mockGetDBConn(t, "test")
-	defer sqlDB.Close()
-	defer mock.ExpectClose()
-	require.Nil(t, err)
-	cli, err := newClient(sqlDB)
-	require.Nil(t, err)
-	require.NotNil(t, cli)
-
-	tm := time.Now()
-	createdAt := tm.Add(time.Duration(1))
-	updatedAt := tm.Add(time.Duration(1))
-
-	testCases := []tCase{
 		{
-			// INSERT INTO `worker_statuses` (`created_at`,`updated_at`,`project_id`,`job_id`,`id`,`type`,`status`,`errmsg`,`ext_bytes`)
-			// VALUES ('2022-04-29 18:49:40.932','2022-04-29 18:49:40.932','p111','j111','w222',1,'1','error','<binary>') ON DUPLICATE KEY
-			// UPDATE `updated_at`=VALUES(`updated_at`),`project_id`=VALUES(`project_id`),`job_id`=VALUES(`job_id`),`id`=VALUES(`id`),
-			// `type`=VALUES(`type`),`status`=VALUES(`status`),`errmsg`=VALUES(`errmsg`),`ext_bytes`=VALUES(`ext_bytes`)
-			fn: "UpsertWorker",
+			// SELECT * FROM `master_meta_kv_data` WHERE status = 1
+			fn: "QueryJobsByStatusOnly",
 			inputs: []interface{}{
-				&libModel.WorkerStatus{
+				1,
+			},
+			output: []*libModel.MasterMetaKVData{
+				{
 					Model: model.Model{
+						SeqID:     1,
 						CreatedAt: createdAt,
 						UpdatedAt: updatedAt,
 					},
-					ProjectID:    "p111",
-					JobID:        "j111",
-					ID:           "w222",
-					Type:         1,
-					Code:         1,
-					ErrorMessage: "error",
-					ExtBytes:     []byte{0x11, 0x22},
+					ProjectID:  "p111",
+					ID:         "j111",
+					StatusCode: 1,
+				},
+				{
+					Model: model.Model{
+						SeqID:     2,
+						CreatedAt: createdAt,
+						UpdatedAt: updatedAt,
+					},
+					ProjectID:  "p112",
+					ID:         "j112",
+					StatusCode: 1,
 				},
 			},
 			mockExpectResFn: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("ON DUPLICATE KEY UPDATE").WillReturnResult(sqlmock.NewResult(1, 1))
+				expectedSQL := "SELECT * FROM `master_meta_kv_data` WHERE status = ? AND `master_meta_kv_data`.`deleted` IS NULL"
+				mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs(1).WillReturnRows(
+					sqlmock.NewRows([]string{
+						"created_at", "updated_at", "project_id", "id", "status", "seq_id",
+					}).AddRow(createdAt, updatedAt, "p111", "j111", 1, 1).
+						AddRow(createdAt, updatedAt, "p112", "j112", 1, 2))
 			},
 		},
 		{
-			fn: "UpsertWorker",
+			fn: "QueryJobsByStatusOnly",
 			inputs: []interface{}{
-				&libModel.WorkerStatus{
+				1,
+			},
+			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				expectedSQL := "SELECT * FROM `master_meta_kv_data` WHERE status = ? AND `master_meta_kv_data`.`deleted` IS NULL"
+				mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs(1).WillReturnError(
+					errors.New("QueryJobsByStatusOnly error"))
+			},
+		},
+		{
+			// SELECT * FROM `master_meta_kv_data` WHERE updated_at > '2022-04-29 18:49:40.932' ORDER BY updated_at, seq_id
+			fn: "QueryJobsUpdatedAfter",
+			inputs: []interface{}{
+				createdAt,
+			},
+			output: []*libModel.MasterMetaKVData{
+				{
 					Model: model.Model{
 						SeqID:     1,
 						CreatedAt: createdAt,
 						UpdatedAt: updatedAt,
 					},
-					ProjectID:    "p111",
-					JobID:        "j111",
-					ID:           "w222",
-					Type:         1,
-					Code:         1,
-					ErrorMessage: "error",
-					ExtBytes:     []byte{0x11, 0x22},
+					ProjectID:  "p111",
+					ID:         "j111",
+					StatusCode: 1,
 				},
 			},
-			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
 			mockExpectResFn: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("INSERT INTO `worker_statuses` [(]`created_at`,`updated_at`,`project_id`,`job_id`," +
-					"`id`,`type`,`status`,`errmsg`,`ext_bytes`,`seq_id`[)]").WillReturnError(&mysql.MySQLError{Number: 1062, Message: "error"})
+				expectedSQL := "SELECT * FROM `master_meta_kv_data` WHERE updated_at > ? AND `master_meta_kv_data`.`deleted` IS NULL ORDER BY updated_at, seq_id"
+				mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs(createdAt).WillReturnRows(
+					sqlmock.NewRows([]string{
+						"created_at", "updated_at", "project_id", "id", "status", "seq_id",
+					}).AddRow(createdAt, updatedAt, "p111", "j111", 1, 1))
 			},
 		},
 		{
-			fn: "DeleteWorker",
+			fn: "QueryJobsUpdatedAfter",
 			inputs: []interface{}{
-				"j111",
-				"w222",
+				createdAt,
 			},
 			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
 			mockExpectResFn: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("DELETE FROM `worker_statuses` WHERE job_id").WithArgs(
-					"j111", "w222").WillReturnError(errors.New("DeleteWorker error"))
+				expectedSQL := "SELECT * FROM `master_meta_kv_data` WHERE updated_at > ? AND `master_meta_kv_data`.`deleted` IS NULL ORDER BY updated_at, seq_id"
+				mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs(createdAt).WillReturnError(
+					errors.New("QueryJobsUpdatedAfter error"))
 			},
 		},
 		{
-			// DELETE FROM `worker_statuses` WHERE project_id = '111-222-334' AND job_id = '111' AND worker_id = '222'
-			fn: "DeleteWorker",
-			inputs: []interface{}{
-				"j112",
-				"w223",
-			},
-			output: &ormResult{
-				rowsAffected: 1,
+			// SELECT status, count(*) AS count FROM `master_meta_kv_data` WHERE `master_meta_kv_data`.`deleted` IS NULL GROUP BY `status`
+			fn:     "CountJobsByStatus",
+			inputs: []interface{}{},
+			output: map[int]int64{
+				1: 3,
+				3: 2,
 			},
 			mockExpectResFn: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("DELETE FROM `worker_statuses` WHERE job_id").WithArgs(
-					"j112", "w223").WillReturnResult(sqlmock.NewResult(0, 1))
+				expectedSQL := "SELECT status, count(*) AS count FROM `master_meta_kv_data` WHERE `master_meta_kv_data`.`deleted` IS NULL GROUP BY `status`"
+				mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WillReturnRows(
+					sqlmock.NewRows([]string{"status", "count"}).AddRow(1, 3).AddRow(3, 2))
 			},
 		},
 		{
-			// 'UPDATE `worker_statuses` SET `error-message`=?,`ext-bytes`=?,`id`=?,`job_id`=?,`project_id`=?,`status`=?,`type`=?,`updated_at`=? WHERE job_id = ? && id = ?'
-			fn: "UpdateWorker",
-			inputs: []interface{}{
-				&libModel.WorkerStatus{
-					ProjectID:    "p111",
-					JobID:        "j111",
-					ID:           "w111",
-					Type:         1,
-					Code:         1,
-					ErrorMessage: "error",
-					ExtBytes:     []byte{0x11, 0x22},
-				},
-			},
+			fn:     "CountJobsByStatus",
+			inputs: []interface{}{},
+			err:    cerrors.ErrMetaOpFail.GenWithStackByArgs(),
 			mockExpectResFn: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("UPDATE `worker_statuses` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT status, count[(][*][)] AS count FROM `master_meta_kv_data`").WillReturnError(
+					errors.New("CountJobsByStatus error"))
 			},
 		},
 		{
-			// SELECT * FROM `worker_statuses` WHERE project_id = '111-222-333' AND job_id = '111' AND
-			// worker_id = '222' ORDER BY `worker_statuses`.`id` LIMIT 1
-			fn: "GetWorkerByID",
+			// SELECT status, count(*) AS count FROM `master_meta_kv_data` WHERE project_id = ? AND `master_meta_kv_data`.`deleted` IS NULL GROUP BY `status`
+			fn: "CountProjectJobsByStatus",
 			inputs: []interface{}{
-				"j111",
-				"w222",
+				"p111",
 			},
-			output: &libModel.WorkerStatus{
-				Model: model.Model{
-					SeqID:     1,
-					CreatedAt: createdAt,
-					UpdatedAt: updatedAt,
-				},
-				ProjectID:    "p111",
-				JobID:        "j111",
-				ID:           "w222",
-				Type:         1,
-				Code:         1,
-				ErrorMessage: "error",
-				ExtBytes:     []byte{0x11, 0x22},
+			output: map[int]int64{
+				2: 1,
+			},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				expectedSQL := "SELECT status, count(*) AS count FROM `master_meta_kv_data` WHERE project_id = ? AND `master_meta_kv_data`.`deleted` IS NULL GROUP BY `status`"
+				mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("p111").WillReturnRows(
+					sqlmock.NewRows([]string{"status", "count"}).AddRow(2, 1))
+			},
+		},
+		{
+			// SELECT id, status IN (?,?) AS terminal FROM `master_meta_kv_data` WHERE `master_meta_kv_data`.`deleted` IS NULL
+			fn:     "QueryJobTerminalStates",
+			inputs: []interface{}{},
+			output: map[string]bool{
+				"j111": true,
+				"j112": false,
+			},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				expectedSQL := "SELECT id, status IN (?,?) AS terminal FROM `master_meta_kv_data` WHERE `master_meta_kv_data`.`deleted` IS NULL"
+				mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).
+					WithArgs(libModel.MasterStatusFinished, libModel.MasterStatusStopped).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "terminal"}).AddRow("j111", true).AddRow("j112", false))
+			},
+		},
+		{
+			fn:     "QueryJobTerminalStates",
+			inputs: []interface{}{},
+			err:    cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, status IN").WillReturnError(
+					errors.New("QueryJobTerminalStates error"))
+			},
+		},
+		{
+			// SELECT DISTINCT `project_id` FROM `master_meta_kv_data` WHERE status IN (?,?) AND `master_meta_kv_data`.`deleted` IS NULL
+			fn:     "ListActiveProjectIDs",
+			inputs: []interface{}{},
+			output: []string{"p111", "p112"},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				expectedSQL := "SELECT DISTINCT `project_id` FROM `master_meta_kv_data` WHERE status IN (?,?) AND `master_meta_kv_data`.`deleted` IS NULL"
+				mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).
+					WithArgs(libModel.MasterStatusUninit, libModel.MasterStatusInit).
+					WillReturnRows(sqlmock.NewRows([]string{"project_id"}).AddRow("p111").AddRow("p112"))
+			},
+		},
+		{
+			fn:     "ListActiveProjectIDs",
+			inputs: []interface{}{},
+			err:    cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT DISTINCT `project_id`").WillReturnError(
+					errors.New("ListActiveProjectIDs error"))
+			},
+		},
+		{
+			fn: "MoveJobToProject",
+			inputs: []interface{}{
+				"j111",
+				"p222",
+			},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `master_meta_kv_data` SET `project_id`=?,`updated_at`=? WHERE id = ?")).
+					WithArgs("p222", anyTime{}, "j111").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `worker_statuses` SET `project_id`=?,`updated_at`=? WHERE job_id = ?")).
+					WithArgs("p222", anyTime{}, "j111").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `resource_meta` SET `project_id`=?,`updated_at`=? WHERE job_id = ?")).
+					WithArgs("p222", anyTime{}, "j111").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `project_operations` (`project_id`,`operation`,`job_id`,`created_at`)")).
+					WithArgs("p222", model.ProjectOperationMove, "j111", anyTime{}).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			// the job doesn't exist
+			fn: "MoveJobToProject",
+			inputs: []interface{}{
+				"j111",
+				"p222",
+			},
+			err: cerrors.ErrMetaEntryNotFound.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE `master_meta_kv_data` SET").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			// the moved job is rolled back if the following statement fails
+			fn: "MoveJobToProject",
+			inputs: []interface{}{
+				"j111",
+				"p222",
+			},
+			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE `master_meta_kv_data` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE `worker_statuses` SET").WillReturnError(errors.New("MoveJobToProject error"))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			fn: "MoveJobToProject",
+			inputs: []interface{}{
+				"j111",
+				"",
+			},
+			err:             cerrors.ErrMetaParamsInvalid.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		testInner(t, mock, cli, tc)
+	}
+}
+
+func TestJobDeadline(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	// the query would take much longer than the deadline of the caller
+	expectedSQL := "SELECT * FROM `master_meta_kv_data` WHERE id = ? AND `master_meta_kv_data`.`deleted` IS NULL ORDER BY `master_meta_kv_data`.`seq_id` LIMIT 1"
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("j111").
+		WillDelayFor(10 * time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("j111"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = cli.GetJobByID(ctx, "j111")
+	require.Error(t, err)
+	require.Regexp(t, ".*ErrMetaOpFail.*", err)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestContextDeadline(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	// the deadline is exceeded before any SQL is sent
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+
+	checkErr := func(err error) {
+		require.Error(t, err)
+		require.Regexp(t, ".*ErrMetaOpFail.*", err)
+		require.ErrorIs(t, perrors.Cause(err), context.DeadlineExceeded)
+	}
+	checkErr(cli.CreateProject(ctx, &model.ProjectInfo{ID: "p111", Name: "tenant1"}))
+	_, err = cli.QueryJobs(ctx)
+	checkErr(err)
+	_, err = cli.GenEpoch(ctx)
+	checkErr(err)
+	checkErr(cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: "w222"}))
+	_, err = cli.QueryResources(ctx)
+	checkErr(err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestGenEpochCanceledInTxn(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT 1 FROM `counters`")).
+		WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `counters`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `counters` WHERE name = ?")).
+		WillDelayFor(10 * time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"seq_id", "name", "value"}).AddRow(1, model.LogicEpochCounter, 1))
+	mock.ExpectRollback()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = cli.GenEpoch(ctx)
+	require.Error(t, err)
+	require.Regexp(t, ".*ErrMetaOpFail.*", err)
+	require.Less(t, time.Since(start), 5*time.Second)
+	// the transaction is rolled back by database/sql asynchronously
+	require.Eventually(t, func() bool {
+		return mock.ExpectationsWereMet() == nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestWorker(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	tm := time.Now()
+	createdAt := tm.Add(time.Duration(1))
+	updatedAt := tm.Add(time.Duration(1))
+
+	testCases := []tCase{
+		{
+			// INSERT INTO `worker_statuses` (`created_at`,`updated_at`,`project_id`,`job_id`,`id`,`type`,`status`,`errmsg`,`ext_bytes`,`progress`,`owner`,`lease_expiry`)
+			// VALUES ('2022-04-29 18:49:40.932','2022-04-29 18:49:40.932','p111','j111','w222',1,'1','error','<binary>',NULL,'',NULL) ON DUPLICATE KEY
+			// UPDATE `updated_at`=VALUES(`updated_at`),`project_id`=VALUES(`project_id`),`job_id`=VALUES(`job_id`),`id`=VALUES(`id`),
+			// `type`=VALUES(`type`),`status`=VALUES(`status`),`errmsg`=VALUES(`errmsg`),`ext_bytes`=VALUES(`ext_bytes`),`progress`=VALUES(`progress`)
+			fn: "UpsertWorker",
+			inputs: []interface{}{
+				&libModel.WorkerStatus{
+					Model: model.Model{
+						CreatedAt: createdAt,
+						UpdatedAt: updatedAt,
+					},
+					ProjectID:    "p111",
+					JobID:        "j111",
+					ID:           "w222",
+					Type:         1,
+					Code:         1,
+					ErrorMessage: "error",
+					ExtBytes:     []byte{0x11, 0x22},
+				},
+			},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("ON DUPLICATE KEY UPDATE").WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{
+			fn: "UpsertWorker",
+			inputs: []interface{}{
+				&libModel.WorkerStatus{
+					Model: model.Model{
+						SeqID:     1,
+						CreatedAt: createdAt,
+						UpdatedAt: updatedAt,
+					},
+					ProjectID:    "p111",
+					JobID:        "j111",
+					ID:           "w222",
+					Type:         1,
+					Code:         1,
+					ErrorMessage: "error",
+					ExtBytes:     []byte{0x11, 0x22},
+				},
+			},
+			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO `worker_statuses` [(]`created_at`,`updated_at`,`project_id`,`job_id`," +
+					"`id`,`type`,`status`,`errmsg`,`ext_bytes`,`progress`,`owner`,`lease_expiry`,`seq_id`[)]").WillReturnError(&mysql.MySQLError{Number: 1062, Message: "error"})
+			},
+		},
+		{
+			fn: "DeleteWorker",
+			inputs: []interface{}{
+				"j111",
+				"w222",
+			},
+			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM `worker_statuses` WHERE job_id").WithArgs(
+					"j111", "w222").WillReturnError(errors.New("DeleteWorker error"))
+			},
+		},
+		{
+			// DELETE FROM `worker_statuses` WHERE project_id = '111-222-334' AND job_id = '111' AND worker_id = '222'
+			fn: "DeleteWorker",
+			inputs: []interface{}{
+				"j112",
+				"w223",
+			},
+			output: &ormResult{
+				rowsAffected: 1,
+			},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM `worker_statuses` WHERE job_id").WithArgs(
+					"j112", "w223").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			fn: "DeleteWorkersByMasterID",
+			inputs: []interface{}{
+				"j111",
+			},
+			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `worker_statuses` WHERE job_id = ?")).WithArgs(
+					"j111").WillReturnError(errors.New("DeleteWorkersByMasterID error"))
+			},
+		},
+		{
+			// DELETE FROM `worker_statuses` WHERE job_id = '111'
+			fn: "DeleteWorkersByMasterID",
+			inputs: []interface{}{
+				"j112",
+			},
+			output: int64(3),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `worker_statuses` WHERE job_id = ?")).WithArgs(
+					"j112").WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+		},
+		{
+			// 'UPDATE `worker_statuses` SET `error-message`=?,`ext-bytes`=?,`id`=?,`job_id`=?,`project_id`=?,`status`=?,`type`=?,`updated_at`=? WHERE job_id = ? && id = ?'
+			fn: "UpdateWorker",
+			inputs: []interface{}{
+				&libModel.WorkerStatus{
+					ProjectID:    "p111",
+					JobID:        "j111",
+					ID:           "w111",
+					Type:         1,
+					Code:         1,
+					ErrorMessage: "error",
+					ExtBytes:     []byte{0x11, 0x22},
+				},
+			},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE `worker_statuses` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			// SELECT * FROM `worker_statuses` WHERE project_id = '111-222-333' AND job_id = '111' AND
+			// worker_id = '222' ORDER BY `worker_statuses`.`id` LIMIT 1
+			fn: "GetWorkerByID",
+			inputs: []interface{}{
+				"j111",
+				"w222",
+			},
+			output: &libModel.WorkerStatus{
+				Model: model.Model{
+					SeqID:     1,
+					CreatedAt: createdAt,
+					UpdatedAt: updatedAt,
+				},
+				ProjectID:    "p111",
+				JobID:        "j111",
+				ID:           "w222",
+				Type:         1,
+				Code:         1,
+				ErrorMessage: "error",
+				ExtBytes:     []byte{0x11, 0x22},
 			},
 			mockExpectResFn: func(mock sqlmock.Sqlmock) {
 				mock.ExpectQuery("SELECT [*] FROM `worker_statuses` WHERE job_id").WithArgs("j111", "w222").WillReturnRows(
@@ -847,7 +1462,7 @@ func TestResource(t *testing.T) {
 	defer sqlDB.Close()
 	defer mock.ExpectClose()
 	require.Nil(t, err)
-	cli, err := newClient(sqlDB)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
 	require.Nil(t, err)
 	require.NotNil(t, cli)
 
@@ -880,8 +1495,8 @@ func TestResource(t *testing.T) {
 						"count(1)",
 					}).AddRow(0))
 				mock.ExpectExec("INSERT INTO `resource_meta` [(]`created_at`,`updated_at`,`project_id`,`id`,`job_id`,"+
-					"`worker_id`,`executor_id`,`deleted`,`seq_id`[)]").WithArgs(
-					createdAt, updatedAt, "111-222-333", "r333", "j111", "w222", "e444", false, 1).
+					"`worker_id`,`executor_id`,`deleted`,`content_type`,`seq_id`[)]").WithArgs(
+					createdAt, updatedAt, "111-222-333", "r333", "j111", "w222", "e444", false, "", 1).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 				mock.ExpectCommit()
 			},
@@ -954,8 +1569,8 @@ func TestResource(t *testing.T) {
 			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
 			mockExpectResFn: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("INSERT INTO `resource_meta` [(]`created_at`,`updated_at`,`project_id`,`id`,`job_id`,"+
-					"`worker_id`,`executor_id`,`deleted`,`seq_id`[)]").WithArgs(
-					createdAt, updatedAt, "111-222-333", "r333", "j111", "w222", "e444", true, 1).WillReturnError(&mysql.MySQLError{Number: 1062, Message: "error"})
+					"`worker_id`,`executor_id`,`deleted`,`content_type`,`seq_id`[)]").WithArgs(
+					createdAt, updatedAt, "111-222-333", "r333", "j111", "w222", "e444", true, "", 1).WillReturnError(&mysql.MySQLError{Number: 1062, Message: "error"})
 			},
 		},
 		{
@@ -983,7 +1598,7 @@ func TestResource(t *testing.T) {
 			},
 		},
 		{
-			// 'UPDATE `resource_meta` SET `deleted`=?,`executor_id`=?,`id`=?,`job_id`=?,`project_id`=?,`worker_id`=?,`updated_at`=? WHERE id = ?'
+			// 'UPDATE `resource_meta` SET `content_type`=?,`deleted`=?,`executor_id`=?,`id`=?,`job_id`=?,`project_id`=?,`worker_id`=?,`updated_at`=? WHERE id = ?'
 			fn: "UpdateResource",
 			inputs: []interface{}{
 				&resourcemeta.ResourceMeta{
@@ -1116,21 +1731,583 @@ func TestResource(t *testing.T) {
 					errors.New("QueryResourcesByExecutorID error"))
 			},
 		},
-	}
-
-	for _, tc := range testCases {
-		testInner(t, mock, cli, tc)
-	}
-}
-
-func TestError(t *testing.T) {
+		{
+			fn: "QueryResourcesByDeleted",
+			inputs: []interface{}{
+				true,
+			},
+			output: []*resourcemeta.ResourceMeta{
+				{
+					Model: model.Model{
+						SeqID:     1,
+						CreatedAt: createdAt,
+						UpdatedAt: updatedAt,
+					},
+					ID:        "r333",
+					ProjectID: "111-222-333",
+					Job:       "j111",
+					Worker:    "w222",
+					Executor:  "e444",
+					Deleted:   true,
+				},
+			},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT [*] FROM `resource_meta` WHERE deleted").WithArgs(true).WillReturnRows(
+					sqlmock.NewRows([]string{
+						"created_at", "updated_at", "project_id", "id", "job_id",
+						"worker_id", "executor_id", "deleted", "seq_id",
+					}).AddRow(createdAt, updatedAt, "111-222-333", "r333", "j111", "w222", "e444", true, 1))
+			},
+		},
+		{
+			fn: "QueryResourcesByDeleted",
+			inputs: []interface{}{
+				false,
+			},
+			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT [*] FROM `resource_meta` WHERE deleted").WithArgs(false).WillReturnError(
+					errors.New("QueryResourcesByDeleted error"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		testInner(t, mock, cli, tc)
+	}
+}
+
+func TestQueryOrphanedResources(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	tm := time.Now()
+	createdAt := tm.Add(time.Duration(1))
+	updatedAt := tm.Add(time.Duration(1))
+
+	expectedSQL := "SELECT resource_meta.* FROM `resource_meta` LEFT JOIN master_meta_kv_data ON " +
+		"master_meta_kv_data.id = resource_meta.job_id AND master_meta_kv_data.deleted IS NULL " +
+		"WHERE master_meta_kv_data.id IS NULL"
+	testCases := []tCase{
+		{
+			fn:     "QueryOrphanedResources",
+			inputs: []interface{}{},
+			output: []*resourcemeta.ResourceMeta{
+				{
+					Model: model.Model{
+						SeqID:     1,
+						CreatedAt: createdAt,
+						UpdatedAt: updatedAt,
+					},
+					ID:        "r333",
+					ProjectID: "111-222-333",
+					Job:       "j111",
+					Worker:    "w222",
+					Executor:  "e444",
+				},
+			},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WillReturnRows(
+					sqlmock.NewRows([]string{
+						"created_at", "updated_at", "project_id", "id", "job_id",
+						"worker_id", "executor_id", "deleted", "seq_id",
+					}).AddRow(createdAt, updatedAt, "111-222-333", "r333", "j111", "w222", "e444", false, 1))
+			},
+		},
+		{
+			fn:     "QueryOrphanedResources",
+			inputs: []interface{}{},
+			err:    cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WillReturnError(
+					errors.New("QueryOrphanedResources error"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		testInner(t, mock, cli, tc)
+	}
+}
+
+func TestQueryInBatches(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	columns := []string{"seq_id", "id", "job_id"}
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT * FROM `resource_meta` ORDER BY `resource_meta`.`seq_id` LIMIT 2")).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "r111", "j111").AddRow(2, "r222", "j111"))
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT * FROM `resource_meta` WHERE `resource_meta`.`seq_id` > ? ORDER BY `resource_meta`.`seq_id` LIMIT 2")).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, "r333", "j111"))
+
+	var ids [][]string
+	err = cli.QueryResourcesInBatches(context.TODO(), 2, func(resources []*resourcemeta.ResourceMeta) error {
+		var batch []string
+		for _, resource := range resources {
+			batch = append(batch, resource.ID)
+		}
+		ids = append(ids, batch)
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, [][]string{{"r111", "r222"}, {"r333"}}, ids)
+	require.Nil(t, mock.ExpectationsWereMet())
+
+	mock.ExpectQuery("SELECT [*] FROM `resource_meta`").WillReturnError(
+		errors.New("QueryResourcesInBatches error"))
+	err = cli.QueryResourcesInBatches(context.TODO(), 2, func(resources []*resourcemeta.ResourceMeta) error {
+		return nil
+	})
+	require.Error(t, err)
+	require.Regexp(t, ".*ErrMetaOpFail.*", err)
+}
+
+func TestQueryWithPagination(t *testing.T) {
 	t.Parallel()
 
 	sqlDB, mock, err := mockGetDBConn(t, "test")
 	defer sqlDB.Close()
 	defer mock.ExpectClose()
 	require.Nil(t, err)
-	cli, err := newClient(sqlDB)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT * FROM `master_meta_kv_data` WHERE `master_meta_kv_data`.`deleted` IS NULL ORDER BY seq_id LIMIT 10 OFFSET 20")).
+		WillReturnRows(sqlmock.NewRows([]string{"seq_id", "id"}).AddRow(21, "j21"))
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT count(*) FROM `master_meta_kv_data` WHERE `master_meta_kv_data`.`deleted` IS NULL")).
+		WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(21))
+	jobs, total, err := cli.QueryJobsWithPagination(context.TODO(), 20, 10)
+	require.Nil(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, "j21", jobs[0].ID)
+	require.Equal(t, int64(21), total)
+	require.Nil(t, mock.ExpectationsWereMet())
+
+	// the count query is skipped if all the rows are loaded
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `project_infos` ORDER BY seq_id")).
+		WillReturnRows(sqlmock.NewRows([]string{"seq_id", "id"}).AddRow(1, "p1").AddRow(2, "p2"))
+	projects, total, err := cli.QueryProjectsWithPagination(context.TODO(), 0, 0)
+	require.Nil(t, err)
+	require.Len(t, projects, 2)
+	require.Equal(t, int64(2), total)
+	require.Nil(t, mock.ExpectationsWereMet())
+
+	mock.ExpectQuery("SELECT [*] FROM `master_meta_kv_data`").WillReturnError(
+		errors.New("QueryJobsWithPagination error"))
+	_, _, err = cli.QueryJobsWithPagination(context.TODO(), 0, 10)
+	require.Error(t, err)
+	require.Regexp(t, ".*ErrMetaOpFail.*", err)
+}
+
+func TestCount(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT count(*) FROM `master_meta_kv_data` WHERE `master_meta_kv_data`.`deleted` IS NULL")).
+		WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(5))
+	cnt, err := cli.CountJobs(context.TODO())
+	require.Nil(t, err)
+	require.Equal(t, int64(5), cnt)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `worker_statuses` WHERE job_id = ?")).
+		WithArgs("j111").WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(3))
+	cnt, err = cli.CountWorkersByMasterID(context.TODO(), "j111")
+	require.Nil(t, err)
+	require.Equal(t, int64(3), cnt)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `resource_meta` WHERE job_id = ?")).
+		WithArgs("j111").WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(2))
+	cnt, err = cli.CountResourcesByJobID(context.TODO(), "j111")
+	require.Nil(t, err)
+	require.Equal(t, int64(2), cnt)
+	require.Nil(t, mock.ExpectationsWereMet())
+
+	mock.ExpectQuery("SELECT count[(][*][)] FROM `resource_meta`").WillReturnError(
+		errors.New("CountResourcesByJobID error"))
+	_, err = cli.CountResourcesByJobID(context.TODO(), "j111")
+	require.Error(t, err)
+	require.Regexp(t, ".*ErrMetaOpFail.*", err)
+}
+
+func TestTransaction(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `master_meta_kv_data`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `worker_statuses`")).
+		WillReturnError(errors.New("UpsertWorker error"))
+	mock.ExpectRollback()
+	err = cli.Transaction(context.TODO(), func(tx Client) error {
+		if err := tx.UpsertJob(context.TODO(), &libModel.MasterMetaKVData{ID: "j111"}); err != nil {
+			return err
+		}
+		return tx.UpsertWorker(context.TODO(), &libModel.WorkerStatus{JobID: "j111", ID: "w111"})
+	})
+	require.Error(t, err)
+	require.Regexp(t, ".*ErrMetaOpFail.*", err)
+	require.Nil(t, mock.ExpectationsWereMet())
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `master_meta_kv_data`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `worker_statuses`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit().WillReturnError(errors.New("commit error"))
+	err = cli.Transaction(context.TODO(), func(tx Client) error {
+		if err := tx.UpsertJob(context.TODO(), &libModel.MasterMetaKVData{ID: "j111"}); err != nil {
+			return err
+		}
+		return tx.UpsertWorker(context.TODO(), &libModel.WorkerStatus{JobID: "j111", ID: "w111"})
+	})
+	require.Error(t, err)
+	require.Regexp(t, ".*ErrMetaOpFail.*commit error.*", err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestJobLabels(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	tm := time.Now()
+	createdAt := tm.Add(time.Duration(1))
+	updatedAt := tm.Add(time.Duration(1))
+
+	expectedSQL := "SELECT master_meta_kv_data.* FROM `master_meta_kv_data` JOIN job_labels ON " +
+		"job_labels.job_id = master_meta_kv_data.id WHERE (job_labels.label_key = ? AND " +
+		"job_labels.label_value = ?) AND `master_meta_kv_data`.`deleted` IS NULL"
+	testCases := []tCase{
+		{
+			// DELETE FROM `job_labels` WHERE job_id = ?
+			// INSERT INTO `job_labels` (`created_at`,`updated_at`,`job_id`,`label_key`,`label_value`) VALUES (?,?,?,?,?)
+			fn: "SetJobLabels",
+			inputs: []interface{}{
+				"j111",
+				map[string]string{"team": "dm"},
+			},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `job_labels` WHERE job_id = ?")).
+					WithArgs("j111").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `job_labels` (`created_at`,`updated_at`,`job_id`,`label_key`,`label_value`)")).
+					WithArgs(anyTime{}, anyTime{}, "j111", "team", "dm").WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			fn: "SetJobLabels",
+			inputs: []interface{}{
+				"j111",
+				map[string]string{},
+			},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `job_labels` WHERE job_id = ?")).
+					WithArgs("j111").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			fn: "SetJobLabels",
+			inputs: []interface{}{
+				"j111",
+				map[string]string{"": "dm"},
+			},
+			err:             cerrors.ErrMetaParamsInvalid.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {},
+		},
+		{
+			fn: "SetJobLabels",
+			inputs: []interface{}{
+				"j111",
+				map[string]string{"team": "dm"},
+			},
+			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `job_labels` WHERE job_id = ?")).
+					WithArgs("j111").WillReturnError(errors.New("SetJobLabels error"))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			// SELECT * FROM `job_labels` WHERE job_id = ?
+			fn: "GetJobLabels",
+			inputs: []interface{}{
+				"j111",
+			},
+			output: map[string]string{"team": "dm", "env": "prod"},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `job_labels` WHERE job_id = ?")).WithArgs("j111").WillReturnRows(
+					sqlmock.NewRows([]string{"created_at", "updated_at", "job_id", "label_key", "label_value", "seq_id"}).
+						AddRow(createdAt, updatedAt, "j111", "team", "dm", 1).
+						AddRow(createdAt, updatedAt, "j111", "env", "prod", 2))
+			},
+		},
+		{
+			fn: "GetJobLabels",
+			inputs: []interface{}{
+				"j111",
+			},
+			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `job_labels` WHERE job_id = ?")).WithArgs("j111").WillReturnError(
+					errors.New("GetJobLabels error"))
+			},
+		},
+		{
+			fn: "QueryJobsByLabel",
+			inputs: []interface{}{
+				"team",
+				"dm",
+			},
+			output: []*libModel.MasterMetaKVData{
+				{
+					Model: model.Model{
+						SeqID:     1,
+						CreatedAt: createdAt,
+						UpdatedAt: updatedAt,
+					},
+					ProjectID:  "p111",
+					ID:         "j111",
+					Tp:         1,
+					NodeID:     "n111",
+					Epoch:      1,
+					StatusCode: 1,
+					Addr:       "1.1.1.1",
+					Config:     []byte{0x11, 0x22},
+				},
+			},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("team", "dm").WillReturnRows(
+					sqlmock.NewRows([]string{
+						"created_at", "updated_at", "project_id", "id",
+						"type", "status", "node_id", "address", "epoch", "config", "seq_id",
+					}).AddRow(
+						createdAt, updatedAt, "p111", "j111", 1, 1, "n111", "1.1.1.1", 1, []byte{0x11, 0x22}, 1))
+			},
+		},
+		{
+			fn: "QueryJobsByLabel",
+			inputs: []interface{}{
+				"team",
+				"dm",
+			},
+			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("team", "dm").WillReturnError(
+					errors.New("QueryJobsByLabel error"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		testInner(t, mock, cli, tc)
+	}
+}
+
+func TestExists(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	jobSQL := "SELECT 1 FROM `master_meta_kv_data` WHERE id = ? AND `master_meta_kv_data`.`deleted` IS NULL LIMIT 1"
+	workerSQL := "SELECT 1 FROM `worker_statuses` WHERE job_id = ? AND id = ? LIMIT 1"
+	resourceSQL := "SELECT 1 FROM `resource_meta` WHERE id = ? LIMIT 1"
+	testCases := []tCase{
+		{
+			fn: "JobExists",
+			inputs: []interface{}{
+				"j111",
+			},
+			output: true,
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(jobSQL)).WithArgs("j111").WillReturnRows(
+					sqlmock.NewRows([]string{"1"}).AddRow(1))
+			},
+		},
+		{
+			fn: "JobExists",
+			inputs: []interface{}{
+				"j112",
+			},
+			output: false,
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(jobSQL)).WithArgs("j112").WillReturnRows(
+					sqlmock.NewRows([]string{"1"}))
+			},
+		},
+		{
+			fn: "JobExists",
+			inputs: []interface{}{
+				"j111",
+			},
+			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(jobSQL)).WithArgs("j111").WillReturnError(
+					errors.New("JobExists error"))
+			},
+		},
+		{
+			fn: "WorkerExists",
+			inputs: []interface{}{
+				"j111",
+				"w222",
+			},
+			output: true,
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(workerSQL)).WithArgs("j111", "w222").WillReturnRows(
+					sqlmock.NewRows([]string{"1"}).AddRow(1))
+			},
+		},
+		{
+			fn: "ResourceExists",
+			inputs: []interface{}{
+				"r333",
+			},
+			output: false,
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(resourceSQL)).WithArgs("r333").WillReturnRows(
+					sqlmock.NewRows([]string{"1"}))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		testInner(t, mock, cli, tc)
+	}
+}
+
+func TestQueryProjectsWithStats(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	tm := time.Now()
+	createdAt := tm.Add(time.Duration(1))
+	updatedAt := tm.Add(time.Duration(1))
+	lastOpTime := tm.Add(time.Duration(2))
+
+	expectedSQL := "SELECT project_infos.*, (SELECT count(*) FROM `master_meta_kv_data` WHERE " +
+		"master_meta_kv_data.project_id = project_infos.id AND `master_meta_kv_data`.`deleted` IS NULL) AS job_count, " +
+		"(SELECT MAX(created_at) FROM `project_operations` WHERE project_operations.project_id = project_infos.id) " +
+		"AS last_op_time FROM `project_infos`"
+	testCases := []tCase{
+		{
+			fn:     "QueryProjectsWithStats",
+			inputs: []interface{}{},
+			output: []*model.ProjectStats{
+				{
+					ProjectInfo: model.ProjectInfo{
+						Model: model.Model{
+							SeqID:     1,
+							CreatedAt: createdAt,
+							UpdatedAt: updatedAt,
+						},
+						ID:   "p111",
+						Name: "tenant1",
+					},
+					JobCount:   2,
+					LastOpTime: &lastOpTime,
+				},
+				{
+					ProjectInfo: model.ProjectInfo{
+						Model: model.Model{
+							SeqID:     2,
+							CreatedAt: createdAt,
+							UpdatedAt: updatedAt,
+						},
+						ID:   "p112",
+						Name: "tenant2",
+					},
+				},
+			},
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WillReturnRows(
+					sqlmock.NewRows([]string{"created_at", "updated_at", "id", "name", "seq_id", "job_count", "last_op_time"}).
+						AddRow(createdAt, updatedAt, "p111", "tenant1", 1, 2, lastOpTime).
+						AddRow(createdAt, updatedAt, "p112", "tenant2", 2, 0, nil))
+			},
+		},
+		{
+			fn:     "QueryProjectsWithStats",
+			inputs: []interface{}{},
+			err:    cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WillReturnError(
+					errors.New("QueryProjectsWithStats error"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		testInner(t, mock, cli, tc)
+	}
+}
+
+func TestError(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
 	require.Nil(t, err)
 	require.NotNil(t, cli)
 
@@ -1155,6 +2332,60 @@ func TestError(t *testing.T) {
 	require.True(t, e.Is(cerrors.ErrMetaEntryNotFound))
 }
 
+func TestGetJobConfigCache(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	type jobConfig struct {
+		SrcHost string `json:"srcHost"`
+	}
+	expectedSQL := "SELECT `config` FROM `master_meta_kv_data` WHERE id = ? AND `master_meta_kv_data`.`deleted` IS NULL"
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("j111").WillReturnRows(
+		sqlmock.NewRows([]string{"config"}).AddRow([]byte(`{"srcHost":"127.0.0.1:1234"}`)))
+
+	var cfg jobConfig
+	err = cli.GetJobConfig(context.TODO(), "j111", &cfg)
+	require.Nil(t, err)
+	require.Equal(t, "127.0.0.1:1234", cfg.SrcHost)
+
+	// the second call hits the cache, no query is sent to backend
+	var cfg2 jobConfig
+	err = cli.GetJobConfig(context.TODO(), "j111", &cfg2)
+	require.Nil(t, err)
+	require.Equal(t, cfg, cfg2)
+	require.Nil(t, mock.ExpectationsWereMet())
+
+	// UpdateJob invalidates the cache
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `master_meta_kv_data` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	err = cli.UpdateJob(context.TODO(), &libModel.MasterMetaKVData{
+		ID:     "j111",
+		Config: []byte(`{"srcHost":"127.0.0.1:5678"}`),
+	}, 0)
+	require.Nil(t, err)
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("j111").WillReturnRows(
+		sqlmock.NewRows([]string{"config"}).AddRow([]byte(`{"srcHost":"127.0.0.1:5678"}`)))
+	err = cli.GetJobConfig(context.TODO(), "j111", &cfg)
+	require.Nil(t, err)
+	require.Equal(t, "127.0.0.1:5678", cfg.SrcHost)
+	require.Nil(t, mock.ExpectationsWereMet())
+
+	// not found job is not cached
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("j112").WillReturnRows(
+		sqlmock.NewRows([]string{"config"}))
+	err = cli.GetJobConfig(context.TODO(), "j112", &cfg)
+	require.Error(t, err)
+	require.True(t, IsNotFoundError(err))
+}
+
 func TestLogicEpoch(t *testing.T) {
 	t.Parallel()
 
@@ -1162,7 +2393,7 @@ func TestLogicEpoch(t *testing.T) {
 	defer sqlDB.Close()
 	defer mock.ExpectClose()
 	require.Nil(t, err)
-	cli, err := newClient(sqlDB)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
 	require.Nil(t, err)
 	require.NotNil(t, cli)
 
@@ -1172,8 +2403,47 @@ func TestLogicEpoch(t *testing.T) {
 			inputs: []interface{}{},
 			err:    cerrors.ErrMetaOpFail.GenWithStackByArgs(),
 			mockExpectResFn: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT [*] FROM `logic_epoches`").WillReturnError(
-					errors.New("InitializeEpoch error"))
+				mock.ExpectQuery("SELECT 1 FROM `counters`").WillReturnError(
+					errors.New("GenEpoch error"))
+			},
+		},
+		{
+			fn: "GenNamedCounter",
+			inputs: []interface{}{
+				"",
+			},
+			err:             cerrors.ErrMetaParamsInvalid.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {},
+		},
+		{
+			fn: "GenNamedCounter",
+			inputs: []interface{}{
+				"seq",
+			},
+			err: cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO `counters`").WillReturnError(
+					errors.New("GenNamedCounter error"))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			fn:     "GetEpoch",
+			inputs: []interface{}{},
+			err:    cerrors.ErrMetaEntryNotFound.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT [*] FROM `counters` WHERE name = [?]").WithArgs("logic_epoch").
+					WillReturnRows(sqlmock.NewRows([]string{"seq_id", "created_at", "updated_at", "name", "value"}))
+			},
+		},
+		{
+			fn:     "GetEpoch",
+			inputs: []interface{}{},
+			err:    cerrors.ErrMetaOpFail.GenWithStackByArgs(),
+			mockExpectResFn: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT [*] FROM `counters` WHERE name = [?]").WithArgs("logic_epoch").
+					WillReturnError(errors.New("GetEpoch error"))
 			},
 		},
 	}
@@ -1181,6 +2451,62 @@ func TestLogicEpoch(t *testing.T) {
 	for _, tc := range testCases {
 		testInner(t, mock, cli, tc)
 	}
+
+	mock.ExpectQuery("SELECT [*] FROM `counters` WHERE name = [?]").WithArgs("logic_epoch").
+		WillReturnRows(sqlmock.NewRows([]string{"seq_id", "created_at", "updated_at", "name", "value"}))
+	_, err = cli.GetEpoch(context.TODO())
+	require.True(t, IsNotFoundError(err))
+}
+
+func TestGenEpochNotInitialized(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	// SELECT 1 FROM `counters` WHERE name = ? LIMIT 1
+	expectedSQL := "SELECT 1 FROM `counters` WHERE name = ? LIMIT 1"
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs(model.LogicEpochCounter).
+		WillReturnRows(sqlmock.NewRows([]string{"1"}))
+	_, err = cli.GenEpoch(context.TODO())
+	require.Error(t, err)
+	require.True(t, cerrors.ErrClientNotInitialized.Equal(err))
+	require.Nil(t, mock.ExpectationsWereMet())
+
+	// the seed row may be created by another client
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs(model.LogicEpochCounter).
+		WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `counters`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `counters` WHERE name = ?")).
+		WillReturnRows(sqlmock.NewRows([]string{"seq_id", "name", "value"}).AddRow(1, model.LogicEpochCounter, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `counters` SET `value`=value + ?")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	epoch, err := cli.GenEpoch(context.TODO())
+	require.Nil(t, err)
+	require.Equal(t, int64(2), epoch)
+	require.Nil(t, mock.ExpectationsWereMet())
+
+	// the backend is not checked again once the seed row is found
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `counters`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `counters` WHERE name = ?")).
+		WillReturnRows(sqlmock.NewRows([]string{"seq_id", "name", "value"}).AddRow(1, model.LogicEpochCounter, 2))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `counters` SET `value`=value + ?")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	epoch, err = cli.GenEpoch(context.TODO())
+	require.Nil(t, err)
+	require.Equal(t, int64(3), epoch)
+	require.Nil(t, mock.ExpectationsWereMet())
 }
 
 func testInner(t *testing.T, m sqlmock.Sqlmock, cli Client, c tCase) {
@@ -1,6 +1,11 @@
 package orm
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	cerrors "github.com/hanfei1991/microcosm/pkg/errors"
+)
 
 // TODO: split the config file
 const (
@@ -9,6 +14,17 @@ const (
 	DefaultFrameMetaPassword  = "123456"
 )
 
+// backends of the framework metastore
+const (
+	// BackendMySQL stores the metastore in a MySQL compatible database, each
+	// project is isolated in its own database
+	BackendMySQL = "mysql"
+	// BackendSQLite stores the metastore in SQLite database files, which is
+	// used by lightweight deployments. The first endpoint of the metastore is
+	// the directory of the files, each project is isolated in its own file.
+	BackendSQLite = "sqlite"
+)
+
 const (
 	defaultConnMaxIdleTime = 30 * time.Second
 	defaultConnMaxLifeTime = 12 * time.Hour
@@ -17,6 +33,14 @@ const (
 	defaultReadTimeout     = "3s"
 	defaultWriteTimeout    = "3s"
 	defaultDialTimeout     = "3s"
+	defaultMaxRetries      = 3
+	defaultRetryBaseDelay  = 50 * time.Millisecond
+	defaultTimezone        = "Local"
+	defaultLogLevel        = "warn"
+	defaultSlowThreshold   = 200 * time.Millisecond
+	// defaultSQLiteBusyTimeout is the time to wait for the lock of a SQLite
+	// database held by another process
+	defaultSQLiteBusyTimeout = 5 * time.Second
 	// TODO: more params for mysql connection
 )
 
@@ -30,6 +54,51 @@ type DBConfig struct {
 	ConnMaxLifeTime time.Duration
 	MaxIdleConns    int
 	MaxOpenConns    int
+	// MaxRetries is the max times to retry an operation that failed with a
+	// transient error, 0 disables retrying
+	MaxRetries int
+	// RetryBaseDelay is the delay before the first retry, it is doubled for
+	// each of the following retries
+	RetryBaseDelay time.Duration
+	// Timezone is the location used to parse and format the datetime columns,
+	// it should be the same as the timezone of the metastore, refer to:
+	// https://github.com/go-sql-driver/mysql#loc
+	Timezone string
+	// LogLevel is the level of the logs of sql executions, it is one of
+	// silent, error, warn and info
+	LogLevel string
+	// SlowThreshold is the threshold of slow queries, which are logged at
+	// warn level, 0 disables the slow query log
+	SlowThreshold time.Duration
+	// Backend is the type of the backend database, it is BackendMySQL or
+	// BackendSQLite, an empty Backend means BackendMySQL
+	Backend string
+}
+
+// validate checks the DBConfig
+func (c DBConfig) validate() error {
+	if c.Timezone != "" {
+		if _, err := time.LoadLocation(c.Timezone); err != nil {
+			return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs(err.Error())
+		}
+	}
+	if c.LogLevel != "" {
+		if _, ok := parseLogLevel(c.LogLevel); !ok {
+			return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs(
+				fmt.Sprintf("invalid log level %s", c.LogLevel))
+		}
+	}
+	switch c.Backend {
+	case "", BackendMySQL, BackendSQLite:
+	default:
+		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs(
+			fmt.Sprintf("invalid backend %s", c.Backend))
+	}
+	if c.SlowThreshold < 0 {
+		return cerrors.ErrMetaParamsInvalid.GenWithStackByArgs(
+			fmt.Sprintf("invalid slow threshold %s", c.SlowThreshold))
+	}
+	return nil
 }
 
 // NewDefaultDBConfig creates a default DBConfig
@@ -42,5 +111,11 @@ func NewDefaultDBConfig() DBConfig {
 		ConnMaxLifeTime: defaultConnMaxLifeTime,
 		MaxIdleConns:    defaultMaxIdleConns,
 		MaxOpenConns:    defaultMaxOpenConns,
+		MaxRetries:      defaultMaxRetries,
+		RetryBaseDelay:  defaultRetryBaseDelay,
+		Timezone:        defaultTimezone,
+		LogLevel:        defaultLogLevel,
+		SlowThreshold:   defaultSlowThreshold,
+		Backend:         BackendMySQL,
 	}
 }
@@ -0,0 +1,71 @@
+package orm
+
+import (
+	"errors"
+
+	dmysql "github.com/go-sql-driver/mysql"
+	perrors "github.com/pingcap/errors"
+	tmysql "github.com/pingcap/tidb/parser/mysql"
+)
+
+// MetaError is the error returned by the metastore operations. Besides the
+// framework error, it carries the MySQL error number and SQL state of the
+// backend error, so callers can check the failure without string matching.
+type MetaError struct {
+	err      *perrors.Error
+	errNo    uint16
+	sqlState string
+}
+
+// newMetaError wraps cause in the framework error rfcErr, the MySQL error
+// number and SQL state are extracted from cause if it is a MySQL error.
+func newMetaError(rfcErr *perrors.Error, cause error) error {
+	if cause == nil {
+		return nil
+	}
+
+	metaErr := &MetaError{err: rfcErr.Wrap(cause)}
+	var mysqlErr *dmysql.MySQLError
+	if errors.As(cause, &mysqlErr) {
+		metaErr.errNo = mysqlErr.Number
+		metaErr.sqlState = tmysql.DefaultMySQLState
+		if state, ok := tmysql.MySQLState[mysqlErr.Number]; ok {
+			metaErr.sqlState = state
+		}
+	}
+	return metaErr
+}
+
+// Error implements the error interface
+func (e *MetaError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the framework error, so errors.Is and errors.As can reach
+// both the framework error and the backend error
+func (e *MetaError) Unwrap() error {
+	return e.err
+}
+
+// Cause returns the framework error, so errors.Cause of pingcap/errors and
+// the Equal of the framework errors can reach the root cause
+func (e *MetaError) Cause() error {
+	return e.err
+}
+
+// RFCCode returns the RFC code of the framework error
+func (e *MetaError) RFCCode() perrors.RFCErrorCode {
+	return e.err.RFCCode()
+}
+
+// MySQLErrNo returns the MySQL error number, or 0 if the backend error is
+// not a MySQL error
+func (e *MetaError) MySQLErrNo() uint16 {
+	return e.errNo
+}
+
+// SQLState returns the SQL state of the MySQL error, or an empty string if
+// the backend error is not a MySQL error
+func (e *MetaError) SQLState() string {
+	return e.sqlState
+}
@@ -0,0 +1,87 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	dmysql "github.com/go-sql-driver/mysql"
+	perrors "github.com/pingcap/errors"
+	"github.com/stretchr/testify/require"
+
+	cerrors "github.com/hanfei1991/microcosm/pkg/errors"
+)
+
+func TestMetaError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		cause    error
+		errNo    uint16
+		sqlState string
+	}{
+		{
+			cause:    &dmysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"},
+			errNo:    1213,
+			sqlState: "40001",
+		},
+		{
+			cause:    &dmysql.MySQLError{Number: 1062, Message: "Duplicate entry 'j111' for key 'uidx_id'"},
+			errNo:    1062,
+			sqlState: "23000",
+		},
+		{
+			// the error number without a known SQL state
+			cause:    &dmysql.MySQLError{Number: 65535, Message: "unknown error"},
+			errNo:    65535,
+			sqlState: "HY000",
+		},
+		{
+			cause:    perrors.Annotate(&dmysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, "update job"),
+			errNo:    1205,
+			sqlState: "HY000",
+		},
+		{
+			cause: errors.New("not a mysql error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		err := newMetaError(cerrors.ErrMetaOpFail, tc.cause)
+		var metaErr *MetaError
+		require.True(t, errors.As(err, &metaErr))
+		require.Equal(t, perrors.RFCErrorCode("DFLOW:ErrMetaOpFail"), metaErr.RFCCode())
+		require.Equal(t, tc.errNo, metaErr.MySQLErrNo())
+		require.Equal(t, tc.sqlState, metaErr.SQLState())
+		require.True(t, errors.Is(err, tc.cause))
+		require.Contains(t, err.Error(), tc.cause.Error())
+	}
+
+	require.NoError(t, newMetaError(cerrors.ErrMetaOpFail, nil))
+
+	// the framework error wrapped as the cause can be checked by Equal
+	err := newMetaError(cerrors.ErrMetaOpFail, cerrors.ErrDuplicateResourceID.GenWithStackByArgs("r1"))
+	require.True(t, cerrors.ErrDuplicateResourceID.Equal(err))
+}
+
+func TestMetaErrorFromClient(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	defer sqlDB.Close()
+	defer mock.ExpectClose()
+	require.Nil(t, err)
+	cli, err := newClient(sqlDB, NewDefaultDBConfig())
+	require.Nil(t, err)
+
+	mock.ExpectQuery("SELECT [*] FROM `project_operations` WHERE project_id").WithArgs("p111").
+		WillReturnError(&dmysql.MySQLError{Number: 1146, Message: "Table 'project_operations' doesn't exist"})
+	_, err = cli.QueryProjectOperations(context.Background(), "p111")
+	require.Error(t, err)
+
+	var metaErr *MetaError
+	require.True(t, errors.As(err, &metaErr))
+	require.Equal(t, perrors.RFCErrorCode("DFLOW:ErrMetaOpFail"), metaErr.RFCCode())
+	require.Equal(t, uint16(1146), metaErr.MySQLErrNo())
+	require.Equal(t, "42S02", metaErr.SQLState())
+}
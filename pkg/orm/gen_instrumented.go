@@ -0,0 +1,218 @@
+//go:build ignore
+// +build ignore
+
+// This program generates instrumented_gen.go, which implements the methods of
+// Client for instrumentedClient. Run it with `go generate ./pkg/orm`.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hanfei1991/microcosm/pkg/orm"
+)
+
+const (
+	ormPkgPath = "github.com/hanfei1991/microcosm/pkg/orm"
+	outputFile = "instrumented_gen.go"
+)
+
+// importAliases are the aliases of the packages whose names conflict with
+// other packages or don't match the convention of pkg/orm
+var importAliases = map[string]string{
+	"github.com/hanfei1991/microcosm/lib/model":                               "libModel",
+	"github.com/hanfei1991/microcosm/pkg/externalresource/resourcemeta/model": "resourcemeta",
+}
+
+// manualMethods are implemented in instrumented.go
+var manualMethods = map[string]struct{}{
+	"Transaction": {},
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+type generator struct {
+	imports map[string]string
+}
+
+func (g *generator) pkgName(pkgPath string) string {
+	name, ok := importAliases[pkgPath]
+	if !ok {
+		name = path.Base(pkgPath)
+	}
+	g.imports[pkgPath] = name
+	return name
+}
+
+func (g *generator) typeString(t reflect.Type) string {
+	if t.Name() != "" {
+		switch {
+		case t.PkgPath() == ormPkgPath:
+			return t.Name()
+		case t.PkgPath() != "":
+			return g.pkgName(t.PkgPath()) + "." + t.Name()
+		case t.Kind() == reflect.Uint8:
+			return "byte"
+		default:
+			return t.Name()
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + g.typeString(t.Elem())
+	case reflect.Slice:
+		return "[]" + g.typeString(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), g.typeString(t.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map[%s]%s", g.typeString(t.Key()), g.typeString(t.Elem()))
+	case reflect.Chan:
+		return t.ChanDir().String() + " " + g.typeString(t.Elem())
+	case reflect.Func:
+		params, results := g.signature(t, false)
+		return "func(" + strings.Join(params, ", ") + ")" + results
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "interface{}"
+		}
+		log.Fatalf("unsupported anonymous interface %s", t)
+	default:
+		log.Fatalf("unsupported type %s", t)
+	}
+	return ""
+}
+
+// signature returns the parameter list and results of a func type, the
+// parameters and results are named if named is true
+func (g *generator) signature(t reflect.Type, named bool) (params []string, results string) {
+	for i := 0; i < t.NumIn(); i++ {
+		in := t.In(i)
+		typ := g.typeString(in)
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			typ = "..." + g.typeString(in.Elem())
+		}
+		if named {
+			typ = paramName(t, i) + " " + typ
+		}
+		params = append(params, typ)
+	}
+
+	outs := make([]string, 0, t.NumOut())
+	for i := 0; i < t.NumOut(); i++ {
+		typ := g.typeString(t.Out(i))
+		if named {
+			typ = resultName(t, i) + " " + typ
+		}
+		outs = append(outs, typ)
+	}
+	switch {
+	case len(outs) == 0:
+	case len(outs) == 1 && !named:
+		results = " " + outs[0]
+	default:
+		results = " (" + strings.Join(outs, ", ") + ")"
+	}
+	return
+}
+
+func paramName(t reflect.Type, i int) string {
+	if i == 0 && t.In(0).String() == "context.Context" {
+		return "ctx"
+	}
+	return fmt.Sprintf("a%d", i)
+}
+
+func resultName(t reflect.Type, i int) string {
+	if i == t.NumOut()-1 && t.Out(i) == errorType {
+		return "err"
+	}
+	return fmt.Sprintf("r%d", i)
+}
+
+func isStdPkg(pkgPath string) bool {
+	return !strings.Contains(strings.SplitN(pkgPath, "/", 2)[0], ".")
+}
+
+func (g *generator) method(buf *bytes.Buffer, m reflect.Method) {
+	t := m.Type
+	params, results := g.signature(t, true)
+	args := make([]string, 0, t.NumIn())
+	for i := 0; i < t.NumIn(); i++ {
+		arg := paramName(t, i)
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			arg += "..."
+		}
+		args = append(args, arg)
+	}
+
+	fmt.Fprintf(buf, "\n// %s implements Client.%s\n", m.Name, m.Name)
+	fmt.Fprintf(buf, "func (c *instrumentedClient) %s(%s)%s {\n", m.Name, strings.Join(params, ", "), results)
+	fmt.Fprintf(buf, "\tdone := c.observe(%q)\n", m.Name)
+	if t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType {
+		fmt.Fprintf(buf, "\tdefer func() { done(err) }()\n")
+	} else {
+		fmt.Fprintf(buf, "\tdefer done(nil)\n")
+	}
+	call := fmt.Sprintf("c.inner.%s(%s)", m.Name, strings.Join(args, ", "))
+	if t.NumOut() > 0 {
+		call = "return " + call
+	}
+	fmt.Fprintf(buf, "\t%s\n}\n", call)
+}
+
+func main() {
+	g := &generator{imports: make(map[string]string)}
+	clientType := reflect.TypeOf((*orm.Client)(nil)).Elem()
+
+	var body bytes.Buffer
+	for i := 0; i < clientType.NumMethod(); i++ {
+		m := clientType.Method(i)
+		if _, ok := manualMethods[m.Name]; ok {
+			continue
+		}
+		g.method(&body, m)
+	}
+
+	pkgPaths := make([]string, 0, len(g.imports))
+	for pkgPath := range g.imports {
+		pkgPaths = append(pkgPaths, pkgPath)
+	}
+	sort.Slice(pkgPaths, func(i, j int) bool {
+		if isStdPkg(pkgPaths[i]) != isStdPkg(pkgPaths[j]) {
+			return isStdPkg(pkgPaths[i])
+		}
+		return pkgPaths[i] < pkgPaths[j]
+	})
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gen_instrumented.go. DO NOT EDIT.\n\npackage orm\n\nimport (\n")
+	for i, pkgPath := range pkgPaths {
+		// the standard packages are grouped before the others
+		if i > 0 && isStdPkg(pkgPaths[i-1]) && !isStdPkg(pkgPath) {
+			fmt.Fprintf(&buf, "\n")
+		}
+		if alias, ok := importAliases[pkgPath]; ok {
+			fmt.Fprintf(&buf, "\t%s %q\n", alias, pkgPath)
+		} else {
+			fmt.Fprintf(&buf, "\t%q\n", pkgPath)
+		}
+	}
+	fmt.Fprintf(&buf, ")\n")
+	buf.Write(body.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("format the generated code failed: %v\n%s", err, buf.String())
+	}
+	if err := os.WriteFile(outputFile, src, 0o644); err != nil {
+		log.Fatalf("write %s failed: %v", outputFile, err)
+	}
+}
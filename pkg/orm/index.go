@@ -0,0 +1,71 @@
+package orm
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	libModel "github.com/hanfei1991/microcosm/lib/model"
+	cerrors "github.com/hanfei1991/microcosm/pkg/errors"
+	resourcemeta "github.com/hanfei1991/microcosm/pkg/externalresource/resourcemeta/model"
+)
+
+// tableIndex is an index of the metastore which is not declared in the gorm
+// tags of the model, it is created explicitly after AutoMigrate.
+type tableIndex struct {
+	model   interface{}
+	name    string
+	columns []string
+}
+
+// explicitIndexes are the indexes for the hot queries of the metastore. The
+// indexes of (job_id, status) on worker_statuses and (executor_id, id) on
+// resource_meta are declared in the gorm tags, so they are not listed here.
+var explicitIndexes = []tableIndex{
+	// QueryJobsByStatus
+	{model: &libModel.MasterMetaKVData{}, name: "idx_mstatus", columns: []string{"status"}},
+	// QueryLatestJobByProjectID
+	{model: &libModel.MasterMetaKVData{}, name: "idx_mpu", columns: []string{"project_id", "updated_at"}},
+	// QueryJobsUpdatedAfter
+	{model: &libModel.MasterMetaKVData{}, name: "idx_mupd", columns: []string{"updated_at"}},
+	// QueryResourcesByDeleted
+	{model: &resourcemeta.ResourceMeta{}, name: "idx_rdel", columns: []string{"deleted"}},
+}
+
+// createIndexes creates the explicit indexes which don't exist. It is
+// idempotent, and is safe to be called by multiple clients concurrently.
+func (c *metaOpsClient) createIndexes(ctx context.Context) error {
+	db := c.db.WithContext(ctx)
+	for _, idx := range explicitIndexes {
+		if err := createIndex(db, idx); err != nil {
+			return newMetaError(cerrors.ErrMetaOpFail, err)
+		}
+	}
+
+	return nil
+}
+
+func createIndex(db *gorm.DB, idx tableIndex) error {
+	migrator := db.Migrator()
+	if migrator.HasIndex(idx.model, idx.name) {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(idx.model); err != nil {
+		return err
+	}
+	columns := make([]interface{}, 0, len(idx.columns))
+	for _, column := range idx.columns {
+		columns = append(columns, clause.Column{Name: column})
+	}
+	err := db.Exec("CREATE INDEX ? ON ? ?",
+		clause.Column{Name: idx.name}, clause.Table{Name: stmt.Table}, columns).Error
+	// the index may be created by another client at the same time
+	if err != nil && migrator.HasIndex(idx.model, idx.name) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,43 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	libModel "github.com/hanfei1991/microcosm/lib/model"
+	resourcemeta "github.com/hanfei1991/microcosm/pkg/externalresource/resourcemeta/model"
+)
+
+func TestCreateIndexesMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	metaCli := cli.(*metaOpsClient)
+	migrator := metaCli.db.Migrator()
+	checkIndexes := func() {
+		// the indexes declared in the gorm tags
+		require.True(t, migrator.HasIndex(&libModel.WorkerStatus{}, "idx_wst"))
+		require.True(t, migrator.HasIndex(&resourcemeta.ResourceMeta{}, "idx_rei"))
+		for _, idx := range explicitIndexes {
+			require.True(t, migrator.HasIndex(idx.model, idx.name), "index %s", idx.name)
+		}
+	}
+
+	// Initialize is called by NewMockClient, calling it again is a no-op
+	checkIndexes()
+	require.Nil(t, cli.Initialize(ctx))
+	checkIndexes()
+
+	// the dropped indexes are created again
+	for _, idx := range explicitIndexes {
+		require.Nil(t, migrator.DropIndex(idx.model, idx.name))
+		require.False(t, migrator.HasIndex(idx.model, idx.name))
+	}
+	require.Nil(t, cli.Initialize(ctx))
+	checkIndexes()
+}
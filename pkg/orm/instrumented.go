@@ -0,0 +1,89 @@
+package orm
+
+//go:generate go run gen_instrumented.go
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/hanfei1991/microcosm/pkg/promutil"
+)
+
+// clientMetrics are the metrics of the methods of an instrumentedClient, all
+// of them are labeled by the method name
+type clientMetrics struct {
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+	inFlight *prometheus.GaugeVec
+}
+
+func newClientMetrics(factory promutil.Factory) *clientMetrics {
+	return &clientMetrics{
+		duration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "metastore",
+			Subsystem: "client",
+			Name:      "op_duration_seconds",
+			Help:      "Bucketed histogram of the latency of the metastore client operations",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 16), // 0.5ms ~ 16s
+		}, []string{"method"}),
+		errors: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "metastore",
+			Subsystem: "client",
+			Name:      "op_errors_total",
+			Help:      "Total number of the metastore client operations that failed",
+		}, []string{"method"}),
+		inFlight: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "metastore",
+			Subsystem: "client",
+			Name:      "op_in_flight",
+			Help:      "Number of the metastore client operations in progress",
+		}, []string{"method"}),
+	}
+}
+
+// instrumentedClient is a Client decorator recording the duration, errors
+// and in-flight count of each method of the inner Client. The methods except
+// Transaction are generated in instrumented_gen.go.
+type instrumentedClient struct {
+	inner   Client
+	metrics *clientMetrics
+}
+
+var _ Client = (*instrumentedClient)(nil)
+
+// newInstrumentedClient wraps inner with the metrics created by factory, the
+// metrics must be registered only once, so factory should be used to create
+// at most one instrumentedClient.
+func newInstrumentedClient(inner Client, factory promutil.Factory) Client {
+	return &instrumentedClient{
+		inner:   inner,
+		metrics: newClientMetrics(factory),
+	}
+}
+
+// observe is called when a method starts, the returned function must be
+// called with the error returned by the method when it returns
+func (c *instrumentedClient) observe(method string) func(err error) {
+	inFlight := c.metrics.inFlight.WithLabelValues(method)
+	inFlight.Inc()
+	start := time.Now()
+	return func(err error) {
+		inFlight.Dec()
+		c.metrics.duration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		if err != nil {
+			c.metrics.errors.WithLabelValues(method).Inc()
+		}
+	}
+}
+
+// Transaction implements Client.Transaction, the operations in the
+// transaction are instrumented too
+func (c *instrumentedClient) Transaction(ctx context.Context, fn func(tx Client) error) (err error) {
+	done := c.observe("Transaction")
+	defer func() { done(err) }()
+	return c.inner.Transaction(ctx, func(tx Client) error {
+		return fn(&instrumentedClient{inner: tx, metrics: c.metrics})
+	})
+}
@@ -0,0 +1,510 @@
+// Code generated by gen_instrumented.go. DO NOT EDIT.
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	libModel "github.com/hanfei1991/microcosm/lib/model"
+	resourcemeta "github.com/hanfei1991/microcosm/pkg/externalresource/resourcemeta/model"
+	"github.com/hanfei1991/microcosm/pkg/orm/model"
+)
+
+// ClaimWorker implements Client.ClaimWorker
+func (c *instrumentedClient) ClaimWorker(ctx context.Context, a1 string, a2 string, a3 string, a4 time.Duration) (r0 bool, err error) {
+	done := c.observe("ClaimWorker")
+	defer func() { done(err) }()
+	return c.inner.ClaimWorker(ctx, a1, a2, a3, a4)
+}
+
+// Close implements Client.Close
+func (c *instrumentedClient) Close() (err error) {
+	done := c.observe("Close")
+	defer func() { done(err) }()
+	return c.inner.Close()
+}
+
+// CountJobs implements Client.CountJobs
+func (c *instrumentedClient) CountJobs(ctx context.Context) (r0 int64, err error) {
+	done := c.observe("CountJobs")
+	defer func() { done(err) }()
+	return c.inner.CountJobs(ctx)
+}
+
+// CountJobsByStatus implements Client.CountJobsByStatus
+func (c *instrumentedClient) CountJobsByStatus(ctx context.Context) (r0 map[int]int64, err error) {
+	done := c.observe("CountJobsByStatus")
+	defer func() { done(err) }()
+	return c.inner.CountJobsByStatus(ctx)
+}
+
+// CountProjectJobsByStatus implements Client.CountProjectJobsByStatus
+func (c *instrumentedClient) CountProjectJobsByStatus(ctx context.Context, a1 string) (r0 map[int]int64, err error) {
+	done := c.observe("CountProjectJobsByStatus")
+	defer func() { done(err) }()
+	return c.inner.CountProjectJobsByStatus(ctx, a1)
+}
+
+// CountResourcesByJobID implements Client.CountResourcesByJobID
+func (c *instrumentedClient) CountResourcesByJobID(ctx context.Context, a1 string) (r0 int64, err error) {
+	done := c.observe("CountResourcesByJobID")
+	defer func() { done(err) }()
+	return c.inner.CountResourcesByJobID(ctx, a1)
+}
+
+// CountWorkersByMasterID implements Client.CountWorkersByMasterID
+func (c *instrumentedClient) CountWorkersByMasterID(ctx context.Context, a1 string) (r0 int64, err error) {
+	done := c.observe("CountWorkersByMasterID")
+	defer func() { done(err) }()
+	return c.inner.CountWorkersByMasterID(ctx, a1)
+}
+
+// CreateProject implements Client.CreateProject
+func (c *instrumentedClient) CreateProject(ctx context.Context, a1 *model.ProjectInfo) (err error) {
+	done := c.observe("CreateProject")
+	defer func() { done(err) }()
+	return c.inner.CreateProject(ctx, a1)
+}
+
+// CreateProjectOperation implements Client.CreateProjectOperation
+func (c *instrumentedClient) CreateProjectOperation(ctx context.Context, a1 *model.ProjectOperation) (err error) {
+	done := c.observe("CreateProjectOperation")
+	defer func() { done(err) }()
+	return c.inner.CreateProjectOperation(ctx, a1)
+}
+
+// CreateResource implements Client.CreateResource
+func (c *instrumentedClient) CreateResource(ctx context.Context, a1 *resourcemeta.ResourceMeta) (err error) {
+	done := c.observe("CreateResource")
+	defer func() { done(err) }()
+	return c.inner.CreateResource(ctx, a1)
+}
+
+// DeleteJob implements Client.DeleteJob
+func (c *instrumentedClient) DeleteJob(ctx context.Context, a1 string) (r0 Result, err error) {
+	done := c.observe("DeleteJob")
+	defer func() { done(err) }()
+	return c.inner.DeleteJob(ctx, a1)
+}
+
+// DeleteProject implements Client.DeleteProject
+func (c *instrumentedClient) DeleteProject(ctx context.Context, a1 string) (r0 Result, err error) {
+	done := c.observe("DeleteProject")
+	defer func() { done(err) }()
+	return c.inner.DeleteProject(ctx, a1)
+}
+
+// DeleteResource implements Client.DeleteResource
+func (c *instrumentedClient) DeleteResource(ctx context.Context, a1 string) (r0 Result, err error) {
+	done := c.observe("DeleteResource")
+	defer func() { done(err) }()
+	return c.inner.DeleteResource(ctx, a1)
+}
+
+// DeleteWorker implements Client.DeleteWorker
+func (c *instrumentedClient) DeleteWorker(ctx context.Context, a1 string, a2 string) (r0 Result, err error) {
+	done := c.observe("DeleteWorker")
+	defer func() { done(err) }()
+	return c.inner.DeleteWorker(ctx, a1, a2)
+}
+
+// DeleteWorkersByMasterID implements Client.DeleteWorkersByMasterID
+func (c *instrumentedClient) DeleteWorkersByMasterID(ctx context.Context, a1 string) (r0 int64, err error) {
+	done := c.observe("DeleteWorkersByMasterID")
+	defer func() { done(err) }()
+	return c.inner.DeleteWorkersByMasterID(ctx, a1)
+}
+
+// GenEpoch implements Client.GenEpoch
+func (c *instrumentedClient) GenEpoch(ctx context.Context) (r0 int64, err error) {
+	done := c.observe("GenEpoch")
+	defer func() { done(err) }()
+	return c.inner.GenEpoch(ctx)
+}
+
+// GenNamedCounter implements Client.GenNamedCounter
+func (c *instrumentedClient) GenNamedCounter(ctx context.Context, a1 string) (r0 int64, err error) {
+	done := c.observe("GenNamedCounter")
+	defer func() { done(err) }()
+	return c.inner.GenNamedCounter(ctx, a1)
+}
+
+// GetEpoch implements Client.GetEpoch
+func (c *instrumentedClient) GetEpoch(ctx context.Context) (r0 int64, err error) {
+	done := c.observe("GetEpoch")
+	defer func() { done(err) }()
+	return c.inner.GetEpoch(ctx)
+}
+
+// GetJobByID implements Client.GetJobByID
+func (c *instrumentedClient) GetJobByID(ctx context.Context, a1 string) (r0 *libModel.MasterMetaKVData, err error) {
+	done := c.observe("GetJobByID")
+	defer func() { done(err) }()
+	return c.inner.GetJobByID(ctx, a1)
+}
+
+// GetJobConfig implements Client.GetJobConfig
+func (c *instrumentedClient) GetJobConfig(ctx context.Context, a1 string, a2 interface{}) (err error) {
+	done := c.observe("GetJobConfig")
+	defer func() { done(err) }()
+	return c.inner.GetJobConfig(ctx, a1, a2)
+}
+
+// GetJobLabels implements Client.GetJobLabels
+func (c *instrumentedClient) GetJobLabels(ctx context.Context, a1 string) (r0 map[string]string, err error) {
+	done := c.observe("GetJobLabels")
+	defer func() { done(err) }()
+	return c.inner.GetJobLabels(ctx, a1)
+}
+
+// GetProjectByID implements Client.GetProjectByID
+func (c *instrumentedClient) GetProjectByID(ctx context.Context, a1 string) (r0 *model.ProjectInfo, err error) {
+	done := c.observe("GetProjectByID")
+	defer func() { done(err) }()
+	return c.inner.GetProjectByID(ctx, a1)
+}
+
+// GetResourceByID implements Client.GetResourceByID
+func (c *instrumentedClient) GetResourceByID(ctx context.Context, a1 string) (r0 *resourcemeta.ResourceMeta, err error) {
+	done := c.observe("GetResourceByID")
+	defer func() { done(err) }()
+	return c.inner.GetResourceByID(ctx, a1)
+}
+
+// GetWorkerByID implements Client.GetWorkerByID
+func (c *instrumentedClient) GetWorkerByID(ctx context.Context, a1 string, a2 string) (r0 *libModel.WorkerStatus, err error) {
+	done := c.observe("GetWorkerByID")
+	defer func() { done(err) }()
+	return c.inner.GetWorkerByID(ctx, a1, a2)
+}
+
+// Initialize implements Client.Initialize
+func (c *instrumentedClient) Initialize(ctx context.Context) (err error) {
+	done := c.observe("Initialize")
+	defer func() { done(err) }()
+	return c.inner.Initialize(ctx)
+}
+
+// IterateProjectOperations implements Client.IterateProjectOperations
+func (c *instrumentedClient) IterateProjectOperations(ctx context.Context, a1 string, a2 func(*model.ProjectOperation) error) (err error) {
+	done := c.observe("IterateProjectOperations")
+	defer func() { done(err) }()
+	return c.inner.IterateProjectOperations(ctx, a1, a2)
+}
+
+// JobExists implements Client.JobExists
+func (c *instrumentedClient) JobExists(ctx context.Context, a1 string) (r0 bool, err error) {
+	done := c.observe("JobExists")
+	defer func() { done(err) }()
+	return c.inner.JobExists(ctx, a1)
+}
+
+// ListActiveProjectIDs implements Client.ListActiveProjectIDs
+func (c *instrumentedClient) ListActiveProjectIDs(ctx context.Context) (r0 []string, err error) {
+	done := c.observe("ListActiveProjectIDs")
+	defer func() { done(err) }()
+	return c.inner.ListActiveProjectIDs(ctx)
+}
+
+// MoveJobToProject implements Client.MoveJobToProject
+func (c *instrumentedClient) MoveJobToProject(ctx context.Context, a1 string, a2 string) (err error) {
+	done := c.observe("MoveJobToProject")
+	defer func() { done(err) }()
+	return c.inner.MoveJobToProject(ctx, a1, a2)
+}
+
+// Ping implements Client.Ping
+func (c *instrumentedClient) Ping(ctx context.Context) (err error) {
+	done := c.observe("Ping")
+	defer func() { done(err) }()
+	return c.inner.Ping(ctx)
+}
+
+// PoolStats implements Client.PoolStats
+func (c *instrumentedClient) PoolStats() (r0 sql.DBStats) {
+	done := c.observe("PoolStats")
+	defer done(nil)
+	return c.inner.PoolStats()
+}
+
+// QueryJobTerminalStates implements Client.QueryJobTerminalStates
+func (c *instrumentedClient) QueryJobTerminalStates(ctx context.Context) (r0 map[string]bool, err error) {
+	done := c.observe("QueryJobTerminalStates")
+	defer func() { done(err) }()
+	return c.inner.QueryJobTerminalStates(ctx)
+}
+
+// QueryJobs implements Client.QueryJobs
+func (c *instrumentedClient) QueryJobs(ctx context.Context) (r0 []*libModel.MasterMetaKVData, err error) {
+	done := c.observe("QueryJobs")
+	defer func() { done(err) }()
+	return c.inner.QueryJobs(ctx)
+}
+
+// QueryJobsByLabel implements Client.QueryJobsByLabel
+func (c *instrumentedClient) QueryJobsByLabel(ctx context.Context, a1 string, a2 string) (r0 []*libModel.MasterMetaKVData, err error) {
+	done := c.observe("QueryJobsByLabel")
+	defer func() { done(err) }()
+	return c.inner.QueryJobsByLabel(ctx, a1, a2)
+}
+
+// QueryJobsByProjectID implements Client.QueryJobsByProjectID
+func (c *instrumentedClient) QueryJobsByProjectID(ctx context.Context, a1 string) (r0 []*libModel.MasterMetaKVData, err error) {
+	done := c.observe("QueryJobsByProjectID")
+	defer func() { done(err) }()
+	return c.inner.QueryJobsByProjectID(ctx, a1)
+}
+
+// QueryJobsByStatus implements Client.QueryJobsByStatus
+func (c *instrumentedClient) QueryJobsByStatus(ctx context.Context, a1 string, a2 int) (r0 []*libModel.MasterMetaKVData, err error) {
+	done := c.observe("QueryJobsByStatus")
+	defer func() { done(err) }()
+	return c.inner.QueryJobsByStatus(ctx, a1, a2)
+}
+
+// QueryJobsByStatusOnly implements Client.QueryJobsByStatusOnly
+func (c *instrumentedClient) QueryJobsByStatusOnly(ctx context.Context, a1 int) (r0 []*libModel.MasterMetaKVData, err error) {
+	done := c.observe("QueryJobsByStatusOnly")
+	defer func() { done(err) }()
+	return c.inner.QueryJobsByStatusOnly(ctx, a1)
+}
+
+// QueryJobsInBatches implements Client.QueryJobsInBatches
+func (c *instrumentedClient) QueryJobsInBatches(ctx context.Context, a1 int, a2 func([]*libModel.MasterMetaKVData) error) (err error) {
+	done := c.observe("QueryJobsInBatches")
+	defer func() { done(err) }()
+	return c.inner.QueryJobsInBatches(ctx, a1, a2)
+}
+
+// QueryJobsUpdatedAfter implements Client.QueryJobsUpdatedAfter
+func (c *instrumentedClient) QueryJobsUpdatedAfter(ctx context.Context, a1 time.Time) (r0 []*libModel.MasterMetaKVData, err error) {
+	done := c.observe("QueryJobsUpdatedAfter")
+	defer func() { done(err) }()
+	return c.inner.QueryJobsUpdatedAfter(ctx, a1)
+}
+
+// QueryJobsWithPagination implements Client.QueryJobsWithPagination
+func (c *instrumentedClient) QueryJobsWithPagination(ctx context.Context, a1 int, a2 int) (r0 []*libModel.MasterMetaKVData, r1 int64, err error) {
+	done := c.observe("QueryJobsWithPagination")
+	defer func() { done(err) }()
+	return c.inner.QueryJobsWithPagination(ctx, a1, a2)
+}
+
+// QueryLatestJobByProjectID implements Client.QueryLatestJobByProjectID
+func (c *instrumentedClient) QueryLatestJobByProjectID(ctx context.Context, a1 string) (r0 *libModel.MasterMetaKVData, err error) {
+	done := c.observe("QueryLatestJobByProjectID")
+	defer func() { done(err) }()
+	return c.inner.QueryLatestJobByProjectID(ctx, a1)
+}
+
+// QueryOrphanedResources implements Client.QueryOrphanedResources
+func (c *instrumentedClient) QueryOrphanedResources(ctx context.Context) (r0 []*resourcemeta.ResourceMeta, err error) {
+	done := c.observe("QueryOrphanedResources")
+	defer func() { done(err) }()
+	return c.inner.QueryOrphanedResources(ctx)
+}
+
+// QueryProjectOperations implements Client.QueryProjectOperations
+func (c *instrumentedClient) QueryProjectOperations(ctx context.Context, a1 string) (r0 []*model.ProjectOperation, err error) {
+	done := c.observe("QueryProjectOperations")
+	defer func() { done(err) }()
+	return c.inner.QueryProjectOperations(ctx, a1)
+}
+
+// QueryProjectOperationsByTimeRange implements Client.QueryProjectOperationsByTimeRange
+func (c *instrumentedClient) QueryProjectOperationsByTimeRange(ctx context.Context, a1 string, a2 TimeRange) (r0 []*model.ProjectOperation, err error) {
+	done := c.observe("QueryProjectOperationsByTimeRange")
+	defer func() { done(err) }()
+	return c.inner.QueryProjectOperationsByTimeRange(ctx, a1, a2)
+}
+
+// QueryProjects implements Client.QueryProjects
+func (c *instrumentedClient) QueryProjects(ctx context.Context) (r0 []*model.ProjectInfo, err error) {
+	done := c.observe("QueryProjects")
+	defer func() { done(err) }()
+	return c.inner.QueryProjects(ctx)
+}
+
+// QueryProjectsWithPagination implements Client.QueryProjectsWithPagination
+func (c *instrumentedClient) QueryProjectsWithPagination(ctx context.Context, a1 int, a2 int) (r0 []*model.ProjectInfo, r1 int64, err error) {
+	done := c.observe("QueryProjectsWithPagination")
+	defer func() { done(err) }()
+	return c.inner.QueryProjectsWithPagination(ctx, a1, a2)
+}
+
+// QueryProjectsWithStats implements Client.QueryProjectsWithStats
+func (c *instrumentedClient) QueryProjectsWithStats(ctx context.Context) (r0 []*model.ProjectStats, err error) {
+	done := c.observe("QueryProjectsWithStats")
+	defer func() { done(err) }()
+	return c.inner.QueryProjectsWithStats(ctx)
+}
+
+// QueryRecentProjectOperations implements Client.QueryRecentProjectOperations
+func (c *instrumentedClient) QueryRecentProjectOperations(ctx context.Context, a1 string, a2 int) (r0 []*model.ProjectOperation, err error) {
+	done := c.observe("QueryRecentProjectOperations")
+	defer func() { done(err) }()
+	return c.inner.QueryRecentProjectOperations(ctx, a1, a2)
+}
+
+// QueryResources implements Client.QueryResources
+func (c *instrumentedClient) QueryResources(ctx context.Context) (r0 []*resourcemeta.ResourceMeta, err error) {
+	done := c.observe("QueryResources")
+	defer func() { done(err) }()
+	return c.inner.QueryResources(ctx)
+}
+
+// QueryResourcesByContentType implements Client.QueryResourcesByContentType
+func (c *instrumentedClient) QueryResourcesByContentType(ctx context.Context, a1 string) (r0 []*resourcemeta.ResourceMeta, err error) {
+	done := c.observe("QueryResourcesByContentType")
+	defer func() { done(err) }()
+	return c.inner.QueryResourcesByContentType(ctx, a1)
+}
+
+// QueryResourcesByDeleted implements Client.QueryResourcesByDeleted
+func (c *instrumentedClient) QueryResourcesByDeleted(ctx context.Context, a1 bool) (r0 []*resourcemeta.ResourceMeta, err error) {
+	done := c.observe("QueryResourcesByDeleted")
+	defer func() { done(err) }()
+	return c.inner.QueryResourcesByDeleted(ctx, a1)
+}
+
+// QueryResourcesByExecutorID implements Client.QueryResourcesByExecutorID
+func (c *instrumentedClient) QueryResourcesByExecutorID(ctx context.Context, a1 string) (r0 []*resourcemeta.ResourceMeta, err error) {
+	done := c.observe("QueryResourcesByExecutorID")
+	defer func() { done(err) }()
+	return c.inner.QueryResourcesByExecutorID(ctx, a1)
+}
+
+// QueryResourcesByJobID implements Client.QueryResourcesByJobID
+func (c *instrumentedClient) QueryResourcesByJobID(ctx context.Context, a1 string) (r0 []*resourcemeta.ResourceMeta, err error) {
+	done := c.observe("QueryResourcesByJobID")
+	defer func() { done(err) }()
+	return c.inner.QueryResourcesByJobID(ctx, a1)
+}
+
+// QueryResourcesByJobIDs implements Client.QueryResourcesByJobIDs
+func (c *instrumentedClient) QueryResourcesByJobIDs(ctx context.Context, a1 []string) (r0 []*resourcemeta.ResourceMeta, err error) {
+	done := c.observe("QueryResourcesByJobIDs")
+	defer func() { done(err) }()
+	return c.inner.QueryResourcesByJobIDs(ctx, a1)
+}
+
+// QueryResourcesInBatches implements Client.QueryResourcesInBatches
+func (c *instrumentedClient) QueryResourcesInBatches(ctx context.Context, a1 int, a2 func([]*resourcemeta.ResourceMeta) error) (err error) {
+	done := c.observe("QueryResourcesInBatches")
+	defer func() { done(err) }()
+	return c.inner.QueryResourcesInBatches(ctx, a1, a2)
+}
+
+// QueryWorkersByMasterID implements Client.QueryWorkersByMasterID
+func (c *instrumentedClient) QueryWorkersByMasterID(ctx context.Context, a1 string) (r0 []*libModel.WorkerStatus, err error) {
+	done := c.observe("QueryWorkersByMasterID")
+	defer func() { done(err) }()
+	return c.inner.QueryWorkersByMasterID(ctx, a1)
+}
+
+// QueryWorkersByStatus implements Client.QueryWorkersByStatus
+func (c *instrumentedClient) QueryWorkersByStatus(ctx context.Context, a1 string, a2 int) (r0 []*libModel.WorkerStatus, err error) {
+	done := c.observe("QueryWorkersByStatus")
+	defer func() { done(err) }()
+	return c.inner.QueryWorkersByStatus(ctx, a1, a2)
+}
+
+// QueryWorkersInBatches implements Client.QueryWorkersInBatches
+func (c *instrumentedClient) QueryWorkersInBatches(ctx context.Context, a1 string, a2 int, a3 func([]*libModel.WorkerStatus) error) (err error) {
+	done := c.observe("QueryWorkersInBatches")
+	defer func() { done(err) }()
+	return c.inner.QueryWorkersInBatches(ctx, a1, a2, a3)
+}
+
+// ReleaseWorkerClaim implements Client.ReleaseWorkerClaim
+func (c *instrumentedClient) ReleaseWorkerClaim(ctx context.Context, a1 string, a2 string, a3 string) (r0 bool, err error) {
+	done := c.observe("ReleaseWorkerClaim")
+	defer func() { done(err) }()
+	return c.inner.ReleaseWorkerClaim(ctx, a1, a2, a3)
+}
+
+// RenewWorkerClaim implements Client.RenewWorkerClaim
+func (c *instrumentedClient) RenewWorkerClaim(ctx context.Context, a1 string, a2 string, a3 string, a4 time.Duration) (r0 bool, err error) {
+	done := c.observe("RenewWorkerClaim")
+	defer func() { done(err) }()
+	return c.inner.RenewWorkerClaim(ctx, a1, a2, a3, a4)
+}
+
+// ResourceExists implements Client.ResourceExists
+func (c *instrumentedClient) ResourceExists(ctx context.Context, a1 string) (r0 bool, err error) {
+	done := c.observe("ResourceExists")
+	defer func() { done(err) }()
+	return c.inner.ResourceExists(ctx, a1)
+}
+
+// SetJobLabels implements Client.SetJobLabels
+func (c *instrumentedClient) SetJobLabels(ctx context.Context, a1 string, a2 map[string]string) (err error) {
+	done := c.observe("SetJobLabels")
+	defer func() { done(err) }()
+	return c.inner.SetJobLabels(ctx, a1, a2)
+}
+
+// UpdateJob implements Client.UpdateJob
+func (c *instrumentedClient) UpdateJob(ctx context.Context, a1 *libModel.MasterMetaKVData, a2 int64) (err error) {
+	done := c.observe("UpdateJob")
+	defer func() { done(err) }()
+	return c.inner.UpdateJob(ctx, a1, a2)
+}
+
+// UpdateJobFields implements Client.UpdateJobFields
+func (c *instrumentedClient) UpdateJobFields(ctx context.Context, a1 string, a2 JobUpdate) (err error) {
+	done := c.observe("UpdateJobFields")
+	defer func() { done(err) }()
+	return c.inner.UpdateJobFields(ctx, a1, a2)
+}
+
+// UpdateResource implements Client.UpdateResource
+func (c *instrumentedClient) UpdateResource(ctx context.Context, a1 *resourcemeta.ResourceMeta) (err error) {
+	done := c.observe("UpdateResource")
+	defer func() { done(err) }()
+	return c.inner.UpdateResource(ctx, a1)
+}
+
+// UpdateWorker implements Client.UpdateWorker
+func (c *instrumentedClient) UpdateWorker(ctx context.Context, a1 *libModel.WorkerStatus) (err error) {
+	done := c.observe("UpdateWorker")
+	defer func() { done(err) }()
+	return c.inner.UpdateWorker(ctx, a1)
+}
+
+// UpsertJob implements Client.UpsertJob
+func (c *instrumentedClient) UpsertJob(ctx context.Context, a1 *libModel.MasterMetaKVData) (err error) {
+	done := c.observe("UpsertJob")
+	defer func() { done(err) }()
+	return c.inner.UpsertJob(ctx, a1)
+}
+
+// UpsertResource implements Client.UpsertResource
+func (c *instrumentedClient) UpsertResource(ctx context.Context, a1 *resourcemeta.ResourceMeta) (err error) {
+	done := c.observe("UpsertResource")
+	defer func() { done(err) }()
+	return c.inner.UpsertResource(ctx, a1)
+}
+
+// UpsertWorker implements Client.UpsertWorker
+func (c *instrumentedClient) UpsertWorker(ctx context.Context, a1 *libModel.WorkerStatus) (err error) {
+	done := c.observe("UpsertWorker")
+	defer func() { done(err) }()
+	return c.inner.UpsertWorker(ctx, a1)
+}
+
+// WatchWorkers implements Client.WatchWorkers
+func (c *instrumentedClient) WatchWorkers(ctx context.Context, a1 string) (r0 []*libModel.WorkerStatus, r1 <-chan *libModel.WorkerStatus, err error) {
+	done := c.observe("WatchWorkers")
+	defer func() { done(err) }()
+	return c.inner.WatchWorkers(ctx, a1)
+}
+
+// WorkerExists implements Client.WorkerExists
+func (c *instrumentedClient) WorkerExists(ctx context.Context, a1 string, a2 string) (r0 bool, err error) {
+	done := c.observe("WorkerExists")
+	defer func() { done(err) }()
+	return c.inner.WorkerExists(ctx, a1, a2)
+}
@@ -0,0 +1,85 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	libModel "github.com/hanfei1991/microcosm/lib/model"
+	"github.com/hanfei1991/microcosm/pkg/promutil"
+)
+
+// gatherByMethod returns the samples of the metric family `name` keyed by
+// the method label
+func gatherByMethod(t *testing.T, reg *promutil.Registry, name string) map[string]*dto.Metric {
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	metrics := make(map[string]*dto.Metric)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "method" {
+					metrics[label.GetValue()] = metric
+				}
+			}
+		}
+	}
+	return metrics
+}
+
+func TestInstrumentedClient(t *testing.T) {
+	t.Parallel()
+
+	inner, err := NewMockClient()
+	require.NoError(t, err)
+	defer inner.Close()
+	reg := promutil.NewRegistry()
+	cli := newInstrumentedClient(inner, promutil.NewFactory4FrameworkImpl(reg))
+
+	ctx := context.Background()
+	for _, id := range []string{"j1", "j2"} {
+		err := cli.UpsertJob(ctx, &libModel.MasterMetaKVData{ProjectID: "p1", ID: id})
+		require.NoError(t, err)
+	}
+	job, err := cli.GetJobByID(ctx, "j1")
+	require.NoError(t, err)
+	require.Equal(t, "j1", job.ID)
+	_, err = cli.GetJobByID(ctx, "j3")
+	require.True(t, IsNotFoundError(err))
+	// the operations in a transaction are instrumented too
+	err = cli.Transaction(ctx, func(tx Client) error {
+		_, err := tx.GetJobByID(ctx, "j2")
+		return err
+	})
+	require.NoError(t, err)
+
+	durations := gatherByMethod(t, reg, "dataflow_metastore_client_op_duration_seconds")
+	require.Len(t, durations, 3)
+	require.Equal(t, uint64(2), durations["UpsertJob"].GetHistogram().GetSampleCount())
+	require.Equal(t, uint64(3), durations["GetJobByID"].GetHistogram().GetSampleCount())
+	require.Equal(t, uint64(1), durations["Transaction"].GetHistogram().GetSampleCount())
+	for _, metric := range durations {
+		require.Contains(t, metric.GetLabel(), &dto.LabelPair{
+			Name: stringPtr("framework"), Value: stringPtr("true"),
+		})
+	}
+
+	errs := gatherByMethod(t, reg, "dataflow_metastore_client_op_errors_total")
+	require.Len(t, errs, 1)
+	require.Equal(t, float64(1), errs["GetJobByID"].GetCounter().GetValue())
+
+	inFlight := gatherByMethod(t, reg, "dataflow_metastore_client_op_in_flight")
+	require.Len(t, inFlight, 3)
+	for method, metric := range inFlight {
+		require.Equal(t, float64(0), metric.GetGauge().GetValue(), method)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,103 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// gormLogLevels maps the LogLevel in DBConfig to the level of gorm logger
+var gormLogLevels = map[string]logger.LogLevel{
+	"silent": logger.Silent,
+	"error":  logger.Error,
+	"warn":   logger.Warn,
+	"info":   logger.Info,
+}
+
+// parseLogLevel returns the gorm log level of level, it is case insensitive
+func parseLogLevel(level string) (logger.LogLevel, bool) {
+	lvl, ok := gormLogLevels[strings.ToLower(level)]
+	return lvl, ok
+}
+
+// gormLogger implements logger.Interface of gorm, it writes the logs of gorm
+// to a zap logger and reports the queries slower than slowThreshold at warn
+// level.
+type gormLogger struct {
+	lg            *zap.Logger
+	level         logger.LogLevel
+	slowThreshold time.Duration
+}
+
+// newGormLogger creates a gorm logger writing to lg. The LogLevel in conf
+// must be validated before, an empty LogLevel means warn.
+func newGormLogger(lg *zap.Logger, conf DBConfig) logger.Interface {
+	level := logger.Warn
+	if lvl, ok := parseLogLevel(conf.LogLevel); ok {
+		level = lvl
+	}
+	return &gormLogger{
+		lg:            lg.WithOptions(zap.AddCallerSkip(3)),
+		level:         level,
+		slowThreshold: conf.SlowThreshold,
+	}
+}
+
+// LogMode implements logger.Interface.LogMode
+func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	newLogger := *l
+	newLogger.level = level
+	return &newLogger
+}
+
+// Info implements logger.Interface.Info
+func (l *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Info {
+		l.lg.Info(fmt.Sprintf(msg, data...))
+	}
+}
+
+// Warn implements logger.Interface.Warn
+func (l *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Warn {
+		l.lg.Warn(fmt.Sprintf(msg, data...))
+	}
+}
+
+// Error implements logger.Interface.Error
+func (l *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Error {
+		l.lg.Error(fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace implements logger.Interface.Trace, it is called after each sql
+// statement is executed
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	// not found is an expected result of queries, it is not logged as error
+	case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		l.lg.Error("sql execution fail", zap.String("sql", sql),
+			zap.Int64("rows", rows), zap.Duration("elapsed", elapsed), zap.Error(err))
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		sql, rows := fc()
+		l.lg.Warn("slow sql", zap.String("sql", sql), zap.Int64("rows", rows),
+			zap.Duration("elapsed", elapsed), zap.Duration("threshold", l.slowThreshold))
+	case l.level >= logger.Info:
+		sql, rows := fc()
+		l.lg.Info("sql execution", zap.String("sql", sql),
+			zap.Int64("rows", rows), zap.Duration("elapsed", elapsed))
+	}
+}
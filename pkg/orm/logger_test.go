@@ -0,0 +1,112 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+
+	cerrors "github.com/hanfei1991/microcosm/pkg/errors"
+)
+
+func newLoggerTestClient(t *testing.T, conf DBConfig) (*metaOpsClient, sqlmock.Sqlmock, *observer.ObservedLogs) {
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	require.Nil(t, err)
+	t.Cleanup(func() {
+		mock.ExpectClose()
+		sqlDB.Close()
+	})
+
+	cli, err := newClient(sqlDB, conf)
+	require.Nil(t, err)
+	core, logs := observer.New(zapcore.DebugLevel)
+	cli.db = cli.db.Session(&gorm.Session{Logger: newGormLogger(zap.New(core), conf)})
+	return cli, mock, logs
+}
+
+func TestGormLoggerSlowQuery(t *testing.T) {
+	t.Parallel()
+
+	conf := NewDefaultDBConfig()
+	conf.SlowThreshold = 10 * time.Millisecond
+	cli, mock, logs := newLoggerTestClient(t, conf)
+
+	expectedSQL := "SELECT * FROM `project_infos` WHERE id = ? ORDER BY `project_infos`.`seq_id` LIMIT 1"
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("p111").
+		WillDelayFor(50 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("p111", "tenant1"))
+	_, err := cli.GetProjectByID(context.Background(), "p111")
+	require.NoError(t, err)
+
+	slowLogs := logs.FilterMessage("slow sql").All()
+	require.Len(t, slowLogs, 1)
+	require.Equal(t, zapcore.WarnLevel, slowLogs[0].Level)
+	require.Contains(t, slowLogs[0].ContextMap()["sql"], "project_infos")
+
+	// fast queries are not logged at warn level
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("p222").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("p222", "tenant2"))
+	_, err = cli.GetProjectByID(context.Background(), "p222")
+	require.NoError(t, err)
+	require.Equal(t, 1, logs.Len())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormLoggerLevel(t *testing.T) {
+	t.Parallel()
+
+	conf := NewDefaultDBConfig()
+	conf.LogLevel = "error"
+	conf.SlowThreshold = time.Millisecond
+	cli, mock, logs := newLoggerTestClient(t, conf)
+
+	expectedSQL := "SELECT * FROM `project_infos` WHERE id = ? ORDER BY `project_infos`.`seq_id` LIMIT 1"
+	// slow queries are not logged below warn level
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("p111").
+		WillDelayFor(10 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("p111", "tenant1"))
+	_, err := cli.GetProjectByID(context.Background(), "p111")
+	require.NoError(t, err)
+	require.Equal(t, 0, logs.Len())
+
+	// not found is not an error
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("p222").
+		WillReturnError(gorm.ErrRecordNotFound)
+	_, err = cli.GetProjectByID(context.Background(), "p222")
+	require.Error(t, err)
+	require.Equal(t, 0, logs.Len())
+
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("p333").
+		WillReturnError(errors.New("mock error"))
+	_, err = cli.GetProjectByID(context.Background(), "p333")
+	require.Error(t, err)
+	errLogs := logs.FilterMessage("sql execution fail").All()
+	require.Len(t, errLogs, 1)
+	require.Equal(t, zapcore.ErrorLevel, errLogs[0].Level)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBConfigLogLevel(t *testing.T) {
+	t.Parallel()
+
+	conf := NewDefaultDBConfig()
+	for _, level := range []string{"", "silent", "error", "warn", "info", "INFO"} {
+		conf.LogLevel = level
+		require.NoError(t, conf.validate())
+	}
+
+	conf.LogLevel = "debug"
+	require.True(t, cerrors.ErrMetaParamsInvalid.Equal(conf.validate()))
+
+	conf.LogLevel = "info"
+	conf.SlowThreshold = -time.Second
+	require.True(t, cerrors.ErrMetaParamsInvalid.Equal(conf.validate()))
+}
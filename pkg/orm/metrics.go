@@ -0,0 +1,33 @@
+package orm
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/hanfei1991/microcosm/pkg/promutil"
+)
+
+var (
+	metricFactory = promutil.NewFactory4Framework()
+
+	epochGeneratedCounter = metricFactory.NewCounter(prometheus.CounterOpts{
+		Namespace: "metastore",
+		Subsystem: "epoch",
+		Name:      "generated_total",
+		Help:      "Total number of epochs generated successfully",
+	})
+
+	genEpochDuration = metricFactory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "metastore",
+		Subsystem: "epoch",
+		Name:      "gen_duration_seconds",
+		Help:      "Bucketed histogram of the latency of generating an epoch",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 16), // 0.5ms ~ 16s
+	})
+
+	transientRetryCounter = metricFactory.NewCounter(prometheus.CounterOpts{
+		Namespace: "metastore",
+		Subsystem: "client",
+		Name:      "transient_retry_total",
+		Help:      "Total number of retries of operations failed with transient errors, such as deadlocks",
+	})
+)
@@ -0,0 +1,38 @@
+package orm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func histogramSampleCount(t *testing.T, h prometheus.Histogram) uint64 {
+	var m dto.Metric
+	require.NoError(t, h.Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+// NOTICE: don't run this test in parallel, the metrics are shared by all
+// clients in the package.
+func TestGenEpochMetrics(t *testing.T) {
+	mock, err := NewMockClient()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	generated := testutil.ToFloat64(epochGeneratedCounter)
+	observed := histogramSampleCount(t, genEpochDuration)
+	for i := 1; i <= 3; i++ {
+		_, err := mock.GenEpoch(ctx)
+		require.NoError(t, err)
+		require.Equal(t, generated+float64(i), testutil.ToFloat64(epochGeneratedCounter))
+		require.Equal(t, observed+uint64(i), histogramSampleCount(t, genEpochDuration))
+	}
+}
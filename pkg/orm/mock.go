@@ -32,9 +32,20 @@ func NewMockClient() (Client, error) {
 		log.L().Error("create gorm client fail", zap.Error(err))
 		return nil, cerrors.ErrMetaNewClientFail.Wrap(err)
 	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, cerrors.ErrMetaNewClientFail.Wrap(err)
+	}
+	// Concurrent write transactions on a shared-cache in-memory database fail
+	// with 'database table is locked' immediately instead of waiting, so we
+	// serialize the access to the database with a single connection.
+	sqlDB.SetMaxOpenConns(1)
 
 	cli := &metaOpsClient{
-		db: db,
+		db:             db,
+		configCache:    newJobConfigCache(defaultJobConfigCacheSize),
+		workerWatchers: newWorkerWatchers(),
+		retryPolicy:    newRetryPolicy(NewDefaultDBConfig()),
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -2,14 +2,21 @@ package orm
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	libModel "github.com/hanfei1991/microcosm/lib/model"
 	cerrors "github.com/hanfei1991/microcosm/pkg/errors"
 	resourcemeta "github.com/hanfei1991/microcosm/pkg/externalresource/resourcemeta/model"
+	"github.com/hanfei1991/microcosm/pkg/meta/metaclient"
 	"github.com/hanfei1991/microcosm/pkg/orm/model"
+	"github.com/hanfei1991/microcosm/pkg/tenant"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,29 +36,133 @@ func TestGenEpochMock(t *testing.T) {
 		require.NoError(t, err)
 	}
 	require.Equal(t, int64(11), epoch)
+}
 
-	// Being a lightweight database, SQLite can’t handle a high level of concurrency
-	// NOTICE: Not Recommend to do high concurrenct test in unit test
-	// TODO: we can add retry for sqlite error 'database table is lock' later
-	/*
-		var wg sync.WaitGroup
-		for i := 0; i < 10; i++ {
-			wg.Add(1)
-			go func(idx int) {
-				t.Logf("goroutine:%d", idx)
-				defer wg.Done()
-				for j := 0; j < 10; j++ {
-					_, err := mock.GenEpoch(ctx)
-					require.NoError(t, err)
-				}
-			}(i)
-		}
-		wg.Wait()
-		t.Logf("end the concurrency")
+func TestGetEpochMock(t *testing.T) {
+	t.Parallel()
+
+	mock, err := NewMockClient()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var epoch int64
+	for j := 0; j < 5; j++ {
 		epoch, err = mock.GenEpoch(ctx)
 		require.NoError(t, err)
-		require.Equal(t, 112, int(epoch))
-	*/
+	}
+	curEpoch, err := mock.GetEpoch(ctx)
+	require.NoError(t, err)
+	require.Equal(t, epoch, curEpoch)
+
+	// GetEpoch doesn't advance the epoch
+	curEpoch, err = mock.GetEpoch(ctx)
+	require.NoError(t, err)
+	require.Equal(t, epoch, curEpoch)
+	epoch, err = mock.GenEpoch(ctx)
+	require.NoError(t, err)
+	require.Equal(t, curEpoch+1, epoch)
+}
+
+func TestGenEpochMigrateLegacyMock(t *testing.T) {
+	t.Parallel()
+
+	mock, err := NewMockClient()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// the epoch of a metastore upgraded from the logic_epoches table
+	db := mock.(*metaOpsClient).db
+	require.NoError(t, db.Exec("CREATE TABLE `logic_epoches` (`seq_id` integer PRIMARY KEY, "+
+		"`created_at` datetime, `updated_at` datetime, `epoch` integer NOT NULL DEFAULT 1)").Error)
+	require.NoError(t, db.Exec("INSERT INTO `logic_epoches` (`seq_id`, `epoch`) VALUES (1, 42)").Error)
+
+	// the counter created with the default value is raised to the legacy epoch
+	require.NoError(t, mock.Initialize(ctx))
+	epoch, err := mock.GenEpoch(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(43), epoch)
+
+	// initializing again never lowers the epoch
+	require.NoError(t, mock.Initialize(ctx))
+	epoch, err = mock.GenEpoch(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(44), epoch)
+}
+
+func TestGenEpochConcurrentMock(t *testing.T) {
+	t.Parallel()
+
+	mock, err := NewMockClient()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		epochs = make(map[int64]struct{})
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				epoch, err := mock.GenEpoch(ctx)
+				require.NoError(t, err)
+				mu.Lock()
+				epochs[epoch] = struct{}{}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Len(t, epochs, 100)
+}
+
+func TestGenNamedCounterMock(t *testing.T) {
+	t.Parallel()
+
+	mock, err := NewMockClient()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// the counters are created on first use and advance independently
+	for j := 1; j <= 10; j++ {
+		seq, err := mock.GenNamedCounter(ctx, "seq")
+		require.NoError(t, err)
+		require.Equal(t, int64(j), seq)
+		if j%2 == 0 {
+			gen, err := mock.GenNamedCounter(ctx, "generation")
+			require.NoError(t, err)
+			require.Equal(t, int64(j/2), gen)
+		}
+	}
+
+	// the epoch is a named counter too, which is initialized with 1
+	epoch, err := mock.GenEpoch(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), epoch)
+	epoch, err = mock.GenNamedCounter(ctx, model.LogicEpochCounter)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), epoch)
+
+	seq, err := mock.GenNamedCounter(ctx, "seq")
+	require.NoError(t, err)
+	require.Equal(t, int64(11), seq)
+
+	_, err = mock.GenNamedCounter(ctx, "")
+	require.Error(t, err)
 }
 
 type mCase struct {
@@ -80,6 +191,142 @@ func TestInitializeMock(t *testing.T) {
 	}
 }
 
+func TestPingMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+
+	ctx := context.TODO()
+	require.Nil(t, cli.Ping(ctx))
+
+	require.Nil(t, cli.Close())
+	err = cli.Ping(ctx)
+	require.Error(t, err)
+	var metaErr *MetaError
+	require.True(t, errors.As(err, &metaErr))
+	require.True(t, errors.Is(err, cerrors.ErrMetaOpFail))
+}
+
+func newSQLiteClient(t *testing.T, dir string) Client {
+	var store metaclient.StoreConfigParams
+	store.SetEndpoints(dir)
+	conf := NewDefaultDBConfig()
+	conf.Backend = BackendSQLite
+	cli, err := NewClient(store, conf)
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	return cli
+}
+
+func TestSQLiteBackend(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cli := newSQLiteClient(t, dir)
+	ctx := context.TODO()
+	require.Nil(t, cli.Initialize(ctx))
+	// the framework metastore is isolated in its own file
+	_, err := os.Stat(filepath.Join(dir, tenant.FrameTenantID+".db"))
+	require.Nil(t, err)
+
+	tm := time.Now()
+	createdAt := tm.Add(time.Duration(1))
+	updatedAt := tm.Add(time.Duration(1))
+	testCases := []mCase{
+		{
+			fn: "CreateProject",
+			inputs: []interface{}{
+				&model.ProjectInfo{
+					Model: model.Model{
+						CreatedAt: createdAt,
+						UpdatedAt: updatedAt,
+					},
+					ID:   "p111",
+					Name: "tenant1",
+				},
+			},
+		},
+		{
+			fn: "GetProjectByID",
+			inputs: []interface{}{
+				"p111",
+			},
+			output: &model.ProjectInfo{
+				Model: model.Model{
+					SeqID:     1,
+					CreatedAt: createdAt,
+					UpdatedAt: updatedAt,
+				},
+				ID:   "p111",
+				Name: "tenant1",
+			},
+		},
+		{
+			fn: "GetProjectByID",
+			inputs: []interface{}{
+				"p112",
+			},
+			err: cerrors.ErrMetaEntryNotFound.GenWithStackByArgs(),
+		},
+		{
+			fn: "UpsertJob",
+			inputs: []interface{}{
+				&libModel.MasterMetaKVData{
+					Model: model.Model{
+						CreatedAt: createdAt,
+						UpdatedAt: updatedAt,
+					},
+					ProjectID: "p111",
+					ID:        "j111",
+				},
+			},
+		},
+		{
+			fn: "QueryJobsByProjectID",
+			inputs: []interface{}{
+				"p111",
+			},
+			output: []*libModel.MasterMetaKVData{
+				{
+					Model: model.Model{
+						SeqID:     1,
+						CreatedAt: createdAt,
+						UpdatedAt: updatedAt,
+					},
+					ProjectID: "p111",
+					ID:        "j111",
+				},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		testInnerMock(t, cli, tc)
+	}
+
+	epoch, err := cli.GenEpoch(ctx)
+	require.Nil(t, err)
+	require.Nil(t, cli.Close())
+
+	// the data is persisted in the file
+	cli = newSQLiteClient(t, dir)
+	defer cli.Close()
+	require.Nil(t, cli.Initialize(ctx))
+	project, err := cli.GetProjectByID(ctx, "p111")
+	require.Nil(t, err)
+	require.Equal(t, "tenant1", project.Name)
+	curEpoch, err := cli.GetEpoch(ctx)
+	require.Nil(t, err)
+	require.Equal(t, epoch, curEpoch)
+
+	conf := NewDefaultDBConfig()
+	conf.Backend = "postgres"
+	_, err = NewClient(metaclient.StoreConfigParams{}, conf)
+	require.True(t, cerrors.ErrMetaParamsInvalid.Equal(err))
+	conf.Backend = BackendSQLite
+	_, err = NewClient(metaclient.StoreConfigParams{}, conf)
+	require.True(t, cerrors.ErrMetaParamsInvalid.Equal(err))
+}
+
 func TestProjectMock(t *testing.T) {
 	cli, err := NewMockClient()
 	require.Nil(t, err)
@@ -268,6 +515,134 @@ func TestProjectOperationMock(t *testing.T) {
 	}
 }
 
+func TestQueryRecentProjectOperationsMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	tm := time.Now()
+	for i := 0; i < 50; i++ {
+		err := cli.CreateProjectOperation(ctx, &model.ProjectOperation{
+			ProjectID: "p111",
+			Operation: "Submit",
+			JobID:     fmt.Sprintf("j%d", i),
+			CreatedAt: tm.Add(time.Duration(i) * time.Second),
+		})
+		require.Nil(t, err)
+	}
+	// operation of another project should not be returned
+	err = cli.CreateProjectOperation(ctx, &model.ProjectOperation{
+		ProjectID: "p112",
+		Operation: "Submit",
+		JobID:     "j100",
+		CreatedAt: tm.Add(time.Hour),
+	})
+	require.Nil(t, err)
+
+	ops, err := cli.QueryRecentProjectOperations(ctx, "p111", 20)
+	require.Nil(t, err)
+	require.Len(t, ops, 20)
+	for i, op := range ops {
+		require.Equal(t, "p111", op.ProjectID)
+		require.Equal(t, fmt.Sprintf("j%d", 49-i), op.JobID)
+		if i > 0 {
+			require.True(t, ops[i-1].CreatedAt.After(op.CreatedAt))
+		}
+	}
+
+	ops, err = cli.QueryRecentProjectOperations(ctx, "p112", 20)
+	require.Nil(t, err)
+	require.Len(t, ops, 1)
+
+	_, err = cli.QueryRecentProjectOperations(ctx, "p111", 0)
+	require.Error(t, err)
+}
+
+func TestIterateProjectOperationsMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	tm := time.Now()
+	// insert the operations out of order, and some of them are created at
+	// the same time
+	offsets := []int{5, 1, 8, 3, 3, 0, 9, 2, 7, 3, 6, 4}
+	for i, offset := range offsets {
+		err := cli.CreateProjectOperation(ctx, &model.ProjectOperation{
+			ProjectID: "p111",
+			Operation: "Submit",
+			JobID:     fmt.Sprintf("j%d", i),
+			CreatedAt: tm.Add(time.Duration(offset) * time.Second),
+		})
+		require.Nil(t, err)
+	}
+	err = cli.CreateProjectOperation(ctx, &model.ProjectOperation{
+		ProjectID: "p112",
+		Operation: "Submit",
+		JobID:     "j100",
+		CreatedAt: tm,
+	})
+	require.Nil(t, err)
+
+	checkOrder := func(ops []*model.ProjectOperation) {
+		require.Len(t, ops, len(offsets))
+		for i, op := range ops {
+			require.Equal(t, "p111", op.ProjectID)
+			if i == 0 {
+				continue
+			}
+			prev := ops[i-1]
+			require.False(t, prev.CreatedAt.After(op.CreatedAt))
+			if prev.CreatedAt.Equal(op.CreatedAt) {
+				require.Less(t, prev.SeqID, op.SeqID)
+			}
+		}
+	}
+
+	var ops []*model.ProjectOperation
+	err = cli.IterateProjectOperations(ctx, "p111", func(op *model.ProjectOperation) error {
+		ops = append(ops, op)
+		return nil
+	})
+	require.Nil(t, err)
+	checkOrder(ops)
+
+	// the batch size doesn't divide the count of operations
+	for _, batchSize := range []int{1, 3, 5} {
+		ops = ops[:0]
+		err = cli.(*metaOpsClient).iterateProjectOperations(ctx, "p111", batchSize,
+			func(op *model.ProjectOperation) error {
+				ops = append(ops, op)
+				return nil
+			})
+		require.Nil(t, err)
+		checkOrder(ops)
+	}
+
+	// the iteration stops on the error of the callback
+	stopErr := errors.New("stop")
+	cnt := 0
+	err = cli.(*metaOpsClient).iterateProjectOperations(ctx, "p111", 5,
+		func(op *model.ProjectOperation) error {
+			cnt++
+			if cnt == 7 {
+				return stopErr
+			}
+			return nil
+		})
+	require.Equal(t, stopErr, err)
+	require.Equal(t, 7, cnt)
+
+	err = cli.IterateProjectOperations(ctx, "p113", func(op *model.ProjectOperation) error {
+		return errors.New("unexpected operation")
+	})
+	require.Nil(t, err)
+}
+
 func TestJobMock(t *testing.T) {
 	cli, err := NewMockClient()
 	require.Nil(t, err)
@@ -434,72 +809,576 @@ func TestJobMock(t *testing.T) {
 	}
 }
 
-func TestWorkerMock(t *testing.T) {
+func TestUpdateJobFieldsMock(t *testing.T) {
 	cli, err := NewMockClient()
 	require.Nil(t, err)
 	require.NotNil(t, cli)
 	defer cli.Close()
 
-	err = cli.Initialize(context.TODO())
+	ctx := context.TODO()
+	job := &libModel.MasterMetaKVData{
+		ProjectID:  "p111",
+		ID:         "j111",
+		Tp:         1,
+		NodeID:     "n111",
+		Epoch:      1,
+		StatusCode: libModel.MasterStatusInit,
+		Addr:       "127.0.0.1",
+		Config:     []byte{0x11, 0x22},
+	}
+	err = cli.UpsertJob(ctx, job)
+	require.Nil(t, err)
+	before, err := cli.GetJobByID(ctx, "j111")
 	require.Nil(t, err)
 
-	tm := time.Now()
-	createdAt := tm.Add(time.Duration(1))
-	updatedAt := tm.Add(time.Duration(1))
+	status := libModel.MasterStatusFinished
+	err = cli.UpdateJobFields(ctx, "j111", JobUpdate{Status: &status})
+	require.Nil(t, err)
 
-	testCases := []mCase{
-		{
-			// INSERT INTO `worker_statuses` (`created_at`,`updated_at`,`project_id`,`job_id`,`worker_id`,`worker_type`,
-			// `worker_statuses`,`worker_err_msg`,`worker_config`,`id`) VALUES ('2022-04-14 11:35:06.119','2022-04-14 11:35:06.119',
-			// '111-222-333','111','222',1,1,'error','<binary>',1)
-			fn: "UpsertWorker",
-			inputs: []interface{}{
-				&libModel.WorkerStatus{
-					Model: model.Model{
-						CreatedAt: createdAt,
-						UpdatedAt: updatedAt,
-					},
-					ProjectID:    "p111",
-					JobID:        "j111",
-					ID:           "w222",
-					Type:         1,
-					Code:         1,
-					ErrorMessage: "error",
-					ExtBytes:     []byte{0x11, 0x22},
-				},
-			},
-		},
-		{
-			fn: "UpsertWorker",
-			inputs: []interface{}{
-				&libModel.WorkerStatus{
-					Model: model.Model{
-						CreatedAt: createdAt,
-						UpdatedAt: updatedAt,
-					},
-					ProjectID:    "p111",
-					JobID:        "j111",
-					ID:           "w224",
-					Type:         1,
-					Code:         1,
-					ErrorMessage: "error",
-					ExtBytes:     []byte{0x11, 0x22},
-				},
-			},
-		},
-		{
-			fn: "DeleteWorker",
-			inputs: []interface{}{
-				"j111",
-				"w223",
-			},
-			output: &ormResult{
-				rowsAffected: 0,
-			},
-		},
-		{
-			// DELETE FROM `worker_statuses` WHERE project_id = '111-222-334' AND job_id = '111' AND worker_id = '222'
-			fn: "DeleteWorker",
+	after, err := cli.GetJobByID(ctx, "j111")
+	require.Nil(t, err)
+	require.Equal(t, libModel.MasterStatusFinished, after.StatusCode)
+	require.False(t, after.UpdatedAt.Before(before.UpdatedAt))
+	require.Equal(t, job.ProjectID, after.ProjectID)
+	require.Equal(t, job.Tp, after.Tp)
+	require.Equal(t, job.NodeID, after.NodeID)
+	require.Equal(t, job.Epoch, after.Epoch)
+	require.Equal(t, job.Addr, after.Addr)
+	require.Equal(t, job.Config, after.Config)
+
+	addr, epoch := "127.0.0.2", libModel.Epoch(2)
+	err = cli.UpdateJobFields(ctx, "j111", JobUpdate{Addr: &addr, Epoch: &epoch})
+	require.Nil(t, err)
+	after, err = cli.GetJobByID(ctx, "j111")
+	require.Nil(t, err)
+	require.Equal(t, libModel.MasterStatusFinished, after.StatusCode)
+	require.Equal(t, addr, after.Addr)
+	require.Equal(t, epoch, after.Epoch)
+	require.Equal(t, job.NodeID, after.NodeID)
+
+	err = cli.UpdateJobFields(ctx, "j111", JobUpdate{})
+	require.Error(t, err)
+}
+
+func TestUpdateJobEpochConflictMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	err = cli.UpsertJob(ctx, &libModel.MasterMetaKVData{
+		ProjectID:  "p111",
+		ID:         "j111",
+		Epoch:      1,
+		StatusCode: libModel.MasterStatusInit,
+		Addr:       "127.0.0.1",
+	})
+	require.Nil(t, err)
+
+	// both masters load the job at epoch 1, the fresh one takes it over first
+	stale, err := cli.GetJobByID(ctx, "j111")
+	require.Nil(t, err)
+	fresh, err := cli.GetJobByID(ctx, "j111")
+	require.Nil(t, err)
+	fresh.Epoch = 2
+	fresh.Addr = "127.0.0.2"
+	err = cli.UpdateJob(ctx, fresh, 1)
+	require.Nil(t, err)
+
+	// the stale master can't overwrite the job
+	stale.StatusCode = libModel.MasterStatusFinished
+	err = cli.UpdateJob(ctx, stale, 1)
+	require.True(t, cerrors.ErrMetaConcurrentModification.Equal(err))
+	job, err := cli.GetJobByID(ctx, "j111")
+	require.Nil(t, err)
+	require.Equal(t, libModel.Epoch(2), job.Epoch)
+	require.Equal(t, "127.0.0.2", job.Addr)
+	require.Equal(t, libModel.MasterStatusInit, job.StatusCode)
+
+	// the fresh master keeps updating the job with its epoch
+	fresh.StatusCode = libModel.MasterStatusFinished
+	err = cli.UpdateJob(ctx, fresh, 2)
+	require.Nil(t, err)
+	job, err = cli.GetJobByID(ctx, "j111")
+	require.Nil(t, err)
+	require.Equal(t, libModel.MasterStatusFinished, job.StatusCode)
+
+	// updating a nonexistent job is not a conflict
+	err = cli.UpdateJob(ctx, &libModel.MasterMetaKVData{ID: "j112"}, 0)
+	require.True(t, IsNotFoundError(err))
+	require.False(t, cerrors.ErrMetaConcurrentModification.Equal(err))
+}
+
+func TestCountJobsByStatusMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	jobs := []struct {
+		projectID string
+		status    libModel.MasterStatusCode
+	}{
+		{"p111", libModel.MasterStatusUninit},
+		{"p111", libModel.MasterStatusInit},
+		{"p111", libModel.MasterStatusInit},
+		{"p111", libModel.MasterStatusFinished},
+		{"p112", libModel.MasterStatusInit},
+		{"p112", libModel.MasterStatusStopped},
+		{"p112", libModel.MasterStatusStopped},
+	}
+	for i, job := range jobs {
+		err := cli.UpsertJob(ctx, &libModel.MasterMetaKVData{
+			ProjectID:  job.projectID,
+			ID:         fmt.Sprintf("j%d", i),
+			StatusCode: job.status,
+		})
+		require.Nil(t, err)
+	}
+	// deleted job is not counted
+	_, err = cli.DeleteJob(ctx, "j0")
+	require.Nil(t, err)
+
+	counts, err := cli.CountJobsByStatus(ctx)
+	require.Nil(t, err)
+	require.Equal(t, map[int]int64{
+		int(libModel.MasterStatusInit):     3,
+		int(libModel.MasterStatusFinished): 1,
+		int(libModel.MasterStatusStopped):  2,
+	}, counts)
+
+	counts, err = cli.CountProjectJobsByStatus(ctx, "p112")
+	require.Nil(t, err)
+	require.Equal(t, map[int]int64{
+		int(libModel.MasterStatusInit):    1,
+		int(libModel.MasterStatusStopped): 2,
+	}, counts)
+
+	counts, err = cli.CountProjectJobsByStatus(ctx, "p113")
+	require.Nil(t, err)
+	require.Len(t, counts, 0)
+}
+
+func TestCountMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	for i := 0; i < 4; i++ {
+		err := cli.UpsertJob(ctx, &libModel.MasterMetaKVData{ID: fmt.Sprintf("j%d", i)})
+		require.Nil(t, err)
+	}
+	for i := 0; i < 3; i++ {
+		err := cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j1", ID: fmt.Sprintf("w%d", i)})
+		require.Nil(t, err)
+		err = cli.UpsertResource(ctx, &resourcemeta.ResourceMeta{ID: fmt.Sprintf("r%d", i), Job: "j1"})
+		require.Nil(t, err)
+	}
+	err = cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j2", ID: "w0"})
+	require.Nil(t, err)
+	err = cli.UpsertResource(ctx, &resourcemeta.ResourceMeta{ID: "r3", Job: "j2"})
+	require.Nil(t, err)
+	// deleted job is not counted
+	_, err = cli.DeleteJob(ctx, "j0")
+	require.Nil(t, err)
+
+	jobs, err := cli.QueryJobs(ctx)
+	require.Nil(t, err)
+	jobCnt, err := cli.CountJobs(ctx)
+	require.Nil(t, err)
+	require.Equal(t, int64(3), jobCnt)
+	require.Equal(t, int64(len(jobs)), jobCnt)
+
+	for _, jobID := range []string{"j1", "j2", "j3"} {
+		workers, err := cli.QueryWorkersByMasterID(ctx, jobID)
+		require.Nil(t, err)
+		workerCnt, err := cli.CountWorkersByMasterID(ctx, jobID)
+		require.Nil(t, err)
+		require.Equal(t, int64(len(workers)), workerCnt)
+
+		resources, err := cli.QueryResourcesByJobID(ctx, jobID)
+		require.Nil(t, err)
+		resourceCnt, err := cli.CountResourcesByJobID(ctx, jobID)
+		require.Nil(t, err)
+		require.Equal(t, int64(len(resources)), resourceCnt)
+	}
+
+	workerCnt, err := cli.CountWorkersByMasterID(ctx, "j1")
+	require.Nil(t, err)
+	require.Equal(t, int64(3), workerCnt)
+	resourceCnt, err := cli.CountResourcesByJobID(ctx, "j3")
+	require.Nil(t, err)
+	require.Equal(t, int64(0), resourceCnt)
+}
+
+func TestQueryJobsByStatusOnlyMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	statuses := []libModel.MasterStatusCode{
+		libModel.MasterStatusInit,
+		libModel.MasterStatusStopped,
+		libModel.MasterStatusInit,
+		libModel.MasterStatusFinished,
+		libModel.MasterStatusInit,
+	}
+	for i, status := range statuses {
+		err := cli.UpsertJob(ctx, &libModel.MasterMetaKVData{
+			ProjectID:  "p111",
+			ID:         fmt.Sprintf("j%d", i),
+			StatusCode: status,
+		})
+		require.Nil(t, err)
+	}
+	// deleted job is not returned
+	_, err = cli.DeleteJob(ctx, "j4")
+	require.Nil(t, err)
+
+	jobs, err := cli.QueryJobsByStatusOnly(ctx, int(libModel.MasterStatusInit))
+	require.Nil(t, err)
+	var ids []string
+	for _, job := range jobs {
+		ids = append(ids, job.ID)
+	}
+	require.ElementsMatch(t, []string{"j0", "j2"}, ids)
+
+	jobs, err = cli.QueryJobsByStatusOnly(ctx, int(libModel.MasterStatusUninit))
+	require.Nil(t, err)
+	require.Empty(t, jobs)
+
+	// the job filter is still available
+	jobs, err = cli.QueryJobsByStatus(ctx, "j2", int(libModel.MasterStatusInit))
+	require.Nil(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, "j2", jobs[0].ID)
+	jobs, err = cli.QueryJobsByStatus(ctx, "j1", int(libModel.MasterStatusInit))
+	require.Nil(t, err)
+	require.Empty(t, jobs)
+}
+
+func TestQueryLatestJobByProjectIDMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	_, err = cli.QueryLatestJobByProjectID(ctx, "p111")
+	require.True(t, IsNotFoundError(err))
+
+	base := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	jobs := []struct {
+		projectID string
+		id        string
+		updatedAt time.Time
+	}{
+		{"p111", "j0", base.Add(time.Hour)},
+		{"p111", "j1", base.Add(3 * time.Hour)},
+		{"p111", "j2", base.Add(2 * time.Hour)},
+		{"p222", "j3", base.Add(4 * time.Hour)},
+	}
+	for _, job := range jobs {
+		err := cli.UpsertJob(ctx, &libModel.MasterMetaKVData{
+			Model: model.Model{
+				CreatedAt: base,
+				UpdatedAt: job.updatedAt,
+			},
+			ProjectID: job.projectID,
+			ID:        job.id,
+		})
+		require.Nil(t, err)
+	}
+
+	job, err := cli.QueryLatestJobByProjectID(ctx, "p111")
+	require.Nil(t, err)
+	require.Equal(t, "j1", job.ID)
+	job, err = cli.QueryLatestJobByProjectID(ctx, "p222")
+	require.Nil(t, err)
+	require.Equal(t, "j3", job.ID)
+
+	// updating a job makes it the latest one
+	err = cli.UpdateJob(ctx, &libModel.MasterMetaKVData{
+		ProjectID:  "p111",
+		ID:         "j0",
+		StatusCode: libModel.MasterStatusFinished,
+	}, 0)
+	require.Nil(t, err)
+	job, err = cli.QueryLatestJobByProjectID(ctx, "p111")
+	require.Nil(t, err)
+	require.Equal(t, "j0", job.ID)
+
+	_, err = cli.QueryLatestJobByProjectID(ctx, "p333")
+	require.True(t, IsNotFoundError(err))
+}
+
+func TestQueryJobsUpdatedAfterMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	for _, id := range []string{"j0", "j1", "j2"} {
+		err := cli.UpsertJob(ctx, &libModel.MasterMetaKVData{
+			ProjectID:  "p111",
+			ID:         id,
+			StatusCode: libModel.MasterStatusInit,
+		})
+		require.Nil(t, err)
+	}
+	jobs, err := cli.QueryJobsUpdatedAfter(ctx, time.Time{})
+	require.Nil(t, err)
+	require.Len(t, jobs, 3)
+
+	// make sure the following updates are after the timestamp
+	time.Sleep(10 * time.Millisecond)
+	since := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	jobs, err = cli.QueryJobsUpdatedAfter(ctx, since)
+	require.Nil(t, err)
+	require.Len(t, jobs, 0)
+
+	err = cli.UpdateJob(ctx, &libModel.MasterMetaKVData{
+		ProjectID:  "p111",
+		ID:         "j1",
+		StatusCode: libModel.MasterStatusFinished,
+	}, 0)
+	require.Nil(t, err)
+	jobs, err = cli.QueryJobsUpdatedAfter(ctx, since)
+	require.Nil(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, "j1", jobs[0].ID)
+	require.Equal(t, libModel.MasterStatusFinished, jobs[0].StatusCode)
+
+	// the jobs are in the order of updated_at
+	time.Sleep(10 * time.Millisecond)
+	err = cli.UpdateJob(ctx, &libModel.MasterMetaKVData{
+		ProjectID:  "p111",
+		ID:         "j0",
+		StatusCode: libModel.MasterStatusStopped,
+	}, 0)
+	require.Nil(t, err)
+	jobs, err = cli.QueryJobsUpdatedAfter(ctx, since)
+	require.Nil(t, err)
+	require.Len(t, jobs, 2)
+	require.Equal(t, "j1", jobs[0].ID)
+	require.Equal(t, "j0", jobs[1].ID)
+	require.True(t, jobs[1].UpdatedAt.After(jobs[0].UpdatedAt))
+}
+
+func TestQueryJobTerminalStatesMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	statuses := []libModel.MasterStatusCode{
+		libModel.MasterStatusUninit,
+		libModel.MasterStatusInit,
+		libModel.MasterStatusFinished,
+		libModel.MasterStatusStopped,
+		libModel.MasterStatusInit,
+	}
+	for i, status := range statuses {
+		err := cli.UpsertJob(ctx, &libModel.MasterMetaKVData{
+			ProjectID:  "p111",
+			ID:         fmt.Sprintf("j%d", i),
+			StatusCode: status,
+		})
+		require.Nil(t, err)
+	}
+	// deleted job is not returned
+	_, err = cli.DeleteJob(ctx, "j4")
+	require.Nil(t, err)
+
+	states, err := cli.QueryJobTerminalStates(ctx)
+	require.Nil(t, err)
+	require.Equal(t, map[string]bool{
+		"j0": false,
+		"j1": false,
+		"j2": true,
+		"j3": true,
+	}, states)
+}
+
+func TestListActiveProjectIDsMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	projectIDs, err := cli.ListActiveProjectIDs(ctx)
+	require.Nil(t, err)
+	require.Len(t, projectIDs, 0)
+
+	jobs := []struct {
+		projectID string
+		status    libModel.MasterStatusCode
+	}{
+		// p111 has both active and terminal jobs
+		{projectID: "p111", status: libModel.MasterStatusInit},
+		{projectID: "p111", status: libModel.MasterStatusUninit},
+		{projectID: "p111", status: libModel.MasterStatusFinished},
+		// p222 has only terminal jobs
+		{projectID: "p222", status: libModel.MasterStatusFinished},
+		{projectID: "p222", status: libModel.MasterStatusStopped},
+		{projectID: "p333", status: libModel.MasterStatusUninit},
+		// p444 has only a deleted active job
+		{projectID: "p444", status: libModel.MasterStatusInit},
+	}
+	for i, job := range jobs {
+		err := cli.UpsertJob(ctx, &libModel.MasterMetaKVData{
+			ProjectID:  job.projectID,
+			ID:         fmt.Sprintf("j%d", i),
+			StatusCode: job.status,
+		})
+		require.Nil(t, err)
+	}
+	_, err = cli.DeleteJob(ctx, "j6")
+	require.Nil(t, err)
+
+	projectIDs, err = cli.ListActiveProjectIDs(ctx)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"p111", "p333"}, projectIDs)
+}
+
+func TestMoveJobToProjectMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	for _, jobID := range []string{"j1", "j2"} {
+		err := cli.UpsertJob(ctx, &libModel.MasterMetaKVData{ProjectID: "p111", ID: jobID})
+		require.Nil(t, err)
+		for i := 0; i < 2; i++ {
+			err := cli.UpsertWorker(ctx, &libModel.WorkerStatus{
+				ProjectID: "p111", JobID: jobID, ID: fmt.Sprintf("w%d", i),
+			})
+			require.Nil(t, err)
+			err = cli.UpsertResource(ctx, &resourcemeta.ResourceMeta{
+				ProjectID: "p111", ID: fmt.Sprintf("%s-r%d", jobID, i), Job: jobID,
+			})
+			require.Nil(t, err)
+		}
+	}
+
+	checkProject := func(jobID, projectID string) {
+		job, err := cli.GetJobByID(ctx, jobID)
+		require.Nil(t, err)
+		require.Equal(t, projectID, job.ProjectID)
+		workers, err := cli.QueryWorkersByMasterID(ctx, jobID)
+		require.Nil(t, err)
+		require.Len(t, workers, 2)
+		for _, worker := range workers {
+			require.Equal(t, projectID, worker.ProjectID)
+		}
+		resources, err := cli.QueryResourcesByJobID(ctx, jobID)
+		require.Nil(t, err)
+		require.Len(t, resources, 2)
+		for _, resource := range resources {
+			require.Equal(t, projectID, resource.ProjectID)
+		}
+	}
+
+	err = cli.MoveJobToProject(ctx, "j1", "p222")
+	require.Nil(t, err)
+	checkProject("j1", "p222")
+	// the other job is not moved
+	checkProject("j2", "p111")
+	ops, err := cli.QueryProjectOperations(ctx, "p222")
+	require.Nil(t, err)
+	require.Len(t, ops, 1)
+	require.Equal(t, model.ProjectOperationMove, ops[0].Operation)
+	require.Equal(t, "j1", ops[0].JobID)
+
+	err = cli.MoveJobToProject(ctx, "j3", "p222")
+	require.True(t, IsNotFoundError(err))
+
+	// the moved rows are rolled back if recording the operation fails
+	metaCli := cli.(*metaOpsClient)
+	require.Nil(t, metaCli.db.Migrator().DropTable(&model.ProjectOperation{}))
+	err = cli.MoveJobToProject(ctx, "j2", "p222")
+	require.True(t, errors.Is(err, cerrors.ErrMetaOpFail))
+	checkProject("j2", "p111")
+}
+
+func TestWorkerMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	err = cli.Initialize(context.TODO())
+	require.Nil(t, err)
+
+	tm := time.Now()
+	createdAt := tm.Add(time.Duration(1))
+	updatedAt := tm.Add(time.Duration(1))
+
+	testCases := []mCase{
+		{
+			// INSERT INTO `worker_statuses` (`created_at`,`updated_at`,`project_id`,`job_id`,`worker_id`,`worker_type`,
+			// `worker_statuses`,`worker_err_msg`,`worker_config`,`id`) VALUES ('2022-04-14 11:35:06.119','2022-04-14 11:35:06.119',
+			// '111-222-333','111','222',1,1,'error','<binary>',1)
+			fn: "UpsertWorker",
+			inputs: []interface{}{
+				&libModel.WorkerStatus{
+					Model: model.Model{
+						CreatedAt: createdAt,
+						UpdatedAt: updatedAt,
+					},
+					ProjectID:    "p111",
+					JobID:        "j111",
+					ID:           "w222",
+					Type:         1,
+					Code:         1,
+					ErrorMessage: "error",
+					ExtBytes:     []byte{0x11, 0x22},
+				},
+			},
+		},
+		{
+			fn: "UpsertWorker",
+			inputs: []interface{}{
+				&libModel.WorkerStatus{
+					Model: model.Model{
+						CreatedAt: createdAt,
+						UpdatedAt: updatedAt,
+					},
+					ProjectID:    "p111",
+					JobID:        "j111",
+					ID:           "w224",
+					Type:         1,
+					Code:         1,
+					ErrorMessage: "error",
+					ExtBytes:     []byte{0x11, 0x22},
+				},
+			},
+		},
+		{
+			fn: "DeleteWorker",
+			inputs: []interface{}{
+				"j111",
+				"w223",
+			},
+			output: &ormResult{
+				rowsAffected: 0,
+			},
+		},
+		{
+			// DELETE FROM `worker_statuses` WHERE project_id = '111-222-334' AND job_id = '111' AND worker_id = '222'
+			fn: "DeleteWorker",
 			inputs: []interface{}{
 				"j112",
 				"w224",
@@ -603,9 +1482,272 @@ func TestWorkerMock(t *testing.T) {
 		},
 	}
 
-	for _, tc := range testCases {
-		testInnerMock(t, cli, tc)
+	for _, tc := range testCases {
+		testInnerMock(t, cli, tc)
+	}
+}
+
+func TestUpsertTwiceMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	err = cli.UpsertJob(ctx, &libModel.MasterMetaKVData{
+		ProjectID: "p111", ID: "j111", NodeID: "n111", StatusCode: libModel.MasterStatusInit, Epoch: 1,
+	})
+	require.Nil(t, err)
+	err = cli.UpsertJob(ctx, &libModel.MasterMetaKVData{
+		ProjectID: "p111", ID: "j111", NodeID: "n222", StatusCode: libModel.MasterStatusFinished, Epoch: 2,
+	})
+	require.Nil(t, err)
+	jobCnt, err := cli.CountJobs(ctx)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), jobCnt)
+	job, err := cli.GetJobByID(ctx, "j111")
+	require.Nil(t, err)
+	require.Equal(t, "n222", job.NodeID)
+	require.Equal(t, libModel.MasterStatusFinished, job.StatusCode)
+	require.Equal(t, libModel.Epoch(2), job.Epoch)
+
+	err = cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: "w111", Code: libModel.WorkerStatusInit})
+	require.Nil(t, err)
+	err = cli.UpsertWorker(ctx, &libModel.WorkerStatus{
+		JobID: "j111", ID: "w111", Code: libModel.WorkerStatusError, ErrorMessage: "error",
+	})
+	require.Nil(t, err)
+	workerCnt, err := cli.CountWorkersByMasterID(ctx, "j111")
+	require.Nil(t, err)
+	require.Equal(t, int64(1), workerCnt)
+	worker, err := cli.GetWorkerByID(ctx, "j111", "w111")
+	require.Nil(t, err)
+	require.Equal(t, libModel.WorkerStatusError, worker.Code)
+	require.Equal(t, "error", worker.ErrorMessage)
+
+	err = cli.UpsertResource(ctx, &resourcemeta.ResourceMeta{ID: "r111", Job: "j111", Executor: "e111"})
+	require.Nil(t, err)
+	err = cli.UpsertResource(ctx, &resourcemeta.ResourceMeta{ID: "r111", Job: "j111", Executor: "e222", Deleted: true})
+	require.Nil(t, err)
+	resourceCnt, err := cli.CountResourcesByJobID(ctx, "j111")
+	require.Nil(t, err)
+	require.Equal(t, int64(1), resourceCnt)
+	resource, err := cli.GetResourceByID(ctx, "r111")
+	require.Nil(t, err)
+	require.Equal(t, "e222", string(resource.Executor))
+	require.True(t, resource.Deleted)
+}
+
+func TestDeleteWorkersByMasterIDMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	for i := 0; i < 3; i++ {
+		err := cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: fmt.Sprintf("w%d", i)})
+		require.Nil(t, err)
+	}
+	for i := 0; i < 2; i++ {
+		err := cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j222", ID: fmt.Sprintf("w%d", i)})
+		require.Nil(t, err)
+	}
+
+	deleted, err := cli.DeleteWorkersByMasterID(ctx, "j111")
+	require.Nil(t, err)
+	require.Equal(t, int64(3), deleted)
+
+	workers, err := cli.QueryWorkersByMasterID(ctx, "j111")
+	require.Nil(t, err)
+	require.Empty(t, workers)
+	workers, err = cli.QueryWorkersByMasterID(ctx, "j222")
+	require.Nil(t, err)
+	require.Len(t, workers, 2)
+
+	// delete again
+	deleted, err = cli.DeleteWorkersByMasterID(ctx, "j111")
+	require.Nil(t, err)
+	require.Equal(t, int64(0), deleted)
+}
+
+func TestClaimWorkerMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	err = cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: "w0"})
+	require.Nil(t, err)
+
+	// claiming an absent worker fails
+	ok, err := cli.ClaimWorker(ctx, "j111", "w1", "e0", time.Minute)
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	// only one of the contended claims succeeds
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		owners []string
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		owner := fmt.Sprintf("e%d", i)
+		go func() {
+			defer wg.Done()
+			ok, err := cli.ClaimWorker(ctx, "j111", "w0", owner, time.Minute)
+			require.Nil(t, err)
+			if ok {
+				mu.Lock()
+				owners = append(owners, owner)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Len(t, owners, 1)
+	owner := owners[0]
+	other := "e-other"
+	worker, err := cli.GetWorkerByID(ctx, "j111", "w0")
+	require.Nil(t, err)
+	require.Equal(t, owner, worker.Owner)
+	require.NotNil(t, worker.LeaseExpiry)
+
+	// the claim is kept by upserting and updating the worker
+	err = cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: "w0", Code: libModel.WorkerStatusInit})
+	require.Nil(t, err)
+	err = cli.UpdateWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: "w0", Code: libModel.WorkerStatusNormal})
+	require.Nil(t, err)
+	worker, err = cli.GetWorkerByID(ctx, "j111", "w0")
+	require.Nil(t, err)
+	require.Equal(t, owner, worker.Owner)
+
+	// claiming again by the owner extends the lease
+	ok, err = cli.ClaimWorker(ctx, "j111", "w0", owner, time.Minute)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	// renewal
+	ok, err = cli.RenewWorkerClaim(ctx, "j111", "w0", other, time.Minute)
+	require.Nil(t, err)
+	require.False(t, ok)
+	ok, err = cli.RenewWorkerClaim(ctx, "j111", "w0", owner, 50*time.Millisecond)
+	require.Nil(t, err)
+	require.True(t, ok)
+	ok, err = cli.ClaimWorker(ctx, "j111", "w0", other, time.Minute)
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	// takeover after the lease expires
+	time.Sleep(100 * time.Millisecond)
+	ok, err = cli.ClaimWorker(ctx, "j111", "w0", other, time.Minute)
+	require.Nil(t, err)
+	require.True(t, ok)
+	ok, err = cli.RenewWorkerClaim(ctx, "j111", "w0", owner, time.Minute)
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	// release
+	ok, err = cli.ReleaseWorkerClaim(ctx, "j111", "w0", owner)
+	require.Nil(t, err)
+	require.False(t, ok)
+	ok, err = cli.ReleaseWorkerClaim(ctx, "j111", "w0", other)
+	require.Nil(t, err)
+	require.True(t, ok)
+	worker, err = cli.GetWorkerByID(ctx, "j111", "w0")
+	require.Nil(t, err)
+	require.Equal(t, "", worker.Owner)
+	require.Nil(t, worker.LeaseExpiry)
+	ok, err = cli.ClaimWorker(ctx, "j111", "w0", owner, time.Minute)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	// invalid params
+	_, err = cli.ClaimWorker(ctx, "j111", "w0", "", time.Minute)
+	require.True(t, cerrors.ErrMetaParamsInvalid.Equal(err))
+	_, err = cli.ClaimWorker(ctx, "j111", "w0", owner, 0)
+	require.True(t, cerrors.ErrMetaParamsInvalid.Equal(err))
+	_, err = cli.RenewWorkerClaim(ctx, "j111", "w0", owner, -time.Second)
+	require.True(t, cerrors.ErrMetaParamsInvalid.Equal(err))
+	_, err = cli.ReleaseWorkerClaim(ctx, "j111", "w0", "")
+	require.True(t, cerrors.ErrMetaParamsInvalid.Equal(err))
+}
+
+func TestUpdateWorkerSameIDMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	for _, jobID := range []string{"j111", "j222"} {
+		err := cli.UpsertWorker(ctx, &libModel.WorkerStatus{
+			ProjectID:    "p111",
+			JobID:        jobID,
+			ID:           "w111",
+			Code:         libModel.WorkerStatusInit,
+			ErrorMessage: "init",
+		})
+		require.Nil(t, err)
 	}
+
+	// only the worker of job j111 is updated
+	err = cli.UpdateWorker(ctx, &libModel.WorkerStatus{
+		ProjectID:    "p111",
+		JobID:        "j111",
+		ID:           "w111",
+		Code:         libModel.WorkerStatusError,
+		ErrorMessage: "error",
+	})
+	require.Nil(t, err)
+
+	worker, err := cli.GetWorkerByID(ctx, "j111", "w111")
+	require.Nil(t, err)
+	require.Equal(t, libModel.WorkerStatusError, worker.Code)
+	require.Equal(t, "error", worker.ErrorMessage)
+
+	worker, err = cli.GetWorkerByID(ctx, "j222", "w111")
+	require.Nil(t, err)
+	require.Equal(t, libModel.WorkerStatusInit, worker.Code)
+	require.Equal(t, "init", worker.ErrorMessage)
+}
+
+func TestWorkerProgressMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	err = cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: "w111"})
+	require.Nil(t, err)
+	worker, err := cli.GetWorkerByID(ctx, "j111", "w111")
+	require.Nil(t, err)
+	require.Nil(t, worker.Progress)
+
+	progress := &libModel.WorkerProgress{Completed: 1, Total: 4, Phase: "dump"}
+	err = cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: "w111", Progress: progress})
+	require.Nil(t, err)
+	worker, err = cli.GetWorkerByID(ctx, "j111", "w111")
+	require.Nil(t, err)
+	require.Equal(t, progress, worker.Progress)
+
+	progress = &libModel.WorkerProgress{Completed: 4, Total: 4, Phase: "dump"}
+	err = cli.UpdateWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: "w111", Progress: progress})
+	require.Nil(t, err)
+	workers, err := cli.QueryWorkersByMasterID(ctx, "j111")
+	require.Nil(t, err)
+	require.Len(t, workers, 1)
+	require.Equal(t, progress, workers[0].Progress)
+
+	// the progress is cleared by updating the worker without it
+	err = cli.UpdateWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: "w111"})
+	require.Nil(t, err)
+	worker, err = cli.GetWorkerByID(ctx, "j111", "w111")
+	require.Nil(t, err)
+	require.Nil(t, worker.Progress)
 }
 
 func TestResourceMock(t *testing.T) {
@@ -763,6 +1905,397 @@ func TestResourceMock(t *testing.T) {
 	}
 }
 
+func TestQueryResourcesByJobIDsMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	for i, jobID := range []string{"j111", "j111", "j222", "j333", "j333"} {
+		err := cli.UpsertResource(ctx, &resourcemeta.ResourceMeta{
+			ProjectID: "p111",
+			ID:        fmt.Sprintf("r%d", i),
+			Job:       jobID,
+			Worker:    "w111",
+			Executor:  "e111",
+		})
+		require.Nil(t, err)
+	}
+
+	resourceIDs := func(resources []*resourcemeta.ResourceMeta) []string {
+		ids := make([]string, 0, len(resources))
+		for _, resource := range resources {
+			ids = append(ids, resource.ID)
+		}
+		return ids
+	}
+	resources, err := cli.QueryResourcesByJobIDs(ctx, []string{"j111", "j333", "j444"})
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"r0", "r1", "r3", "r4"}, resourceIDs(resources))
+
+	// the job ids are queried in chunks
+	resources, err = cli.(*metaOpsClient).queryResourcesByJobIDs(ctx, []string{"j111", "j333", "j444"}, 2)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"r0", "r1", "r3", "r4"}, resourceIDs(resources))
+
+	resources, err = cli.QueryResourcesByJobIDs(ctx, nil)
+	require.Nil(t, err)
+	require.Len(t, resources, 0)
+}
+
+func TestQueryResourcesByDeletedMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	resources, err := cli.QueryResourcesByDeleted(ctx, true)
+	require.Nil(t, err)
+	require.Empty(t, resources)
+
+	deleted := map[string]bool{
+		"r0": false,
+		"r1": true,
+		"r2": false,
+		"r3": true,
+		"r4": true,
+	}
+	for id, isDeleted := range deleted {
+		err := cli.UpsertResource(ctx, &resourcemeta.ResourceMeta{
+			ID:       id,
+			Job:      "j111",
+			Worker:   "w222",
+			Executor: "e444",
+			Deleted:  isDeleted,
+		})
+		require.Nil(t, err)
+	}
+
+	getIDs := func(resources []*resourcemeta.ResourceMeta) []string {
+		var ids []string
+		for _, resource := range resources {
+			ids = append(ids, resource.ID)
+		}
+		return ids
+	}
+	resources, err = cli.QueryResourcesByDeleted(ctx, true)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"r1", "r3", "r4"}, getIDs(resources))
+	resources, err = cli.QueryResourcesByDeleted(ctx, false)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"r0", "r2"}, getIDs(resources))
+
+	// a resource marked as deleted moves to the deleted set
+	err = cli.UpdateResource(ctx, &resourcemeta.ResourceMeta{
+		ID:       "r0",
+		Job:      "j111",
+		Worker:   "w222",
+		Executor: "e444",
+		Deleted:  true,
+	})
+	require.Nil(t, err)
+	resources, err = cli.QueryResourcesByDeleted(ctx, true)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"r0", "r1", "r3", "r4"}, getIDs(resources))
+	resources, err = cli.QueryResourcesByDeleted(ctx, false)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"r2"}, getIDs(resources))
+}
+
+func TestResourceContentTypeMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	contentTypes := map[string]string{
+		"r0": "csv",
+		"r1": "parquet",
+		"r2": "csv",
+		"r3": "",
+	}
+	for id, contentType := range contentTypes {
+		err := cli.UpsertResource(ctx, &resourcemeta.ResourceMeta{
+			ID:          id,
+			Job:         "j111",
+			Worker:      "w222",
+			Executor:    "e444",
+			ContentType: contentType,
+		})
+		require.Nil(t, err)
+	}
+	for id, contentType := range contentTypes {
+		resource, err := cli.GetResourceByID(ctx, id)
+		require.Nil(t, err)
+		require.Equal(t, contentType, resource.ContentType)
+	}
+
+	getIDs := func(resources []*resourcemeta.ResourceMeta) []string {
+		var ids []string
+		for _, resource := range resources {
+			ids = append(ids, resource.ID)
+		}
+		return ids
+	}
+	resources, err := cli.QueryResourcesByContentType(ctx, "csv")
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"r0", "r2"}, getIDs(resources))
+	resources, err = cli.QueryResourcesByContentType(ctx, "binlog")
+	require.Nil(t, err)
+	require.Empty(t, resources)
+
+	// the content type is persisted by UpdateResource
+	err = cli.UpdateResource(ctx, &resourcemeta.ResourceMeta{
+		ID:          "r3",
+		Job:         "j111",
+		Worker:      "w222",
+		Executor:    "e444",
+		ContentType: "parquet",
+	})
+	require.Nil(t, err)
+	resources, err = cli.QueryResourcesByContentType(ctx, "parquet")
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"r1", "r3"}, getIDs(resources))
+
+	// the column is added back by Initialize to the table of an older schema
+	migrator := cli.(*metaOpsClient).db.Migrator()
+	require.Nil(t, migrator.DropIndex(&resourcemeta.ResourceMeta{}, "idx_rct"))
+	require.Nil(t, migrator.DropColumn(&resourcemeta.ResourceMeta{}, "content_type"))
+	require.False(t, migrator.HasColumn(&resourcemeta.ResourceMeta{}, "content_type"))
+	require.Nil(t, cli.Initialize(ctx))
+	require.True(t, migrator.HasColumn(&resourcemeta.ResourceMeta{}, "content_type"))
+	resource, err := cli.GetResourceByID(ctx, "r1")
+	require.Nil(t, err)
+	require.Equal(t, "", resource.ContentType)
+}
+
+func TestQueryInBatchesMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	const (
+		rowCount  = 25
+		batchSize = 10
+	)
+	for i := 0; i < rowCount; i++ {
+		err := cli.UpsertJob(ctx, &libModel.MasterMetaKVData{ID: fmt.Sprintf("j%d", i)})
+		require.Nil(t, err)
+		err = cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: fmt.Sprintf("w%d", i)})
+		require.Nil(t, err)
+		err = cli.UpsertResource(ctx, &resourcemeta.ResourceMeta{ID: fmt.Sprintf("r%d", i), Job: "j111"})
+		require.Nil(t, err)
+	}
+	// workers of other masters are not visited
+	err = cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j112", ID: "w0"})
+	require.Nil(t, err)
+
+	expectedSizes := []int{batchSize, batchSize, rowCount - 2*batchSize}
+
+	var sizes []int
+	jobIDs := make(map[string]struct{})
+	err = cli.QueryJobsInBatches(ctx, batchSize, func(jobs []*libModel.MasterMetaKVData) error {
+		sizes = append(sizes, len(jobs))
+		for _, job := range jobs {
+			require.NotContains(t, jobIDs, job.ID)
+			jobIDs[job.ID] = struct{}{}
+		}
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, expectedSizes, sizes)
+	require.Len(t, jobIDs, rowCount)
+
+	sizes = nil
+	workerIDs := make(map[string]struct{})
+	err = cli.QueryWorkersInBatches(ctx, "j111", batchSize, func(workers []*libModel.WorkerStatus) error {
+		sizes = append(sizes, len(workers))
+		for _, worker := range workers {
+			require.Equal(t, "j111", worker.JobID)
+			require.NotContains(t, workerIDs, worker.ID)
+			workerIDs[worker.ID] = struct{}{}
+		}
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, expectedSizes, sizes)
+	require.Len(t, workerIDs, rowCount)
+
+	sizes = nil
+	resourceIDs := make(map[string]struct{})
+	err = cli.QueryResourcesInBatches(ctx, batchSize, func(resources []*resourcemeta.ResourceMeta) error {
+		sizes = append(sizes, len(resources))
+		for _, resource := range resources {
+			require.NotContains(t, resourceIDs, resource.ID)
+			resourceIDs[resource.ID] = struct{}{}
+		}
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, expectedSizes, sizes)
+	require.Len(t, resourceIDs, rowCount)
+
+	// the error of callback stops the iteration
+	callbackErr := errors.New("callback error")
+	calls := 0
+	err = cli.QueryJobsInBatches(ctx, batchSize, func(jobs []*libModel.MasterMetaKVData) error {
+		calls++
+		return callbackErr
+	})
+	require.Equal(t, callbackErr, err)
+	require.Equal(t, 1, calls)
+
+	err = cli.QueryJobsInBatches(ctx, 0, func(jobs []*libModel.MasterMetaKVData) error {
+		return nil
+	})
+	require.True(t, cerrors.ErrMetaParamsInvalid.Equal(err))
+}
+
+func TestQueryWithPaginationMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	const (
+		rowCount = 25
+		pageSize = 10
+	)
+	for i := 0; i < rowCount; i++ {
+		err := cli.UpsertJob(ctx, &libModel.MasterMetaKVData{ID: fmt.Sprintf("j%d", i)})
+		require.Nil(t, err)
+		err = cli.CreateProject(ctx, &model.ProjectInfo{ID: fmt.Sprintf("p%d", i)})
+		require.Nil(t, err)
+	}
+
+	var jobIDs []string
+	for offset := 0; offset < rowCount; offset += pageSize {
+		jobs, total, err := cli.QueryJobsWithPagination(ctx, offset, pageSize)
+		require.Nil(t, err)
+		require.Equal(t, int64(rowCount), total)
+		require.LessOrEqual(t, len(jobs), pageSize)
+		for _, job := range jobs {
+			jobIDs = append(jobIDs, job.ID)
+		}
+	}
+	require.Len(t, jobIDs, rowCount)
+	for i, id := range jobIDs {
+		require.Equal(t, fmt.Sprintf("j%d", i), id)
+	}
+
+	var projectIDs []string
+	for offset := 0; offset < rowCount; offset += pageSize {
+		projects, total, err := cli.QueryProjectsWithPagination(ctx, offset, pageSize)
+		require.Nil(t, err)
+		require.Equal(t, int64(rowCount), total)
+		for _, project := range projects {
+			projectIDs = append(projectIDs, project.ID)
+		}
+	}
+	require.Len(t, projectIDs, rowCount)
+	for i, id := range projectIDs {
+		require.Equal(t, fmt.Sprintf("p%d", i), id)
+	}
+
+	// out of range
+	jobs, total, err := cli.QueryJobsWithPagination(ctx, rowCount, pageSize)
+	require.Nil(t, err)
+	require.Empty(t, jobs)
+	require.Equal(t, int64(rowCount), total)
+
+	// no limit
+	jobs, total, err = cli.QueryJobsWithPagination(ctx, 2*pageSize, 0)
+	require.Nil(t, err)
+	require.Len(t, jobs, rowCount-2*pageSize)
+	require.Equal(t, int64(rowCount), total)
+
+	_, _, err = cli.QueryJobsWithPagination(ctx, -1, pageSize)
+	require.Error(t, err)
+	require.True(t, cerrors.ErrMetaParamsInvalid.Equal(err))
+}
+
+func TestQueryOrphanedResourcesMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	for _, jobID := range []string{"j111", "j112"} {
+		err := cli.UpsertJob(ctx, &libModel.MasterMetaKVData{
+			ID:         jobID,
+			StatusCode: libModel.MasterStatusInit,
+		})
+		require.Nil(t, err)
+	}
+	_, err = cli.DeleteJob(ctx, "j112")
+	require.Nil(t, err)
+
+	resources := []struct {
+		id    string
+		jobID string
+	}{
+		{"r111", "j111"}, // resource of a live job
+		{"r112", "j112"}, // resource of a deleted job
+		{"r113", "j113"}, // resource of a job that never exists
+	}
+	for _, res := range resources {
+		err := cli.CreateResource(ctx, &resourcemeta.ResourceMeta{
+			ID:       res.id,
+			Job:      res.jobID,
+			Worker:   "w222",
+			Executor: "e444",
+		})
+		require.Nil(t, err)
+	}
+
+	orphaned, err := cli.QueryOrphanedResources(ctx)
+	require.Nil(t, err)
+	require.Len(t, orphaned, 2)
+	ids := []string{orphaned[0].ID, orphaned[1].ID}
+	require.ElementsMatch(t, []string{"r112", "r113"}, ids)
+	require.Equal(t, "w222", orphaned[0].Worker)
+}
+
+func TestDeleteRowsAffectedMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	require.Nil(t, cli.CreateProject(ctx, &model.ProjectInfo{ID: "p111", Name: "tenant1"}))
+	require.Nil(t, cli.UpsertJob(ctx, &libModel.MasterMetaKVData{ProjectID: "p111", ID: "j111"}))
+	require.Nil(t, cli.UpsertWorker(ctx, &libModel.WorkerStatus{ProjectID: "p111", JobID: "j111", ID: "w111"}))
+	require.Nil(t, cli.UpsertResource(ctx, &resourcemeta.ResourceMeta{
+		ProjectID: "p111", ID: "r111", Job: "j111", Worker: "w111", Executor: "e111",
+	}))
+
+	deletes := []struct {
+		name string
+		fn   func() (Result, error)
+	}{
+		{"project", func() (Result, error) { return cli.DeleteProject(ctx, "p111") }},
+		{"job", func() (Result, error) { return cli.DeleteJob(ctx, "j111") }},
+		{"worker", func() (Result, error) { return cli.DeleteWorker(ctx, "j111", "w111") }},
+		{"resource", func() (Result, error) { return cli.DeleteResource(ctx, "r111") }},
+	}
+	for _, d := range deletes {
+		// the first delete removes the existing row, the second one is a no-op
+		for _, expected := range []int64{1, 0} {
+			res, err := d.fn()
+			require.Nil(t, err, d.name)
+			require.Equal(t, expected, res.RowsAffected(), d.name)
+		}
+	}
+}
+
 func testInnerMock(t *testing.T, cli Client, c mCase) {
 	var args []reflect.Value
 	args = append(args, reflect.ValueOf(context.Background()))
@@ -800,3 +2333,250 @@ func testInnerMock(t *testing.T, cli Client, c mCase) {
 		}
 	}
 }
+
+func TestJobLabelsMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	for _, jobID := range []string{"j111", "j112", "j113", "j114"} {
+		err := cli.UpsertJob(ctx, &libModel.MasterMetaKVData{
+			ID:         jobID,
+			StatusCode: libModel.MasterStatusInit,
+		})
+		require.Nil(t, err)
+	}
+
+	require.Nil(t, cli.SetJobLabels(ctx, "j111", map[string]string{"team": "dm", "env": "prod"}))
+	require.Nil(t, cli.SetJobLabels(ctx, "j112", map[string]string{"team": "cdc", "env": "prod"}))
+	require.Nil(t, cli.SetJobLabels(ctx, "j113", map[string]string{"team": "dm", "env": "test"}))
+	require.Nil(t, cli.SetJobLabels(ctx, "j114", map[string]string{"team": "dm"}))
+
+	labels, err := cli.GetJobLabels(ctx, "j111")
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"team": "dm", "env": "prod"}, labels)
+
+	queryJobIDs := func(key, value string) []string {
+		jobs, err := cli.QueryJobsByLabel(ctx, key, value)
+		require.Nil(t, err)
+		ids := make([]string, 0, len(jobs))
+		for _, job := range jobs {
+			ids = append(ids, job.ID)
+		}
+		return ids
+	}
+	require.ElementsMatch(t, []string{"j111", "j113", "j114"}, queryJobIDs("team", "dm"))
+	require.ElementsMatch(t, []string{"j111", "j112"}, queryJobIDs("env", "prod"))
+	require.Empty(t, queryJobIDs("env", "staging"))
+
+	// set labels replaces all the old labels
+	require.Nil(t, cli.SetJobLabels(ctx, "j114", map[string]string{"team": "cdc"}))
+	require.ElementsMatch(t, []string{"j111", "j113"}, queryJobIDs("team", "dm"))
+	require.ElementsMatch(t, []string{"j112", "j114"}, queryJobIDs("team", "cdc"))
+
+	// labels of deleted jobs are ignored
+	_, err = cli.DeleteJob(ctx, "j113")
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"j111"}, queryJobIDs("team", "dm"))
+
+	require.Nil(t, cli.SetJobLabels(ctx, "j111", nil))
+	labels, err = cli.GetJobLabels(ctx, "j111")
+	require.Nil(t, err)
+	require.Empty(t, labels)
+	require.Empty(t, queryJobIDs("team", "dm"))
+}
+
+func TestExistsMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	require.Nil(t, cli.UpsertJob(ctx, &libModel.MasterMetaKVData{ID: "j111"}))
+	require.Nil(t, cli.UpsertJob(ctx, &libModel.MasterMetaKVData{ID: "j112"}))
+	_, err = cli.DeleteJob(ctx, "j112")
+	require.Nil(t, err)
+	require.Nil(t, cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: "w222"}))
+	require.Nil(t, cli.CreateResource(ctx, &resourcemeta.ResourceMeta{ID: "r333", Job: "j111"}))
+
+	ok, err := cli.JobExists(ctx, "j111")
+	require.Nil(t, err)
+	require.True(t, ok)
+	// absent and deleted jobs don't exist, and no error is returned
+	ok, err = cli.JobExists(ctx, "j112")
+	require.Nil(t, err)
+	require.False(t, ok)
+	ok, err = cli.JobExists(ctx, "j113")
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	ok, err = cli.WorkerExists(ctx, "j111", "w222")
+	require.Nil(t, err)
+	require.True(t, ok)
+	ok, err = cli.WorkerExists(ctx, "j112", "w222")
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	ok, err = cli.ResourceExists(ctx, "r333")
+	require.Nil(t, err)
+	require.True(t, ok)
+	ok, err = cli.ResourceExists(ctx, "r334")
+	require.Nil(t, err)
+	require.False(t, ok)
+}
+
+func TestWatchWorkersMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	require.Nil(t, cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: "w111"}))
+	require.Nil(t, cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j112", ID: "w112"}))
+
+	workers, updates, err := cli.WatchWorkers(ctx, "j111")
+	require.Nil(t, err)
+	require.Len(t, workers, 1)
+	require.Equal(t, "w111", workers[0].ID)
+
+	// workers of other masters are not delivered
+	require.Nil(t, cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j112", ID: "w113"}))
+	require.Nil(t, cli.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: "w114"}))
+	require.Nil(t, cli.UpdateWorker(ctx, &libModel.WorkerStatus{
+		JobID: "j111",
+		ID:    "w111",
+		Code:  libModel.WorkerStatusFinished,
+	}))
+
+	update := <-updates
+	require.Equal(t, "w114", update.ID)
+	update = <-updates
+	require.Equal(t, "w111", update.ID)
+	require.Equal(t, libModel.WorkerStatusFinished, update.Code)
+
+	cancel()
+	_, ok := <-updates
+	require.False(t, ok)
+}
+
+func TestTransactionMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	_, updates, err := cli.WatchWorkers(ctx, "j111")
+	require.Nil(t, err)
+
+	// the job and the worker are rolled back together
+	txnErr := errors.New("txn error")
+	err = cli.Transaction(ctx, func(tx Client) error {
+		if err := tx.UpsertJob(ctx, &libModel.MasterMetaKVData{ID: "j111"}); err != nil {
+			return err
+		}
+		if err := tx.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: "w111"}); err != nil {
+			return err
+		}
+		// the uncommitted rows are visible in the transaction
+		exists, err := tx.WorkerExists(ctx, "j111", "w111")
+		require.Nil(t, err)
+		require.True(t, exists)
+		return txnErr
+	})
+	require.ErrorIs(t, err, txnErr)
+
+	_, err = cli.GetJobByID(ctx, "j111")
+	require.True(t, IsNotFoundError(err))
+	exists, err := cli.WorkerExists(ctx, "j111", "w111")
+	require.Nil(t, err)
+	require.False(t, exists)
+	// the watchers are not notified of the rolled back worker
+	select {
+	case update := <-updates:
+		require.FailNow(t, "unexpected worker update", "%v", update)
+	default:
+	}
+
+	err = cli.Transaction(ctx, func(tx Client) error {
+		if err := tx.UpsertJob(ctx, &libModel.MasterMetaKVData{ID: "j111"}); err != nil {
+			return err
+		}
+		return tx.UpsertWorker(ctx, &libModel.WorkerStatus{JobID: "j111", ID: "w111"})
+	})
+	require.Nil(t, err)
+
+	job, err := cli.GetJobByID(ctx, "j111")
+	require.Nil(t, err)
+	require.Equal(t, "j111", job.ID)
+	exists, err = cli.WorkerExists(ctx, "j111", "w111")
+	require.Nil(t, err)
+	require.True(t, exists)
+	update := <-updates
+	require.Equal(t, "w111", update.ID)
+}
+
+func TestQueryProjectsWithStatsMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	for _, projectID := range []string{"p111", "p112", "p113"} {
+		require.Nil(t, cli.CreateProject(ctx, &model.ProjectInfo{ID: projectID, Name: "name-" + projectID}))
+	}
+	jobs := []struct {
+		projectID string
+		jobID     string
+	}{
+		{"p111", "j111"},
+		{"p111", "j112"},
+		{"p111", "j113"},
+		{"p112", "j114"},
+	}
+	for _, job := range jobs {
+		require.Nil(t, cli.UpsertJob(ctx, &libModel.MasterMetaKVData{ProjectID: job.projectID, ID: job.jobID}))
+	}
+	// deleted jobs are not counted
+	_, err = cli.DeleteJob(ctx, "j113")
+	require.Nil(t, err)
+
+	tm := time.Now().Truncate(time.Second)
+	ops := []*model.ProjectOperation{
+		{ProjectID: "p111", Operation: "Submit", JobID: "j111", CreatedAt: tm.Add(-time.Hour)},
+		{ProjectID: "p111", Operation: "Submit", JobID: "j112", CreatedAt: tm},
+		{ProjectID: "p112", Operation: "Submit", JobID: "j114", CreatedAt: tm.Add(-time.Minute)},
+	}
+	for _, op := range ops {
+		require.Nil(t, cli.CreateProjectOperation(ctx, op))
+	}
+
+	stats, err := cli.QueryProjectsWithStats(ctx)
+	require.Nil(t, err)
+	require.Len(t, stats, 3)
+	statsMap := make(map[string]*model.ProjectStats, len(stats))
+	for _, st := range stats {
+		statsMap[st.ID] = st
+	}
+
+	require.Equal(t, "name-p111", statsMap["p111"].Name)
+	require.Equal(t, int64(2), statsMap["p111"].JobCount)
+	require.NotNil(t, statsMap["p111"].LastOpTime)
+	require.True(t, tm.Equal(*statsMap["p111"].LastOpTime))
+
+	require.Equal(t, int64(1), statsMap["p112"].JobCount)
+	require.NotNil(t, statsMap["p112"].LastOpTime)
+	require.True(t, tm.Add(-time.Minute).Equal(*statsMap["p112"].LastOpTime))
+
+	// project without jobs and operations
+	require.Equal(t, "name-p113", statsMap["p113"].Name)
+	require.Equal(t, int64(0), statsMap["p113"].JobCount)
+	require.Nil(t, statsMap["p113"].LastOpTime)
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// LogicEpochCounter is the name of the counter used to generate epoch
+	LogicEpochCounter = "logic_epoch"
+
+	defaultMinEpoch = 1
+	// legacyEpochTable is the table of the epoch before it is moved to the
+	// counters
+	legacyEpochTable = "logic_epoches"
+)
+
+// Counter is a named counter which increases monotonically
+type Counter struct {
+	Model
+	Name  string `gorm:"column:name;type:varchar(128) not null;uniqueIndex:uidx_name"`
+	Value int64  `gorm:"column:value;type:bigint not null default 0"`
+}
+
+// InitializeCounter inserts the record of counter `name` with the initial
+// value into the backend table `counters`, it does nothing if the record exists.
+func InitializeCounter(ctx context.Context, db *gorm.DB, name string, value int64) error {
+	// Do nothing on conflict
+	// INSERT INTO `counters` (`created_at`,`updated_at`,`name`,`value`) VALUES
+	// ('2022-05-04 14:02:08.624','2022-05-04 14:02:08.624','logic_epoch',1) ON DUPLICATE KEY UPDATE `seq_id`=`seq_id`
+	return db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&Counter{
+		Name:  name,
+		Value: value,
+	}).Error
+}
+
+// GenNamedCounter will increase the counter `name` by 1 and return the new
+// value. The record of the counter is created on first use, so the first
+// value of a new counter is 1. The record is locked until the transaction
+// ends, so concurrent callers never get the same value. The transaction is
+// rolled back if ctx is done before it commits.
+func GenNamedCounter(ctx context.Context, db *gorm.DB, name string) (int64, error) {
+	var value int64
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		//(1)create the counter if not exists
+		if err := InitializeCounter(ctx, tx, name, 0); err != nil {
+			// return any error will rollback
+			return err
+		}
+
+		//(2)select value and lock the record
+		var counter Counter
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("name = ?", name).First(&counter).Error; err != nil {
+			return err
+		}
+
+		//(3)update value = value + 1
+		if err := tx.Model(&Counter{}).Where("name = ?", name).
+			Update("value", gorm.Expr("value + ?", 1)).Error; err != nil {
+			return err
+		}
+		value = counter.Value + 1
+
+		// return nil will commit the whole transaction
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return value, nil
+}
+
+// GetNamedCounter returns the current value of the counter `name` without
+// increasing it
+func GetNamedCounter(ctx context.Context, db *gorm.DB, name string) (int64, error) {
+	var counter Counter
+	if err := db.WithContext(ctx).Where("name = ?", name).First(&counter).Error; err != nil {
+		return 0, err
+	}
+
+	return counter.Value, nil
+}
+
+// InitializeEpoch insert the epoch counter into the backend table `counters`.
+// The epoch was kept in the table `logic_epoches` before, if the table exists
+// the counter is seeded with the epoch in it, so that the epoch never goes
+// back after an upgrade.
+func InitializeEpoch(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seed := int64(defaultMinEpoch)
+		if tx.Migrator().HasTable(legacyEpochTable) {
+			var epoch sql.NullInt64
+			if err := tx.Table(legacyEpochTable).Select("MAX(epoch)").Row().Scan(&epoch); err != nil {
+				return err
+			}
+			if epoch.Valid && epoch.Int64 > seed {
+				seed = epoch.Int64
+			}
+		}
+
+		if err := InitializeCounter(ctx, tx, LogicEpochCounter, seed); err != nil {
+			return err
+		}
+		if seed == defaultMinEpoch {
+			return nil
+		}
+		// the counter may have been created with a lower value before the
+		// legacy epoch is migrated
+		return tx.Model(&Counter{}).Where("name = ? AND value < ?", LogicEpochCounter, seed).
+			Update("value", seed).Error
+	})
+}
+
+// GenEpoch will increasing the backend epoch by 1 and return the new epoch
+func GenEpoch(ctx context.Context, db *gorm.DB) (int64, error) {
+	return GenNamedCounter(ctx, db, LogicEpochCounter)
+}
+
+// GetEpoch returns the current backend epoch without increasing it
+func GetEpoch(ctx context.Context, db *gorm.DB) (int64, error) {
+	return GetNamedCounter(ctx, db, LogicEpochCounter)
+}
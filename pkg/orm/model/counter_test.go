@@ -0,0 +1,134 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gsql "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func mockGetDBConn(t *testing.T, dsnStr string) (*gorm.DB, sqlmock.Sqlmock, error) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	// common execution for orm
+	mock.ExpectQuery("SELECT VERSION()").WillReturnRows(sqlmock.NewRows(
+		[]string{"VERSION()"}).AddRow("5.7.35-log"))
+
+	gdb, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: false,
+	}), &gorm.Config{
+		SkipDefaultTransaction: true,
+		// TODO: logger
+	})
+	require.NoError(t, err)
+
+	return gdb, mock, nil
+}
+
+// expectHasLegacyEpochTable expects the queries of HasTable("logic_epoches")
+func expectHasLegacyEpochTable(mock sqlmock.Sqlmock, exists bool) {
+	count := 0
+	if exists {
+		count = 1
+	}
+	mock.ExpectQuery("SELECT DATABASE[(][)]").WillReturnRows(sqlmock.NewRows([]string{"DATABASE()"}).AddRow("test"))
+	mock.ExpectQuery("SELECT SCHEMA_NAME from Information_schema.SCHEMATA").
+		WillReturnRows(sqlmock.NewRows([]string{"SCHEMA_NAME"}).AddRow("test"))
+	mock.ExpectQuery("SELECT count[(][*][)] FROM information_schema.tables").
+		WithArgs("test", legacyEpochTable, "BASE TABLE").
+		WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(count))
+}
+
+func TestInitializeEpoch(t *testing.T) {
+	gdb, mock, err := mockGetDBConn(t, "test")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	expectHasLegacyEpochTable(mock, false)
+	mock.ExpectExec("INSERT INTO `counters` [(]`created_at`,`updated_at`,`name`,`value`[)]").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), LogicEpochCounter, defaultMinEpoch).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	err = InitializeEpoch(ctx, gdb)
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	expectHasLegacyEpochTable(mock, false)
+	mock.ExpectExec("INSERT INTO `counters` [(]`created_at`,`updated_at`,`name`,`value`[)]").
+		WillReturnError(&gsql.MySQLError{Number: 1062, Message: "test error"})
+	mock.ExpectRollback()
+	err = InitializeEpoch(ctx, gdb)
+	require.Error(t, err)
+
+	// the counter is seeded with the epoch in the legacy table, and raised to
+	// it if the counter exists with a lower value
+	mock.ExpectBegin()
+	expectHasLegacyEpochTable(mock, true)
+	mock.ExpectQuery("SELECT MAX[(]epoch[)] FROM `logic_epoches`").
+		WillReturnRows(sqlmock.NewRows([]string{"MAX(epoch)"}).AddRow(42))
+	mock.ExpectExec("INSERT INTO `counters` [(]`created_at`,`updated_at`,`name`,`value`[)]").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), LogicEpochCounter, 42).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE `counters` SET `value`=[?],`updated_at`=[?] WHERE name = [?] AND value < [?]").
+		WithArgs(42, sqlmock.AnyArg(), LogicEpochCounter, 42).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	err = InitializeEpoch(ctx, gdb)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// INSERT INTO `counters` (`created_at`,`updated_at`,`name`,`value`) VALUES (?,?,?,?) ON DUPLICATE KEY UPDATE `seq_id`=`seq_id`
+// SELECT * FROM `counters` WHERE name = ? ORDER BY `counters`.`seq_id` LIMIT 1 FOR UPDATE
+// UPDATE `counters` SET `value`=value + ?,`updated_at`=? WHERE name = ?
+func TestGenNamedCounter(t *testing.T) {
+	gdb, mock, err := mockGetDBConn(t, "test")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+
+	tm := time.Now()
+	createdAt := tm.Add(time.Duration(1))
+	updatedAt := tm.Add(time.Duration(1))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `counters`").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "seq", 0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT [*] FROM `counters` WHERE name = [?] .* FOR UPDATE").WithArgs("seq").WillReturnRows(
+		sqlmock.NewRows([]string{"seq_id", "created_at", "updated_at", "name", "value"}).AddRow(2, createdAt, updatedAt, "seq", 0))
+	mock.ExpectExec("UPDATE `counters` SET").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	value, err := GenNamedCounter(ctx, gdb, "seq")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), value)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `counters`").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), LogicEpochCounter, 0).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT [*] FROM `counters` WHERE name = [?] .* FOR UPDATE").WithArgs(LogicEpochCounter).WillReturnRows(
+		sqlmock.NewRows([]string{"seq_id", "created_at", "updated_at", "name", "value"}).AddRow(1, createdAt, updatedAt, LogicEpochCounter, 10))
+	mock.ExpectExec("UPDATE `counters` SET").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	epoch, err := GenEpoch(ctx, gdb)
+	require.NoError(t, err)
+	require.Equal(t, int64(11), epoch)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `counters`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT [*] FROM `counters` WHERE name = [?] .* FOR UPDATE").WithArgs(LogicEpochCounter).WillReturnRows(
+		sqlmock.NewRows([]string{"seq_id", "created_at", "updated_at", "name", "value"}).AddRow(1, createdAt, updatedAt, LogicEpochCounter, 11))
+	mock.ExpectExec("UPDATE `counters` SET").WillReturnError(errors.New("gen epoch error"))
+	mock.ExpectRollback()
+	_, err = GenEpoch(ctx, gdb)
+	require.Error(t, err)
+}
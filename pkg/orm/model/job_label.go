@@ -0,0 +1,10 @@
+package model
+
+// JobLabel is a key-value label attached to a job, which is used to organize
+// and filter jobs. A job has at most one value for each label key.
+type JobLabel struct {
+	Model
+	JobID string `gorm:"column:job_id;type:varchar(64) not null;uniqueIndex:uidx_jlk,priority:1"`
+	Key   string `gorm:"column:label_key;type:varchar(64) not null;uniqueIndex:uidx_jlk,priority:2;index:idx_lkv,priority:1"`
+	Value string `gorm:"column:label_value;type:varchar(128) not null;index:idx_lkv,priority:2"`
+}
@@ -9,6 +9,10 @@ type ProjectInfo struct {
 	Name string `gorm:"type:varchar(64) not null"`
 }
 
+// ProjectOperationMove is the operation recorded in the project which a job is
+// moved to
+const ProjectOperationMove = "Move"
+
 // ProjectOperation records each operation of a project
 type ProjectOperation struct {
 	SeqID     uint      `gorm:"primaryKey;auto_increment"`
@@ -17,3 +21,13 @@ type ProjectOperation struct {
 	JobID     string    `gorm:"type:varchar(64) not null"`
 	CreatedAt time.Time `gorm:"autoCreateTime;index:idx_op"`
 }
+
+// ProjectStats is a project annotated with the statistics of its jobs and operations
+type ProjectStats struct {
+	ProjectInfo
+	// JobCount is the number of jobs in the project
+	JobCount int64
+	// LastOpTime is the time of the most recent operation, nil if the
+	// project has no operation
+	LastOpTime *time.Time
+}
@@ -0,0 +1,16 @@
+package model
+
+// SchemaVersion records the version of the metastore schema, the table has
+// only one record whose SeqID is SchemaVersionSeqID.
+type SchemaVersion struct {
+	Model
+	// Version is the schema version of the latest binary initializing the
+	// metastore
+	Version int64 `gorm:"column:version;type:bigint not null"`
+	// MinCompatibleVersion is the min schema version of the binaries which
+	// can operate on the metastore
+	MinCompatibleVersion int64 `gorm:"column:min_compatible_version;type:bigint not null"`
+}
+
+// SchemaVersionSeqID is the SeqID of the only record of SchemaVersion
+const SchemaVersionSeqID = 1
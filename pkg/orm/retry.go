@@ -0,0 +1,72 @@
+package orm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	dmysql "github.com/go-sql-driver/mysql"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// MySQL error codes of the transient failures, the statement may succeed
+// if it is executed again
+const (
+	mysqlErrLockWaitTimeout = 1205
+	mysqlErrLockDeadlock    = 1213
+)
+
+// retryPolicy retries the operations failed with transient errors, with an
+// exponential backoff between the retries. Only the operations that can be
+// executed more than once safely, such as reads and upserts, should be retried.
+type retryPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+}
+
+func newRetryPolicy(conf DBConfig) retryPolicy {
+	return retryPolicy{
+		maxRetries: conf.MaxRetries,
+		baseDelay:  conf.RetryBaseDelay,
+	}
+}
+
+// retry runs op, and runs it again if it fails with a transient error until
+// the retries are used up or ctx is done. The last error of op is returned.
+func (c *metaOpsClient) retry(ctx context.Context, op func() error) error {
+	delay := c.retryPolicy.baseDelay
+	for i := 0; ; i++ {
+		err := op()
+		if err == nil || i >= c.retryPolicy.maxRetries || !isTransientError(err) {
+			return err
+		}
+
+		transientRetryCounter.Inc()
+		log.L().Warn("metastore operation failed with transient error, retry later",
+			zap.Int("retry", i+1), zap.Duration("delay", delay), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// isTransientError returns whether err is a transient failure of the backend
+func isTransientError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	var mysqlErr *dmysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlErrLockDeadlock, mysqlErrLockWaitTimeout:
+			return true
+		}
+	}
+	return false
+}
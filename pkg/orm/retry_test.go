@@ -0,0 +1,98 @@
+package orm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hanfei1991/microcosm/pkg/orm/model"
+)
+
+func newRetryTestClient(t *testing.T, maxRetries int) (*metaOpsClient, sqlmock.Sqlmock) {
+	sqlDB, mock, err := mockGetDBConn(t, "test")
+	require.Nil(t, err)
+	t.Cleanup(func() {
+		mock.ExpectClose()
+		sqlDB.Close()
+	})
+
+	conf := NewDefaultDBConfig()
+	conf.MaxRetries = maxRetries
+	conf.RetryBaseDelay = time.Millisecond
+	cli, err := newClient(sqlDB, conf)
+	require.Nil(t, err)
+	return cli, mock
+}
+
+func TestRetryTransientError(t *testing.T) {
+	t.Parallel()
+
+	cli, mock := newRetryTestClient(t, 3)
+	expectedSQL := "SELECT * FROM `project_infos` WHERE id = ? ORDER BY `project_infos`.`seq_id` LIMIT 1"
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("p111").
+		WillReturnError(&mysql.MySQLError{Number: mysqlErrLockDeadlock, Message: "deadlock"})
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("p111").
+		WillReturnError(&mysql.MySQLError{Number: mysqlErrLockWaitTimeout, Message: "lock wait timeout"})
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("p111").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("p111", "tenant1"))
+
+	project, err := cli.GetProjectByID(context.TODO(), "p111")
+	require.Nil(t, err)
+	require.Equal(t, "tenant1", project.Name)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestRetryExhausted(t *testing.T) {
+	t.Parallel()
+
+	cli, mock := newRetryTestClient(t, 2)
+	for i := 0; i < 3; i++ {
+		mock.ExpectQuery("SELECT [*] FROM `project_infos`").
+			WillReturnError(&mysql.MySQLError{Number: mysqlErrLockDeadlock, Message: "deadlock"})
+	}
+
+	_, err := cli.QueryProjects(context.TODO())
+	require.Error(t, err)
+	require.Regexp(t, ".*ErrMetaOpFail.*", err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestNoRetry(t *testing.T) {
+	t.Parallel()
+
+	cli, mock := newRetryTestClient(t, 3)
+
+	// non-idempotent create is not retried
+	mock.ExpectExec("INSERT INTO `project_infos`").
+		WillReturnError(&mysql.MySQLError{Number: mysqlErrLockDeadlock, Message: "deadlock"})
+	err := cli.CreateProject(context.TODO(), &model.ProjectInfo{ID: "p111", Name: "tenant1"})
+	require.Error(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+
+	// non-transient error is not retried
+	mock.ExpectQuery("SELECT [*] FROM `project_infos`").
+		WillReturnError(&mysql.MySQLError{Number: 1146, Message: "table doesn't exist"})
+	_, err = cli.QueryProjects(context.TODO())
+	require.Error(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestIsTransientError(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, isTransientError(&mysql.MySQLError{Number: mysqlErrLockDeadlock}))
+	require.True(t, isTransientError(&mysql.MySQLError{Number: mysqlErrLockWaitTimeout}))
+	require.True(t, isTransientError(driver.ErrBadConn))
+	require.True(t, isTransientError(fmt.Errorf("query: %w", driver.ErrBadConn)))
+	require.False(t, isTransientError(&mysql.MySQLError{Number: 1062}))
+	require.False(t, isTransientError(errors.New("unknown error")))
+	require.False(t, isTransientError(context.Canceled))
+}
@@ -0,0 +1,104 @@
+package orm
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	cerrors "github.com/hanfei1991/microcosm/pkg/errors"
+	"github.com/hanfei1991/microcosm/pkg/orm/model"
+)
+
+const (
+	// schemaVersion is the version of the metastore schema used by this
+	// binary, it must be increased when the schema is changed
+	// version 2: add the content_type column to resource_meta
+	// version 3: add the owner and lease_expiry columns to worker_statuses
+	// version 4: add the progress column to worker_statuses
+	// version 5: add the explicit indexes of master_meta_kv_data and resource_meta
+	// version 6: add the index of updated_at to master_meta_kv_data
+	schemaVersion int64 = 6
+	// minCompatibleSchemaVersion is the min schema version of the binaries
+	// which can operate on the schema of this binary. It must be increased
+	// to schemaVersion if the change of the schema breaks the older binaries.
+	minCompatibleSchemaVersion int64 = 1
+)
+
+// checkSchemaCompatible checks whether the binary with schema version
+// expected can operate on the metastore with the stored schema version.
+// Between two schema versions, the older one is compatible with the newer
+// one if it is not less than the min compatible version of the newer one, so
+//   - a newer metastore is compatible if expected is in its forward-compatible
+//     range, which is recorded in stored.MinCompatibleVersion
+//   - an older metastore is compatible if stored.Version is not less than
+//     minCompatible of this binary
+func checkSchemaCompatible(expected, minCompatible int64, stored *model.SchemaVersion) error {
+	switch {
+	case stored.Version > expected && expected < stored.MinCompatibleVersion,
+		stored.Version < expected && stored.Version < minCompatible:
+		return cerrors.ErrMetaSchemaIncompatible.GenWithStackByArgs(stored.Version, expected)
+	}
+	return nil
+}
+
+// getSchemaVersion returns the stored schema version, or nil if the version
+// has never been stamped
+func (c *metaOpsClient) getSchemaVersion(ctx context.Context) (*model.SchemaVersion, error) {
+	db := c.db.WithContext(ctx)
+	if !db.Migrator().HasTable(&model.SchemaVersion{}) {
+		return nil, nil
+	}
+
+	var version model.SchemaVersion
+	if err := c.retry(ctx, func() error {
+		return db.Where("seq_id = ?", model.SchemaVersionSeqID).Take(&version).Error
+	}); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+
+		return nil, newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return &version, nil
+}
+
+// checkSchemaVersion returns ErrMetaSchemaIncompatible if this binary can't
+// operate on the stored schema, an unstamped metastore is always compatible
+func (c *metaOpsClient) checkSchemaVersion(ctx context.Context) error {
+	stored, err := c.getSchemaVersion(ctx)
+	if err != nil || stored == nil {
+		return err
+	}
+
+	return checkSchemaCompatible(schemaVersion, minCompatibleSchemaVersion, stored)
+}
+
+// stampSchemaVersion records the schema version of this binary, a newer
+// stored version is kept as it is.
+func (c *metaOpsClient) stampSchemaVersion(ctx context.Context) error {
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var stored model.SchemaVersion
+		err := tx.Where("seq_id = ?", model.SchemaVersionSeqID).Take(&stored).Error
+		if err == gorm.ErrRecordNotFound {
+			return tx.Create(&model.SchemaVersion{
+				Model:                model.Model{SeqID: model.SchemaVersionSeqID},
+				Version:              schemaVersion,
+				MinCompatibleVersion: minCompatibleSchemaVersion,
+			}).Error
+		}
+		if err != nil || stored.Version >= schemaVersion {
+			return err
+		}
+
+		return tx.Model(&stored).Updates(map[string]interface{}{
+			"version":                schemaVersion,
+			"min_compatible_version": minCompatibleSchemaVersion,
+		}).Error
+	})
+	if err != nil {
+		return newMetaError(cerrors.ErrMetaOpFail, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	cerrors "github.com/hanfei1991/microcosm/pkg/errors"
+	"github.com/hanfei1991/microcosm/pkg/orm/model"
+)
+
+func TestCheckSchemaCompatible(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		expected      int64
+		minCompatible int64
+		stored        model.SchemaVersion
+		compatible    bool
+	}{
+		// matching
+		{expected: 3, minCompatible: 2, stored: model.SchemaVersion{Version: 3, MinCompatibleVersion: 2}, compatible: true},
+		{expected: 3, minCompatible: 3, stored: model.SchemaVersion{Version: 3, MinCompatibleVersion: 3}, compatible: true},
+		// newer metastore
+		{expected: 3, minCompatible: 1, stored: model.SchemaVersion{Version: 5, MinCompatibleVersion: 3}, compatible: true},
+		{expected: 3, minCompatible: 1, stored: model.SchemaVersion{Version: 5, MinCompatibleVersion: 2}, compatible: true},
+		{expected: 3, minCompatible: 1, stored: model.SchemaVersion{Version: 5, MinCompatibleVersion: 4}, compatible: false},
+		// older metastore
+		{expected: 5, minCompatible: 3, stored: model.SchemaVersion{Version: 3, MinCompatibleVersion: 1}, compatible: true},
+		{expected: 5, minCompatible: 3, stored: model.SchemaVersion{Version: 4, MinCompatibleVersion: 4}, compatible: true},
+		{expected: 5, minCompatible: 3, stored: model.SchemaVersion{Version: 2, MinCompatibleVersion: 1}, compatible: false},
+	}
+
+	for _, tc := range testCases {
+		err := checkSchemaCompatible(tc.expected, tc.minCompatible, &tc.stored)
+		if tc.compatible {
+			require.NoError(t, err, "case %+v", tc)
+		} else {
+			require.True(t, cerrors.ErrMetaSchemaIncompatible.Equal(err), "case %+v", tc)
+		}
+	}
+}
+
+func TestSchemaVersionMock(t *testing.T) {
+	cli, err := NewMockClient()
+	require.Nil(t, err)
+	require.NotNil(t, cli)
+	defer cli.Close()
+
+	ctx := context.TODO()
+	metaCli := cli.(*metaOpsClient)
+	// stamped by Initialize
+	stored, err := metaCli.getSchemaVersion(ctx)
+	require.Nil(t, err)
+	require.NotNil(t, stored)
+	require.Equal(t, schemaVersion, stored.Version)
+	require.Equal(t, minCompatibleSchemaVersion, stored.MinCompatibleVersion)
+	require.Nil(t, metaCli.checkSchemaVersion(ctx))
+
+	setStored := func(version, minCompatible int64) {
+		err := metaCli.db.Model(&model.SchemaVersion{}).Where("seq_id = ?", model.SchemaVersionSeqID).
+			Updates(map[string]interface{}{
+				"version":                version,
+				"min_compatible_version": minCompatible,
+			}).Error
+		require.Nil(t, err)
+	}
+
+	// a newer compatible metastore is not downgraded by Initialize
+	setStored(schemaVersion+1, minCompatibleSchemaVersion)
+	require.Nil(t, metaCli.checkSchemaVersion(ctx))
+	require.Nil(t, cli.Initialize(ctx))
+	stored, err = metaCli.getSchemaVersion(ctx)
+	require.Nil(t, err)
+	require.Equal(t, schemaVersion+1, stored.Version)
+
+	// a newer incompatible metastore is refused
+	setStored(schemaVersion+1, schemaVersion+1)
+	err = metaCli.checkSchemaVersion(ctx)
+	require.True(t, cerrors.ErrMetaSchemaIncompatible.Equal(err))
+	err = cli.Initialize(ctx)
+	require.True(t, cerrors.ErrMetaSchemaIncompatible.Equal(err))
+
+	// an older incompatible metastore is refused
+	setStored(minCompatibleSchemaVersion-1, minCompatibleSchemaVersion-1)
+	err = metaCli.checkSchemaVersion(ctx)
+	require.True(t, cerrors.ErrMetaSchemaIncompatible.Equal(err))
+
+	// an unstamped metastore is compatible, and stamped by Initialize
+	require.Nil(t, metaCli.db.Where("seq_id = ?", model.SchemaVersionSeqID).
+		Delete(&model.SchemaVersion{}).Error)
+	stored, err = metaCli.getSchemaVersion(ctx)
+	require.Nil(t, err)
+	require.Nil(t, stored)
+	require.Nil(t, metaCli.checkSchemaVersion(ctx))
+	require.Nil(t, cli.Initialize(ctx))
+	stored, err = metaCli.getSchemaVersion(ctx)
+	require.Nil(t, err)
+	require.Equal(t, schemaVersion, stored.Version)
+}
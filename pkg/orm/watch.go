@@ -0,0 +1,100 @@
+package orm
+
+import (
+	"context"
+	"sync"
+
+	libModel "github.com/hanfei1991/microcosm/lib/model"
+)
+
+const defaultWorkerWatchBufferSize = 128
+
+type workerWatcher struct {
+	ch chan *libModel.WorkerStatus
+}
+
+// workerWatchers dispatches the worker statuses written through the client
+// to the watchers of the corresponding master. A watcher that can't keep up
+// with the updates is removed and its channel is closed, so that it won't
+// block the writers and won't miss updates silently.
+type workerWatchers struct {
+	mu       sync.Mutex
+	watchers map[string]map[*workerWatcher]struct{}
+}
+
+func newWorkerWatchers() *workerWatchers {
+	return &workerWatchers{
+		watchers: make(map[string]map[*workerWatcher]struct{}),
+	}
+}
+
+// add registers a watcher of masterID, the watcher is removed after ctx is done
+func (w *workerWatchers) add(ctx context.Context, masterID string) *workerWatcher {
+	watcher := &workerWatcher{
+		ch: make(chan *libModel.WorkerStatus, defaultWorkerWatchBufferSize),
+	}
+
+	w.mu.Lock()
+	if _, ok := w.watchers[masterID]; !ok {
+		w.watchers[masterID] = make(map[*workerWatcher]struct{})
+	}
+	w.watchers[masterID][watcher] = struct{}{}
+	w.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		w.remove(masterID, watcher)
+	}()
+	return watcher
+}
+
+func (w *workerWatchers) remove(masterID string, watcher *workerWatcher) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.removeNoLock(masterID, watcher)
+}
+
+func (w *workerWatchers) removeNoLock(masterID string, watcher *workerWatcher) {
+	watchers, ok := w.watchers[masterID]
+	if !ok {
+		return
+	}
+	if _, ok := watchers[watcher]; !ok {
+		return
+	}
+	delete(watchers, watcher)
+	if len(watchers) == 0 {
+		delete(w.watchers, masterID)
+	}
+	close(watcher.ch)
+}
+
+// notify sends a copy of the worker status to the watchers of its master
+func (w *workerWatchers) notify(worker *libModel.WorkerStatus) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for watcher := range w.watchers[worker.JobID] {
+		status := *worker
+		if worker.ExtBytes != nil {
+			status.ExtBytes = append([]byte(nil), worker.ExtBytes...)
+		}
+		select {
+		case watcher.ch <- &status:
+		default:
+			w.removeNoLock(worker.JobID, watcher)
+		}
+	}
+}
+
+// close removes all watchers
+func (w *workerWatchers) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for masterID, watchers := range w.watchers {
+		for watcher := range watchers {
+			w.removeNoLock(masterID, watcher)
+		}
+	}
+}
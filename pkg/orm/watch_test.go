@@ -0,0 +1,51 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	libModel "github.com/hanfei1991/microcosm/lib/model"
+)
+
+func TestWorkerWatchers(t *testing.T) {
+	t.Parallel()
+
+	w := newWorkerWatchers()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fast := w.add(ctx, "j111")
+	slow := w.add(ctx, "j111")
+	other := w.add(ctx, "j112")
+
+	// the notified status doesn't share memory with the written one
+	worker := &libModel.WorkerStatus{JobID: "j111", ID: "w222", ExtBytes: []byte{0x11}}
+	w.notify(worker)
+	worker.ExtBytes[0] = 0x22
+	status := <-fast.ch
+	require.Equal(t, "w222", status.ID)
+	require.Equal(t, []byte{0x11}, status.ExtBytes)
+
+	// the slow watcher is removed after its buffer is full
+	for i := 0; i < defaultWorkerWatchBufferSize; i++ {
+		w.notify(&libModel.WorkerStatus{JobID: "j111", ID: "w222"})
+		<-fast.ch
+	}
+	for range slow.ch {
+	}
+	require.Len(t, w.watchers["j111"], 1)
+	require.Len(t, other.ch, 0)
+
+	// watchers are removed after the context is done
+	cancel()
+	_, ok := <-fast.ch
+	require.False(t, ok)
+	_, ok = <-other.ch
+	require.False(t, ok)
+	w.mu.Lock()
+	require.Len(t, w.watchers, 0)
+	w.mu.Unlock()
+	w.close()
+}
@@ -74,21 +74,21 @@ func (f *wrappingFactory) NewHistogramVec(opts prometheus.HistogramOpts, labelNa
 }
 
 func wrapCounterOpts(prefix string, constLabels prometheus.Labels, opts *prometheus.CounterOpts) *prometheus.CounterOpts {
-	wrapOptsCommon(prefix, constLabels, &opts.Namespace, opts.ConstLabels)
+	wrapOptsCommon(prefix, constLabels, &opts.Namespace, &opts.ConstLabels)
 	return opts
 }
 
 func wrapGaugeOpts(prefix string, constLabels prometheus.Labels, opts *prometheus.GaugeOpts) *prometheus.GaugeOpts {
-	wrapOptsCommon(prefix, constLabels, &opts.Namespace, opts.ConstLabels)
+	wrapOptsCommon(prefix, constLabels, &opts.Namespace, &opts.ConstLabels)
 	return opts
 }
 
 func wrapHistogramOpts(prefix string, constLabels prometheus.Labels, opts *prometheus.HistogramOpts) *prometheus.HistogramOpts {
-	wrapOptsCommon(prefix, constLabels, &opts.Namespace, opts.ConstLabels)
+	wrapOptsCommon(prefix, constLabels, &opts.Namespace, &opts.ConstLabels)
 	return opts
 }
 
-func wrapOptsCommon(prefix string, constLabels prometheus.Labels, namespace *string, cls prometheus.Labels) {
+func wrapOptsCommon(prefix string, constLabels prometheus.Labels, namespace *string, cls *prometheus.Labels) {
 	// namespace SHOULD NOT be nil
 	if prefix != "" {
 		if *namespace != "" {
@@ -97,10 +97,13 @@ func wrapOptsCommon(prefix string, constLabels prometheus.Labels, namespace *str
 			*namespace = prefix
 		}
 	}
+	if *cls == nil && len(constLabels) > 0 {
+		*cls = make(prometheus.Labels, len(constLabels))
+	}
 	for name, value := range constLabels {
-		if _, exists := cls[name]; exists {
+		if _, exists := (*cls)[name]; exists {
 			log.L().Panic("duplicate label name", zap.String("label", name))
 		}
-		cls[name] = value
+		(*cls)[name] = value
 	}
 }
@@ -53,6 +53,13 @@ func NewFactory4Worker(reg *Registry, info tenant.ProjectInfo, jobType libModel.
 	return NewFactory4WorkerImpl(globalMetricRegistry, info, jobType, jobID, workerID)
 }
 
+// UnregisterWorkerMetrics unregisters all metrics produced by the Factory of
+// the worker(jobmaster/worker), it should be called when the worker exits so
+// that the metrics don't leak across its restarts.
+func UnregisterWorkerMetrics(workerID libModel.WorkerID) {
+	globalMetricRegistry.Unregister(workerID)
+}
+
 // NewFactory4Framework return a Factory for dataflow framework
 // NOTICE: we use auto service label tagged by cloud service to distinguish
 // different dataflow engine or different executor
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/pingcap/errors"
+	"go.uber.org/atomic"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -13,6 +14,8 @@ type ConcurrencyQuota interface {
 	Consume(ctx context.Context) error
 	TryConsume() bool
 	Release()
+	// Used returns the quota which is consumed and not released yet
+	Used() int64
 }
 
 // NewConcurrencyQuota creates a new concurrencyQuotaImpl instance that
@@ -22,17 +25,31 @@ func NewConcurrencyQuota(total int64) ConcurrencyQuota {
 }
 
 type concurrencyQuotaImpl struct {
-	sem *semaphore.Weighted
+	sem  *semaphore.Weighted
+	used atomic.Int64
 }
 
 func (c *concurrencyQuotaImpl) Consume(ctx context.Context) error {
-	return errors.Trace(c.sem.Acquire(ctx, 1))
+	if err := c.sem.Acquire(ctx, 1); err != nil {
+		return errors.Trace(err)
+	}
+	c.used.Inc()
+	return nil
 }
 
 func (c *concurrencyQuotaImpl) TryConsume() bool {
-	return c.sem.TryAcquire(1)
+	if !c.sem.TryAcquire(1) {
+		return false
+	}
+	c.used.Inc()
+	return true
 }
 
 func (c *concurrencyQuotaImpl) Release() {
+	c.used.Dec()
 	c.sem.Release(1)
 }
+
+func (c *concurrencyQuotaImpl) Used() int64 {
+	return c.used.Load()
+}
@@ -18,9 +18,12 @@ func TestConcurrencyQuota(t *testing.T) {
 	require.True(t, quota.TryConsume())
 	require.True(t, quota.TryConsume())
 	require.False(t, quota.TryConsume())
+	require.Equal(t, int64(5), quota.Used())
 	quota.Release()
+	require.Equal(t, int64(4), quota.Used())
 	require.True(t, quota.TryConsume())
 	require.False(t, quota.TryConsume())
+	require.Equal(t, int64(5), quota.Used())
 }
 
 func TestConcurrencyQuotaBlocking(t *testing.T) {
@@ -35,4 +38,5 @@ func TestConcurrencyQuotaBlocking(t *testing.T) {
 	err = quota.Consume(timeoutCtx)
 	require.Error(t, err)
 	require.Regexp(t, ".*context deadline exceeded.*", err.Error())
+	require.Equal(t, int64(1), quota.Used())
 }
@@ -1,28 +1,133 @@
 package servermaster
 
 import (
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/hanfei1991/microcosm/lib"
 	libModel "github.com/hanfei1991/microcosm/lib/model"
 	"github.com/hanfei1991/microcosm/pb"
+	"github.com/hanfei1991/microcosm/pkg/clock"
 	"github.com/hanfei1991/microcosm/pkg/errors"
 
 	"github.com/pingcap/tiflow/dm/pkg/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 )
 
+const (
+	// dispatchBackoffBase is the backoff of a pending job after its first
+	// failed dispatch, it is doubled for each of the following failures.
+	dispatchBackoffBase = time.Second
+	// dispatchBackoffMax is the max backoff of a pending job
+	dispatchBackoffMax = time.Minute
+	// maxDispatchAttempts is the max count of failed dispatches of a job
+	// before it is moved to failed jobs.
+	maxDispatchAttempts = 8
+	// terminatedJobRetention is the time the finished, stopped or failed
+	// jobs are retained in JobFsm before they are pruned.
+	terminatedJobRetention = time.Hour
+	// newJobReconcileDelay is the time after which a job created in
+	// metastore is reconciled, a newer job may be still being submitted.
+	newJobReconcileDelay = time.Minute
+)
+
 type jobHolder struct {
 	lib.WorkerHandle
 	*libModel.MasterMetaKVData
 	// True means the job is loaded from metastore during jobmanager failover.
 	// Otherwise it is added by SubmitJob.
 	addFromFailover bool
+	// dispatchAttempts is the count of failed dispatches before the job is
+	// online, it is carried back to the pending job if the dispatch fails.
+	dispatchAttempts int
+	// waitAckTime is the time when the job is dispatched and starts waiting
+	// for the ack of the job master.
+	waitAckTime time.Time
+}
+
+// pendingJob is a job waiting to be dispatched
+type pendingJob struct {
+	*libModel.MasterMetaKVData
+	// dispatchAttempts is the count of failed dispatches of the job
+	dispatchAttempts int
+	// nextDispatchTime is the earliest time to dispatch the job again
+	nextDispatchTime time.Time
+}
+
+// failedJob is a job that fails to be dispatched for maxDispatchAttempts
+// times, it is not dispatched again by this server master.
+type failedJob struct {
+	*libModel.MasterMetaKVData
+	reason   error
+	failedAt time.Time
+}
+
+// terminatedJob is a job that has finished or stopped, it is retained to
+// report the status of terminated jobs until it is pruned.
+type terminatedJob struct {
+	*libModel.MasterMetaKVData
+	status       libModel.MasterStatusCode
+	terminatedAt time.Time
+}
+
+// queryStatus returns the status of the terminated job in QueryJobResponse
+func (job *terminatedJob) queryStatus() pb.QueryJobResponse_JobStatus {
+	if job.status == libModel.MasterStatusFinished {
+		return pb.QueryJobResponse_finished
+	}
+	return pb.QueryJobResponse_stopped
+}
+
+// state returns the state of the terminated job in JobFsm
+func (job *terminatedJob) state() JobState {
+	if job.status == libModel.MasterStatusFinished {
+		return JobStateFinished
+	}
+	return JobStateStopped
+}
+
+// JobState is the state of a job in JobFsm
+type JobState int32
+
+// All JobState
+const (
+	JobStateNotFound JobState = iota
+	JobStatePending
+	JobStateWaitAck
+	JobStateOnline
+	JobStateFinished
+	JobStateStopped
+	// JobStateFailed is the state of a job that fails to be dispatched for
+	// maxDispatchAttempts times
+	JobStateFailed
+)
+
+// JobStateNameMapping maps from job state to human-readable string
+var JobStateNameMapping = map[JobState]string{
+	JobStateNotFound: "not-found",
+	JobStatePending:  "pending",
+	JobStateWaitAck:  "wait-ack",
+	JobStateOnline:   "online",
+	JobStateFinished: "finished",
+	JobStateStopped:  "stopped",
+	JobStateFailed:   "failed",
+}
+
+// String implements fmt.Stringer
+func (s JobState) String() string {
+	val, ok := JobStateNameMapping[s]
+	if !ok {
+		return "unknown"
+	}
+	return val
 }
 
 // JobFsm manages state of all job masters, job master state forms a finite-state
-// machine. Note job master managed in JobFsm is in running status, which means
-// the job is not terminated or finished.
+// machine. Note the job masters in the states below are in running status,
+// the finished or stopped jobs are only retained in terminatedJobs.
 //
 // ,-------.                   ,-------.            ,-------.       ,--------.
 // |WaitAck|                   |Online |            |Pending|       |Finished|
@@ -67,9 +172,81 @@ type JobFsm struct {
 	JobStats
 
 	jobsMu      sync.RWMutex
-	pendingJobs map[libModel.MasterID]*libModel.MasterMetaKVData
+	pendingJobs map[libModel.MasterID]*pendingJob
 	waitAckJobs map[libModel.MasterID]*jobHolder
 	onlineJobs  map[libModel.MasterID]*jobHolder
+	failedJobs  map[libModel.MasterID]*failedJob
+	// terminatedJobs are the finished or stopped jobs, they are removed by
+	// PruneTerminatedJobs after being retained for a while.
+	terminatedJobs map[libModel.MasterID]*terminatedJob
+	// finishedCount and stoppedCount are the counts of the terminated jobs,
+	// including the ones pruned from terminatedJobs.
+	finishedCount int
+	stoppedCount  int
+	// reconciled is true after the first ReconcileJobs, the terminated jobs
+	// unknown to JobFsm are only loaded from metastore in the first one,
+	// because the ones unknown later are the pruned jobs.
+	reconciled bool
+
+	metrics *jobFsmMetrics
+	// onTransition is called after each state change of a job, it is nil if
+	// no hook is set.
+	onTransition TransitionHook
+
+	// dispatchPaused stops dispatching jobs in IterPendingJobs and
+	// IterWaitAckJobs, the jobs stay where they are until dispatch is resumed.
+	dispatchPaused atomic.Bool
+
+	clocker clock.Clock
+}
+
+// jobFsmMetrics are the gauges of the job counts in the states of JobFsm
+type jobFsmMetrics struct {
+	pending    prometheus.Gauge
+	dispatched prometheus.Gauge
+	online     prometheus.Gauge
+}
+
+func newJobFsmMetrics() *jobFsmMetrics {
+	return &jobFsmMetrics{
+		pending:    jobFsmJobNumGauge.WithLabelValues(pb.QueryJobResponse_pending.String()),
+		dispatched: jobFsmJobNumGauge.WithLabelValues(pb.QueryJobResponse_dispatched.String()),
+		online:     jobFsmJobNumGauge.WithLabelValues(pb.QueryJobResponse_online.String()),
+	}
+}
+
+// TransitionHook is called after a job in JobFsm moves from one state to
+// another.
+type TransitionHook func(jobID libModel.MasterID, from, to JobState)
+
+// JobFsmOption configures a JobFsm
+type JobFsmOption func(*JobFsm)
+
+// WithTransitionHook sets the hook called after each state change of a job.
+// The hook is called without the lock of JobFsm held, so it may call JobFsm,
+// but the hooks of concurrent changes may be called in any order.
+func WithTransitionHook(hook TransitionHook) JobFsmOption {
+	return func(fsm *JobFsm) {
+		fsm.onTransition = hook
+	}
+}
+
+// jobTransition is a state change of a job, it is collected under the lock
+// of JobFsm and passed to the transition hook after the lock is released.
+type jobTransition struct {
+	jobID    libModel.MasterID
+	from, to JobState
+}
+
+// notifyTransitions calls the transition hook with the collected
+// transitions, it must be called without fsm.jobsMu held.
+func (fsm *JobFsm) notifyTransitions(transitions *[]jobTransition) {
+	if fsm.onTransition == nil {
+		return
+	}
+	for _, t := range *transitions {
+		fsm.onTransition(t.jobID, t.from, t.to)
+	}
 }
 
 // JobStats defines a statistics interface for JobFsm
@@ -78,12 +255,21 @@ type JobStats interface {
 }
 
 // NewJobFsm creates a new job fsm
-func NewJobFsm() *JobFsm {
-	return &JobFsm{
-		pendingJobs: make(map[libModel.MasterID]*libModel.MasterMetaKVData),
+func NewJobFsm(opts ...JobFsmOption) *JobFsm {
+	fsm := &JobFsm{
+		pendingJobs: make(map[libModel.MasterID]*pendingJob),
 		waitAckJobs: make(map[libModel.MasterID]*jobHolder),
 		onlineJobs:  make(map[libModel.MasterID]*jobHolder),
+		failedJobs:  make(map[libModel.MasterID]*failedJob),
+
+		terminatedJobs: make(map[libModel.MasterID]*terminatedJob),
+		metrics:        newJobFsmMetrics(),
+		clocker:        clock.New(),
 	}
+	for _, opt := range opts {
+		opt(fsm)
+	}
+	return fsm
 }
 
 // QueryOnlineJob queries job from online job list
@@ -93,11 +279,12 @@ func (fsm *JobFsm) QueryOnlineJob(jobID libModel.MasterID) *jobHolder {
 	return fsm.onlineJobs[jobID]
 }
 
-// QueryJob queries job with given jobID and returns QueryJobResponse
+// QueryJob queries job with given jobID and returns QueryJobResponse.
+// It only reads the jobs, so concurrent queries don't block each other.
 func (fsm *JobFsm) QueryJob(jobID libModel.MasterID) *pb.QueryJobResponse {
 	checkPendingJob := func() *pb.QueryJobResponse {
-		fsm.jobsMu.Lock()
-		defer fsm.jobsMu.Unlock()
+		fsm.jobsMu.RLock()
+		defer fsm.jobsMu.RUnlock()
 
 		meta, ok := fsm.pendingJobs[jobID]
 		if !ok {
@@ -112,8 +299,8 @@ func (fsm *JobFsm) QueryJob(jobID libModel.MasterID) *pb.QueryJobResponse {
 	}
 
 	checkWaitAckJob := func() *pb.QueryJobResponse {
-		fsm.jobsMu.Lock()
-		defer fsm.jobsMu.Unlock()
+		fsm.jobsMu.RLock()
+		defer fsm.jobsMu.RUnlock()
 
 		job, ok := fsm.waitAckJobs[jobID]
 		if !ok {
@@ -129,8 +316,8 @@ func (fsm *JobFsm) QueryJob(jobID libModel.MasterID) *pb.QueryJobResponse {
 	}
 
 	checkOnlineJob := func() *pb.QueryJobResponse {
-		fsm.jobsMu.Lock()
-		defer fsm.jobsMu.Unlock()
+		fsm.jobsMu.RLock()
+		defer fsm.jobsMu.RUnlock()
 
 		job, ok := fsm.onlineJobs[jobID]
 		if !ok {
@@ -157,48 +344,230 @@ func (fsm *JobFsm) QueryJob(jobID libModel.MasterID) *pb.QueryJobResponse {
 		return resp
 	}
 
+	checkFailedJob := func() *pb.QueryJobResponse {
+		fsm.jobsMu.RLock()
+		defer fsm.jobsMu.RUnlock()
+
+		job, ok := fsm.failedJobs[jobID]
+		if !ok {
+			return nil
+		}
+		resp := &pb.QueryJobResponse{
+			Tp:     int64(job.Tp),
+			Config: job.Config,
+			Status: pb.QueryJobResponse_stopped,
+			Err: &pb.Error{
+				Code:    pb.ErrorCode_SubJobSubmitFailed,
+				Message: job.reason.Error(),
+			},
+		}
+		return resp
+	}
+
+	checkTerminatedJob := func() *pb.QueryJobResponse {
+		fsm.jobsMu.RLock()
+		defer fsm.jobsMu.RUnlock()
+
+		job, ok := fsm.terminatedJobs[jobID]
+		if !ok {
+			return nil
+		}
+		return &pb.QueryJobResponse{
+			Tp:     int64(job.Tp),
+			Config: job.Config,
+			Status: job.queryStatus(),
+		}
+	}
+
 	if resp := checkPendingJob(); resp != nil {
 		return resp
 	}
+	if resp := checkFailedJob(); resp != nil {
+		return resp
+	}
+	if resp := checkTerminatedJob(); resp != nil {
+		return resp
+	}
 	if resp := checkWaitAckJob(); resp != nil {
 		return resp
 	}
 	return checkOnlineJob()
 }
 
+// GetJobState returns the state of the job, it returns JobStateNotFound and
+// false if the job is not managed by JobFsm.
+func (fsm *JobFsm) GetJobState(jobID libModel.MasterID) (JobState, bool) {
+	fsm.jobsMu.RLock()
+	defer fsm.jobsMu.RUnlock()
+	state := fsm.jobStateNoLock(jobID)
+	return state, state != JobStateNotFound
+}
+
+// jobStateNoLock returns the state of the job.
+// Note fsm.jobsMu must be held when calling this function.
+func (fsm *JobFsm) jobStateNoLock(jobID libModel.MasterID) JobState {
+	if _, ok := fsm.pendingJobs[jobID]; ok {
+		return JobStatePending
+	}
+	if _, ok := fsm.waitAckJobs[jobID]; ok {
+		return JobStateWaitAck
+	}
+	if _, ok := fsm.onlineJobs[jobID]; ok {
+		return JobStateOnline
+	}
+	if _, ok := fsm.failedJobs[jobID]; ok {
+		return JobStateFailed
+	}
+	if job, ok := fsm.terminatedJobs[jobID]; ok {
+		return job.state()
+	}
+	return JobStateNotFound
+}
+
 // JobDispatched is called when a job is firstly created or server master is failovered
 func (fsm *JobFsm) JobDispatched(job *libModel.MasterMetaKVData, addFromFailover bool) {
+	var transitions []jobTransition
+	defer fsm.notifyTransitions(&transitions)
 	fsm.jobsMu.Lock()
 	defer fsm.jobsMu.Unlock()
+	defer fsm.updateMetricsNoLock()
+	from := fsm.jobStateNoLock(job.ID)
+	// the job may be added to pending jobs by ReconcileJobs before it is
+	// dispatched by SubmitJob, it must not be dispatched again
+	delete(fsm.pendingJobs, job.ID)
 	fsm.waitAckJobs[job.ID] = &jobHolder{
 		MasterMetaKVData: job,
 		addFromFailover:  addFromFailover,
+		waitAckTime:      fsm.clocker.Now(),
+	}
+	if from != JobStateWaitAck {
+		transitions = append(transitions, jobTransition{job.ID, from, JobStateWaitAck})
+	}
+}
+
+// updateMetricsNoLock sets the gauges of the job counts to the sizes of the
+// job maps, it is called after the maps are changed.
+// Note fsm.jobsMu must be held when calling this function.
+func (fsm *JobFsm) updateMetricsNoLock() {
+	fsm.metrics.pending.Set(float64(len(fsm.pendingJobs)))
+	fsm.metrics.dispatched.Set(float64(len(fsm.waitAckJobs)))
+	fsm.metrics.online.Set(float64(len(fsm.onlineJobs)))
+}
+
+// PauseDispatch stops dispatching jobs, jobs that are already running are
+// not affected. It is used during maintenance of the cluster.
+func (fsm *JobFsm) PauseDispatch() {
+	if !fsm.dispatchPaused.Swap(true) {
+		log.L().Info("job dispatch is paused")
+	}
+}
+
+// ResumeDispatch resumes dispatching jobs, jobs pending during the pause will
+// be dispatched in the following IterPendingJobs.
+func (fsm *JobFsm) ResumeDispatch() {
+	if fsm.dispatchPaused.Swap(false) {
+		log.L().Info("job dispatch is resumed")
 	}
 }
 
+// DispatchPaused returns whether job dispatch is paused
+func (fsm *JobFsm) DispatchPaused() bool {
+	return fsm.dispatchPaused.Load()
+}
+
 // IterPendingJobs iterates all pending jobs and dispatch(via create worker) them again.
+// It does nothing if job dispatch is paused.
+// A job that fails to be dispatched is retried with exponential backoff, and
+// is moved to failed jobs after maxDispatchAttempts failures. If the quota of
+// creating workers is exceeded, the remaining jobs are dispatched in the
+// following IterPendingJobs and ErrMasterConcurrencyExceeded is returned.
 func (fsm *JobFsm) IterPendingJobs(dispatchJobFn func(job *libModel.MasterMetaKVData) (string, error)) error {
+	if fsm.DispatchPaused() {
+		return nil
+	}
+
+	var transitions []jobTransition
+	defer fsm.notifyTransitions(&transitions)
 	fsm.jobsMu.Lock()
 	defer fsm.jobsMu.Unlock()
+	defer fsm.updateMetricsNoLock()
 
+	now := fsm.clocker.Now()
 	for oldJobID, job := range fsm.pendingJobs {
-		id, err := dispatchJobFn(job)
+		if now.Before(job.nextDispatchTime) {
+			continue
+		}
+		id, err := dispatchJobFn(job.MasterMetaKVData)
 		if err != nil {
-			return err
+			if errors.ErrMasterConcurrencyExceeded.Equal(err) {
+				return err
+			}
+			if fsm.jobDispatchFailed(oldJobID, job, err) {
+				transitions = append(transitions, jobTransition{oldJobID, JobStatePending, JobStateFailed})
+			}
+			continue
 		}
 		delete(fsm.pendingJobs, oldJobID)
 		job.ID = id
 		fsm.waitAckJobs[id] = &jobHolder{
-			MasterMetaKVData: job,
+			MasterMetaKVData: job.MasterMetaKVData,
+			dispatchAttempts: job.dispatchAttempts,
+			waitAckTime:      now,
 		}
-		log.L().Info("job master recovered", zap.Any("job", job))
+		transitions = append(transitions, jobTransition{id, JobStatePending, JobStateWaitAck})
+		log.L().Info("job master recovered", zap.Any("job", job.MasterMetaKVData))
 	}
 
 	return nil
 }
 
-// IterWaitAckJobs iterates wait ack jobs, failover them if they are added from failover
+// jobDispatchFailed records a failed dispatch of the pending job, the job is
+// moved to failed jobs if it runs out of dispatch attempts, and true is
+// returned in that case.
+// Note fsm.jobsMu must be held when calling this function.
+func (fsm *JobFsm) jobDispatchFailed(jobID libModel.MasterID, job *pendingJob, err error) bool {
+	job.dispatchAttempts++
+	if job.dispatchAttempts >= maxDispatchAttempts {
+		delete(fsm.pendingJobs, jobID)
+		reason := errors.ErrJobDispatchExhausted.GenWithStackByArgs(jobID, job.dispatchAttempts, err)
+		fsm.failedJobs[jobID] = &failedJob{
+			MasterMetaKVData: job.MasterMetaKVData,
+			reason:           reason,
+			failedAt:         fsm.clocker.Now(),
+		}
+		log.L().Error("job fails to be dispatched too many times, give up",
+			zap.String("id", jobID), zap.Int("attempts", job.dispatchAttempts), zap.Error(err))
+		return true
+	}
+
+	backoff := dispatchBackoff(job.dispatchAttempts)
+	job.nextDispatchTime = fsm.clocker.Now().Add(backoff)
+	log.L().Warn("dispatch job failed, retry later",
+		zap.String("id", jobID), zap.Int("attempts", job.dispatchAttempts),
+		zap.Duration("backoff", backoff), zap.Error(err))
+	return false
+}
+
+// dispatchBackoff returns the backoff of a pending job after its failed
+// dispatch attempts.
+func dispatchBackoff(attempts int) time.Duration {
+	backoff := dispatchBackoffBase
+	for i := 1; i < attempts && backoff < dispatchBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > dispatchBackoffMax {
+		backoff = dispatchBackoffMax
+	}
+	return backoff
+}
+
+// IterWaitAckJobs iterates wait ack jobs, failover them if they are added from failover.
+// It does nothing if job dispatch is paused.
 func (fsm *JobFsm) IterWaitAckJobs(dispatchJobFn func(job *libModel.MasterMetaKVData) (string, error)) error {
+	if fsm.DispatchPaused() {
+		return nil
+	}
+
 	fsm.jobsMu.Lock()
 	defer fsm.jobsMu.Unlock()
 
@@ -211,16 +580,57 @@ func (fsm *JobFsm) IterWaitAckJobs(dispatchJobFn func(job *libModel.MasterMetaKV
 			return err
 		}
 		fsm.waitAckJobs[id].addFromFailover = false
+		fsm.waitAckJobs[id].waitAckTime = fsm.clocker.Now()
 		log.L().Info("tombstone job master doesn't receive heartbeat in time, recreate it", zap.Any("job", job))
 	}
 
 	return nil
 }
 
+// RedispatchStaleWaitAckJobs moves the jobs that have waited for the ack of
+// the job master longer than threshold back to pending jobs, e.g. because the
+// online event of the job master is lost. It is counted as a failed dispatch
+// of the job, so a job that is stale too many times is moved to failed jobs.
+// The jobs added from failover are recreated by IterWaitAckJobs instead.
+// It returns the IDs of the stale jobs, sorted by ID.
+func (fsm *JobFsm) RedispatchStaleWaitAckJobs(threshold time.Duration) []libModel.MasterID {
+	var transitions []jobTransition
+	defer fsm.notifyTransitions(&transitions)
+	fsm.jobsMu.Lock()
+	defer fsm.jobsMu.Unlock()
+	defer fsm.updateMetricsNoLock()
+
+	now := fsm.clocker.Now()
+	var stale []libModel.MasterID
+	for id, job := range fsm.waitAckJobs {
+		if job.addFromFailover || now.Sub(job.waitAckTime) <= threshold {
+			continue
+		}
+		pending := &pendingJob{
+			MasterMetaKVData: job.MasterMetaKVData,
+			dispatchAttempts: job.dispatchAttempts,
+		}
+		fsm.pendingJobs[id] = pending
+		delete(fsm.waitAckJobs, id)
+		to := JobStatePending
+		reason := errors.ErrJobWaitAckTimeout.GenWithStackByArgs(id, threshold)
+		if fsm.jobDispatchFailed(id, pending, reason) {
+			to = JobStateFailed
+		}
+		transitions = append(transitions, jobTransition{id, JobStateWaitAck, to})
+		stale = append(stale, id)
+	}
+	sort.Strings(stale)
+	return stale
+}
+
 // JobOnline is called when the first heartbeat of job is received
 func (fsm *JobFsm) JobOnline(worker lib.WorkerHandle) error {
+	var transitions []jobTransition
+	defer fsm.notifyTransitions(&transitions)
 	fsm.jobsMu.Lock()
 	defer fsm.jobsMu.Unlock()
+	defer fsm.updateMetricsNoLock()
 
 	job, ok := fsm.waitAckJobs[worker.ID()]
 	if !ok {
@@ -231,44 +641,211 @@ func (fsm *JobFsm) JobOnline(worker lib.WorkerHandle) error {
 		MasterMetaKVData: job.MasterMetaKVData,
 	}
 	delete(fsm.waitAckJobs, worker.ID())
+	transitions = append(transitions, jobTransition{worker.ID(), JobStateWaitAck, JobStateOnline})
 	return nil
 }
 
 // JobOffline is called when a job meets error or finishes
 func (fsm *JobFsm) JobOffline(worker lib.WorkerHandle, needFailover bool) {
+	var transitions []jobTransition
+	defer fsm.notifyTransitions(&transitions)
 	fsm.jobsMu.Lock()
 	defer fsm.jobsMu.Unlock()
+	defer fsm.updateMetricsNoLock()
 
-	job, ok := fsm.onlineJobs[worker.ID()]
-	if ok {
-		delete(fsm.onlineJobs, worker.ID())
+	from := fsm.jobStateNoLock(worker.ID())
+	job, ok := fsm.removeRunningJob(worker.ID())
+	if !ok {
+		return
+	}
+	to := JobStateNotFound
+	if needFailover {
+		fsm.pendingJobs[worker.ID()] = &pendingJob{MasterMetaKVData: job.MasterMetaKVData}
+		to = JobStatePending
+	}
+	transitions = append(transitions, jobTransition{worker.ID(), from, to})
+}
+
+// JobTerminated is called when a job finishes or stops, the job is retained
+// in terminated jobs with the given status.
+func (fsm *JobFsm) JobTerminated(worker lib.WorkerHandle, status libModel.MasterStatusCode) {
+	var transitions []jobTransition
+	defer fsm.notifyTransitions(&transitions)
+	fsm.jobsMu.Lock()
+	defer fsm.jobsMu.Unlock()
+	defer fsm.updateMetricsNoLock()
+
+	from := fsm.jobStateNoLock(worker.ID())
+	job, ok := fsm.removeRunningJob(worker.ID())
+	if !ok {
+		return
+	}
+	terminated := fsm.addTerminatedJobNoLock(job.MasterMetaKVData, status)
+	transitions = append(transitions, jobTransition{worker.ID(), from, terminated.state()})
+}
+
+// addTerminatedJobNoLock adds the job to terminated jobs and counts it.
+// Note fsm.jobsMu must be held when calling this function.
+func (fsm *JobFsm) addTerminatedJobNoLock(
+	job *libModel.MasterMetaKVData, status libModel.MasterStatusCode,
+) *terminatedJob {
+	terminated := &terminatedJob{
+		MasterMetaKVData: job,
+		status:           status,
+		terminatedAt:     fsm.clocker.Now(),
+	}
+	fsm.terminatedJobs[job.ID] = terminated
+	if terminated.state() == JobStateFinished {
+		fsm.finishedCount++
 	} else {
-		job, ok = fsm.waitAckJobs[worker.ID()]
-		if !ok {
-			log.L().Warn("unknown worker, ignore it", zap.String("id", worker.ID()))
-			return
+		fsm.stoppedCount++
+	}
+	return terminated
+}
+
+// PruneTerminatedJobs removes the terminated jobs and failed jobs that have
+// been retained longer than retention, and returns the count of the pruned
+// jobs. The pruned terminated jobs are still counted in JobCount, and their
+// status can be queried from metastore. The pruned failed jobs are no longer
+// counted, they may be added to pending jobs by ReconcileJobs again if they
+// are still running in metastore.
+func (fsm *JobFsm) PruneTerminatedJobs(retention time.Duration) int {
+	var transitions []jobTransition
+	defer fsm.notifyTransitions(&transitions)
+	fsm.jobsMu.Lock()
+	defer fsm.jobsMu.Unlock()
+
+	now := fsm.clocker.Now()
+	pruned := 0
+	for id, job := range fsm.terminatedJobs {
+		if now.Sub(job.terminatedAt) > retention {
+			delete(fsm.terminatedJobs, id)
+			transitions = append(transitions, jobTransition{id, job.state(), JobStateNotFound})
+			pruned++
 		}
-		delete(fsm.waitAckJobs, worker.ID())
 	}
-	if needFailover {
-		fsm.pendingJobs[worker.ID()] = job.MasterMetaKVData
+	for id, job := range fsm.failedJobs {
+		if now.Sub(job.failedAt) > retention {
+			delete(fsm.failedJobs, id)
+			transitions = append(transitions, jobTransition{id, JobStateFailed, JobStateNotFound})
+			pruned++
+		}
 	}
+	return pruned
 }
 
-// JobDispatchFailed is called when a job dispatch fails
-func (fsm *JobFsm) JobDispatchFailed(worker lib.WorkerHandle) error {
+// removeRunningJob removes the job from online jobs or wait ack jobs, it
+// returns false if the job is in neither of them.
+// Note fsm.jobsMu must be held when calling this function.
+func (fsm *JobFsm) removeRunningJob(jobID libModel.MasterID) (*jobHolder, bool) {
+	job, ok := fsm.onlineJobs[jobID]
+	if ok {
+		delete(fsm.onlineJobs, jobID)
+		return job, true
+	}
+	job, ok = fsm.waitAckJobs[jobID]
+	if !ok {
+		log.L().Warn("unknown worker, ignore it", zap.String("id", jobID))
+		return nil, false
+	}
+	delete(fsm.waitAckJobs, jobID)
+	return job, true
+}
+
+// JobDispatchFailed is called when a job dispatch fails, the failure is
+// counted in the dispatch attempts of the job.
+func (fsm *JobFsm) JobDispatchFailed(worker lib.WorkerHandle, result error) error {
+	var transitions []jobTransition
+	defer fsm.notifyTransitions(&transitions)
 	fsm.jobsMu.Lock()
 	defer fsm.jobsMu.Unlock()
+	defer fsm.updateMetricsNoLock()
 
 	job, ok := fsm.waitAckJobs[worker.ID()]
 	if !ok {
 		return errors.ErrWorkerNotFound.GenWithStackByArgs(worker.ID())
 	}
-	fsm.pendingJobs[worker.ID()] = job.MasterMetaKVData
+	pending := &pendingJob{
+		MasterMetaKVData: job.MasterMetaKVData,
+		dispatchAttempts: job.dispatchAttempts,
+	}
+	fsm.pendingJobs[worker.ID()] = pending
 	delete(fsm.waitAckJobs, worker.ID())
+	to := JobStatePending
+	if fsm.jobDispatchFailed(worker.ID(), pending, result) {
+		to = JobStateFailed
+	}
+	transitions = append(transitions, jobTransition{worker.ID(), JobStateWaitAck, to})
 	return nil
 }
 
+// ReconcileJobs compares jobs managed by JobFsm with jobs persisted in
+// metastore, and corrects the drift between them.
+// - Jobs that are finished or stopped in metastore are moved to terminated
+//   jobs, the ones unknown to JobFsm are added to terminated jobs without
+//   being counted as corrected in the first reconciliation, and ignored
+//   later.
+// - Jobs that are active in metastore but not managed by JobFsm are added to
+//   pending jobs, and they will be dispatched in the following IterPendingJobs.
+//   The ones created within newJobReconcileDelay are skipped.
+// It returns the count of corrected jobs.
+func (fsm *JobFsm) ReconcileJobs(jobs []*libModel.MasterMetaKVData) int {
+	var transitions []jobTransition
+	defer fsm.notifyTransitions(&transitions)
+	fsm.jobsMu.Lock()
+	defer fsm.jobsMu.Unlock()
+	defer fsm.updateMetricsNoLock()
+
+	loadTerminated := !fsm.reconciled
+	fsm.reconciled = true
+	corrected := 0
+	for _, job := range jobs {
+		state := fsm.jobStateNoLock(job.ID)
+
+		switch {
+		case job.StatusCode.IsTerminal():
+			if state == JobStateFinished || state == JobStateStopped {
+				continue
+			}
+			if state == JobStateNotFound && !loadTerminated {
+				continue
+			}
+			terminated := fsm.addTerminatedJobNoLock(job, job.StatusCode)
+			transitions = append(transitions, jobTransition{job.ID, state, terminated.state()})
+			if state == JobStateNotFound {
+				continue
+			}
+			delete(fsm.pendingJobs, job.ID)
+			delete(fsm.waitAckJobs, job.ID)
+			delete(fsm.onlineJobs, job.ID)
+			delete(fsm.failedJobs, job.ID)
+			log.L().Warn("job is terminated in metastore, remove it from job fsm",
+				zap.String("id", job.ID), zap.Any("status", job.StatusCode),
+				zap.Stringer("state", state))
+		case job.StatusCode.IsActive():
+			// failed jobs are not dispatched again
+			if state != JobStateNotFound {
+				continue
+			}
+			// the job created recently may be still being submitted, it is
+			// left to the following reconciliation in case SubmitJob
+			// dispatches it or deletes it after the snapshot of jobs is read
+			if fsm.clocker.Since(job.CreatedAt) < newJobReconcileDelay {
+				continue
+			}
+			fsm.pendingJobs[job.ID] = &pendingJob{MasterMetaKVData: job}
+			transitions = append(transitions, jobTransition{job.ID, JobStateNotFound, JobStatePending})
+			log.L().Warn("job is running in metastore but missing in job fsm, add it to pending jobs",
+				zap.String("id", job.ID))
+		default:
+			continue
+		}
+		corrected++
+	}
+
+	return corrected
+}
+
 // JobCount queries job count based on job status
 func (fsm *JobFsm) JobCount(status pb.QueryJobResponse_JobStatus) int {
 	fsm.jobsMu.RLock()
@@ -280,8 +857,12 @@ func (fsm *JobFsm) JobCount(status pb.QueryJobResponse_JobStatus) int {
 		return len(fsm.waitAckJobs)
 	case pb.QueryJobResponse_online:
 		return len(fsm.onlineJobs)
+	case pb.QueryJobResponse_finished:
+		return fsm.finishedCount
+	case pb.QueryJobResponse_stopped:
+		// jobs that fail to be dispatched are queried as stopped
+		return fsm.stoppedCount + len(fsm.failedJobs)
 	default:
-		// TODO: support other job status count
 		return 0
 	}
 }
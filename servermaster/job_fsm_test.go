@@ -1,19 +1,30 @@
 package servermaster
 
 import (
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 
 	"github.com/hanfei1991/microcosm/lib/master"
 	libModel "github.com/hanfei1991/microcosm/lib/model"
 	"github.com/hanfei1991/microcosm/pb"
+	"github.com/hanfei1991/microcosm/pkg/clock"
+	derrors "github.com/hanfei1991/microcosm/pkg/errors"
+	ormModel "github.com/hanfei1991/microcosm/pkg/orm/model"
 )
 
 func TestJobFsmStateTrans(t *testing.T) {
 	t.Parallel()
 
 	fsm := NewJobFsm()
+	mockClock := clock.NewMock()
+	fsm.clocker = mockClock
 
 	id := "fsm-test-job-master-1"
 	job := &libModel.MasterMetaKVData{
@@ -75,12 +86,21 @@ func TestJobFsmStateTrans(t *testing.T) {
 		WorkerID:     id,
 		WorkerStatus: &libModel.WorkerStatus{Code: libModel.WorkerStatusNormal},
 		IsTombstone:  true,
-	})
+	}, errors.New("dispatch failed"))
 	require.Nil(t, err)
 	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_pending))
 	require.Equal(t, 0, fsm.JobCount(pb.QueryJobResponse_dispatched))
 
+	// Tick in the backoff of the failed dispatch, the job keeps pending
+	err = fsm.IterPendingJobs(func(job *libModel.MasterMetaKVData) (string, error) {
+		require.FailNow(t, "job in backoff is dispatched")
+		return id, nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_pending))
+
 	// Tick, Pending -> WaitAck
+	mockClock.Add(dispatchBackoffBase)
 	err = fsm.IterPendingJobs(func(job *libModel.MasterMetaKVData) (string, error) {
 		return id, nil
 	})
@@ -103,3 +123,570 @@ func TestJobFsmStateTrans(t *testing.T) {
 
 	fsm.JobOffline(invalidWorker, true)
 }
+
+func TestJobFsmReconcileJobs(t *testing.T) {
+	t.Parallel()
+
+	fsm := NewJobFsm()
+
+	finishedJob := &libModel.MasterMetaKVData{ID: "job-finished"}
+	stoppedJob := &libModel.MasterMetaKVData{ID: "job-stopped"}
+	runningJob := &libModel.MasterMetaKVData{ID: "job-running"}
+	fsm.JobDispatched(finishedJob, false)
+	fsm.JobDispatched(stoppedJob, false)
+	fsm.JobDispatched(runningJob, false)
+	for _, id := range []string{finishedJob.ID, runningJob.ID} {
+		err := fsm.JobOnline(&master.MockHandle{
+			WorkerID:     id,
+			WorkerStatus: &libModel.WorkerStatus{Code: libModel.WorkerStatusNormal},
+			ExecutorID:   "executor-1",
+		})
+		require.Nil(t, err)
+	}
+	require.Equal(t, 2, fsm.JobCount(pb.QueryJobResponse_online))
+	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_dispatched))
+
+	// job-finished and job-stopped are terminated in metastore, job-missing
+	// and job-uninit are active in metastore but not managed by job fsm,
+	// job-finished-before is finished before job fsm is created.
+	corrected := fsm.ReconcileJobs([]*libModel.MasterMetaKVData{
+		{ID: finishedJob.ID, StatusCode: libModel.MasterStatusFinished},
+		{ID: stoppedJob.ID, StatusCode: libModel.MasterStatusStopped},
+		{ID: runningJob.ID, StatusCode: libModel.MasterStatusInit},
+		{ID: "job-missing", StatusCode: libModel.MasterStatusInit},
+		{ID: "job-uninit", StatusCode: libModel.MasterStatusUninit},
+		{ID: "job-finished-before", StatusCode: libModel.MasterStatusFinished},
+	})
+	require.Equal(t, 4, corrected)
+	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_online))
+	require.NotNil(t, fsm.QueryOnlineJob(runningJob.ID))
+	require.Equal(t, 0, fsm.JobCount(pb.QueryJobResponse_dispatched))
+	require.Equal(t, 2, fsm.JobCount(pb.QueryJobResponse_pending))
+	require.Equal(t, pb.QueryJobResponse_pending, fsm.QueryJob("job-missing").Status)
+	require.Equal(t, pb.QueryJobResponse_pending, fsm.QueryJob("job-uninit").Status)
+	require.Equal(t, 2, fsm.JobCount(pb.QueryJobResponse_finished))
+	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_stopped))
+	require.Equal(t, pb.QueryJobResponse_stopped, fsm.QueryJob(stoppedJob.ID).Status)
+	require.Equal(t, pb.QueryJobResponse_finished, fsm.QueryJob("job-finished-before").Status)
+
+	// reconcile again, nothing to correct, the terminated job unknown to job
+	// fsm is pruned before, it is not loaded again
+	corrected = fsm.ReconcileJobs([]*libModel.MasterMetaKVData{
+		{ID: finishedJob.ID, StatusCode: libModel.MasterStatusFinished},
+		{ID: runningJob.ID, StatusCode: libModel.MasterStatusInit},
+		{ID: "job-missing", StatusCode: libModel.MasterStatusInit},
+		{ID: "job-finished-before", StatusCode: libModel.MasterStatusFinished},
+		{ID: "job-pruned", StatusCode: libModel.MasterStatusFinished},
+	})
+	require.Equal(t, 0, corrected)
+	require.Equal(t, 2, fsm.JobCount(pb.QueryJobResponse_finished))
+	require.Nil(t, fsm.QueryJob("job-pruned"))
+}
+
+func TestJobFsmReconcileActiveJobs(t *testing.T) {
+	t.Parallel()
+
+	fsm := NewJobFsm()
+	mockClock := clock.NewMock()
+	mockClock.Set(time.Now())
+	fsm.clocker = mockClock
+
+	// job-uninit is stored by SubmitJob but not dispatched before failover
+	old := mockClock.Now().Add(-time.Hour)
+	corrected := fsm.ReconcileJobs([]*libModel.MasterMetaKVData{
+		{Model: ormModel.Model{CreatedAt: old}, ID: "job-uninit", StatusCode: libModel.MasterStatusUninit},
+		{Model: ormModel.Model{CreatedAt: old}, ID: "job-init", StatusCode: libModel.MasterStatusInit},
+	})
+	require.Equal(t, 2, corrected)
+	state, ok := fsm.GetJobState("job-uninit")
+	require.True(t, ok)
+	require.Equal(t, JobStatePending, state)
+	require.Equal(t, 2, fsm.JobCount(pb.QueryJobResponse_pending))
+}
+
+func TestJobFsmReconcileSubmittingJob(t *testing.T) {
+	t.Parallel()
+
+	fsm := NewJobFsm()
+	mockClock := clock.NewMock()
+	mockClock.Set(time.Now())
+	fsm.clocker = mockClock
+
+	// the snapshot of jobs is read after SubmitJob stores job-new, and
+	// JobDispatched is called after the snapshot is read
+	job := &libModel.MasterMetaKVData{
+		Model:      ormModel.Model{CreatedAt: mockClock.Now()},
+		ID:         "job-new",
+		StatusCode: libModel.MasterStatusUninit,
+	}
+	snapshot := []*libModel.MasterMetaKVData{job}
+	require.Equal(t, 0, fsm.ReconcileJobs(snapshot))
+	fsm.JobDispatched(job, false)
+	require.Equal(t, 0, fsm.JobCount(pb.QueryJobResponse_pending))
+	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_dispatched))
+
+	// the job added to pending jobs by an older snapshot is not dispatched
+	// twice once SubmitJob dispatches it
+	mockClock.Add(2 * newJobReconcileDelay)
+	job = &libModel.MasterMetaKVData{
+		Model:      ormModel.Model{CreatedAt: mockClock.Now().Add(-2 * newJobReconcileDelay)},
+		ID:         "job-slow",
+		StatusCode: libModel.MasterStatusUninit,
+	}
+	require.Equal(t, 1, fsm.ReconcileJobs([]*libModel.MasterMetaKVData{job}))
+	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_pending))
+	fsm.JobDispatched(job, false)
+	require.Equal(t, 0, fsm.JobCount(pb.QueryJobResponse_pending))
+	require.Equal(t, 2, fsm.JobCount(pb.QueryJobResponse_dispatched))
+	dispatched := 0
+	err := fsm.IterPendingJobs(func(job *libModel.MasterMetaKVData) (string, error) {
+		dispatched++
+		return job.ID, nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 0, dispatched)
+}
+
+func TestJobFsmPauseDispatch(t *testing.T) {
+	t.Parallel()
+
+	fsm := NewJobFsm()
+	fsm.JobDispatched(&libModel.MasterMetaKVData{ID: "failover-job"}, true)
+	fsm.ReconcileJobs([]*libModel.MasterMetaKVData{
+		{ID: "pending-job", StatusCode: libModel.MasterStatusInit},
+	})
+
+	createWorkerCount := 0
+	dispatchJobFn := func(job *libModel.MasterMetaKVData) (string, error) {
+		createWorkerCount++
+		return job.ID, nil
+	}
+
+	fsm.PauseDispatch()
+	require.True(t, fsm.DispatchPaused())
+	require.Nil(t, fsm.IterPendingJobs(dispatchJobFn))
+	require.Nil(t, fsm.IterWaitAckJobs(dispatchJobFn))
+	require.Equal(t, 0, createWorkerCount)
+	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_pending))
+
+	fsm.ResumeDispatch()
+	require.False(t, fsm.DispatchPaused())
+	require.Nil(t, fsm.IterPendingJobs(dispatchJobFn))
+	require.Nil(t, fsm.IterWaitAckJobs(dispatchJobFn))
+	require.Equal(t, 2, createWorkerCount)
+	require.Equal(t, 0, fsm.JobCount(pb.QueryJobResponse_pending))
+	require.Equal(t, 2, fsm.JobCount(pb.QueryJobResponse_dispatched))
+}
+
+func TestJobFsmDispatchBackoff(t *testing.T) {
+	t.Parallel()
+
+	fsm := NewJobFsm()
+	mockClock := clock.NewMock()
+	fsm.clocker = mockClock
+	jobID := "failing-job"
+	fsm.ReconcileJobs([]*libModel.MasterMetaKVData{
+		{ID: jobID, StatusCode: libModel.MasterStatusInit},
+	})
+
+	dispatchTimes := make([]time.Time, 0, maxDispatchAttempts)
+	dispatchJobFn := func(job *libModel.MasterMetaKVData) (string, error) {
+		dispatchTimes = append(dispatchTimes, mockClock.Now())
+		return "", errors.New("dispatch failed")
+	}
+
+	// advance the clock by a second for each tick until the job fails out
+	for i := 0; i < 1000 && fsm.JobCount(pb.QueryJobResponse_pending) > 0; i++ {
+		require.Nil(t, fsm.IterPendingJobs(dispatchJobFn))
+		mockClock.Add(time.Second)
+	}
+	require.Len(t, dispatchTimes, maxDispatchAttempts)
+	require.Equal(t, 0, fsm.JobCount(pb.QueryJobResponse_pending))
+
+	// the interval between the dispatches grows until the max backoff
+	for i := 1; i < len(dispatchTimes); i++ {
+		interval := dispatchTimes[i].Sub(dispatchTimes[i-1])
+		require.Equal(t, dispatchBackoff(i), interval)
+		if i > 1 {
+			prev := dispatchTimes[i-1].Sub(dispatchTimes[i-2])
+			require.True(t, interval > prev || interval == dispatchBackoffMax)
+		}
+	}
+	require.Equal(t, dispatchBackoffMax, dispatchBackoff(maxDispatchAttempts*2))
+
+	// the failed job is not dispatched any more, even after reconciliation
+	fsm.ReconcileJobs([]*libModel.MasterMetaKVData{
+		{ID: jobID, StatusCode: libModel.MasterStatusInit},
+	})
+	mockClock.Add(time.Hour)
+	require.Nil(t, fsm.IterPendingJobs(dispatchJobFn))
+	require.Len(t, dispatchTimes, maxDispatchAttempts)
+	resp := fsm.QueryJob(jobID)
+	require.Equal(t, pb.QueryJobResponse_stopped, resp.Status)
+	require.Equal(t, pb.ErrorCode_SubJobSubmitFailed, resp.Err.Code)
+	require.Contains(t, resp.Err.Message, "failed to be dispatched after 8 attempts")
+	require.True(t, derrors.ErrJobDispatchExhausted.Equal(fsm.failedJobs[jobID].reason))
+
+	// the quota error doesn't consume the dispatch attempts
+	quotaJobID := "quota-job"
+	fsm.ReconcileJobs([]*libModel.MasterMetaKVData{
+		{ID: quotaJobID, StatusCode: libModel.MasterStatusInit},
+	})
+	for i := 0; i < maxDispatchAttempts*2; i++ {
+		err := fsm.IterPendingJobs(func(job *libModel.MasterMetaKVData) (string, error) {
+			return "", derrors.ErrMasterConcurrencyExceeded.GenWithStackByArgs()
+		})
+		require.True(t, derrors.ErrMasterConcurrencyExceeded.Equal(err))
+	}
+	require.Equal(t, 0, fsm.pendingJobs[quotaJobID].dispatchAttempts)
+	require.Nil(t, fsm.IterPendingJobs(func(job *libModel.MasterMetaKVData) (string, error) {
+		return job.ID, nil
+	}))
+	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_dispatched))
+}
+
+func TestJobFsmTerminatedJobCount(t *testing.T) {
+	t.Parallel()
+
+	fsm := NewJobFsm()
+	handle := func(id string) *master.MockHandle {
+		return &master.MockHandle{
+			WorkerID:     id,
+			WorkerStatus: &libModel.WorkerStatus{Code: libModel.WorkerStatusNormal},
+			ExecutorID:   "executor-1",
+		}
+	}
+	for _, id := range []string{"job-1", "job-2", "job-3"} {
+		fsm.JobDispatched(&libModel.MasterMetaKVData{ID: id, Config: []byte(id)}, false)
+	}
+	require.Nil(t, fsm.JobOnline(handle("job-1")))
+	require.Nil(t, fsm.JobOnline(handle("job-2")))
+	require.Equal(t, 0, fsm.JobCount(pb.QueryJobResponse_finished))
+	require.Equal(t, 0, fsm.JobCount(pb.QueryJobResponse_stopped))
+
+	// Online -> Finished
+	fsm.JobTerminated(handle("job-1"), libModel.MasterStatusFinished)
+	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_online))
+	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_finished))
+	resp := fsm.QueryJob("job-1")
+	require.Equal(t, pb.QueryJobResponse_finished, resp.Status)
+	require.Equal(t, []byte("job-1"), resp.Config)
+
+	// Online -> Stopped
+	fsm.JobTerminated(handle("job-2"), libModel.MasterStatusStopped)
+	require.Equal(t, 0, fsm.JobCount(pb.QueryJobResponse_online))
+	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_stopped))
+	require.Equal(t, pb.QueryJobResponse_stopped, fsm.QueryJob("job-2").Status)
+
+	// WaitAck -> Finished
+	fsm.JobTerminated(handle("job-3"), libModel.MasterStatusFinished)
+	require.Equal(t, 0, fsm.JobCount(pb.QueryJobResponse_dispatched))
+	require.Equal(t, 2, fsm.JobCount(pb.QueryJobResponse_finished))
+
+	// terminating an unknown job does nothing
+	fsm.JobTerminated(handle("job-unknown"), libModel.MasterStatusFinished)
+	require.Equal(t, 2, fsm.JobCount(pb.QueryJobResponse_finished))
+	require.Nil(t, fsm.QueryJob("job-unknown"))
+
+	// jobs failed to be dispatched are counted as stopped
+	fsm.pendingJobs["job-4"] = &pendingJob{
+		MasterMetaKVData: &libModel.MasterMetaKVData{ID: "job-4"},
+		dispatchAttempts: maxDispatchAttempts - 1,
+	}
+	err := fsm.IterPendingJobs(func(job *libModel.MasterMetaKVData) (string, error) {
+		return "", errors.New("dispatch failed")
+	})
+	require.Nil(t, err)
+	require.Equal(t, 2, fsm.JobCount(pb.QueryJobResponse_stopped))
+}
+
+func TestJobFsmPruneTerminatedJobs(t *testing.T) {
+	t.Parallel()
+
+	fsm := NewJobFsm()
+	mockClock := clock.NewMock()
+	mockClock.Set(time.Now())
+	fsm.clocker = mockClock
+
+	// terminate a batch of jobs every 10 minutes, the terminated jobs are
+	// bounded by the retention
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			id := fmt.Sprintf("job-%d-%d", i, j)
+			fsm.JobDispatched(&libModel.MasterMetaKVData{ID: id}, false)
+			fsm.JobTerminated(&master.MockHandle{WorkerID: id}, libModel.MasterStatusFinished)
+		}
+		mockClock.Add(10 * time.Minute)
+		fsm.PruneTerminatedJobs(30 * time.Minute)
+		require.LessOrEqual(t, len(fsm.terminatedJobs), 30)
+	}
+	require.Len(t, fsm.terminatedJobs, 30)
+	require.Equal(t, 100, fsm.JobCount(pb.QueryJobResponse_finished))
+	state, ok := fsm.GetJobState("job-0-0")
+	require.False(t, ok)
+	require.Equal(t, JobStateNotFound, state)
+	require.Equal(t, pb.QueryJobResponse_finished, fsm.QueryJob("job-9-0").Status)
+
+	// failed jobs are pruned too, and no longer counted
+	fsm.failedJobs["job-failed"] = &failedJob{
+		MasterMetaKVData: &libModel.MasterMetaKVData{ID: "job-failed"},
+		reason:           errors.New("dispatch failed"),
+		failedAt:         mockClock.Now(),
+	}
+	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_stopped))
+	mockClock.Add(time.Hour)
+	require.Equal(t, 31, fsm.PruneTerminatedJobs(30*time.Minute))
+	require.Empty(t, fsm.terminatedJobs)
+	require.Empty(t, fsm.failedJobs)
+	require.Equal(t, 0, fsm.JobCount(pb.QueryJobResponse_stopped))
+	require.Equal(t, 100, fsm.JobCount(pb.QueryJobResponse_finished))
+}
+
+func TestJobFsmQueryJobConcurrently(t *testing.T) {
+	t.Parallel()
+
+	fsm := NewJobFsm()
+	fsm.JobDispatched(&libModel.MasterMetaKVData{ID: "job-wait-ack"}, false)
+	fsm.JobDispatched(&libModel.MasterMetaKVData{ID: "job-online"}, false)
+	err := fsm.JobOnline(&master.MockHandle{
+		WorkerID:     "job-online",
+		WorkerStatus: &libModel.WorkerStatus{Code: libModel.WorkerStatusNormal},
+		ExecutorID:   "executor-1",
+	})
+	require.Nil(t, err)
+
+	// QueryJob doesn't wait for the other readers of the jobs
+	fsm.jobsMu.RLock()
+	defer fsm.jobsMu.RUnlock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				require.Equal(t, pb.QueryJobResponse_dispatched, fsm.QueryJob("job-wait-ack").Status)
+				require.Equal(t, pb.QueryJobResponse_online, fsm.QueryJob("job-online").Status)
+				require.Nil(t, fsm.QueryJob("job-unknown"))
+			}()
+		}
+		wg.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "QueryJob is blocked by the readers of the jobs")
+	}
+}
+
+func TestJobFsmGetJobState(t *testing.T) {
+	t.Parallel()
+
+	fsm := NewJobFsm()
+	handle := func(id string) *master.MockHandle {
+		return &master.MockHandle{
+			WorkerID:     id,
+			WorkerStatus: &libModel.WorkerStatus{Code: libModel.WorkerStatusNormal},
+			ExecutorID:   "executor-1",
+		}
+	}
+	for _, id := range []string{"job-wait-ack", "job-online", "job-pending", "job-finished", "job-stopped"} {
+		fsm.JobDispatched(&libModel.MasterMetaKVData{ID: id}, false)
+	}
+	for _, id := range []string{"job-online", "job-pending", "job-finished", "job-stopped"} {
+		require.Nil(t, fsm.JobOnline(handle(id)))
+	}
+	fsm.JobOffline(handle("job-pending"), true /* needFailover */)
+	fsm.JobTerminated(handle("job-finished"), libModel.MasterStatusFinished)
+	fsm.JobTerminated(handle("job-stopped"), libModel.MasterStatusStopped)
+	fsm.failedJobs["job-failed"] = &failedJob{
+		MasterMetaKVData: &libModel.MasterMetaKVData{ID: "job-failed"},
+		reason:           errors.New("dispatch failed"),
+	}
+
+	testCases := []struct {
+		id    string
+		state JobState
+	}{
+		{"job-pending", JobStatePending},
+		{"job-wait-ack", JobStateWaitAck},
+		{"job-online", JobStateOnline},
+		{"job-finished", JobStateFinished},
+		{"job-stopped", JobStateStopped},
+		{"job-failed", JobStateFailed},
+	}
+	for _, tc := range testCases {
+		state, ok := fsm.GetJobState(tc.id)
+		require.True(t, ok, tc.id)
+		require.Equal(t, tc.state, state, tc.id)
+	}
+	state, ok := fsm.GetJobState("job-unknown")
+	require.False(t, ok)
+	require.Equal(t, JobStateNotFound, state)
+	require.Equal(t, "wait-ack", JobStateWaitAck.String())
+	require.Equal(t, "unknown", JobState(-1).String())
+}
+
+func TestJobFsmRedispatchStaleWaitAckJobs(t *testing.T) {
+	t.Parallel()
+
+	fsm := NewJobFsm()
+	mockClock := clock.NewMock()
+	mockClock.Set(time.Now())
+	fsm.clocker = mockClock
+
+	// job-stale has waited for ack since an hour ago
+	fsm.waitAckJobs["job-stale"] = &jobHolder{
+		MasterMetaKVData: &libModel.MasterMetaKVData{ID: "job-stale"},
+		waitAckTime:      mockClock.Now().Add(-time.Hour),
+	}
+	fsm.JobDispatched(&libModel.MasterMetaKVData{ID: "job-fresh"}, false)
+	fsm.JobDispatched(&libModel.MasterMetaKVData{ID: "job-failover"}, true)
+	fsm.JobDispatched(&libModel.MasterMetaKVData{ID: "job-online"}, false)
+	require.Nil(t, fsm.JobOnline(&master.MockHandle{
+		WorkerID:     "job-online",
+		WorkerStatus: &libModel.WorkerStatus{Code: libModel.WorkerStatusNormal},
+		ExecutorID:   "executor-1",
+	}))
+	require.Equal(t, []libModel.MasterID{"job-stale"}, fsm.RedispatchStaleWaitAckJobs(time.Minute))
+	state, _ := fsm.GetJobState("job-stale")
+	require.Equal(t, JobStatePending, state)
+	require.Equal(t, 1, fsm.pendingJobs["job-stale"].dispatchAttempts)
+
+	// the other wait ack job becomes stale as time goes by, the online job
+	// and the job added from failover are never redispatched
+	mockClock.Add(2 * time.Minute)
+	require.Empty(t, fsm.RedispatchStaleWaitAckJobs(2*time.Hour))
+	require.Equal(t, []libModel.MasterID{"job-fresh"}, fsm.RedispatchStaleWaitAckJobs(time.Minute))
+	require.Equal(t, 2, fsm.JobCount(pb.QueryJobResponse_pending))
+	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_dispatched))
+	require.Equal(t, 1, fsm.JobCount(pb.QueryJobResponse_online))
+
+	// the job redispatched from pending waits for ack from the beginning,
+	// and it is moved to failed jobs after being stale too many times
+	for i := 1; i < maxDispatchAttempts; i++ {
+		mockClock.Add(dispatchBackoffMax)
+		err := fsm.IterPendingJobs(func(job *libModel.MasterMetaKVData) (string, error) {
+			return job.ID, nil
+		})
+		require.Nil(t, err)
+		state, _ = fsm.GetJobState("job-stale")
+		require.Equal(t, JobStateWaitAck, state)
+		require.Empty(t, fsm.RedispatchStaleWaitAckJobs(dispatchBackoffMax))
+		mockClock.Add(dispatchBackoffMax + time.Second)
+		require.Equal(t, []libModel.MasterID{"job-fresh", "job-stale"}, fsm.RedispatchStaleWaitAckJobs(dispatchBackoffMax))
+	}
+	for _, id := range []libModel.MasterID{"job-fresh", "job-stale"} {
+		state, _ = fsm.GetJobState(id)
+		require.Equal(t, JobStateFailed, state, id)
+	}
+	reason := fsm.failedJobs["job-stale"].reason
+	require.True(t, derrors.ErrJobDispatchExhausted.Equal(reason))
+	require.Contains(t, reason.Error(), "is not acked by its job master")
+}
+
+func TestJobFsmMetrics(t *testing.T) {
+	t.Parallel()
+
+	fsm := NewJobFsm()
+	// the gauges registered globally are shared by all job fsms in the tests
+	gauge := func() prometheus.Gauge {
+		return prometheus.NewGauge(prometheus.GaugeOpts{Name: "test"})
+	}
+	fsm.metrics = &jobFsmMetrics{pending: gauge(), dispatched: gauge(), online: gauge()}
+	requireMetrics := func() {
+		require.Equal(t, float64(fsm.JobCount(pb.QueryJobResponse_pending)), testutil.ToFloat64(fsm.metrics.pending))
+		require.Equal(t, float64(fsm.JobCount(pb.QueryJobResponse_dispatched)), testutil.ToFloat64(fsm.metrics.dispatched))
+		require.Equal(t, float64(fsm.JobCount(pb.QueryJobResponse_online)), testutil.ToFloat64(fsm.metrics.online))
+	}
+	handle := func(id string) *master.MockHandle {
+		return &master.MockHandle{
+			WorkerID:     id,
+			WorkerStatus: &libModel.WorkerStatus{Code: libModel.WorkerStatusNormal},
+			ExecutorID:   "executor-1",
+		}
+	}
+
+	for _, id := range []string{"job-1", "job-2", "job-3"} {
+		fsm.JobDispatched(&libModel.MasterMetaKVData{ID: id}, false)
+	}
+	requireMetrics()
+	require.Equal(t, float64(3), testutil.ToFloat64(fsm.metrics.dispatched))
+
+	require.Nil(t, fsm.JobOnline(handle("job-1")))
+	require.Nil(t, fsm.JobOnline(handle("job-2")))
+	requireMetrics()
+	require.Equal(t, float64(2), testutil.ToFloat64(fsm.metrics.online))
+
+	fsm.JobOffline(handle("job-1"), true /* needFailover */)
+	require.Nil(t, fsm.JobDispatchFailed(handle("job-3"), errors.New("dispatch failed")))
+	requireMetrics()
+	require.Equal(t, float64(2), testutil.ToFloat64(fsm.metrics.pending))
+	require.Equal(t, float64(0), testutil.ToFloat64(fsm.metrics.dispatched))
+
+	fsm.JobTerminated(handle("job-2"), libModel.MasterStatusFinished)
+	require.Nil(t, fsm.IterPendingJobs(func(job *libModel.MasterMetaKVData) (string, error) {
+		return job.ID, nil
+	}))
+	requireMetrics()
+	require.Equal(t, float64(0), testutil.ToFloat64(fsm.metrics.online))
+	require.Equal(t, float64(1), testutil.ToFloat64(fsm.metrics.dispatched))
+}
+
+func TestJobFsmTransitionHook(t *testing.T) {
+	t.Parallel()
+
+	type transition struct {
+		id       string
+		from, to JobState
+	}
+	var (
+		fsm         *JobFsm
+		transitions []transition
+	)
+	fsm = NewJobFsm(WithTransitionHook(func(id libModel.MasterID, from, to JobState) {
+		// the hook is called without the lock held, so it can query the fsm
+		state, _ := fsm.GetJobState(id)
+		require.Equal(t, to, state)
+		transitions = append(transitions, transition{id, from, to})
+	}))
+	mockClock := clock.NewMock()
+	fsm.clocker = mockClock
+	handle := func(id string) *master.MockHandle {
+		return &master.MockHandle{
+			WorkerID:     id,
+			WorkerStatus: &libModel.WorkerStatus{Code: libModel.WorkerStatusNormal},
+			ExecutorID:   "executor-1",
+		}
+	}
+	id := "job-1"
+
+	fsm.JobDispatched(&libModel.MasterMetaKVData{ID: id}, false)
+	require.Nil(t, fsm.JobOnline(handle(id)))
+	fsm.JobOffline(handle(id), true /* needFailover */)
+	require.Nil(t, fsm.IterPendingJobs(func(job *libModel.MasterMetaKVData) (string, error) {
+		return job.ID, nil
+	}))
+	require.Nil(t, fsm.JobDispatchFailed(handle(id), errors.New("dispatch failed")))
+	mockClock.Add(dispatchBackoffBase)
+	require.Nil(t, fsm.IterPendingJobs(func(job *libModel.MasterMetaKVData) (string, error) {
+		return job.ID, nil
+	}))
+	require.Nil(t, fsm.JobOnline(handle(id)))
+	fsm.JobTerminated(handle(id), libModel.MasterStatusFinished)
+	// failed operations make no transition
+	require.Error(t, fsm.JobOnline(handle(id)))
+	fsm.JobOffline(handle(id), true /* needFailover */)
+
+	require.Equal(t, []transition{
+		{id, JobStateNotFound, JobStateWaitAck},
+		{id, JobStateWaitAck, JobStateOnline},
+		{id, JobStateOnline, JobStatePending},
+		{id, JobStatePending, JobStateWaitAck},
+		{id, JobStateWaitAck, JobStatePending},
+		{id, JobStatePending, JobStateWaitAck},
+		{id, JobStateWaitAck, JobStateOnline},
+		{id, JobStateOnline, JobStateFinished},
+	}, transitions)
+}
@@ -32,9 +32,26 @@ type JobManager interface {
 	PauseJob(ctx context.Context, req *pb.PauseJobRequest) *pb.PauseJobResponse
 
 	GetJobStatuses(ctx context.Context) (map[libModel.MasterID]libModel.MasterStatusCode, error)
+
+	// PauseDispatch stops dispatching new jobs and failover jobs, running jobs
+	// are not affected.
+	PauseDispatch()
+	// ResumeDispatch resumes job dispatch and dispatches the pending jobs
+	// immediately.
+	ResumeDispatch() error
+	// DispatchPaused returns whether job dispatch is paused.
+	DispatchPaused() bool
 }
 
-const defaultJobMasterCost = 1
+const (
+	defaultJobMasterCost = 1
+	// reconcileJobsInterval is the interval to reconcile jobs in JobFsm with
+	// jobs persisted in metastore.
+	reconcileJobsInterval = time.Minute
+	// staleWaitAckJobThreshold is the time after which a job waiting for the
+	// ack of its job master is dispatched again.
+	staleWaitAckJobThreshold = 5 * time.Minute
+)
 
 // JobManagerImplV2 is a special job master that manages all the job masters, and notify the offline executor to them.
 // worker state transition
@@ -51,6 +68,8 @@ type JobManagerImplV2 struct {
 	clocker          clock.Clock
 	frameMetaClient  pkgOrm.Client
 	tombstoneCleaned bool
+
+	lastReconcileTime time.Time
 }
 
 // PauseJob implements proto/Master.PauseJob
@@ -301,11 +320,7 @@ func (jm *JobManagerImplV2) Tick(ctx context.Context) error {
 		return false, err
 	}
 
-	err := jm.JobFsm.IterPendingJobs(
-		func(job *libModel.MasterMetaKVData) (string, error) {
-			return jm.BaseMaster.CreateWorker(
-				job.Tp, job, defaultJobMasterCost)
-		})
+	err := jm.dispatchPendingJobs()
 	if _, err = filterQuotaError(err); err != nil {
 		return err
 	}
@@ -344,6 +359,61 @@ func (jm *JobManagerImplV2) Tick(ctx context.Context) error {
 		}
 	}
 
+	if jm.tombstoneCleaned && jm.clocker.Since(jm.lastReconcileTime) >= reconcileJobsInterval {
+		if err := jm.reconcileJobs(ctx); err != nil {
+			// reconciliation is best-effort, retry it in next round
+			log.L().Warn("reconcile jobs failed", zap.Error(err))
+		}
+		jm.lastReconcileTime = jm.clocker.Now()
+		if pruned := jm.JobFsm.PruneTerminatedJobs(terminatedJobRetention); pruned > 0 {
+			log.L().Info("prune terminated jobs", zap.Int("pruned", pruned))
+		}
+		if stale := jm.JobFsm.RedispatchStaleWaitAckJobs(staleWaitAckJobThreshold); len(stale) > 0 {
+			log.L().Warn("jobs wait for the ack of job master too long, redispatch them",
+				zap.Strings("ids", stale), zap.Duration("threshold", staleWaitAckJobThreshold))
+		}
+	}
+
+	return nil
+}
+
+// dispatchPendingJobs creates job masters for all pending jobs
+func (jm *JobManagerImplV2) dispatchPendingJobs() error {
+	return jm.JobFsm.IterPendingJobs(
+		func(job *libModel.MasterMetaKVData) (string, error) {
+			return jm.BaseMaster.CreateWorker(
+				job.Tp, job, defaultJobMasterCost)
+		})
+}
+
+// ResumeDispatch implements JobManager.ResumeDispatch
+func (jm *JobManagerImplV2) ResumeDispatch() error {
+	jm.JobFsm.ResumeDispatch()
+	err := jm.dispatchPendingJobs()
+	if derrors.ErrMasterConcurrencyExceeded.Equal(err) {
+		// the remaining pending jobs will be dispatched in Tick
+		log.L().Warn("create worker exceeds quota, retry later", zap.Error(err))
+		return nil
+	}
+	return err
+}
+
+// reconcileJobs loads all jobs from metastore and corrects the drift of JobFsm
+func (jm *JobManagerImplV2) reconcileJobs(ctx context.Context) error {
+	jobs, err := jm.frameMetaClient.QueryJobs(ctx)
+	if err != nil {
+		return err
+	}
+	filtered := make([]*libModel.MasterMetaKVData, 0, len(jobs))
+	for _, job := range jobs {
+		if job.Tp == lib.JobManager {
+			continue
+		}
+		filtered = append(filtered, job)
+	}
+	if corrected := jm.JobFsm.ReconcileJobs(filtered); corrected > 0 {
+		log.L().Info("reconcile jobs with metastore", zap.Int("corrected", corrected))
+	}
 	return nil
 }
 
@@ -357,7 +427,7 @@ func (jm *JobManagerImplV2) OnMasterRecovered(ctx context.Context) error {
 		if job.Tp == lib.JobManager {
 			continue
 		}
-		if job.StatusCode == libModel.MasterStatusFinished || job.StatusCode == libModel.MasterStatusStopped {
+		if job.StatusCode.IsTerminal() {
 			log.L().Info("skip finished or stopped job", zap.Any("job", job))
 			continue
 		}
@@ -371,7 +441,7 @@ func (jm *JobManagerImplV2) OnMasterRecovered(ctx context.Context) error {
 func (jm *JobManagerImplV2) OnWorkerDispatched(worker lib.WorkerHandle, result error) error {
 	if result != nil {
 		log.L().Warn("dispatch worker met error", zap.Error(result))
-		return jm.JobFsm.JobDispatchFailed(worker)
+		return jm.JobFsm.JobDispatchFailed(worker, result)
 	}
 	return nil
 }
@@ -384,13 +454,13 @@ func (jm *JobManagerImplV2) OnWorkerOnline(worker lib.WorkerHandle) error {
 
 // OnWorkerOffline implements lib.MasterImpl.OnWorkerOffline
 func (jm *JobManagerImplV2) OnWorkerOffline(worker lib.WorkerHandle, reason error) error {
-	needFailover := true
+	var terminatedStatus libModel.MasterStatusCode
 	if derrors.ErrWorkerFinish.Equal(reason) {
 		log.L().Info("job master finished", zap.String("id", worker.ID()))
-		needFailover = false
+		terminatedStatus = libModel.MasterStatusFinished
 	} else if derrors.ErrWorkerStop.Equal(reason) {
 		log.L().Info("job master stopped", zap.String("id", worker.ID()))
-		needFailover = false
+		terminatedStatus = libModel.MasterStatusStopped
 	} else {
 		log.L().Info("on worker offline", zap.Any("id", worker.ID()), zap.Any("reason", reason))
 	}
@@ -399,7 +469,11 @@ func (jm *JobManagerImplV2) OnWorkerOffline(worker lib.WorkerHandle, reason erro
 	if err := worker.GetTombstone().CleanTombstone(ctx); err != nil {
 		return err
 	}
-	jm.JobFsm.JobOffline(worker, needFailover)
+	if terminatedStatus != 0 {
+		jm.JobFsm.JobTerminated(worker, terminatedStatus)
+	} else {
+		jm.JobFsm.JobOffline(worker, true /* needFailover */)
+	}
 	return nil
 }
 
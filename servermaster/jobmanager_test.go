@@ -17,6 +17,7 @@ import (
 	"github.com/hanfei1991/microcosm/pkg/clock"
 	"github.com/hanfei1991/microcosm/pkg/errors"
 	resourcemeta "github.com/hanfei1991/microcosm/pkg/externalresource/resourcemeta/model"
+	pkgOrm "github.com/hanfei1991/microcosm/pkg/orm"
 	"github.com/hanfei1991/microcosm/pkg/uuid"
 )
 
@@ -36,6 +37,7 @@ func TestJobManagerSubmitJob(t *testing.T) {
 		BaseMaster:      mockMaster.DefaultBaseMaster,
 		JobFsm:          NewJobFsm(),
 		uuidGen:         uuid.NewGenerator(),
+		clocker:         clock.New(),
 		frameMetaClient: mockMaster.GetFrameMetaClient(),
 	}
 	// set master impl to JobManagerImplV2
@@ -88,6 +90,7 @@ func TestCreateWorkerReturnError(t *testing.T) {
 		BaseMaster:      mockMaster,
 		JobFsm:          NewJobFsm(),
 		uuidGen:         uuid.NewGenerator(),
+		clocker:         clock.New(),
 		frameMetaClient: mockMaster.GetFrameMetaClient(),
 	}
 	mockMaster.Impl = mgr
@@ -171,6 +174,91 @@ func TestJobManagerCancelJob(t *testing.T) {
 	require.Equal(t, &pb.CancelJobResponse{}, resp)
 }
 
+// deadlineRecordClient records the deadline of the context passed to GetJobByID
+type deadlineRecordClient struct {
+	pkgOrm.Client
+	deadline    time.Time
+	hasDeadline bool
+}
+
+func (c *deadlineRecordClient) GetJobByID(ctx context.Context, jobID string) (*libModel.MasterMetaKVData, error) {
+	c.deadline, c.hasDeadline = ctx.Deadline()
+	return c.Client.GetJobByID(ctx, jobID)
+}
+
+func TestJobManagerCancelJobDeadline(t *testing.T) {
+	t.Parallel()
+
+	mockMaster := lib.NewMockMasterImpl("", "cancel-job-deadline-test")
+	metaClient := &deadlineRecordClient{Client: mockMaster.GetFrameMetaClient()}
+	mgr := &JobManagerImplV2{
+		BaseMaster:      mockMaster.DefaultBaseMaster,
+		JobFsm:          NewJobFsm(),
+		clocker:         clock.New(),
+		frameMetaClient: metaClient,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+	resp := mgr.CancelJob(ctx, &pb.CancelJobRequest{JobIdStr: "job-not-exist"})
+	require.Equal(t, pb.ErrorCode_UnKnownJob, resp.Err.Code)
+	// the deadline of the rpc flows into the metastore operation
+	require.True(t, metaClient.hasDeadline)
+	require.Equal(t, deadline, metaClient.deadline)
+
+	// a canceled rpc doesn't reach the metastore
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	resp = mgr.CancelJob(ctx, &pb.CancelJobRequest{JobIdStr: "job-not-exist"})
+	require.Equal(t, pb.ErrorCode_UnknownError, resp.Err.Code)
+}
+
+func TestJobManagerReconcileJobs(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockMaster := lib.NewMockMasterImpl("", "reconcile-jobs-test")
+	mockMaster.On("InitImpl", mock.Anything).Return(nil)
+	// the jobs stored below are old enough to be reconciled
+	fsm := NewJobFsm()
+	mockClock := clock.NewMock()
+	mockClock.Set(time.Now().Add(2 * newJobReconcileDelay))
+	fsm.clocker = mockClock
+	mgr := &JobManagerImplV2{
+		BaseMaster:      mockMaster.DefaultBaseMaster,
+		JobFsm:          fsm,
+		clocker:         clock.New(),
+		frameMetaClient: mockMaster.GetFrameMetaClient(),
+	}
+
+	finishedJob := &libModel.MasterMetaKVData{
+		ID:         "job-finished",
+		Tp:         lib.FakeJobMaster,
+		StatusCode: libModel.MasterStatusFinished,
+	}
+	runningJob := &libModel.MasterMetaKVData{
+		ID:         "job-running",
+		Tp:         lib.FakeJobMaster,
+		StatusCode: libModel.MasterStatusInit,
+	}
+	for _, job := range []*libModel.MasterMetaKVData{finishedJob, runningJob} {
+		err := mgr.frameMetaClient.UpsertJob(ctx, job)
+		require.NoError(t, err)
+	}
+	mgr.JobFsm.JobDispatched(&libModel.MasterMetaKVData{ID: finishedJob.ID}, false)
+
+	err := mgr.reconcileJobs(ctx)
+	require.NoError(t, err)
+	require.Equal(t, pb.QueryJobResponse_finished, mgr.JobFsm.QueryJob(finishedJob.ID).Status)
+	require.Equal(t, 0, mgr.JobCount(pb.QueryJobResponse_dispatched))
+	require.Equal(t, 1, mgr.JobCount(pb.QueryJobResponse_finished))
+	require.Equal(t, 1, mgr.JobCount(pb.QueryJobResponse_pending))
+	require.Equal(t, pb.QueryJobResponse_pending, mgr.JobFsm.QueryJob(runningJob.ID).Status)
+}
+
 func TestJobManagerQueryJob(t *testing.T) {
 	t.Parallel()
 
@@ -247,6 +335,7 @@ func TestJobManagerOnlineJob(t *testing.T) {
 		BaseMaster:      mockMaster.DefaultBaseMaster,
 		JobFsm:          NewJobFsm(),
 		uuidGen:         uuid.NewGenerator(),
+		clocker:         clock.New(),
 		frameMetaClient: mockMaster.GetFrameMetaClient(),
 	}
 	// set master impl to JobManagerImplV2
@@ -324,6 +413,7 @@ func TestJobManagerTickExceedQuota(t *testing.T) {
 		BaseMaster:      mockMaster,
 		JobFsm:          NewJobFsm(),
 		uuidGen:         uuid.NewGenerator(),
+		clocker:         clock.New(),
 		frameMetaClient: mockMaster.GetFrameMetaClient(),
 	}
 	mockMaster.Impl = mgr
@@ -341,3 +431,63 @@ func TestJobManagerTickExceedQuota(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 1, mgr.JobFsm.JobCount(pb.QueryJobResponse_dispatched))
 }
+
+type mockBaseMasterCreateWorkerCount struct {
+	*lib.MockMasterImpl
+	created int
+}
+
+func (m *mockBaseMasterCreateWorkerCount) CreateWorker(
+	workerType lib.WorkerType,
+	config lib.WorkerConfig,
+	cost model.RescUnit,
+	resources ...resourcemeta.ResourceID,
+) (libModel.WorkerID, error) {
+	m.created++
+	return config.(*libModel.MasterMetaKVData).ID, nil
+}
+
+func TestJobManagerPauseDispatch(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	masterImpl := lib.NewMockMasterImpl("", "pause-dispatch")
+	mockMaster := &mockBaseMasterCreateWorkerCount{
+		MockMasterImpl: masterImpl,
+	}
+	mgr := &JobManagerImplV2{
+		BaseMaster:      mockMaster,
+		JobFsm:          NewJobFsm(),
+		uuidGen:         uuid.NewGenerator(),
+		clocker:         clock.New(),
+		frameMetaClient: mockMaster.GetFrameMetaClient(),
+	}
+	mockMaster.Impl = mgr
+	err := mockMaster.Init(ctx)
+	require.NoError(t, err)
+
+	mgr.PauseDispatch()
+	require.True(t, mgr.DispatchPaused())
+	mgr.JobFsm.ReconcileJobs([]*libModel.MasterMetaKVData{
+		{ID: "job-1", StatusCode: libModel.MasterStatusInit},
+		{ID: "job-2", StatusCode: libModel.MasterStatusInit},
+	})
+
+	// pending jobs stay pending while dispatch is paused
+	for i := 0; i < 3; i++ {
+		err = mgr.Tick(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 2, mgr.JobFsm.JobCount(pb.QueryJobResponse_pending))
+		require.Equal(t, 0, mockMaster.created)
+	}
+
+	// pending jobs are dispatched as soon as dispatch is resumed
+	err = mgr.ResumeDispatch()
+	require.NoError(t, err)
+	require.False(t, mgr.DispatchPaused())
+	require.Equal(t, 0, mgr.JobFsm.JobCount(pb.QueryJobResponse_pending))
+	require.Equal(t, 2, mgr.JobFsm.JobCount(pb.QueryJobResponse_dispatched))
+	require.Equal(t, 2, mockMaster.created)
+}
@@ -2,6 +2,8 @@ package servermaster
 
 import (
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/hanfei1991/microcosm/pkg/promutil"
 )
 
 var (
@@ -19,6 +21,18 @@ var (
 			Name:      "job_num",
 			Help:      "number of jobs in this cluster",
 		}, []string{"status"})
+
+	metricFactory = promutil.NewFactory4Framework()
+
+	// jobFsmJobNumGauge is updated by JobFsm whenever its jobs change, unlike
+	// serverJobNumGauge which is collected periodically by the leader.
+	jobFsmJobNumGauge = metricFactory.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "server_master",
+			Subsystem: "job_fsm",
+			Name:      "job_num",
+			Help:      "number of jobs in each state of the job fsm",
+		}, []string{"state"})
 )
 
 // initServerMetrics registers statistics of server
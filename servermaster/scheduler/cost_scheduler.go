@@ -32,7 +32,22 @@ func NewDeterministicCostScheduler(capacityProvider CapacityProvider, seed int64
 
 // ScheduleByCost is a native random based scheduling strategy
 func (s *CostScheduler) ScheduleByCost(cost schedModel.ResourceUnit) (model.ExecutorID, bool) {
-	executorCaps := s.capacityProvider.CapacitiesForAllExecutors()
+	return s.scheduleAmong(cost, s.capacityProvider.CapacitiesForAllExecutors())
+}
+
+// Schedule implements SchedulingPolicy.Schedule, it is the default policy
+// of Scheduler.
+func (s *CostScheduler) Schedule(
+	request *schedModel.SchedulerRequest,
+	capacities map[model.ExecutorID]*schedModel.ExecutorResourceStatus,
+) (model.ExecutorID, bool) {
+	return s.scheduleAmong(request.Cost, capacities)
+}
+
+func (s *CostScheduler) scheduleAmong(
+	cost schedModel.ResourceUnit,
+	executorCaps map[model.ExecutorID]*schedModel.ExecutorResourceStatus,
+) (model.ExecutorID, bool) {
 	executorList := make([]model.ExecutorID, 0, len(executorCaps))
 	for executorID := range executorCaps {
 		executorList = append(executorList, executorID)
@@ -0,0 +1,21 @@
+package scheduler
+
+import (
+	"github.com/hanfei1991/microcosm/model"
+	schedModel "github.com/hanfei1991/microcosm/servermaster/scheduler/model"
+)
+
+// SchedulingPolicy decides the executor a task is placed on, for the tasks
+// that are not constrained to an executor by their external resources.
+// Implementing it allows placements such as bin-packing, spreading or
+// affinity without changing the Scheduler.
+type SchedulingPolicy interface {
+	// Schedule returns the executor to run the task of the request among the
+	// executors in capacities, or false if none of them is suitable.
+	Schedule(
+		request *schedModel.SchedulerRequest,
+		capacities map[model.ExecutorID]*schedModel.ExecutorResourceStatus,
+	) (model.ExecutorID, bool)
+}
+
+var _ SchedulingPolicy = (*CostScheduler)(nil)
@@ -16,18 +16,32 @@ import (
 // real scheduler and resource placement manager.
 type Scheduler struct {
 	capacityProvider     CapacityProvider
-	costScheduler        *CostScheduler
+	policy               SchedulingPolicy
 	placementConstrainer PlacementConstrainer
 }
 
-// NewScheduler creates a new Scheduler instance
+// NewScheduler creates a new Scheduler instance, which places the tasks
+// randomly among the executors with enough capacity.
 func NewScheduler(
 	capacityProvider CapacityProvider,
 	placementConstrainer PlacementConstrainer,
+) *Scheduler {
+	return NewSchedulerWithPolicy(
+		capacityProvider,
+		placementConstrainer,
+		NewRandomizedCostScheduler(capacityProvider))
+}
+
+// NewSchedulerWithPolicy creates a new Scheduler instance, which places the
+// tasks without resource constraints by the given policy.
+func NewSchedulerWithPolicy(
+	capacityProvider CapacityProvider,
+	placementConstrainer PlacementConstrainer,
+	policy SchedulingPolicy,
 ) *Scheduler {
 	return &Scheduler{
 		capacityProvider:     capacityProvider,
-		costScheduler:        NewRandomizedCostScheduler(capacityProvider),
+		policy:               policy,
 		placementConstrainer: placementConstrainer,
 	}
 }
@@ -39,7 +53,7 @@ func (s *Scheduler) ScheduleTask(
 ) (*schedModel.SchedulerResponse, error) {
 	if len(request.ExternalResources) == 0 {
 		// There is no requirement for external resources.
-		return s.scheduleByCostOnly(request)
+		return s.scheduleByPolicy(request)
 	}
 
 	constraint, err := s.getConstraint(ctx, request.ExternalResources)
@@ -48,7 +62,7 @@ func (s *Scheduler) ScheduleTask(
 	}
 	if constraint == "" {
 		// No constraint is found
-		return s.scheduleByCostOnly(request)
+		return s.scheduleByPolicy(request)
 	}
 
 	// Checks that the required executor has enough capacity to
@@ -59,16 +73,23 @@ func (s *Scheduler) ScheduleTask(
 	return &schedModel.SchedulerResponse{ExecutorID: constraint}, nil
 }
 
-func (s *Scheduler) scheduleByCostOnly(
+func (s *Scheduler) scheduleByPolicy(
 	request *schedModel.SchedulerRequest,
 ) (*schedModel.SchedulerResponse, error) {
-	target, ok := s.costScheduler.ScheduleByCost(request.Cost)
-	if ok {
-		return &schedModel.SchedulerResponse{
-			ExecutorID: target,
-		}, nil
+	capacities := s.capacityProvider.CapacitiesForAllExecutors()
+	target, ok := s.policy.Schedule(request, capacities)
+	if !ok {
+		return nil, derror.ErrClusterResourceNotEnough.GenWithStackByArgs()
+	}
+	if _, exists := capacities[target]; !exists {
+		// The policy should only choose among the given executors.
+		log.L().Warn("scheduling policy chose an unknown executor",
+			zap.String("executor-id", string(target)))
+		return nil, derror.ErrClusterResourceNotEnough.GenWithStackByArgs()
 	}
-	return nil, derror.ErrClusterResourceNotEnough.GenWithStackByArgs()
+	return &schedModel.SchedulerResponse{
+		ExecutorID: target,
+	}, nil
 }
 
 func (s *Scheduler) checkCostAllows(
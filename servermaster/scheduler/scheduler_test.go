@@ -137,3 +137,92 @@ func TestSchedulerConstraintConflict(t *testing.T) {
 	require.Error(t, err)
 	require.Regexp(t, ".*Scheduler could not assign executor due to conflicting.*", err)
 }
+
+// mostLoadedPolicy is a bin-packing policy, which places a task on the
+// executor with the least remaining capacity that can still run it.
+type mostLoadedPolicy struct {
+	requests []*schedModel.SchedulerRequest
+}
+
+func (p *mostLoadedPolicy) Schedule(
+	request *schedModel.SchedulerRequest,
+	capacities map[model.ExecutorID]*schedModel.ExecutorResourceStatus,
+) (model.ExecutorID, bool) {
+	p.requests = append(p.requests, request)
+
+	var (
+		target    model.ExecutorID
+		remaining schedModel.ResourceUnit
+	)
+	for executorID, status := range capacities {
+		if status.Remaining() < request.Cost {
+			continue
+		}
+		if target == "" || status.Remaining() < remaining ||
+			(status.Remaining() == remaining && executorID < target) {
+			target, remaining = executorID, status.Remaining()
+		}
+	}
+	return target, target != ""
+}
+
+type fixedPolicy struct {
+	target model.ExecutorID
+}
+
+func (p *fixedPolicy) Schedule(
+	_ *schedModel.SchedulerRequest,
+	_ map[model.ExecutorID]*schedModel.ExecutorResourceStatus,
+) (model.ExecutorID, bool) {
+	return p.target, true
+}
+
+func TestSchedulerWithPolicy(t *testing.T) {
+	policy := &mostLoadedPolicy{}
+	sched := NewSchedulerWithPolicy(
+		getMockCapacityDataForScheduler(),
+		getMockResourceConstraintForScheduler(),
+		policy)
+
+	resp, err := sched.ScheduleTask(context.Background(), &schedModel.SchedulerRequest{
+		Cost: 20,
+	})
+	require.NoError(t, err)
+	// executor-2 and executor-3 have the least remaining capacity
+	require.Equal(t, &schedModel.SchedulerResponse{ExecutorID: "executor-2"}, resp)
+
+	resp, err = sched.ScheduleTask(context.Background(), &schedModel.SchedulerRequest{
+		Cost: 35,
+	})
+	require.NoError(t, err)
+	require.Equal(t, &schedModel.SchedulerResponse{ExecutorID: "executor-1"}, resp)
+
+	_, err = sched.ScheduleTask(context.Background(), &schedModel.SchedulerRequest{
+		Cost: 50,
+	})
+	require.Error(t, err)
+	require.Regexp(t, ".*ErrClusterResourceNotEnough.*", err)
+	require.Len(t, policy.requests, 3)
+
+	// resource constraints take precedence over the policy
+	resp, err = sched.ScheduleTask(context.Background(), &schedModel.SchedulerRequest{
+		Cost:              20,
+		ExternalResources: []resourcemeta.ResourceID{"resource-1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &schedModel.SchedulerResponse{ExecutorID: "executor-1"}, resp)
+	require.Len(t, policy.requests, 3)
+}
+
+func TestSchedulerPolicyUnknownExecutor(t *testing.T) {
+	sched := NewSchedulerWithPolicy(
+		getMockCapacityDataForScheduler(),
+		getMockResourceConstraintForScheduler(),
+		&fixedPolicy{target: "executor-4"})
+
+	_, err := sched.ScheduleTask(context.Background(), &schedModel.SchedulerRequest{
+		Cost: 10,
+	})
+	require.Error(t, err)
+	require.Regexp(t, ".*ErrClusterResourceNotEnough.*", err)
+}
@@ -253,6 +253,18 @@ func (m *mockJobManager) GetJobStatuses(ctx context.Context) (map[libModel.Maste
 	panic("not implemented")
 }
 
+func (m *mockJobManager) PauseDispatch() {
+	panic("not implemented")
+}
+
+func (m *mockJobManager) ResumeDispatch() error {
+	panic("not implemented")
+}
+
+func (m *mockJobManager) DispatchPaused() bool {
+	panic("not implemented")
+}
+
 type mockExecutorManager struct {
 	executorMu sync.RWMutex
 	count      map[model.ExecutorStatus]int
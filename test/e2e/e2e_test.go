@@ -103,6 +103,8 @@ func TestSubmitTest(t *testing.T) {
 				SrcHost: demoHost,
 				DstHost: demoHost,
 				FileNum: config.FileNum,
+				// the demo servers don't support TLS
+				Insecure: true,
 			}
 			testSubmitTest(t, cfg, config, demoAddr, flowControl)
 		}(i)